@@ -0,0 +1,128 @@
+package hdwallet
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Account is a key of one chain seen through the operations applications need,
+// so code holding a []Account signs for Bitcoin, Ethereum and TRON the same way
+//
+// Implementations live in the chain packages (bitcoin.NewAccount, ethereum.NewAccount,
+// tron.NewAccount) and sign through a Signer, so keys may be derived by a Wallet or
+// held by an HSM
+type Account interface {
+	// Coin returns the SLIP-0044 coin type of the chain
+	Coin() uint32
+	// Address returns the address in the chain's canonical text form
+	Address() string
+	PublicKey() *PublicKey
+	// SignDigest signs a 32-byte digest and returns the signature in the chain's native
+	// format: 65-byte r || s || recovery ID for Ethereum and TRON, DER for Bitcoin
+	SignDigest(ctx context.Context, digest []byte) ([]byte, error)
+	// SignTransaction signs a serialized unsigned transaction of the chain and returns
+	// it signed, see the implementations for the accepted payloads
+	SignTransaction(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// ContextSigner is implemented by signers that accept audit and policy metadata
+// through a context, such as WalletSigner and AuditedSigner
+type ContextSigner interface {
+	SignDigestContext(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// SignWithContext signs digest with signer, passing ctx along when signer is a ContextSigner
+func SignWithContext(ctx context.Context, signer Signer, digest []byte) ([]byte, error) {
+	if contextSigner, ok := signer.(ContextSigner); ok {
+		return contextSigner.SignDigestContext(ctx, digest)
+	}
+
+	return signer.SignDigest(digest)
+}
+
+// SignerPublicKey returns the secp256k1 public key of signer, which may be a
+// *PublicKey or the dcrd *secp256k1.PublicKey of a third party Signer
+func SignerPublicKey(signer Signer) (*PublicKey, error) {
+	switch publicKey := signer.Public().(type) {
+	case *PublicKey:
+		return publicKey, nil
+	case *secp256k1.PublicKey:
+		return PublicKeyFromSecp256k1(publicKey), nil
+	default:
+		return nil, fmt.Errorf("signer key %T is not a secp256k1 key", publicKey)
+	}
+}
+
+// RecoverableSignature converts a DER signature of digest by publicKey, as returned by
+// any Signer, to the 65-byte r || s || v form of Ethereum and TRON where v is the
+// recovery ID (0 to 3, in practice 0 or 1); high-S signatures are normalized to low S
+func RecoverableSignature(publicKey *PublicKey, digest, signature []byte) ([]byte, error) {
+	parsed, err := ParseDERSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := parsed.Normalize()
+	for recoveryID := range 4 {
+		candidate, err := NewSignature(normalized.R(), normalized.S(), recoveryID)
+		if err != nil {
+			return nil, err
+		}
+		recovered, err := RecoverPublicKey(digest, candidate)
+		if err == nil && recovered.Equal(publicKey) {
+			return candidate.Recoverable()
+		}
+	}
+
+	return nil, errors.New("signature does not match the public key")
+}
+
+// WalletSigner is a Signer for the key at one path of a Wallet
+// Signatures go through the wallet's Policy, Auditor, metrics and lock like SignDigest
+type WalletSigner struct {
+	wallet    *Wallet
+	account   uint32
+	chain     uint32
+	address   uint32
+	publicKey *PublicKey
+}
+
+// Signer returns a Signer for the key at m/44'/coin'/account'/chain/address
+func (w *Wallet) Signer(account, chain, address uint32) (*WalletSigner, error) {
+	publicKey, err := w.PublicKey(account, chain, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletSigner{
+		wallet:    w,
+		account:   account,
+		chain:     chain,
+		address:   address,
+		publicKey: publicKey,
+	}, nil
+}
+
+// Public returns the *PublicKey of the signer
+func (s *WalletSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Path returns the derivation path of the key
+func (s *WalletSigner) Path() DerivationPath {
+	return BIP44Path(s.wallet.coin, s.account, s.chain, s.address)
+}
+
+// SignDigest implements Signer
+func (s *WalletSigner) SignDigest(digest []byte) ([]byte, error) {
+	return s.SignDigestContext(context.Background(), digest)
+}
+
+// SignDigestContext implements ContextSigner
+func (s *WalletSigner) SignDigestContext(ctx context.Context, digest []byte) ([]byte, error) {
+	return s.wallet.SignDigestContext(ctx, s.account, s.chain, s.address, digest)
+}
@@ -0,0 +1,355 @@
+package hdwallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/not-for-prod/hdwallet/codec"
+)
+
+// NormalizeAddress validates address for coin and returns its canonical form:
+// EIP-55 for Ethereum, lowercase bech32 or base58check for Bitcoin, base58check for TRON,
+// lowercase bech32 for the Cosmos Hub
+//
+// Mixed-case Ethereum addresses must carry a valid EIP-55 checksum and bech32
+// addresses must not mix cases, so most single-character typos are rejected
+func NormalizeAddress(coin uint32, address string) (string, error) {
+	address = strings.TrimSpace(address)
+
+	switch coin {
+	case 60: // Ethereum
+		return normalizeEthereumAddress(address)
+	case 195: // TRON
+		return normalizeBase58Address(address, 0x41)
+	case 0: // Bitcoin
+		if strings.HasPrefix(strings.ToLower(address), "bc1") {
+			return normalizeSegwitAddress(address, "bc")
+		}
+		return normalizeBase58Address(address, 0x00, 0x05)
+	case 118: // Cosmos Hub
+		return normalizeBech32Address(address, "cosmos")
+	default:
+		return "", fmt.Errorf("address validation is not supported for coin %d", coin)
+	}
+}
+
+func normalizeEthereumAddress(address string) (string, error) {
+	raw, ok := strings.CutPrefix(address, "0x")
+	if !ok || len(raw) != 40 {
+		return "", fmt.Errorf("invalid Ethereum address %q", address)
+	}
+
+	decoded, err := hex.DecodeString(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid Ethereum address %q: %w", address, err)
+	}
+
+	checksummed := ChecksumEthereumAddress(decoded)
+	if raw != strings.ToLower(raw) && raw != strings.ToUpper(raw) && address != checksummed {
+		return "", fmt.Errorf("invalid Ethereum address %q: EIP-55 checksum mismatch", address)
+	}
+
+	return checksummed, nil
+}
+
+func normalizeBase58Address(address string, versions ...byte) (string, error) {
+	payload, err := codec.Base58CheckDecode(address, codec.Strict)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	if len(payload) != 21 {
+		return "", fmt.Errorf("invalid address %q: wrong length", address)
+	}
+	if bytes.IndexByte(versions, payload[0]) < 0 {
+		return "", fmt.Errorf("invalid address %q: unexpected version byte 0x%02x", address, payload[0])
+	}
+
+	// Base58 has a single encoding per payload, the address is already canonical
+	return address, nil
+}
+
+func normalizeSegwitAddress(address, hrp string) (string, error) {
+	if address != strings.ToLower(address) && address != strings.ToUpper(address) {
+		return "", fmt.Errorf("invalid segwit address %q: mixed case", address)
+	}
+	address = strings.ToLower(address)
+
+	// SegwitDecode enforces the BIP-350 checksum variant and program length of the version
+	decodedHRP, _, _, err := codec.SegwitDecode(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid segwit address %q: %w", address, err)
+	}
+	if decodedHRP != hrp {
+		return "", fmt.Errorf("invalid segwit address %q: wrong network", address)
+	}
+
+	return address, nil
+}
+
+func normalizeBech32Address(address, hrp string) (string, error) {
+	if address != strings.ToLower(address) && address != strings.ToUpper(address) {
+		return "", fmt.Errorf("invalid bech32 address %q: mixed case", address)
+	}
+	address = strings.ToLower(address)
+
+	decodedHRP, payload, variant, err := codec.Bech32Decode(address, codec.Strict)
+	if err != nil {
+		return "", fmt.Errorf("invalid bech32 address %q: %w", address, err)
+	}
+	if decodedHRP != hrp || variant != codec.Bech32 {
+		return "", fmt.Errorf("invalid bech32 address %q: wrong prefix or checksum variant", address)
+	}
+	// Accounts are 20-byte key hashes, module and contract accounts 32-byte hashes
+	if len(payload) != 20 && len(payload) != 32 {
+		return "", fmt.Errorf("invalid bech32 address %q: wrong length", address)
+	}
+
+	return address, nil
+}
+
+// AddressPurpose returns the derivation purpose of the kind of address, BIP84Purpose
+// for a bc1q address for example, so callers can reject addresses no key at their
+// paths can produce. Bitcoin P2WSH and other script addresses are not derived from
+// a single key and are rejected
+func AddressPurpose(coin uint32, address string) (uint32, error) {
+	normalized, err := NormalizeAddress(coin, address)
+	if err != nil {
+		return 0, err
+	}
+	if coin != 0 {
+		return Purpose, nil
+	}
+
+	if strings.HasPrefix(normalized, "bc1") {
+		_, version, program, err := codec.SegwitDecode(normalized)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case version == 0 && len(program) == 20:
+			return BIP84Purpose, nil
+		case version == 1 && len(program) == 32:
+			return BIP86Purpose, nil
+		}
+		return 0, fmt.Errorf("segwit v%d address %s is not a single-key address", version, normalized)
+	}
+
+	payload, err := codec.Base58CheckDecode(normalized, codec.Strict)
+	if err != nil {
+		return 0, err
+	}
+	if payload[0] == 0x05 {
+		return BIP49Purpose, nil
+	}
+
+	return Purpose, nil
+}
+
+// AddressBookEntry is a counterparty address
+type AddressBookEntry struct {
+	Coin uint32 `json:"coin"`
+	// Address is stored in the canonical form returned by NormalizeAddress
+	Address   string    `json:"address"`
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// NearDuplicate flags an existing entry that looks like another address
+// without being equal to it, the signature of address poisoning and typos
+type NearDuplicate struct {
+	Entry AddressBookEntry
+	// Reason is "same prefix and suffix" or "edit distance N"
+	Reason string
+}
+
+// AddressBook stores counterparty addresses in canonical form per coin
+// It is safe for concurrent use
+type AddressBook struct {
+	mu      sync.RWMutex
+	entries map[uint32]map[string]AddressBookEntry
+}
+
+// NewAddressBook returns an address book holding entries, which are validated and normalized
+func NewAddressBook(entries ...AddressBookEntry) (*AddressBook, error) {
+	book := &AddressBook{entries: make(map[uint32]map[string]AddressBookEntry)}
+	for _, entry := range entries {
+		if _, _, err := book.Add(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return book, nil
+}
+
+// Add validates and normalizes entry.Address for entry.Coin and stores the entry,
+// replacing the label of an existing identical address
+// Entries that look like the new address are returned so callers can ask for confirmation
+func (b *AddressBook) Add(entry AddressBookEntry) (AddressBookEntry, []NearDuplicate, error) {
+	normalized, err := NormalizeAddress(entry.Coin, entry.Address)
+	if err != nil {
+		return AddressBookEntry{}, nil, err
+	}
+	entry.Address = normalized
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	similar := b.nearDuplicates(entry.Coin, normalized)
+
+	if b.entries[entry.Coin] == nil {
+		b.entries[entry.Coin] = make(map[string]AddressBookEntry)
+	}
+	b.entries[entry.Coin][normalized] = entry
+
+	return entry, similar, nil
+}
+
+// Lookup returns the entry of an address in any accepted spelling
+func (b *AddressBook) Lookup(coin uint32, address string) (AddressBookEntry, bool, error) {
+	normalized, err := NormalizeAddress(coin, address)
+	if err != nil {
+		return AddressBookEntry{}, false, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	entry, ok := b.entries[coin][normalized]
+
+	return entry, ok, nil
+}
+
+// Check validates an address about to be used, for example as a withdrawal destination,
+// and returns the book entries it resembles without matching; an address that is in
+// the book is not flagged against itself
+func (b *AddressBook) Check(coin uint32, address string) ([]NearDuplicate, error) {
+	normalized, err := NormalizeAddress(coin, address)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.nearDuplicates(coin, normalized), nil
+}
+
+// Remove deletes an address, removing a missing address is not an error
+func (b *AddressBook) Remove(coin uint32, address string) error {
+	normalized, err := NormalizeAddress(coin, address)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.entries[coin], normalized)
+
+	return nil
+}
+
+// Entries returns every entry sorted by coin and address
+func (b *AddressBook) Entries() []AddressBookEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var entries []AddressBookEntry
+	for _, byAddress := range b.entries {
+		for _, entry := range byAddress {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Coin != entries[j].Coin {
+			return entries[i].Coin < entries[j].Coin
+		}
+		return entries[i].Address < entries[j].Address
+	})
+
+	return entries
+}
+
+// nearDuplicates returns the entries of coin resembling address, b.mu must be held
+func (b *AddressBook) nearDuplicates(coin uint32, address string) []NearDuplicate {
+	var similar []NearDuplicate
+	for existing, entry := range b.entries[coin] {
+		if existing == address {
+			continue
+		}
+		if reason := resemblance(existing, address); reason != "" {
+			similar = append(similar, NearDuplicate{Entry: entry, Reason: reason})
+		}
+	}
+	sort.Slice(similar, func(i, j int) bool { return similar[i].Entry.Address < similar[j].Entry.Address })
+
+	return similar
+}
+
+// resemblanceAffix is the number of leading and trailing characters (after the common
+// format prefix) that poisoning attacks typically match, as wallets display them
+const resemblanceAffix = 4
+
+// resemblance explains why two distinct canonical addresses look alike, or returns ""
+// Addresses are compared case-insensitively as EIP-55 casing is not what users read
+func resemblance(a, b string) string {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+
+	body := func(s string) string {
+		for _, prefix := range []string{"0x", "bc1q", "bc1p", "t", "1", "3"} {
+			if rest, ok := strings.CutPrefix(s, prefix); ok {
+				return rest
+			}
+		}
+		return s
+	}
+	ba, bb := body(a), body(b)
+	if len(ba) > 2*resemblanceAffix && len(bb) > 2*resemblanceAffix &&
+		ba[:resemblanceAffix] == bb[:resemblanceAffix] &&
+		ba[len(ba)-resemblanceAffix:] == bb[len(bb)-resemblanceAffix:] {
+		return "same prefix and suffix"
+	}
+
+	if distance := editDistance(a, b, 2); distance <= 2 {
+		return fmt.Sprintf("edit distance %d", distance)
+	}
+
+	return ""
+}
+
+// editDistance returns the Levenshtein distance of a and b, or limit+1 when it exceeds limit
+func editDistance(a, b string, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
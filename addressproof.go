@@ -0,0 +1,201 @@
+package hdwallet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// addressProofVersion is the current AddressProof format version
+const addressProofVersion = 1
+
+// ErrInvalidAddressProof is matched (errors.Is) by every AddressProof verification failure
+var ErrInvalidAddressProof = errors.New("invalid address proof")
+
+// AddressProof attests that an address was derived from an account xpub at a BIP32
+// path and that its owner controls the key: the key of the address signs a canonical
+// statement of the address, its key origin and a verifier-chosen message, such as a
+// travel-rule request ID or an ownership challenge
+//
+// Verification needs nothing but the proof; verifiers compare XPub with the account
+// they have on record, and MasterFingerprint is asserted key-origin data that cannot
+// be checked without the seed
+type AddressProof struct {
+	Version int    `json:"version"`
+	Address string `json:"address"`
+	// AddressFormat is one of the AddressFormat constants
+	AddressFormat string `json:"address_format"`
+	// Path is the full path of the address, m/purpose'/coin'/account'/chain/index with
+	// the purpose and coin of AddressFormat
+	Path              DerivationPath `json:"path"`
+	XPub              string         `json:"xpub"`
+	MasterFingerprint string         `json:"master_fingerprint"`
+	Message           string         `json:"message"`
+	// Signature is the DER encoded signature of the SHA-256 of Statement
+	Signature []byte `json:"signature"`
+}
+
+// ProveAddress returns the proof of the address at m/purpose'/coin'/account'/chain/index,
+// signed over message. The address is encoded in format, one of the AddressFormat
+// constants, which must be derived under purpose for the coin of the wallet; an empty
+// format selects the format of the coin under purpose (AddressFormatBitcoinP2TR for
+// BIP86Purpose, for example), and a zero purpose the default format of the coin
+// The signature is checked by the Policy and reported to the Auditor, if configured
+func (w *Wallet) ProveAddress(purpose uint32, format string, account, chain, index uint32, message string) (*AddressProof, error) {
+	return w.ProveAddressContext(context.Background(), purpose, format, account, chain, index, message)
+}
+
+// ProveAddressContext is ProveAddress with a context carrying audit metadata (see WithAuditContext)
+func (w *Wallet) ProveAddressContext(ctx context.Context, purpose uint32, format string, account, chain, index uint32,
+	message string) (*AddressProof, error) {
+	if format == "" {
+		var err error
+		if format, err = addressFormatFor(w.coin, purpose); err != nil {
+			return nil, err
+		}
+	}
+	origin, ok := addressOrigins[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown address format %q", format)
+	}
+	if origin.coin != w.coin {
+		return nil, fmt.Errorf("address format %s is for coin %d, the wallet is for coin %d", format, origin.coin, w.coin)
+	}
+	if purpose != 0 && purpose != origin.purpose {
+		return nil, fmt.Errorf("address format %s is derived under purpose %d', not %d'", format, origin.purpose, purpose)
+	}
+	if account >= HardenedOffset || chain >= HardenedOffset || index >= HardenedOffset {
+		return nil, errors.New("account, chain and index must be below 2^31")
+	}
+
+	accountPath, err := accountPath(format, account)
+	if err != nil {
+		return nil, err
+	}
+	proof := &AddressProof{
+		Version:       addressProofVersion,
+		AddressFormat: format,
+		Path:          append(accountPath, chain, index),
+		Message:       message,
+	}
+	err = w.withMasterKey(func(masterKey *bip32.Key) error {
+		accountKey, err := DerivePath(masterKey, accountPath)
+		if err != nil {
+			return err
+		}
+		defer wipeKey(accountKey)
+
+		proof.MasterFingerprint = fingerprint(masterKey.PublicKey().Key)
+		proof.XPub = accountKey.PublicKey().String()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := w.deriveKeyAt(proof.Path)
+	if err != nil {
+		return nil, err
+	}
+	proof.Address = addressFormats[format](privateKey.PublicKey())
+	privateKey.Zero()
+
+	start := time.Now()
+	digest := sha256.Sum256([]byte(proof.Statement()))
+	proof.Signature, err = w.signDigestAt(ctx, proof.Path, digest[:])
+	observe(w.observer(), MetricSign, coinLabel(w.coin), start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
+
+// Statement returns the canonical text signed by the proof
+func (p *AddressProof) Statement() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "hdwallet address proof v%d\n", p.Version)
+	fmt.Fprintf(&builder, "address: %s\n", p.Address)
+	fmt.Fprintf(&builder, "address format: %s\n", p.AddressFormat)
+	fmt.Fprintf(&builder, "path: %s\n", p.Path)
+	fmt.Fprintf(&builder, "xpub: %s\n", p.XPub)
+	fmt.Fprintf(&builder, "master fingerprint: %s\n", p.MasterFingerprint)
+	fmt.Fprintf(&builder, "message: %q", p.Message)
+
+	return builder.String()
+}
+
+// Verify checks that the proof was made for message, that AddressFormat is derived
+// under the purpose and coin of Path, that Address is derived from XPub at Path and
+// that the key of the address signed the statement
+func (p *AddressProof) Verify(message string) error {
+	if p.Version != addressProofVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidAddressProof, p.Version)
+	}
+	if p.Message != message {
+		return fmt.Errorf("%w: proof was made for message %q", ErrInvalidAddressProof, p.Message)
+	}
+
+	format, ok := addressFormats[p.AddressFormat]
+	if !ok {
+		return fmt.Errorf("%w: unknown address format %q", ErrInvalidAddressProof, p.AddressFormat)
+	}
+	origin := addressOrigins[p.AddressFormat]
+	if len(p.Path) < 2 || p.Path[0] != origin.purpose+HardenedOffset || p.Path[1] != origin.coin+HardenedOffset {
+		return fmt.Errorf("%w: %s addresses are not derived at %s", ErrInvalidAddressProof, p.AddressFormat, p.Path)
+	}
+
+	publicKey, err := p.derivePublicKey()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAddressProof, err)
+	}
+	if address := format(publicKey); address != p.Address {
+		return fmt.Errorf("%w: %s at %s is %s, not %s", ErrInvalidAddressProof, p.XPub, p.Path, address, p.Address)
+	}
+
+	digest := sha256.Sum256([]byte(p.Statement()))
+	if !VerifyDigest(publicKey, digest[:], p.Signature) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidAddressProof)
+	}
+
+	return nil
+}
+
+// derivePublicKey derives the key at Path below XPub, checking that XPub is the
+// account key of Path
+func (p *AddressProof) derivePublicKey() (*PublicKey, error) {
+	path := p.Path
+	if len(path) != 5 || path[0] < HardenedOffset || path[1] < HardenedOffset ||
+		path[2] < HardenedOffset || path[3] >= HardenedOffset || path[4] >= HardenedOffset {
+		return nil, fmt.Errorf("path %s is not an account address path", path)
+	}
+
+	account, err := bip32.B58Deserialize(p.XPub)
+	if err != nil {
+		return nil, fmt.Errorf("parse xpub: %w", err)
+	}
+	if account.IsPrivate {
+		return nil, errors.New("xpub is an extended private key")
+	}
+	if account.Depth != 3 || len(account.ChildNumber) != 4 || binary.BigEndian.Uint32(account.ChildNumber) != path[2] {
+		return nil, fmt.Errorf("xpub is not the key of account %s", path[:3])
+	}
+
+	chainKey, err := account.NewChildKey(path[3])
+	if err != nil {
+		return nil, err
+	}
+	child, err := chainKey.NewChildKey(path[4])
+	if err != nil {
+		return nil, err
+	}
+
+	return PublicKeyFromBytes(child.Key)
+}
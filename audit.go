@@ -0,0 +1,264 @@
+package hdwallet
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+)
+
+// AuditOperation is the kind of key usage reported to an Auditor
+type AuditOperation string
+
+const (
+	// AuditDeriveKey is reported when a private key is derived and handed to the caller
+	AuditDeriveKey AuditOperation = "derive_key"
+	// AuditSign is reported when a digest is signed
+	AuditSign AuditOperation = "sign"
+)
+
+// AuditEvent describes a single private key access or signature
+type AuditEvent struct {
+	Time      time.Time      `json:"time"`
+	Operation AuditOperation `json:"operation"`
+	Coin      uint32         `json:"coin"`
+	// Path is the derivation path of the key, empty for keys of external signers without a known path
+	Path DerivationPath `json:"path,omitempty"`
+	// DigestHash is the hex encoded SHA-256 of the signed digest, so logs identify
+	// signed payloads without storing them; empty for derivations
+	DigestHash string `json:"digest_hash,omitempty"`
+	// Context is the caller-supplied metadata attached with WithAuditContext
+	// (request ID, operator, customer ID...)
+	Context map[string]string `json:"context,omitempty"`
+}
+
+// Auditor receives an event before every audited key access or signature
+// Returning an error aborts the operation, so a failing audit log fails closed
+type Auditor interface {
+	Audit(event AuditEvent) error
+}
+
+// AuditorFunc adapts a function to the Auditor interface
+type AuditorFunc func(event AuditEvent) error
+
+// Audit calls f(event)
+func (f AuditorFunc) Audit(event AuditEvent) error {
+	return f(event)
+}
+
+type auditContextKey struct{}
+
+// WithAuditContext returns a copy of ctx carrying values that are attached to the
+// AuditEvent of every operation performed with it
+// Values already present in ctx are kept unless overridden
+func WithAuditContext(ctx context.Context, values map[string]string) context.Context {
+	merged := make(map[string]string, len(values))
+	if parent, ok := ctx.Value(auditContextKey{}).(map[string]string); ok {
+		maps.Copy(merged, parent)
+	}
+	maps.Copy(merged, values)
+
+	return context.WithValue(ctx, auditContextKey{}, merged)
+}
+
+// newAuditEvent builds the event of an operation, hashing digest and copying the caller context
+func newAuditEvent(ctx context.Context, operation AuditOperation, coin uint32, path DerivationPath,
+	digest []byte) AuditEvent {
+	event := AuditEvent{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Coin:      coin,
+		Path:      path,
+	}
+
+	if digest != nil {
+		hash := sha256.Sum256(digest)
+		event.DigestHash = hex.EncodeToString(hash[:])
+	}
+
+	if values, ok := ctx.Value(auditContextKey{}).(map[string]string); ok {
+		event.Context = maps.Clone(values)
+	}
+
+	return event
+}
+
+// AuditedSigner reports every signature of an external Signer (HSM, hardware token)
+// to an Auditor, so signers that are not derived by a Wallet are audited the same way
+type AuditedSigner struct {
+	signer  Signer
+	auditor Auditor
+	coin    uint32
+	path    DerivationPath
+}
+
+// NewAuditedSigner wraps signer; coin and path describe the key in the emitted events
+func NewAuditedSigner(signer Signer, auditor Auditor, coin uint32, path DerivationPath) *AuditedSigner {
+	return &AuditedSigner{signer: signer, auditor: auditor, coin: coin, path: path}
+}
+
+// Public returns the public key of the wrapped signer
+func (s *AuditedSigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+// SignDigest audits and then signs digest with the wrapped signer
+func (s *AuditedSigner) SignDigest(digest []byte) ([]byte, error) {
+	return s.SignDigestContext(context.Background(), digest)
+}
+
+// SignDigestContext is SignDigest with a context carrying audit metadata
+func (s *AuditedSigner) SignDigestContext(ctx context.Context, digest []byte) ([]byte, error) {
+	if err := s.auditor.Audit(newAuditEvent(ctx, AuditSign, s.coin, s.path, digest)); err != nil {
+		return nil, fmt.Errorf("audit %s %s: %w", AuditSign, s.path, err)
+	}
+
+	return s.signer.SignDigest(digest)
+}
+
+// HashChainAuditor is an Auditor writing tamper-evident JSON Lines records
+//
+// Every record carries the hash of the previous record, and its own hash covers
+// that link, its sequence number and the event. Editing, removing or reordering
+// any record breaks the chain, which VerifyAuditLog detects
+type HashChainAuditor struct {
+	mu   sync.Mutex
+	w    io.Writer
+	seq  uint64
+	prev [sha256.Size]byte
+}
+
+// auditRecord is a single line of a HashChainAuditor log
+type auditRecord struct {
+	Seq   uint64          `json:"seq"`
+	Prev  string          `json:"prev"`
+	Event json.RawMessage `json:"event"`
+	Hash  string          `json:"hash"`
+}
+
+// NewHashChainAuditor returns an auditor appending records to w
+// The chain starts from an all-zero previous hash; use ResumeHashChainAuditor to
+// continue an existing log after a restart
+func NewHashChainAuditor(w io.Writer) *HashChainAuditor {
+	return &HashChainAuditor{w: w}
+}
+
+// ResumeHashChainAuditor verifies the existing log and returns an auditor appending
+// records to w that continue its chain; an empty log starts a new chain
+//
+// Typically log and w are the same file, opened for reading and then for appending
+func ResumeHashChainAuditor(w io.Writer, log io.Reader) (*HashChainAuditor, error) {
+	seq, prev, err := verifyAuditChain(log)
+	if err != nil {
+		return nil, fmt.Errorf("resume audit log: %w", err)
+	}
+
+	return &HashChainAuditor{w: w, seq: seq, prev: prev}, nil
+}
+
+// Audit implements Auditor
+func (a *HashChainAuditor) Audit(event AuditEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	hash := auditRecordHash(a.prev, a.seq, payload)
+	line, err := json.Marshal(auditRecord{
+		Seq:   a.seq,
+		Prev:  hex.EncodeToString(a.prev[:]),
+		Event: payload,
+		Hash:  hex.EncodeToString(hash[:]),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = a.w.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	a.seq++
+	a.prev = hash
+
+	return nil
+}
+
+// VerifyAuditLog checks the hash chain of a log written by HashChainAuditor
+// and returns the number of verified records
+func VerifyAuditLog(r io.Reader) (uint64, error) {
+	seq, _, err := verifyAuditChain(r)
+	if err != nil {
+		return seq, err
+	}
+	if seq == 0 {
+		return 0, errors.New("audit log is empty")
+	}
+
+	return seq, nil
+}
+
+// verifyAuditChain checks the hash chain of a log and returns the sequence number
+// and previous hash of the next record
+func verifyAuditChain(r io.Reader) (uint64, [sha256.Size]byte, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var (
+		seq  uint64
+		prev [sha256.Size]byte
+	)
+	for scanner.Scan() {
+		var record auditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return seq, prev, fmt.Errorf("record %d: %w", seq, err)
+		}
+
+		if record.Seq != seq {
+			return seq, prev, fmt.Errorf("record %d: unexpected sequence number %d", seq, record.Seq)
+		}
+		if record.Prev != hex.EncodeToString(prev[:]) {
+			return seq, prev, fmt.Errorf("record %d: broken link to previous record", seq)
+		}
+
+		hash := auditRecordHash(prev, seq, record.Event)
+		claimed, err := hex.DecodeString(record.Hash)
+		if err != nil || !bytes.Equal(claimed, hash[:]) {
+			return seq, prev, fmt.Errorf("record %d: hash mismatch", seq)
+		}
+
+		seq++
+		prev = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return seq, prev, err
+	}
+
+	return seq, prev, nil
+}
+
+// auditRecordHash computes SHA-256(prev || seq || event)
+func auditRecordHash(prev [sha256.Size]byte, seq uint64, event []byte) [sha256.Size]byte {
+	hash := sha256.New()
+	hash.Write(prev[:])
+	_ = binary.Write(hash, binary.BigEndian, seq)
+	hash.Write(event)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], hash.Sum(nil))
+
+	return sum
+}
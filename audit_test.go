@@ -0,0 +1,75 @@
+package hdwallet
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// auditLog returns a log of count records written by a HashChainAuditor, one per line
+func auditLog(t *testing.T, count int) []string {
+	t.Helper()
+	var log bytes.Buffer
+	auditor := NewHashChainAuditor(&log)
+	for i := range count {
+		ctx := WithAuditContext(context.Background(), map[string]string{"request": string(rune('a' + i))})
+		event := newAuditEvent(ctx, AuditSign, 195, BIP44Path(195, 0, 0, uint32(i)), []byte{byte(i)})
+		if err := auditor.Audit(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return strings.SplitAfter(strings.TrimSuffix(log.String(), "\n"), "\n")
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	lines := auditLog(t, 4)
+
+	for _, test := range []struct {
+		name  string
+		lines []string
+		want  uint64
+		valid bool
+	}{
+		{"intact", lines, 4, true},
+		{"edited event", []string{lines[0], strings.Replace(lines[1], `"coin":195`, `"coin":60`, 1), lines[2], lines[3]}, 1, false},
+		{"edited hash", []string{lines[0], lines[1], strings.Replace(lines[2], `"hash":"`, `"hash":"00`, 1), lines[3]}, 2, false},
+		{"reordered", []string{lines[0], lines[2], lines[1], lines[3]}, 1, false},
+		{"removed", []string{lines[0], lines[1], lines[3]}, 2, false},
+		{"truncated head", lines[1:], 0, false},
+		{"truncated tail", lines[:3], 3, true},
+		{"empty", nil, 0, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			verified, err := VerifyAuditLog(strings.NewReader(strings.Join(test.lines, "")))
+			if (err == nil) != test.valid {
+				t.Fatalf("VerifyAuditLog error = %v, want valid %v", err, test.valid)
+			}
+			if verified != test.want {
+				t.Errorf("VerifyAuditLog verified %d records, want %d", verified, test.want)
+			}
+		})
+	}
+}
+
+func TestResumeHashChainAuditor(t *testing.T) {
+	lines := auditLog(t, 2)
+	log := bytes.NewBufferString(strings.Join(lines, "") + "\n")
+
+	auditor, err := ResumeHashChainAuditor(log, bytes.NewReader(log.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = auditor.Audit(newAuditEvent(context.Background(), AuditDeriveKey, 195, BIP44Path(195, 0, 0, 9), nil)); err != nil {
+		t.Fatal(err)
+	}
+	if verified, err := VerifyAuditLog(bytes.NewReader(log.Bytes())); err != nil || verified != 3 {
+		t.Fatalf("VerifyAuditLog = %d, %v, want 3 records", verified, err)
+	}
+
+	tampered := strings.Replace(lines[1], `"seq":1`, `"seq":2`, 1)
+	if _, err = ResumeHashChainAuditor(&bytes.Buffer{}, strings.NewReader(lines[0]+tampered+"\n")); err == nil {
+		t.Error("ResumeHashChainAuditor accepted a broken chain")
+	}
+}
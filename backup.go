@@ -0,0 +1,249 @@
+package hdwallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tyler-smith/go-bip32"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Backup key scopes below the root key, see BackupKey.Child
+const (
+	BackupScopeLabels   = "labels"
+	BackupScopeMetadata = "metadata"
+)
+
+const (
+	// encryptedBackupVersion is the current EncryptedBackup format version
+	encryptedBackupVersion = 1
+	// backupKeySalt domain-separates the HKDF deriving backup keys
+	backupKeySalt = "hdwallet-backup-key/v1"
+)
+
+// BackupKey is a symmetric key encrypting label and metadata backups, derived
+// deterministically from the seed so that backups are restored with the mnemonic alone
+//
+// The root key of a wallet is 32 bytes of BIP85 HEX entropy; scoped keys are derived
+// from it with HKDF, for example root/coin-60/account-0/labels. A key decrypts the
+// backups of its own scope only, and derives the keys of every scope below it, so a
+// restore service can be given the key of one account without the others
+type BackupKey struct {
+	scope string
+	key   []byte
+}
+
+// NewBackupKey returns the root backup key of masterKey, derived from the BIP85 HEX
+// application at m/83696968'/128169'/32'/index'. Bumping index rotates every backup key
+func NewBackupKey(masterKey *bip32.Key, index uint32) (*BackupKey, error) {
+	entropy, err := BIP85Hex(masterKey, 32, index)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(entropy)
+
+	return newBackupKey(entropy, "root", "root")
+}
+
+// BackupKey returns the root backup key of the wallet at BIP85 index, see NewBackupKey
+// The derivation is checked by the Policy and reported to the Auditor, if configured
+func (w *Wallet) BackupKey(index uint32) (*BackupKey, error) {
+	return w.BackupKeyContext(context.Background(), index)
+}
+
+// BackupKeyContext is BackupKey with a context carrying audit metadata (see WithAuditContext)
+func (w *Wallet) BackupKeyContext(ctx context.Context, index uint32) (*BackupKey, error) {
+	if index >= HardenedOffset {
+		return nil, fmt.Errorf("BIP85 index %d is not below 2^31", index)
+	}
+	path := DerivationPath{
+		BIP85Purpose + HardenedOffset,
+		BIP85ApplicationHex + HardenedOffset,
+		32 + HardenedOffset,
+		index + HardenedOffset,
+	}
+	if err := w.authorize(ctx, AuditDeriveKey, path, nil); err != nil {
+		return nil, err
+	}
+
+	var key *BackupKey
+	err := w.withMasterKey(func(masterKey *bip32.Key) error {
+		var err error
+		key, err = NewBackupKey(masterKey, index)
+		return err
+	})
+
+	return key, err
+}
+
+// newBackupKey expands ikm into the key of scope
+func newBackupKey(ikm []byte, scope, info string) (*BackupKey, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, []byte(backupKeySalt), []byte(info)), key); err != nil {
+		return nil, err
+	}
+
+	return &BackupKey{scope: scope, key: key}, nil
+}
+
+// Child derives the key of the named scope below k
+func (k *BackupKey) Child(name string) (*BackupKey, error) {
+	if name == "" || strings.ContainsAny(name, "/ ") {
+		return nil, fmt.Errorf("invalid backup scope name %q", name)
+	}
+	if k.key == nil {
+		return nil, errors.New("backup key is wiped")
+	}
+	scope := k.scope + "/" + name
+
+	return newBackupKey(k.key, scope, scope)
+}
+
+// Account derives the key of an account of coin, root/coin-<coin>/account-<account>
+func (k *BackupKey) Account(coin, account uint32) (*BackupKey, error) {
+	coinKey, err := k.Child(fmt.Sprintf("coin-%d", coin))
+	if err != nil {
+		return nil, err
+	}
+	defer coinKey.Zero()
+
+	return coinKey.Child(fmt.Sprintf("account-%d", account))
+}
+
+// Scope returns the scope path of the key, for example "root/coin-60/account-0"
+func (k *BackupKey) Scope() string {
+	return k.scope
+}
+
+// ID returns a hex identifier of the key, recorded in its backups so restore tools
+// can tell a wrong key from a corrupted backup
+func (k *BackupKey) ID() string {
+	mac := hmac.New(sha256.New, k.key)
+	mac.Write([]byte("hdwallet-backup-key-id"))
+
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Zero wipes the key from memory
+func (k *BackupKey) Zero() {
+	wipeBytes(k.key)
+	k.key = nil
+}
+
+// EncryptedBackup is a backup payload encrypted under a BackupKey
+type EncryptedBackup struct {
+	Version    int    `json:"version"`
+	Scope      string `json:"scope"`
+	KeyID      string `json:"key_id"`
+	Cipher     string `json:"cipher"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Encrypt encrypts plaintext with AES-256-GCM under k
+func (k *BackupKey) Encrypt(plaintext []byte) (*EncryptedBackup, error) {
+	if k.key == nil {
+		return nil, errors.New("backup key is wiped")
+	}
+
+	aead, err := newSecretAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if err = readEntropy(DefaultEntropySource(), nonce); err != nil {
+		return nil, err
+	}
+
+	backup := &EncryptedBackup{
+		Version: encryptedBackupVersion,
+		Scope:   k.scope,
+		KeyID:   k.ID(),
+		Cipher:  "aes-256-gcm",
+		Nonce:   nonce,
+	}
+	backup.Ciphertext = aead.Seal(nil, nonce, plaintext, backup.additionalData())
+
+	return backup, nil
+}
+
+// Decrypt returns the plaintext of backup, failing when it was encrypted under
+// another key or modified
+func (k *BackupKey) Decrypt(backup *EncryptedBackup) ([]byte, error) {
+	if backup.Version != encryptedBackupVersion {
+		return nil, fmt.Errorf("unsupported encrypted backup version %d", backup.Version)
+	}
+	if backup.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported cipher %q", backup.Cipher)
+	}
+	if k.key == nil {
+		return nil, errors.New("backup key is wiped")
+	}
+	if backup.Scope != k.scope || backup.KeyID != k.ID() {
+		return nil, fmt.Errorf("backup of scope %s (key %s) is not encrypted under the key of %s (key %s)",
+			backup.Scope, backup.KeyID, k.scope, k.ID())
+	}
+
+	aead, err := newSecretAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(backup.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+
+	plaintext, err := aead.Open(nil, backup.Nonce, backup.Ciphertext, backup.additionalData())
+	if err != nil {
+		return nil, errors.New("corrupted backup")
+	}
+
+	return plaintext, nil
+}
+
+// additionalData binds the format version, cipher and scope to the ciphertext
+func (b *EncryptedBackup) additionalData() []byte {
+	return []byte(fmt.Sprintf("hdwallet-encrypted-backup/v%d/%s/%s", b.Version, b.Cipher, b.Scope))
+}
+
+// ExportLabelsEncrypted writes the labels of store to w as a JSON EncryptedBackup of
+// their BIP-329 export, encrypted under key (typically the "labels" child of a root
+// or account backup key)
+func ExportLabelsEncrypted(w io.Writer, store LabelStore, key *BackupKey) error {
+	var plaintext bytes.Buffer
+	if err := ExportLabels(&plaintext, store); err != nil {
+		return err
+	}
+	defer wipeBytes(plaintext.Bytes())
+
+	backup, err := key.Encrypt(plaintext.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(backup)
+}
+
+// ImportLabelsEncrypted reads a backup written by ExportLabelsEncrypted into store and
+// returns the number of imported labels, see ImportLabels
+func ImportLabelsEncrypted(r io.Reader, store LabelStore, key *BackupKey) (int, error) {
+	var backup EncryptedBackup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return 0, fmt.Errorf("decode encrypted backup: %w", err)
+	}
+
+	plaintext, err := key.Decrypt(&backup)
+	if err != nil {
+		return 0, err
+	}
+	defer wipeBytes(plaintext)
+
+	return ImportLabels(bytes.NewReader(plaintext), store)
+}
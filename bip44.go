@@ -1,12 +1,26 @@
 package hdwallet
 
-import "github.com/tyler-smith/go-bip32"
+import (
+	"fmt"
+
+	"github.com/tyler-smith/go-bip32"
+)
 
 const (
 	HardenedOffset uint32 = 0x80000000
 	Purpose        uint32 = 44
 )
 
+// Purposes of the single-key Bitcoin address types derived outside BIP44
+const (
+	// BIP49Purpose derives P2WPKH nested in P2SH addresses, 3...
+	BIP49Purpose uint32 = 49
+	// BIP84Purpose derives native P2WPKH addresses, bc1q...
+	BIP84Purpose uint32 = 84
+	// BIP86Purpose derives single-key P2TR addresses, bc1p...
+	BIP86Purpose uint32 = 86
+)
+
 // DeriveKeyFromPath derives a private key from a master key using BIP44 hierarchical deterministic derivation
 // BIP44 defines a specific derivation path structure: m/purpose'/coin_type'/account'/change/address_index
 // Where:
@@ -20,28 +34,12 @@ const (
 // Hardened derivation provides additional security by making it impossible to derive
 // the parent private key from a child private key and parent public key
 func DeriveKeyFromPath(masterKey *bip32.Key, coin, account, chain, address uint32) (*bip32.Key, error) {
-	// Step 1: Derive purpose level (m/44')
-	// Purpose is hardened and set to 44 (0x8000002C) as per BIP44 specification
-	// This level identifies that we're using BIP44 derivation standard
-	child, err := masterKey.NewChildKey(Purpose + HardenedOffset)
-	if err != nil {
+	if err := checkBIP44Indexes(coin, account, chain, address); err != nil {
 		return nil, err
 	}
 
-	// Step 2: Derive coin type level (m/44'/coin_type')
-	// Coin type is hardened and identifies the cryptocurrency
-	// Examples: Bitcoin=0', Testnet=1', Litecoin=2', Ethereum=60'
-	// Full list: https://github.com/satoshilabs/slips/blob/master/slip-0044.md
-	child, err = child.NewChildKey(coin + HardenedOffset)
-	if err != nil {
-		return nil, err
-	}
-
-	// Step 3: Derive account level (m/44'/coin_type'/account')
-	// Account is hardened and allows users to segregate funds into multiple accounts
-	// Accounts are numbered from 0' and should be used sequentially
-	// This enables features like separate accounts for different purposes
-	child, err = child.NewChildKey(account + HardenedOffset)
+	// Steps 1-3: Derive the hardened account level (m/44'/coin_type'/account')
+	child, err := DeriveAccountKey(masterKey, coin, account)
 	if err != nil {
 		return nil, err
 	}
@@ -71,3 +69,54 @@ func DeriveKeyFromPath(masterKey *bip32.Key, coin, account, chain, address uint3
 	// - Sign transactions
 	return child, nil
 }
+
+// DeriveAccountKey derives the BIP44 account level key m/44'/coin_type'/account' from a master key
+// The account key is the last hardened level of the path: its extended public key (xpub)
+// is enough to derive every receiving and change address of the account without the seed
+func DeriveAccountKey(masterKey *bip32.Key, coin, account uint32) (*bip32.Key, error) {
+	if err := checkBIP44Indexes(coin, account, 0, 0); err != nil {
+		return nil, err
+	}
+
+	// Step 1: Derive purpose level (m/44')
+	// Purpose is hardened and set to 44 (0x8000002C) as per BIP44 specification
+	// This level identifies that we're using BIP44 derivation standard
+	child, err := masterKey.NewChildKey(Purpose + HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Derive coin type level (m/44'/coin_type')
+	// Coin type is hardened and identifies the cryptocurrency
+	// Examples: Bitcoin=0', Testnet=1', Litecoin=2', Ethereum=60'
+	// Full list: https://github.com/satoshilabs/slips/blob/master/slip-0044.md
+	child, err = child.NewChildKey(coin + HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 3: Derive account level (m/44'/coin_type'/account')
+	// Account is hardened and allows users to segregate funds into multiple accounts
+	// Accounts are numbered from 0' and should be used sequentially
+	// This enables features like separate accounts for different purposes
+	child, err = child.NewChildKey(account + HardenedOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	return child, nil
+}
+
+// DerivePath derives the key at path below key, one level at a time
+// key may be an extended public key when path has no hardened level
+func DerivePath(key *bip32.Key, path DerivationPath) (*bip32.Key, error) {
+	child := key
+	for _, index := range path {
+		var err error
+		if child, err = child.NewChildKey(index); err != nil {
+			return nil, fmt.Errorf("derive %s: %w", path, err)
+		}
+	}
+
+	return child, nil
+}
@@ -0,0 +1,72 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+const (
+	// BIP85Purpose is the purpose level of BIP85 deterministic entropy, m/83696968'
+	BIP85Purpose = 83696968
+	// BIP85ApplicationHex is the BIP85 application of raw hex entropy
+	BIP85ApplicationHex = 128169
+)
+
+// bip85HMACKey is the HMAC-SHA512 key applied to derived BIP85 private keys
+var bip85HMACKey = []byte("bip-entropy-from-k")
+
+// DeriveBIP85Entropy returns the 64 bytes of BIP85 entropy of path, which must start
+// with m/83696968' and be hardened at every level
+//
+// BIP85 entropy is independent of the wallet's keys and of every other path, so
+// child secrets (passwords, backup keys, child mnemonics) can be handed out without
+// exposing the seed, and are recovered from the mnemonic alone
+func DeriveBIP85Entropy(masterKey *bip32.Key, path DerivationPath) ([]byte, error) {
+	if len(path) < 2 || path[0] != BIP85Purpose+HardenedOffset {
+		return nil, fmt.Errorf("BIP85 path %s must start with m/%d'", path, BIP85Purpose)
+	}
+	for _, index := range path {
+		if index < HardenedOffset {
+			return nil, fmt.Errorf("BIP85 path %s must be hardened at every level", path)
+		}
+	}
+
+	key, err := DerivePath(masterKey, path)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeKey(key)
+
+	mac := hmac.New(sha512.New, bip85HMACKey)
+	mac.Write(key.Key)
+
+	return mac.Sum(nil), nil
+}
+
+// BIP85Hex returns length bytes (16 to 64) of the BIP85 HEX application at
+// m/83696968'/128169'/length'/index'
+func BIP85Hex(masterKey *bip32.Key, length, index uint32) ([]byte, error) {
+	if length < 16 || length > 64 {
+		return nil, errors.New("BIP85 hex entropy length must be between 16 and 64 bytes")
+	}
+	if index >= HardenedOffset {
+		return nil, fmt.Errorf("BIP85 index %d is not below 2^31", index)
+	}
+
+	entropy, err := DeriveBIP85Entropy(masterKey, DerivationPath{
+		BIP85Purpose + HardenedOffset,
+		BIP85ApplicationHex + HardenedOffset,
+		length + HardenedOffset,
+		index + HardenedOffset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(entropy[length:])
+
+	return entropy[:length:length], nil
+}
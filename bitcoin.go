@@ -0,0 +1,73 @@
+package hdwallet
+
+import (
+	"crypto/sha256"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/not-for-prod/hdwallet/codec"
+)
+
+// GenerateBitcoinP2PKHAddress generates the legacy mainnet address of a public key,
+// base58check of 0x00 and RIPEMD-160(SHA-256(compressed key)), derived under m/44'
+//
+// Example: 1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA
+func GenerateBitcoinP2PKHAddress(publicKey *PublicKey) string {
+	return codec.Base58CheckEncode(append([]byte{0x00}, hash160(publicKey.SerializeCompressed())...))
+}
+
+// GenerateBitcoinP2SHP2WPKHAddress generates the mainnet address of the P2WPKH script of
+// a public key nested in P2SH, as derived under m/49' (BIP-49)
+//
+// Example: 37VucYSaXLCAsxYyAPfbSi9eh4iEcbShgf
+func GenerateBitcoinP2SHP2WPKHAddress(publicKey *PublicKey) string {
+	redeemScript := append([]byte{0x00, 0x14}, hash160(publicKey.SerializeCompressed())...)
+
+	return codec.Base58CheckEncode(append([]byte{0x05}, hash160(redeemScript)...))
+}
+
+// GenerateBitcoinP2WPKHAddress generates the native segwit mainnet address of a public
+// key, as derived under m/84' (BIP-84)
+//
+// Example: bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu
+func GenerateBitcoinP2WPKHAddress(publicKey *PublicKey) string {
+	// A 20-byte version 0 program is always valid
+	address, _ := codec.SegwitEncode("bc", 0, hash160(publicKey.SerializeCompressed()))
+
+	return address
+}
+
+// GenerateBitcoinP2TRAddress generates the single-key taproot mainnet address of a public
+// key, committing to no script path, as derived under m/86' (BIP-86)
+//
+// Example: bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr
+func GenerateBitcoinP2TRAddress(publicKey *PublicKey) string {
+	// A 32-byte version 1 program is always valid
+	address, _ := codec.SegwitEncode("bc", 1, taprootOutputKey(publicKey))
+
+	return address
+}
+
+// taprootOutputKey returns the x-only BIP-341 output key Q = P + tG of the internal key P
+// with t = hash_TapTweak(x(P)), P taken with an even Y coordinate
+func taprootOutputKey(publicKey *PublicKey) []byte {
+	xOnly := publicKey.SerializeCompressed()[1:]
+
+	tag := sha256.Sum256([]byte("TapTweak"))
+	hash := sha256.New()
+	hash.Write(tag[:])
+	hash.Write(tag[:])
+	hash.Write(xOnly)
+
+	// A tweak out of range has negligible probability, BIP-341 fails the key then
+	var tweak secp256k1.ModNScalar
+	tweak.SetByteSlice(hash.Sum(nil))
+
+	var internal, tweakPoint, output secp256k1.JacobianPoint
+	evenKey, _ := secp256k1.ParsePubKey(append([]byte{0x02}, xOnly...))
+	evenKey.AsJacobian(&internal)
+	secp256k1.ScalarBaseMultNonConst(&tweak, &tweakPoint)
+	secp256k1.AddNonConst(&internal, &tweakPoint, &output)
+	output.ToAffine()
+
+	return secp256k1.NewPublicKey(&output.X, &output.Y).SerializeCompressed()[1:]
+}
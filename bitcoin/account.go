@@ -0,0 +1,230 @@
+package bitcoin
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/not-for-prod/hdwallet"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// CoinType is the SLIP-0044 coin type of Bitcoin
+const CoinType = 0
+
+// Asset is the asset name of bitcoin in the signing intents of SignTransaction
+const Asset = "BTC"
+
+// Account is the hdwallet.Account of a Bitcoin key, receiving on its P2WPKH address
+type Account struct {
+	signer       hdwallet.Signer
+	publicKey    *hdwallet.PublicKey
+	pubKeyHash   [20]byte
+	address      string
+	network      Network
+	sigHashTypes []SigHashType
+}
+
+var _ hdwallet.Account = (*Account)(nil)
+
+// AccountOption configures an Account
+type AccountOption func(*Account)
+
+// AllowSigHashTypes lets SignTransaction sign inputs whose PSBT requests one of types,
+// besides SIGHASH_ALL. The other types leave parts of the transaction open after
+// signing: SIGHASH_NONE commits to no output, SIGHASH_SINGLE to a single one and
+// SIGHASH_ANYONECANPAY to no other input, so a PSBT requesting them could be turned
+// into a different payment; only allow them for protocols that rely on them
+func AllowSigHashTypes(types ...SigHashType) AccountOption {
+	return func(a *Account) {
+		a.sigHashTypes = append(a.sigHashTypes, types...)
+	}
+}
+
+// NewAccount returns the Bitcoin account of signer on network, typically Wallet.Signer
+// or an HSM signer
+func NewAccount(signer hdwallet.Signer, network Network, opts ...AccountOption) (*Account, error) {
+	publicKey, err := hdwallet.SignerPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	var pubKeyHash [20]byte
+	copy(pubKeyHash[:], hash160(publicKey.SerializeCompressed()))
+	address, err := SegwitAddress(network, 0, pubKeyHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{
+		signer:       signer,
+		publicKey:    publicKey,
+		pubKeyHash:   pubKeyHash,
+		address:      address,
+		network:      network,
+		sigHashTypes: []SigHashType{SigHashAll},
+	}
+	for _, opt := range opts {
+		opt(account)
+	}
+
+	return account, nil
+}
+
+// Coin implements hdwallet.Account
+func (a *Account) Coin() uint32 {
+	return CoinType
+}
+
+// Address returns the P2WPKH address of the account
+func (a *Account) Address() string {
+	return a.address
+}
+
+// PublicKey implements hdwallet.Account
+func (a *Account) PublicKey() *hdwallet.PublicKey {
+	return a.publicKey
+}
+
+// SignDigest returns the DER signature of digest, normalized to low S as required
+// by standardness rules (BIP-146)
+func (a *Account) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	signature, err := hdwallet.SignWithContext(ctx, a.signer, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := hdwallet.ParseDERSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	if !a.publicKey.Verify(digest, parsed) {
+		return nil, errors.New("signature does not match the public key")
+	}
+
+	return parsed.Normalize().DER(), nil
+}
+
+// SignTransaction adds the account's partial signatures to a BIP-174 (version 0)
+// PSBT in binary form and returns it serialized
+//
+// Every input with a witness UTXO paying to the account's P2WPKH script is signed
+// with its PSBT sighash type, SIGHASH_ALL by default; other types are refused unless
+// allowed with AllowSigHashTypes, and other inputs are left for their co-signers.
+// Finalizing and extracting the transaction is left to PSBT tooling
+//
+// Policies see the payment of the transaction as its signing intent, replacing any
+// declared in ctx: the outputs not paying back to the account, their total and
+// addresses. The amount is declared with the first signature, the following inputs
+// of the same payment declare zero so limits count it once
+func (a *Account) SignTransaction(ctx context.Context, payload []byte) ([]byte, error) {
+	p, err := parsePSBT(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	script := append([]byte{0x00, 0x14}, a.pubKeyHash[:]...)
+
+	// Check every input before signing any, so a refused PSBT leaves no signature
+	var digests []inputDigest
+	for i, input := range p.inputs {
+		utxo, ok := input.get([]byte{psbtInWitnessUTXO})
+		if !ok {
+			continue
+		}
+		amount, pkScript, err := parseWitnessUTXO(utxo)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		if !bytes.Equal(pkScript, script) {
+			continue
+		}
+
+		hashType := SigHashAll
+		if value, ok := input.get([]byte{psbtInSighashType}); ok {
+			if len(value) != 4 {
+				return nil, fmt.Errorf("input %d: invalid sighash type", i)
+			}
+			hashType = SigHashType(binary.LittleEndian.Uint32(value))
+		}
+		if !slices.Contains(a.sigHashTypes, hashType) {
+			return nil, fmt.Errorf("input %d: sighash type 0x%02x is not allowed", i, uint32(hashType))
+		}
+
+		digest, err := WitnessV0SignatureHash(p.tx, i, P2WPKHScriptCode(a.pubKeyHash), amount, hashType)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		digests = append(digests, inputDigest{index: i, hashType: hashType, digest: digest})
+	}
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("no input spends %s", a.address)
+	}
+
+	intent := a.intent(p.tx, script)
+	for _, input := range digests {
+		signature, err := a.SignDigest(hdwallet.WithSigningIntent(ctx, intent), input.digest[:])
+		if err != nil {
+			return nil, fmt.Errorf("sign input %d: %w", input.index, err)
+		}
+		intent.Amount = new(big.Int)
+
+		key := append([]byte{psbtInPartialSig}, a.publicKey.SerializeCompressed()...)
+		p.inputs[input.index].set(key, append(signature, byte(input.hashType)))
+	}
+
+	return p.serialize(), nil
+}
+
+// inputDigest is the signature hash of an input to sign
+type inputDigest struct {
+	index    int
+	hashType SigHashType
+	digest   [32]byte
+}
+
+// intent describes the payment of tx: the outputs not paying to the account's script
+func (a *Account) intent(tx *Transaction, script []byte) hdwallet.SigningIntent {
+	amount := new(big.Int)
+	var destinations []string
+	for _, output := range tx.Outputs {
+		if bytes.Equal(output.PkScript, script) {
+			continue
+		}
+		amount.Add(amount, big.NewInt(output.Value))
+		destinations = append(destinations, ScriptAddress(a.network, output.PkScript))
+	}
+
+	return hdwallet.SigningIntent{Asset: Asset, Amount: amount, Destination: strings.Join(destinations, ",")}
+}
+
+// parseWitnessUTXO decodes a PSBT witness UTXO, a serialized transaction output
+func parseWitnessUTXO(value []byte) (int64, []byte, error) {
+	r := bytes.NewReader(value)
+
+	var amount uint64
+	if err := binary.Read(r, binary.LittleEndian, &amount); err != nil {
+		return 0, nil, fmt.Errorf("read witness UTXO: %w", err)
+	}
+	pkScript, err := readBytes(r)
+	if err != nil || r.Len() != 0 {
+		return 0, nil, errors.New("invalid witness UTXO")
+	}
+
+	return int64(amount), pkScript, nil
+}
+
+// hash160 returns RIPEMD-160(SHA-256(data))
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	hash := ripemd160.New()
+	hash.Write(sum[:])
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,64 @@
+package bitcoin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/not-for-prod/hdwallet/codec"
+)
+
+// Network holds the address encoding parameters of a Bitcoin network
+type Network struct {
+	Name      string
+	Bech32HRP string
+	// PubKeyHashAddrID and ScriptHashAddrID are the base58check versions of P2PKH
+	// and P2SH addresses
+	PubKeyHashAddrID byte
+	ScriptHashAddrID byte
+}
+
+var (
+	MainNet = Network{Name: "mainnet", Bech32HRP: "bc", PubKeyHashAddrID: 0x00, ScriptHashAddrID: 0x05}
+	TestNet = Network{Name: "testnet", Bech32HRP: "tb", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4}
+	RegTest = Network{Name: "regtest", Bech32HRP: "bcrt", PubKeyHashAddrID: 0x6f, ScriptHashAddrID: 0xc4}
+)
+
+// SegwitAddress encodes a witness program as a BIP-173 (version 0) or BIP-350 (version 1+) address
+func SegwitAddress(network Network, version byte, program []byte) (string, error) {
+	return codec.SegwitEncode(network.Bech32HRP, version, program)
+}
+
+// P2WSHScript returns the scriptPubKey OP_0 <SHA-256(witnessScript)>
+func P2WSHScript(witnessScript []byte) []byte {
+	hash := sha256.Sum256(witnessScript)
+
+	return append([]byte{0x00, 0x20}, hash[:]...)
+}
+
+// P2TRScript returns the scriptPubKey OP_1 <outputKey> of a taproot output
+func P2TRScript(outputKey [32]byte) []byte {
+	return append([]byte{0x51, 0x20}, outputKey[:]...)
+}
+
+// ScriptAddress returns the address of an output script on network: segwit, P2PKH and
+// P2SH scripts have one, other scripts are returned as "script:" and their hex
+func ScriptAddress(network Network, pkScript []byte) string {
+	switch {
+	case len(pkScript) == 25 && pkScript[0] == 0x76 && pkScript[1] == 0xa9 && pkScript[2] == 0x14 &&
+		pkScript[23] == 0x88 && pkScript[24] == 0xac:
+		return codec.Base58CheckEncode(append([]byte{network.PubKeyHashAddrID}, pkScript[3:23]...))
+	case len(pkScript) == 23 && pkScript[0] == 0xa9 && pkScript[1] == 0x14 && pkScript[22] == 0x87:
+		return codec.Base58CheckEncode(append([]byte{network.ScriptHashAddrID}, pkScript[2:22]...))
+	case len(pkScript) >= 4 && (pkScript[0] == 0x00 || pkScript[0] >= 0x51 && pkScript[0] <= 0x60) &&
+		int(pkScript[1]) == len(pkScript)-2:
+		version := pkScript[0]
+		if version != 0x00 {
+			version -= 0x50
+		}
+		if address, err := SegwitAddress(network, version, pkScript[2:]); err == nil {
+			return address
+		}
+	}
+
+	return "script:" + hex.EncodeToString(pkScript)
+}
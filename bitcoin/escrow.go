@@ -0,0 +1,378 @@
+package bitcoin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// RecoveryChain is the BIP-44 chain of recovery keys, next to the external (0) and
+// internal (1) chains, so recovery keys are never handed out as receive addresses
+const RecoveryChain = 2
+
+// DeriveRecoveryKey returns the recovery key index of account, m/44'/coin'/account'/2/index,
+// normally derived from the seed of the recovery party rather than the primary wallet
+func DeriveRecoveryKey(wallet *hdwallet.Wallet, account, index uint32) (*hdwallet.PrivateKey, error) {
+	return wallet.DeriveKey(account, RecoveryChain, index)
+}
+
+// RecoveryPublicKey returns the public key of DeriveRecoveryKey
+func RecoveryPublicKey(wallet *hdwallet.Wallet, account, index uint32) (*hdwallet.PublicKey, error) {
+	return wallet.PublicKey(account, RecoveryChain, index)
+}
+
+// TimelockKind distinguishes relative (BIP-68/112, OP_CHECKSEQUENCEVERIFY) and
+// absolute (BIP-65, OP_CHECKLOCKTIMEVERIFY) timelocks
+type TimelockKind int
+
+const (
+	RelativeTimelock TimelockKind = iota
+	AbsoluteTimelock
+)
+
+const (
+	sequenceTypeFlag     = 1 << 22
+	sequenceGranularity  = 512 * time.Second
+	lockTimeThreshold    = 500_000_000
+	maxRelativeLockValue = 0xffff
+)
+
+// Timelock is the delay before the recovery key can spend, Value is its consensus
+// encoding: an nSequence for relative locks, an nLockTime for absolute ones
+type Timelock struct {
+	Kind  TimelockKind
+	Value uint32
+}
+
+// RelativeBlocks is a timelock expiring blocks after the escrow output confirms
+func RelativeBlocks(blocks uint16) Timelock {
+	return Timelock{Kind: RelativeTimelock, Value: uint32(blocks)}
+}
+
+// RelativeTime is a timelock expiring d after the escrow output confirms,
+// rounded up to the 512 second granularity of BIP-68
+func RelativeTime(d time.Duration) (Timelock, error) {
+	units := (d + sequenceGranularity - 1) / sequenceGranularity
+	if d <= 0 || units > maxRelativeLockValue {
+		return Timelock{}, fmt.Errorf("relative timelock %s out of range", d)
+	}
+
+	return Timelock{Kind: RelativeTimelock, Value: sequenceTypeFlag | uint32(units)}, nil
+}
+
+// AbsoluteHeight is a timelock expiring at a block height
+func AbsoluteHeight(height uint32) (Timelock, error) {
+	if height == 0 || height >= lockTimeThreshold {
+		return Timelock{}, fmt.Errorf("block height %d out of range", height)
+	}
+
+	return Timelock{Kind: AbsoluteTimelock, Value: height}, nil
+}
+
+// AbsoluteTime is a timelock expiring at t, compared with the median time past of the chain
+func AbsoluteTime(t time.Time) (Timelock, error) {
+	unix := t.Unix()
+	if unix < lockTimeThreshold || unix > 0xffffffff {
+		return Timelock{}, fmt.Errorf("lock time %s out of range", t)
+	}
+
+	return Timelock{Kind: AbsoluteTimelock, Value: uint32(unix)}, nil
+}
+
+// opcode returns the verifying opcode of the timelock
+func (t Timelock) opcode() byte {
+	if t.Kind == AbsoluteTimelock {
+		return opCheckLockTimeVerify
+	}
+
+	return opCheckSequenceVerify
+}
+
+// validate rejects values the matching opcode would fail on or that disable the lock
+func (t Timelock) validate() error {
+	switch t.Kind {
+	case RelativeTimelock:
+		if t.Value&^(sequenceTypeFlag|maxRelativeLockValue) != 0 || t.Value&maxRelativeLockValue == 0 {
+			return fmt.Errorf("invalid relative timelock 0x%08x", t.Value)
+		}
+	case AbsoluteTimelock:
+		if t.Value == 0 {
+			return errors.New("absolute timelock must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown timelock kind %d", t.Kind)
+	}
+
+	return nil
+}
+
+const (
+	opFalse               = 0x00
+	opTrue                = 0x51
+	opIf                  = 0x63
+	opElse                = 0x67
+	opEndIf               = 0x68
+	opDrop                = 0x75
+	opCheckSig            = 0xac
+	opCheckLockTimeVerify = 0xb1
+	opCheckSequenceVerify = 0xb2
+)
+
+// TimelockEscrow lets Primary spend at any time and Recovery once Lock has expired
+type TimelockEscrow struct {
+	Primary  *hdwallet.PublicKey
+	Recovery *hdwallet.PublicKey
+	Lock     Timelock
+}
+
+// WitnessScript returns the P2WSH script
+//
+//	OP_IF <primary> OP_CHECKSIG
+//	OP_ELSE <lock> OP_CHECKSEQUENCEVERIFY|OP_CHECKLOCKTIMEVERIFY OP_DROP <recovery> OP_CHECKSIG
+//	OP_ENDIF
+func (e *TimelockEscrow) WitnessScript() ([]byte, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	script := []byte{opIf}
+	script = appendPush(script, e.Primary.SerializeCompressed())
+	script = append(script, opCheckSig, opElse)
+	script = appendScriptNum(script, int64(e.Lock.Value))
+	script = append(script, e.Lock.opcode(), opDrop)
+	script = appendPush(script, e.Recovery.SerializeCompressed())
+
+	return append(script, opCheckSig, opEndIf), nil
+}
+
+// P2WSHAddress returns the native segwit address of WitnessScript
+func (e *TimelockEscrow) P2WSHAddress(network Network) (string, error) {
+	script, err := e.WitnessScript()
+	if err != nil {
+		return "", err
+	}
+
+	return SegwitAddress(network, 0, P2WSHScript(script)[2:])
+}
+
+// TapLeafScript returns the tapscript of the recovery path,
+// <lock> OP_CHECKSEQUENCEVERIFY|OP_CHECKLOCKTIMEVERIFY OP_DROP <x(recovery)> OP_CHECKSIG;
+// the primary key spends through the key path
+func (e *TimelockEscrow) TapLeafScript() ([]byte, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	recovery := XOnlyPublicKey(e.Recovery)
+	script := appendScriptNum(nil, int64(e.Lock.Value))
+	script = append(script, e.Lock.opcode(), opDrop)
+	script = appendPush(script, recovery[:])
+
+	return append(script, opCheckSig), nil
+}
+
+// TaprootOutput returns the x-only output key of the taproot escrow, Primary tweaked
+// with the single recovery leaf, and the parity of its Y coordinate
+func (e *TimelockEscrow) TaprootOutput() ([32]byte, byte, error) {
+	leaf, err := e.TapLeafScript()
+	if err != nil {
+		return [32]byte{}, 0, err
+	}
+	root := TapLeafHash(TapLeafVersion, leaf)
+
+	return TaprootOutputKey(e.Primary, root[:])
+}
+
+// TaprootAddress returns the bech32m address of TaprootOutput
+func (e *TimelockEscrow) TaprootAddress(network Network) (string, error) {
+	outputKey, _, err := e.TaprootOutput()
+	if err != nil {
+		return "", err
+	}
+
+	return SegwitAddress(network, 1, outputKey[:])
+}
+
+// MerkleRoot returns the script tree root the primary key is tweaked with,
+// for TweakPrivateKey when signing key path spends
+func (e *TimelockEscrow) MerkleRoot() ([32]byte, error) {
+	leaf, err := e.TapLeafScript()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return TapLeafHash(TapLeafVersion, leaf), nil
+}
+
+func (e *TimelockEscrow) validate() error {
+	if e.Primary == nil || e.Recovery == nil {
+		return errors.New("escrow needs a primary and a recovery key")
+	}
+
+	return e.Lock.validate()
+}
+
+// SpendTemplate describes how to spend an escrow output along one of its paths:
+// the transaction fields the path requires and how to assemble the witness
+// from a signature over the digest computed with Tapscript (taproot) or WitnessScript (P2WSH)
+type SpendTemplate struct {
+	// Sequence is the nSequence of the spending input
+	Sequence uint32
+	// LockTime is the nLockTime of the spending transaction, 0 when unconstrained
+	LockTime uint32
+	// Version is the minimum transaction version, 2 for relative timelocks (BIP-68)
+	Version int32
+
+	// WitnessScript is set for P2WSH spends, the scriptCode of WitnessV0SignatureHash
+	WitnessScript []byte
+	// Tapscript is set for taproot script path spends, for TaprootSignatureHash
+	Tapscript *TapscriptSpend
+
+	witness func(signature []byte) [][]byte
+}
+
+// Apply sets the fields required by the template on input inputIndex of tx
+func (s *SpendTemplate) Apply(tx *Transaction, inputIndex int) error {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return fmt.Errorf("input index %d out of range", inputIndex)
+	}
+
+	tx.Inputs[inputIndex].Sequence = s.Sequence
+	tx.Version = max(tx.Version, s.Version)
+	if s.LockTime != 0 {
+		if tx.LockTime != 0 && (tx.LockTime < lockTimeThreshold) != (s.LockTime < lockTimeThreshold) {
+			return errors.New("transaction lock time mixes block heights and timestamps")
+		}
+		tx.LockTime = max(tx.LockTime, s.LockTime)
+	}
+
+	return nil
+}
+
+// Witness returns the input witness for signature, which must include its sighash byte
+// for P2WSH and for non-default taproot sighash types
+func (s *SpendTemplate) Witness(signature []byte) [][]byte {
+	return s.witness(signature)
+}
+
+// PrimarySpend returns the template of a P2WSH spend by the primary key
+func (e *TimelockEscrow) PrimarySpend() (*SpendTemplate, error) {
+	script, err := e.WitnessScript()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SpendTemplate{
+		Sequence:      0xfffffffd,
+		Version:       2,
+		WitnessScript: script,
+		witness: func(signature []byte) [][]byte {
+			return [][]byte{signature, {0x01}, script}
+		},
+	}, nil
+}
+
+// RecoverySpend returns the template of a P2WSH spend by the recovery key after the lock
+func (e *TimelockEscrow) RecoverySpend() (*SpendTemplate, error) {
+	script, err := e.WitnessScript()
+	if err != nil {
+		return nil, err
+	}
+
+	template := e.recoveryTemplate()
+	template.WitnessScript = script
+	template.witness = func(signature []byte) [][]byte {
+		// An empty element selects the OP_ELSE branch (MINIMALIF)
+		return [][]byte{signature, {}, script}
+	}
+
+	return template, nil
+}
+
+// TaprootPrimarySpend returns the template of a taproot key path spend, signed with
+// the primary key tweaked by TweakPrivateKey(primary, MerkleRoot())
+func (e *TimelockEscrow) TaprootPrimarySpend() (*SpendTemplate, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	return &SpendTemplate{
+		Sequence: 0xfffffffd,
+		Version:  2,
+		witness: func(signature []byte) [][]byte {
+			return [][]byte{signature}
+		},
+	}, nil
+}
+
+// TaprootRecoverySpend returns the template of a taproot script path spend by the recovery key
+func (e *TimelockEscrow) TaprootRecoverySpend() (*SpendTemplate, error) {
+	leaf, err := e.TapLeafScript()
+	if err != nil {
+		return nil, err
+	}
+	_, parity, err := e.TaprootOutput()
+	if err != nil {
+		return nil, err
+	}
+
+	internal := XOnlyPublicKey(e.Primary)
+	controlBlock := append([]byte{TapLeafVersion | parity}, internal[:]...)
+
+	template := e.recoveryTemplate()
+	template.Tapscript = &TapscriptSpend{LeafHash: TapLeafHash(TapLeafVersion, leaf)}
+	template.witness = func(signature []byte) [][]byte {
+		return [][]byte{signature, leaf, controlBlock}
+	}
+
+	return template, nil
+}
+
+// recoveryTemplate returns the transaction fields satisfying the lock
+func (e *TimelockEscrow) recoveryTemplate() *SpendTemplate {
+	if e.Lock.Kind == AbsoluteTimelock {
+		// nLockTime is only enforced when the input sequence is not final
+		return &SpendTemplate{Sequence: 0xfffffffe, LockTime: e.Lock.Value, Version: 2}
+	}
+
+	return &SpendTemplate{Sequence: e.Lock.Value, Version: 2}
+}
+
+// appendPush appends a minimal push of data, which is at most 75 bytes here
+func appendPush(script, data []byte) []byte {
+	script = append(script, byte(len(data)))
+
+	return append(script, data...)
+}
+
+// appendScriptNum appends a minimally encoded script number
+func appendScriptNum(script []byte, n int64) []byte {
+	switch {
+	case n == 0:
+		return append(script, opFalse)
+	case n >= 1 && n <= 16:
+		return append(script, opTrue+byte(n-1))
+	}
+
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+
+	var encoded []byte
+	for n > 0 {
+		encoded = append(encoded, byte(n))
+		n >>= 8
+	}
+	// The most significant bit is the sign
+	if encoded[len(encoded)-1]&0x80 != 0 {
+		encoded = append(encoded, 0)
+	}
+	if negative {
+		encoded[len(encoded)-1] |= 0x80
+	}
+
+	return appendPush(script, encoded)
+}
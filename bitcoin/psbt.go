@@ -0,0 +1,151 @@
+package bitcoin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// psbtMagic starts every BIP-174 partially signed transaction
+var psbtMagic = []byte("psbt\xff")
+
+const (
+	psbtGlobalUnsignedTx = 0x00
+	psbtInWitnessUTXO    = 0x01
+	psbtInPartialSig     = 0x02
+	psbtInSighashType    = 0x03
+)
+
+// psbtPair is one key-value record of a PSBT map, kept verbatim so records this
+// package does not understand survive a round trip
+type psbtPair struct {
+	key   []byte
+	value []byte
+}
+
+// psbtMap is a PSBT map in serialization order
+type psbtMap []psbtPair
+
+// get returns the value of key
+func (m psbtMap) get(key []byte) ([]byte, bool) {
+	for _, pair := range m {
+		if bytes.Equal(pair.key, key) {
+			return pair.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// set replaces the value of key, or appends the record when key is missing
+func (m *psbtMap) set(key, value []byte) {
+	for i, pair := range *m {
+		if bytes.Equal(pair.key, key) {
+			(*m)[i].value = value
+			return
+		}
+	}
+	*m = append(*m, psbtPair{key: key, value: value})
+}
+
+// psbt is a version 0 PSBT split into its global, input and output maps
+type psbt struct {
+	tx      *Transaction
+	global  psbtMap
+	inputs  []psbtMap
+	outputs []psbtMap
+}
+
+// parsePSBT decodes a BIP-174 version 0 PSBT in binary form
+func parsePSBT(data []byte) (*psbt, error) {
+	if !bytes.HasPrefix(data, psbtMagic) {
+		return nil, errors.New("missing PSBT magic")
+	}
+	r := bytes.NewReader(data[len(psbtMagic):])
+
+	p := &psbt{}
+	var err error
+	if p.global, err = readPSBTMap(r); err != nil {
+		return nil, fmt.Errorf("read global map: %w", err)
+	}
+
+	unsigned, ok := p.global.get([]byte{psbtGlobalUnsignedTx})
+	if !ok {
+		return nil, errors.New("PSBT has no unsigned transaction")
+	}
+	if p.tx, err = ParseTransaction(unsigned); err != nil {
+		return nil, fmt.Errorf("parse unsigned transaction: %w", err)
+	}
+	for _, input := range p.tx.Inputs {
+		if len(input.ScriptSig) > 0 || len(input.Witness) > 0 {
+			return nil, errors.New("PSBT unsigned transaction has signature data")
+		}
+	}
+
+	for i := range p.tx.Inputs {
+		input, err := readPSBTMap(r)
+		if err != nil {
+			return nil, fmt.Errorf("read input %d map: %w", i, err)
+		}
+		p.inputs = append(p.inputs, input)
+	}
+	for i := range p.tx.Outputs {
+		output, err := readPSBTMap(r)
+		if err != nil {
+			return nil, fmt.Errorf("read output %d map: %w", i, err)
+		}
+		p.outputs = append(p.outputs, output)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after PSBT", r.Len())
+	}
+
+	return p, nil
+}
+
+// serialize encodes the PSBT in binary form
+func (p *psbt) serialize() []byte {
+	buf := slices.Clone(psbtMagic)
+	buf = appendPSBTMap(buf, p.global)
+	for _, input := range p.inputs {
+		buf = appendPSBTMap(buf, input)
+	}
+	for _, output := range p.outputs {
+		buf = appendPSBTMap(buf, output)
+	}
+
+	return buf
+}
+
+func readPSBTMap(r *bytes.Reader) (psbtMap, error) {
+	var m psbtMap
+	for {
+		key, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		// A zero-length key is the map separator
+		if len(key) == 0 {
+			return m, nil
+		}
+		if _, ok := m.get(key); ok {
+			return nil, fmt.Errorf("duplicate key %x", key)
+		}
+
+		value, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, psbtPair{key: key, value: value})
+	}
+}
+
+func appendPSBTMap(buf []byte, m psbtMap) []byte {
+	for _, pair := range m {
+		buf = appendBytes(buf, pair.key)
+		buf = appendBytes(buf, pair.value)
+	}
+
+	return append(buf, 0x00)
+}
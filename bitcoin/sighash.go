@@ -0,0 +1,323 @@
+package bitcoin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// SigHashType selects which parts of a transaction a signature commits to
+type SigHashType uint32
+
+const (
+	// SigHashDefault is the BIP-341 type committing to everything, encoded as a 64-byte signature
+	SigHashDefault      SigHashType = 0x00
+	SigHashAll          SigHashType = 0x01
+	SigHashNone         SigHashType = 0x02
+	SigHashSingle       SigHashType = 0x03
+	SigHashAnyoneCanPay SigHashType = 0x80
+
+	sigHashBaseMask = 0x1f
+)
+
+const (
+	opCodeSeparator = 0xab
+	opPushData1     = 0x4c
+	opPushData2     = 0x4d
+	opPushData4     = 0x4e
+
+	// TapLeafVersion is the leaf version of BIP-342 tapscript
+	TapLeafVersion = 0xc0
+
+	// annexTag is the first byte of a BIP-341 annex
+	annexTag = 0x50
+)
+
+// LegacySignatureHash returns the pre-segwit signature hash of input inputIndex,
+// where subScript is the script being executed, normally the scriptPubKey of the
+// spent output or the redeem script of P2SH; OP_CODESEPARATOR opcodes are removed
+//
+// SIGHASH_SINGLE without a matching output yields the value 1, as consensus requires
+func LegacySignatureHash(tx *Transaction, inputIndex int, subScript []byte, hashType SigHashType) ([32]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return [32]byte{}, fmt.Errorf("input index %d out of range", inputIndex)
+	}
+
+	base := hashType & sigHashBaseMask
+	if base == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		return [32]byte{1}, nil
+	}
+
+	script, err := removeCodeSeparators(subScript)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	stripped := Transaction{Version: tx.Version, LockTime: tx.LockTime}
+	if hashType&SigHashAnyoneCanPay != 0 {
+		input := tx.Inputs[inputIndex]
+		stripped.Inputs = []Input{{PreviousOutPoint: input.PreviousOutPoint, ScriptSig: script, Sequence: input.Sequence}}
+	} else {
+		for i, input := range tx.Inputs {
+			copied := Input{PreviousOutPoint: input.PreviousOutPoint, Sequence: input.Sequence}
+			switch {
+			case i == inputIndex:
+				copied.ScriptSig = script
+			case base == SigHashNone || base == SigHashSingle:
+				// Other inputs can be updated freely
+				copied.Sequence = 0
+			}
+			stripped.Inputs = append(stripped.Inputs, copied)
+		}
+	}
+
+	switch base {
+	case SigHashNone:
+	case SigHashSingle:
+		for range inputIndex {
+			stripped.Outputs = append(stripped.Outputs, Output{Value: -1})
+		}
+		stripped.Outputs = append(stripped.Outputs, tx.Outputs[inputIndex])
+	default:
+		stripped.Outputs = tx.Outputs
+	}
+
+	preimage := binary.LittleEndian.AppendUint32(stripped.serialize(false), uint32(hashType))
+
+	return doubleSHA256(preimage), nil
+}
+
+// WitnessV0SignatureHash returns the BIP-143 signature hash of segwit v0 input inputIndex
+// spending amount satoshis; scriptCode is P2WPKHScriptCode for P2WPKH and the witness
+// script for P2WSH
+func WitnessV0SignatureHash(tx *Transaction, inputIndex int, scriptCode []byte, amount int64,
+	hashType SigHashType) ([32]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return [32]byte{}, fmt.Errorf("input index %d out of range", inputIndex)
+	}
+
+	base := hashType & sigHashBaseMask
+	anyoneCanPay := hashType&SigHashAnyoneCanPay != 0
+
+	var hashPrevouts, hashSequence, hashOutputs [32]byte
+	if !anyoneCanPay {
+		hashPrevouts = doubleSHA256(tx.prevouts())
+	}
+	if !anyoneCanPay && base != SigHashSingle && base != SigHashNone {
+		hashSequence = doubleSHA256(tx.sequences())
+	}
+	switch {
+	case base != SigHashSingle && base != SigHashNone:
+		hashOutputs = doubleSHA256(tx.outputs())
+	case base == SigHashSingle && inputIndex < len(tx.Outputs):
+		hashOutputs = doubleSHA256(appendOutput(nil, tx.Outputs[inputIndex]))
+	}
+
+	input := tx.Inputs[inputIndex]
+
+	var preimage []byte
+	preimage = binary.LittleEndian.AppendUint32(preimage, uint32(tx.Version))
+	preimage = append(preimage, hashPrevouts[:]...)
+	preimage = append(preimage, hashSequence[:]...)
+	preimage = appendOutPoint(preimage, input.PreviousOutPoint)
+	preimage = appendBytes(preimage, scriptCode)
+	preimage = binary.LittleEndian.AppendUint64(preimage, uint64(amount))
+	preimage = binary.LittleEndian.AppendUint32(preimage, input.Sequence)
+	preimage = append(preimage, hashOutputs[:]...)
+	preimage = binary.LittleEndian.AppendUint32(preimage, tx.LockTime)
+	preimage = binary.LittleEndian.AppendUint32(preimage, uint32(hashType))
+
+	return doubleSHA256(preimage), nil
+}
+
+// P2WPKHScriptCode returns the BIP-143 scriptCode of a P2WPKH output paying to pubKeyHash,
+// OP_DUP OP_HASH160 <pubKeyHash> OP_EQUALVERIFY OP_CHECKSIG
+func P2WPKHScriptCode(pubKeyHash [20]byte) []byte {
+	script := append([]byte{0x76, 0xa9, 0x14}, pubKeyHash[:]...)
+
+	return append(script, 0x88, 0xac)
+}
+
+// TapscriptSpend selects a BIP-342 script path spend in TaprootSignatureHash
+type TapscriptSpend struct {
+	// LeafHash is TapLeafHash of the executed leaf script
+	LeafHash [32]byte
+	// CodeSeparator is the opcode position of the last executed OP_CODESEPARATOR,
+	// nil when none was executed
+	CodeSeparator *uint32
+}
+
+// TaprootSignatureHash returns the BIP-341 signature hash of taproot input inputIndex
+//
+// prevOuts are the outputs spent by every input of the transaction, in input order,
+// as taproot signatures commit to all spent amounts and scripts; script is nil for key
+// path spends; annex, including its 0x50 tag, is nil unless the witness carries one
+func TaprootSignatureHash(tx *Transaction, inputIndex int, prevOuts []Output, hashType SigHashType,
+	script *TapscriptSpend, annex []byte) ([32]byte, error) {
+	if inputIndex < 0 || inputIndex >= len(tx.Inputs) {
+		return [32]byte{}, fmt.Errorf("input index %d out of range", inputIndex)
+	}
+	if len(prevOuts) != len(tx.Inputs) {
+		return [32]byte{}, fmt.Errorf("%d spent outputs given for %d inputs", len(prevOuts), len(tx.Inputs))
+	}
+	switch hashType {
+	case SigHashDefault, SigHashAll, SigHashNone, SigHashSingle,
+		SigHashAll | SigHashAnyoneCanPay, SigHashNone | SigHashAnyoneCanPay, SigHashSingle | SigHashAnyoneCanPay:
+	default:
+		return [32]byte{}, fmt.Errorf("invalid taproot sighash type 0x%02x", uint32(hashType))
+	}
+	if annex != nil && (len(annex) == 0 || annex[0] != annexTag) {
+		return [32]byte{}, errors.New("annex must start with 0x50")
+	}
+
+	base := hashType & 0x03
+	anyoneCanPay := hashType&SigHashAnyoneCanPay != 0
+	if base == SigHashSingle && inputIndex >= len(tx.Outputs) {
+		return [32]byte{}, fmt.Errorf("SIGHASH_SINGLE input %d has no matching output", inputIndex)
+	}
+
+	// Epoch 0
+	msg := []byte{0x00, byte(hashType)}
+	msg = binary.LittleEndian.AppendUint32(msg, uint32(tx.Version))
+	msg = binary.LittleEndian.AppendUint32(msg, tx.LockTime)
+
+	if !anyoneCanPay {
+		var amounts, scripts []byte
+		for _, prevOut := range prevOuts {
+			amounts = binary.LittleEndian.AppendUint64(amounts, uint64(prevOut.Value))
+			scripts = appendBytes(scripts, prevOut.PkScript)
+		}
+		msg = appendSHA256(msg, tx.prevouts())
+		msg = appendSHA256(msg, amounts)
+		msg = appendSHA256(msg, scripts)
+		msg = appendSHA256(msg, tx.sequences())
+	}
+	if base != SigHashNone && base != SigHashSingle {
+		msg = appendSHA256(msg, tx.outputs())
+	}
+
+	var spendType byte
+	if script != nil {
+		spendType |= 2
+	}
+	if annex != nil {
+		spendType |= 1
+	}
+	msg = append(msg, spendType)
+
+	if anyoneCanPay {
+		input := tx.Inputs[inputIndex]
+		msg = appendOutPoint(msg, input.PreviousOutPoint)
+		msg = appendOutput(msg, prevOuts[inputIndex])
+		msg = binary.LittleEndian.AppendUint32(msg, input.Sequence)
+	} else {
+		msg = binary.LittleEndian.AppendUint32(msg, uint32(inputIndex))
+	}
+
+	if annex != nil {
+		msg = appendSHA256(msg, appendBytes(nil, annex))
+	}
+	if base == SigHashSingle {
+		msg = appendSHA256(msg, appendOutput(nil, tx.Outputs[inputIndex]))
+	}
+
+	if script != nil {
+		codeSeparator := uint32(0xffffffff)
+		if script.CodeSeparator != nil {
+			codeSeparator = *script.CodeSeparator
+		}
+		msg = append(msg, script.LeafHash[:]...)
+		msg = append(msg, 0x00) // key_version of BIP-342
+		msg = binary.LittleEndian.AppendUint32(msg, codeSeparator)
+	}
+
+	return TaggedHash("TapSighash", msg), nil
+}
+
+// TapLeafHash returns the BIP-341 hash of a leaf script, leafVersion is TapLeafVersion for tapscript
+func TapLeafHash(leafVersion byte, script []byte) [32]byte {
+	return TaggedHash("TapLeaf", appendBytes([]byte{leafVersion}, script))
+}
+
+// TaggedHash returns the BIP-340 tagged hash SHA-256(SHA-256(tag) || SHA-256(tag) || data...)
+func TaggedHash(tag string, data ...[]byte) [32]byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	hash := sha256.New()
+	hash.Write(tagHash[:])
+	hash.Write(tagHash[:])
+	for _, d := range data {
+		hash.Write(d)
+	}
+
+	var sum [32]byte
+	copy(sum[:], hash.Sum(nil))
+
+	return sum
+}
+
+func (t *Transaction) prevouts() []byte {
+	var buf []byte
+	for _, input := range t.Inputs {
+		buf = appendOutPoint(buf, input.PreviousOutPoint)
+	}
+
+	return buf
+}
+
+func (t *Transaction) sequences() []byte {
+	var buf []byte
+	for _, input := range t.Inputs {
+		buf = binary.LittleEndian.AppendUint32(buf, input.Sequence)
+	}
+
+	return buf
+}
+
+func (t *Transaction) outputs() []byte {
+	var buf []byte
+	for _, output := range t.Outputs {
+		buf = appendOutput(buf, output)
+	}
+
+	return buf
+}
+
+func appendSHA256(buf, data []byte) []byte {
+	hash := sha256.Sum256(data)
+
+	return append(buf, hash[:]...)
+}
+
+// removeCodeSeparators returns script without its OP_CODESEPARATOR opcodes,
+// leaving push data that happens to contain 0xab untouched
+func removeCodeSeparators(script []byte) ([]byte, error) {
+	out := make([]byte, 0, len(script))
+	for i := 0; i < len(script); {
+		op := script[i]
+		size := 1
+		switch {
+		case op >= 0x01 && op < opPushData1:
+			size += int(op)
+		case op == opPushData1 && i+1 < len(script):
+			size += 1 + int(script[i+1])
+		case op == opPushData2 && i+2 < len(script):
+			size += 2 + int(binary.LittleEndian.Uint16(script[i+1:]))
+		case op == opPushData4 && i+4 < len(script):
+			size += 4 + int(binary.LittleEndian.Uint32(script[i+1:]))
+		case op == opPushData1 || op == opPushData2 || op == opPushData4:
+			return nil, errors.New("script push data truncated")
+		}
+		if i+size > len(script) {
+			return nil, errors.New("script push data truncated")
+		}
+
+		if op != opCodeSeparator {
+			out = append(out, script[i:i+size]...)
+		}
+		i += size
+	}
+
+	return out, nil
+}
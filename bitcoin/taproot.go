@@ -0,0 +1,84 @@
+package bitcoin
+
+import (
+	"errors"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// XOnlyPublicKey returns the BIP-340 32-byte encoding of a public key, its X coordinate
+func XOnlyPublicKey(publicKey *hdwallet.PublicKey) [32]byte {
+	var xOnly [32]byte
+	copy(xOnly[:], publicKey.SerializeCompressed()[1:])
+
+	return xOnly
+}
+
+// TaprootOutputKey tweaks internalKey with the script tree merkleRoot (nil for key path
+// only outputs) as BIP-341 taproot_tweak_pubkey does, and returns the x-only output key
+// and the parity of its Y coordinate, needed in control blocks
+func TaprootOutputKey(internalKey *hdwallet.PublicKey, merkleRoot []byte) ([32]byte, byte, error) {
+	tweak, err := taprootTweak(internalKey, merkleRoot)
+	if err != nil {
+		return [32]byte{}, 0, err
+	}
+
+	// lift_x: the internal key with an even Y coordinate
+	internalX := XOnlyPublicKey(internalKey)
+	even, err := secp256k1.ParsePubKey(append([]byte{0x02}, internalX[:]...))
+	if err != nil {
+		return [32]byte{}, 0, err
+	}
+
+	var point, tweakPoint, output secp256k1.JacobianPoint
+	even.AsJacobian(&point)
+	secp256k1.ScalarBaseMultNonConst(tweak, &tweakPoint)
+	secp256k1.AddNonConst(&point, &tweakPoint, &output)
+	if (output.X.IsZero() && output.Y.IsZero()) || output.Z.IsZero() {
+		return [32]byte{}, 0, errors.New("taproot output key is the point at infinity")
+	}
+	output.ToAffine()
+
+	outputKey := hdwallet.PublicKeyFromSecp256k1(secp256k1.NewPublicKey(&output.X, &output.Y))
+	parity := byte(0)
+	if output.Y.IsOdd() {
+		parity = 1
+	}
+
+	return XOnlyPublicKey(outputKey), parity, nil
+}
+
+// TweakPrivateKey returns the private key of the taproot output key of the internal
+// key privateKey, used to sign key path spends (BIP-341 taproot_tweak_seckey)
+func TweakPrivateKey(privateKey *hdwallet.PrivateKey, merkleRoot []byte) (*hdwallet.PrivateKey, error) {
+	publicKey := privateKey.PublicKey()
+	tweak, err := taprootTweak(publicKey, merkleRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	scalar := privateKey.Secp256k1().Key
+	if publicKey.SerializeCompressed()[0] == secp256k1.PubKeyFormatCompressedOdd {
+		scalar.Negate()
+	}
+	scalar.Add(tweak)
+	if scalar.IsZero() {
+		return nil, errors.New("tweaked private key is zero")
+	}
+
+	return hdwallet.PrivateKeyFromSecp256k1(secp256k1.NewPrivateKey(&scalar)), nil
+}
+
+// taprootTweak returns int(hash_TapTweak(x(P) || merkleRoot)), rejecting values not below the group order
+func taprootTweak(internalKey *hdwallet.PublicKey, merkleRoot []byte) (*secp256k1.ModNScalar, error) {
+	internalX := XOnlyPublicKey(internalKey)
+	hash := TaggedHash("TapTweak", internalX[:], merkleRoot)
+
+	var tweak secp256k1.ModNScalar
+	if overflow := tweak.SetBytes(&hash); overflow != 0 {
+		return nil, errors.New("taproot tweak exceeds the group order")
+	}
+
+	return &tweak, nil
+}
@@ -0,0 +1,298 @@
+// Package bitcoin computes Bitcoin transaction signature hashes (legacy, BIP-143
+// and BIP-341) so external signers can be given correct digests
+//
+// Only the parts of a transaction that are committed to by signatures are modelled;
+// building, funding and broadcasting transactions is left to the caller
+package bitcoin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// OutPoint references an output of a previous transaction
+type OutPoint struct {
+	// Hash is the previous transaction hash in internal byte order, the reverse of its TxID
+	Hash  [32]byte
+	Index uint32
+}
+
+// NewOutPoint returns the outpoint of output index of the transaction with the given hex TxID
+func NewOutPoint(txid string, index uint32) (OutPoint, error) {
+	decoded, err := hex.DecodeString(txid)
+	if err != nil || len(decoded) != 32 {
+		return OutPoint{}, fmt.Errorf("invalid txid %q", txid)
+	}
+
+	outpoint := OutPoint{Index: index}
+	copy(outpoint.Hash[:], decoded)
+	slices.Reverse(outpoint.Hash[:])
+
+	return outpoint, nil
+}
+
+// String formats the outpoint as "txid:index"
+func (o OutPoint) String() string {
+	hash := o.Hash
+	slices.Reverse(hash[:])
+
+	return fmt.Sprintf("%x:%d", hash, o.Index)
+}
+
+// Input is a transaction input
+type Input struct {
+	PreviousOutPoint OutPoint
+	ScriptSig        []byte
+	Sequence         uint32
+	Witness          [][]byte
+}
+
+// Output is a transaction output, Value is in satoshis
+type Output struct {
+	Value    int64
+	PkScript []byte
+}
+
+// Transaction is a Bitcoin transaction
+type Transaction struct {
+	Version  int32
+	Inputs   []Input
+	Outputs  []Output
+	LockTime uint32
+}
+
+// ParseTransaction decodes a transaction in network serialization, with or without witness data
+func ParseTransaction(data []byte) (*Transaction, error) {
+	r := bytes.NewReader(data)
+	tx := &Transaction{}
+
+	var err error
+	if tx.Version, err = readInt32(r); err != nil {
+		return nil, fmt.Errorf("read version: %w", err)
+	}
+
+	// BIP-144: a zero input count followed by flag 0x01 marks witness serialization
+	segwit := len(data) > 6 && data[4] == 0x00 && data[5] == 0x01
+	if segwit {
+		_, _ = r.Seek(2, io.SeekCurrent)
+	}
+
+	inputs, err := readCompactSize(r)
+	if err != nil {
+		return nil, fmt.Errorf("read input count: %w", err)
+	}
+	for i := uint64(0); i < inputs; i++ {
+		var input Input
+		if _, err = io.ReadFull(r, input.PreviousOutPoint.Hash[:]); err != nil {
+			return nil, fmt.Errorf("read input %d: %w", i, err)
+		}
+		if input.PreviousOutPoint.Index, err = readUint32(r); err != nil {
+			return nil, fmt.Errorf("read input %d: %w", i, err)
+		}
+		if input.ScriptSig, err = readBytes(r); err != nil {
+			return nil, fmt.Errorf("read input %d: %w", i, err)
+		}
+		if input.Sequence, err = readUint32(r); err != nil {
+			return nil, fmt.Errorf("read input %d: %w", i, err)
+		}
+		tx.Inputs = append(tx.Inputs, input)
+	}
+
+	outputs, err := readCompactSize(r)
+	if err != nil {
+		return nil, fmt.Errorf("read output count: %w", err)
+	}
+	for i := uint64(0); i < outputs; i++ {
+		var output Output
+		var value uint64
+		if err = binary.Read(r, binary.LittleEndian, &value); err != nil {
+			return nil, fmt.Errorf("read output %d: %w", i, err)
+		}
+		output.Value = int64(value)
+		if output.PkScript, err = readBytes(r); err != nil {
+			return nil, fmt.Errorf("read output %d: %w", i, err)
+		}
+		tx.Outputs = append(tx.Outputs, output)
+	}
+
+	if segwit {
+		for i := range tx.Inputs {
+			items, err := readCompactSize(r)
+			if err != nil {
+				return nil, fmt.Errorf("read witness %d: %w", i, err)
+			}
+			for j := uint64(0); j < items; j++ {
+				item, err := readBytes(r)
+				if err != nil {
+					return nil, fmt.Errorf("read witness %d: %w", i, err)
+				}
+				tx.Inputs[i].Witness = append(tx.Inputs[i].Witness, item)
+			}
+		}
+	}
+
+	if tx.LockTime, err = readUint32(r); err != nil {
+		return nil, fmt.Errorf("read lock time: %w", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after transaction", r.Len())
+	}
+
+	return tx, nil
+}
+
+// Serialize encodes the transaction, with witness data when any input has some
+func (t *Transaction) Serialize() []byte {
+	hasWitness := slices.ContainsFunc(t.Inputs, func(input Input) bool { return len(input.Witness) > 0 })
+
+	return t.serialize(hasWitness)
+}
+
+// TxID returns the transaction ID, the double SHA-256 of the serialization without
+// witness data, in the reversed byte order used by explorers and RPCs
+func (t *Transaction) TxID() string {
+	hash := doubleSHA256(t.serialize(false))
+	slices.Reverse(hash[:])
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (t *Transaction) serialize(witness bool) []byte {
+	var buf []byte
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(t.Version))
+	if witness {
+		buf = append(buf, 0x00, 0x01)
+	}
+
+	buf = appendCompactSize(buf, uint64(len(t.Inputs)))
+	for _, input := range t.Inputs {
+		buf = appendOutPoint(buf, input.PreviousOutPoint)
+		buf = appendBytes(buf, input.ScriptSig)
+		buf = binary.LittleEndian.AppendUint32(buf, input.Sequence)
+	}
+
+	buf = appendCompactSize(buf, uint64(len(t.Outputs)))
+	for _, output := range t.Outputs {
+		buf = appendOutput(buf, output)
+	}
+
+	if witness {
+		for _, input := range t.Inputs {
+			buf = appendCompactSize(buf, uint64(len(input.Witness)))
+			for _, item := range input.Witness {
+				buf = appendBytes(buf, item)
+			}
+		}
+	}
+
+	return binary.LittleEndian.AppendUint32(buf, t.LockTime)
+}
+
+func appendOutPoint(buf []byte, outpoint OutPoint) []byte {
+	buf = append(buf, outpoint.Hash[:]...)
+	return binary.LittleEndian.AppendUint32(buf, outpoint.Index)
+}
+
+func appendOutput(buf []byte, output Output) []byte {
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(output.Value))
+	return appendBytes(buf, output.PkScript)
+}
+
+func appendBytes(buf, data []byte) []byte {
+	buf = appendCompactSize(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+func appendCompactSize(buf []byte, n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return append(buf, byte(n))
+	case n <= 0xffff:
+		return binary.LittleEndian.AppendUint16(append(buf, 0xfd), uint16(n))
+	case n <= 0xffffffff:
+		return binary.LittleEndian.AppendUint32(append(buf, 0xfe), uint32(n))
+	default:
+		return binary.LittleEndian.AppendUint64(append(buf, 0xff), n)
+	}
+}
+
+func readCompactSize(r *bytes.Reader) (uint64, error) {
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	switch prefix {
+	case 0xfd:
+		var v uint16
+		err = binary.Read(r, binary.LittleEndian, &v)
+		n = uint64(v)
+		if err == nil && n < 0xfd {
+			err = errors.New("non-canonical compact size")
+		}
+	case 0xfe:
+		var v uint32
+		err = binary.Read(r, binary.LittleEndian, &v)
+		n = uint64(v)
+		if err == nil && n <= 0xffff {
+			err = errors.New("non-canonical compact size")
+		}
+	case 0xff:
+		err = binary.Read(r, binary.LittleEndian, &n)
+		if err == nil && n <= 0xffffffff {
+			err = errors.New("non-canonical compact size")
+		}
+	default:
+		n = uint64(prefix)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Every counted element takes at least one byte
+	if n > uint64(r.Len()) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	return n, nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readCompactSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, n)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func readUint32(r *bytes.Reader) (uint32, error) {
+	var v uint32
+	err := binary.Read(r, binary.LittleEndian, &v)
+
+	return v, err
+}
+
+func readInt32(r *bytes.Reader) (int32, error) {
+	v, err := readUint32(r)
+
+	return int32(v), err
+}
+
+func doubleSHA256(data []byte) [32]byte {
+	first := sha256.Sum256(data)
+
+	return sha256.Sum256(first[:])
+}
@@ -0,0 +1,164 @@
+// Package bls derives BLS12-381 keys for Ethereum consensus layer validators
+// following EIP-2333 (tree key derivation) and EIP-2334 (validator paths)
+//
+// EIP-2333 has no hardened/non-hardened distinction: every child is derived from
+// the parent secret through a Lamport public key, so paths are written without
+// apostrophes, for example m/12381/3600/0/0/0 for the signing key of validator 0
+package bls
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// Purpose is the EIP-2334 purpose level
+	Purpose = 12381
+	// CoinType is the EIP-2334 coin type of Ethereum
+	CoinType = 3600
+)
+
+// curveOrder is r, the order of the BLS12-381 subgroups
+var curveOrder, _ = new(big.Int).SetString("73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001", 16)
+
+// SecretKey is a BLS12-381 secret key, a non-zero scalar modulo r
+type SecretKey struct {
+	scalar *big.Int
+}
+
+// MasterKey derives the EIP-2333 master secret key from a seed of at least 32 bytes
+// (the 64-byte BIP39 seed of the wallet mnemonic)
+func MasterKey(seed []byte) (*SecretKey, error) {
+	if len(seed) < 32 {
+		return nil, errors.New("seed must be at least 32 bytes")
+	}
+
+	return &SecretKey{scalar: hkdfModR(seed)}, nil
+}
+
+// FromMnemonic derives the key at path from a BIP39 mnemonic and passphrase
+func FromMnemonic(mnemonic, passphrase, path string) (*SecretKey, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := MasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return master.DerivePath(path)
+}
+
+// SigningKeyPath returns the EIP-2334 path of the signing key of validator index
+func SigningKeyPath(index uint32) string {
+	return fmt.Sprintf("m/%d/%d/%d/0/0", Purpose, CoinType, index)
+}
+
+// WithdrawalKeyPath returns the EIP-2334 path of the withdrawal key of validator index
+func WithdrawalKeyPath(index uint32) string {
+	return fmt.Sprintf("m/%d/%d/%d/0", Purpose, CoinType, index)
+}
+
+// Child derives the child secret key at index
+func (k *SecretKey) Child(index uint32) *SecretKey {
+	return &SecretKey{scalar: hkdfModR(k.compressedLamportPublicKey(index))}
+}
+
+// DerivePath derives the key at path, which must start at the master key ("m/...")
+func (k *SecretKey) DerivePath(path string) (*SecretKey, error) {
+	levels := strings.Split(path, "/")
+	if levels[0] != "m" {
+		return nil, fmt.Errorf("path %q must start with m", path)
+	}
+
+	key := k
+	for _, level := range levels[1:] {
+		index, err := strconv.ParseUint(level, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path level %q: %w", level, err)
+		}
+		key = key.Child(uint32(index))
+	}
+
+	return key, nil
+}
+
+// Bytes returns the 32-byte big-endian encoding of the secret key, as stored in EIP-2335 keystores
+func (k *SecretKey) Bytes() [32]byte {
+	var out [32]byte
+	k.scalar.FillBytes(out[:])
+
+	return out
+}
+
+// PublicKey returns the 48-byte compressed G1 public key (sk * G1)
+func (k *SecretKey) PublicKey() [48]byte {
+	g1 := bls12381.NewG1()
+	point := g1.MulScalarBig(g1.New(), g1.One(), k.scalar)
+
+	var out [48]byte
+	copy(out[:], g1.ToCompressed(point))
+
+	return out
+}
+
+// compressedLamportPublicKey implements parent_SK_to_lamport_PK
+func (k *SecretKey) compressedLamportPublicKey(index uint32) []byte {
+	salt := binary.BigEndian.AppendUint32(nil, index)
+	ikm := k.Bytes()
+
+	notIKM := ikm
+	for i := range notIKM {
+		notIKM[i] ^= 0xff
+	}
+
+	lamportPK := sha256.New()
+	for _, secret := range [][]byte{ikm[:], notIKM[:]} {
+		// IKM_to_lamport_SK: 255 chunks of 32 bytes expanded from the input
+		okm := make([]byte, 255*32)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, nil), okm); err != nil {
+			panic(err) // 8160 bytes is exactly the HKDF-SHA256 output limit
+		}
+
+		for chunk := 0; chunk < 255; chunk++ {
+			hash := sha256.Sum256(okm[chunk*32 : (chunk+1)*32])
+			lamportPK.Write(hash[:])
+		}
+	}
+
+	return lamportPK.Sum(nil)
+}
+
+// hkdfModR implements HKDF_mod_r with an empty key_info
+func hkdfModR(ikm []byte) *big.Int {
+	const outputLen = 48
+
+	salt := []byte("BLS-SIG-KEYGEN-SALT-")
+	secretKey := new(big.Int)
+	for secretKey.Sign() == 0 {
+		hash := sha256.Sum256(salt)
+		salt = hash[:]
+
+		okm := make([]byte, outputLen)
+		reader := hkdf.New(sha256.New, append(append([]byte(nil), ikm...), 0), salt, []byte{0, outputLen})
+		if _, err := io.ReadFull(reader, okm); err != nil {
+			panic(err)
+		}
+
+		secretKey.SetBytes(okm).Mod(secretKey, curveOrder)
+	}
+
+	return secretKey
+}
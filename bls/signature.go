@@ -0,0 +1,112 @@
+package bls
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// SignatureDST is the hash-to-curve domain separation tag of the proof-of-possession
+// scheme used by the Ethereum consensus layer
+const SignatureDST = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"
+
+// SecretKeyFromBytes parses a 32-byte big-endian secret key, as returned by Bytes
+func SecretKeyFromBytes(data []byte) (*SecretKey, error) {
+	if len(data) != 32 {
+		return nil, fmt.Errorf("secret key has %d bytes, expected 32", len(data))
+	}
+
+	scalar := new(big.Int).SetBytes(data)
+	if scalar.Sign() == 0 || scalar.Cmp(curveOrder) >= 0 {
+		return nil, errors.New("secret key is not in [1, r)")
+	}
+
+	return &SecretKey{scalar: scalar}, nil
+}
+
+// Sign signs message and returns the 96-byte compressed G2 signature, sk * H(message)
+func (k *SecretKey) Sign(message []byte) ([96]byte, error) {
+	g2 := bls12381.NewG2()
+	point, err := g2.HashToCurve(message, []byte(SignatureDST))
+	if err != nil {
+		return [96]byte{}, err
+	}
+
+	var out [96]byte
+	copy(out[:], g2.ToCompressed(g2.MulScalarBig(g2.New(), point, k.scalar)))
+
+	return out, nil
+}
+
+// Verify checks a signature produced by Sign against a compressed G1 public key
+// Keys and signatures outside the prime order subgroups, or at infinity, are rejected
+func Verify(publicKey [48]byte, message []byte, signature [96]byte) bool {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+
+	public, err := g1.FromCompressed(publicKey[:])
+	if err != nil || g1.IsZero(public) || !g1.InCorrectSubgroup(public) {
+		return false
+	}
+	sig, err := g2.FromCompressed(signature[:])
+	if err != nil || !g2.InCorrectSubgroup(sig) {
+		return false
+	}
+	hash, err := g2.HashToCurve(message, []byte(SignatureDST))
+	if err != nil {
+		return false
+	}
+
+	// e(pk, H(m)) == e(G1, sig)
+	return bls12381.NewEngine().AddPair(public, hash).AddPairInv(g1.One(), sig).Check()
+}
+
+// KeyPair returns the key as an hdwallet.KeyPair
+func (k *SecretKey) KeyPair() hdwallet.KeyPair {
+	return keyPair{key: k}
+}
+
+// keyPair adapts SecretKey to hdwallet.KeyPair
+type keyPair struct {
+	key *SecretKey
+}
+
+// Curve implements hdwallet.KeyPair
+func (k keyPair) Curve() hdwallet.Curve {
+	return hdwallet.CurveBLS12381
+}
+
+// PublicKeyBytes implements hdwallet.KeyPair
+func (k keyPair) PublicKeyBytes() []byte {
+	publicKey := k.key.PublicKey()
+
+	return publicKey[:]
+}
+
+// PrivateKeyBytes implements hdwallet.KeyPair
+func (k keyPair) PrivateKeyBytes() []byte {
+	secret := k.key.Bytes()
+
+	return secret[:]
+}
+
+// Sign implements hdwallet.KeyPair
+func (k keyPair) Sign(message []byte) ([]byte, error) {
+	signature, err := k.key.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature[:], nil
+}
+
+// Verify implements hdwallet.KeyPair
+func (k keyPair) Verify(message, signature []byte) bool {
+	if len(signature) != 96 {
+		return false
+	}
+
+	return Verify(k.key.PublicKey(), message, [96]byte(signature))
+}
@@ -0,0 +1,329 @@
+package hdwallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/not-for-prod/hdwallet/codec"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// AddressEncoding spells out how an address is computed from a public key, so a
+// verifier can recompute it from these parameters rather than trusting a format name
+type AddressEncoding struct {
+	// Format is the AddressFormat constant these parameters implement
+	Format string `json:"format"`
+	// PublicKey is the serialization that is hashed, "uncompressed-xy" (64 bytes without
+	// prefix), "compressed" (33 bytes) or "taproot-output" (the 32-byte x-only BIP-86
+	// output key)
+	PublicKey string `json:"public_key"`
+	// Hash is the digest taken of it, "keccak256-last20", "hash160", "p2wpkh-hash160"
+	// (hash160 of the P2WPKH script of the hash160) or "none"
+	Hash string `json:"hash"`
+	// Version is the hex prefix added before encoding, or the witness version of
+	// segwit addresses, empty for none
+	Version string `json:"version,omitempty"`
+	// Encoding is the text encoding, "base58check", "hex-eip55", "segwit" or "bech32"
+	Encoding string `json:"encoding"`
+	// Prefix is the human-readable part of segwit and bech32 addresses
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// addressEncodings are the parameters of the address formats
+var addressEncodings = map[string]AddressEncoding{
+	AddressFormatTron: {
+		Format:    AddressFormatTron,
+		PublicKey: "uncompressed-xy",
+		Hash:      "keccak256-last20",
+		Version:   "41",
+		Encoding:  "base58check",
+	},
+	AddressFormatEthereum: {
+		Format:    AddressFormatEthereum,
+		PublicKey: "uncompressed-xy",
+		Hash:      "keccak256-last20",
+		Encoding:  "hex-eip55",
+	},
+	AddressFormatBitcoinP2PKH: {
+		Format:    AddressFormatBitcoinP2PKH,
+		PublicKey: "compressed",
+		Hash:      "hash160",
+		Version:   "00",
+		Encoding:  "base58check",
+	},
+	AddressFormatBitcoinP2SHP2WPKH: {
+		Format:    AddressFormatBitcoinP2SHP2WPKH,
+		PublicKey: "compressed",
+		Hash:      "p2wpkh-hash160",
+		Version:   "05",
+		Encoding:  "base58check",
+	},
+	AddressFormatBitcoinP2WPKH: {
+		Format:    AddressFormatBitcoinP2WPKH,
+		PublicKey: "compressed",
+		Hash:      "hash160",
+		Version:   "00",
+		Encoding:  "segwit",
+		Prefix:    "bc",
+	},
+	AddressFormatBitcoinP2TR: {
+		Format:    AddressFormatBitcoinP2TR,
+		PublicKey: "taproot-output",
+		Hash:      "none",
+		Version:   "01",
+		Encoding:  "segwit",
+		Prefix:    "bc",
+	},
+	AddressFormatCosmos: {
+		Format:    AddressFormatCosmos,
+		PublicKey: "compressed",
+		Hash:      "hash160",
+		Encoding:  "bech32",
+		Prefix:    "cosmos",
+	},
+}
+
+// AddressEncodingOf returns the encoding parameters of an address format
+func AddressEncodingOf(format string) (AddressEncoding, error) {
+	encoding, ok := addressEncodings[format]
+	if !ok {
+		return AddressEncoding{}, fmt.Errorf("unknown address format %q", format)
+	}
+
+	return encoding, nil
+}
+
+// Encode computes the address of publicKey by interpreting the parameters
+func (e AddressEncoding) Encode(publicKey *PublicKey) (string, error) {
+	var serialized []byte
+	switch e.PublicKey {
+	case "uncompressed-xy":
+		serialized = publicKey.SerializeUncompressed()[1:]
+	case "compressed":
+		serialized = publicKey.SerializeCompressed()
+	case "taproot-output":
+		serialized = taprootOutputKey(publicKey)
+	default:
+		return "", fmt.Errorf("unknown public key serialization %q", e.PublicKey)
+	}
+
+	var payload []byte
+	switch e.Hash {
+	case "keccak256-last20":
+		payload = keccak256(serialized)[12:]
+	case "hash160":
+		payload = hash160(serialized)
+	case "p2wpkh-hash160":
+		payload = hash160(append([]byte{0x00, 0x14}, hash160(serialized)...))
+	case "none":
+		payload = serialized
+	default:
+		return "", fmt.Errorf("unknown address hash %q", e.Hash)
+	}
+
+	version, err := hex.DecodeString(e.Version)
+	if err != nil {
+		return "", fmt.Errorf("invalid address version %q: %w", e.Version, err)
+	}
+
+	switch e.Encoding {
+	case "base58check":
+		if len(version) != 1 {
+			return "", errors.New("base58check needs a one byte version")
+		}
+		return codec.Base58CheckEncode(append(version, payload...)), nil
+	case "hex-eip55":
+		if len(version) != 0 {
+			return "", errors.New("hex-eip55 takes no version")
+		}
+		return ChecksumEthereumAddress(payload), nil
+	case "segwit":
+		if len(version) != 1 || e.Prefix == "" {
+			return "", errors.New("segwit needs a witness version and a prefix")
+		}
+		return codec.SegwitEncode(e.Prefix, version[0], payload)
+	case "bech32":
+		if len(version) != 0 || e.Prefix == "" {
+			return "", errors.New("bech32 takes no version and needs a prefix")
+		}
+		return codec.Bech32Encode(e.Prefix, payload, codec.Bech32)
+	default:
+		return "", fmt.Errorf("unknown address encoding %q", e.Encoding)
+	}
+}
+
+// bundleVersion is the current Bundle format version
+const bundleVersion = 1
+
+// Bundle lists addresses shown to users together with their derivation paths,
+// public keys and encoding parameters, for VerifyBundle to check on an air-gapped
+// machine holding the cold seed. It contains no private material
+type Bundle struct {
+	Version int `json:"version"`
+	// MasterFingerprint is the BIP32 fingerprint of the master key the paths start from
+	MasterFingerprint string          `json:"master_fingerprint"`
+	CreatedAt         time.Time       `json:"created_at"`
+	Addresses         []BundleAddress `json:"addresses"`
+}
+
+// BundleAddress is an address as shown online and how it is claimed to derive from the seed
+type BundleAddress struct {
+	Coin uint32         `json:"coin"`
+	Path DerivationPath `json:"path"`
+	// PublicKey is the hex compressed public key at Path
+	PublicKey string          `json:"public_key"`
+	Address   string          `json:"address"`
+	Encoding  AddressEncoding `json:"encoding"`
+}
+
+// NewBundle returns an empty bundle for the seed with the given master fingerprint,
+// as found in a Manifest
+func NewBundle(masterFingerprint string) *Bundle {
+	return &Bundle{
+		Version:           bundleVersion,
+		MasterFingerprint: masterFingerprint,
+		CreatedAt:         time.Now().UTC(),
+	}
+}
+
+// Add records an address displayed or stored online, as is: mistakes are what
+// VerifyBundle is meant to find
+func (b *Bundle) Add(coin uint32, path DerivationPath, publicKey *PublicKey, address, format string) error {
+	encoding, err := AddressEncodingOf(format)
+	if err != nil {
+		return err
+	}
+
+	b.Addresses = append(b.Addresses, BundleAddress{
+		Coin:      coin,
+		Path:      path,
+		PublicKey: hex.EncodeToString(publicKey.SerializeCompressed()),
+		Address:   address,
+		Encoding:  encoding,
+	})
+
+	return nil
+}
+
+// AddXPub records addresses derived from the account xpub of coin and account,
+// on chain at the given indexes, in the given address format, which sets the
+// purpose of the account path (m/84' for AddressFormatBitcoinP2WPKH)
+func (b *Bundle) AddXPub(coin, account uint32, xpub, format string, chain uint32, indexes ...uint32) error {
+	accountKey, err := bip32.B58Deserialize(xpub)
+	if err != nil {
+		return fmt.Errorf("invalid xpub: %w", err)
+	}
+	encoding, err := AddressEncodingOf(format)
+	if err != nil {
+		return err
+	}
+
+	if origin := addressOrigins[format]; origin.coin != coin {
+		return fmt.Errorf("address format %s is for coin %d", format, origin.coin)
+	}
+	accountPath, err := accountPath(format, account)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		relative := DerivationPath{chain, index}
+		child, err := DerivePath(accountKey.PublicKey(), relative)
+		if err != nil {
+			return err
+		}
+		publicKey, err := PublicKeyFromBytes(child.Key)
+		if err != nil {
+			return err
+		}
+		address, err := encoding.Encode(publicKey)
+		if err != nil {
+			return err
+		}
+
+		path := append(slices.Clone(accountPath), relative...)
+		if err = b.Add(coin, path, publicKey, address, format); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// VerifyBundle recomputes every address of bundle from seed: the public key at each
+// path must match the recorded one, the encoding parameters must be those of the
+// recorded format, and encoding the key must give the recorded address
+// It returns the number of verified addresses and the failures joined in one error
+func VerifyBundle(seed []byte, bundle *Bundle) (int, error) {
+	if bundle.Version != bundleVersion {
+		return 0, fmt.Errorf("unsupported bundle version %d", bundle.Version)
+	}
+
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return 0, err
+	}
+	if fp := fingerprint(masterKey.PublicKey().Key); fp != bundle.MasterFingerprint {
+		return 0, fmt.Errorf("bundle is for master fingerprint %s, the seed has %s", bundle.MasterFingerprint, fp)
+	}
+
+	verified := 0
+	var failures []error
+	for i, entry := range bundle.Addresses {
+		if err := verifyBundleAddress(masterKey, entry); err != nil {
+			failures = append(failures, fmt.Errorf("address %d (%s at %s): %w", i, entry.Address, entry.Path, err))
+			continue
+		}
+		verified++
+	}
+
+	return verified, errors.Join(failures...)
+}
+
+func verifyBundleAddress(masterKey *bip32.Key, entry BundleAddress) error {
+	expected, err := AddressEncodingOf(entry.Encoding.Format)
+	if err != nil {
+		return err
+	}
+	if entry.Encoding != expected {
+		return fmt.Errorf("encoding parameters differ from %s", entry.Encoding.Format)
+	}
+
+	origin := addressOrigins[entry.Encoding.Format]
+	if origin.coin != entry.Coin {
+		return fmt.Errorf("address format %s is for coin %d, not %d", entry.Encoding.Format, origin.coin, entry.Coin)
+	}
+	if len(entry.Path) < 2 || entry.Path[0] != origin.purpose+HardenedOffset || entry.Path[1] != entry.Coin+HardenedOffset {
+		return fmt.Errorf("path is not below m/%d'/%d'", origin.purpose, entry.Coin)
+	}
+
+	child, err := DerivePath(masterKey, entry.Path)
+	if err != nil {
+		return err
+	}
+	defer wipeKey(child)
+	publicKey := child.PublicKey().Key
+
+	recorded, err := hex.DecodeString(entry.PublicKey)
+	if err != nil || !bytes.Equal(recorded, publicKey) {
+		return errors.New("public key does not derive from the seed")
+	}
+
+	parsed, err := PublicKeyFromBytes(publicKey)
+	if err != nil {
+		return err
+	}
+	address, err := entry.Encoding.Encode(parsed)
+	if err != nil {
+		return err
+	}
+	if address != entry.Address {
+		return fmt.Errorf("key encodes to %s", address)
+	}
+
+	return nil
+}
@@ -0,0 +1,439 @@
+// Package ceremony runs multi-party key generation ceremonies: N participants each
+// contribute entropy to a seed that none of them can predict or bias on their own
+//
+// The ceremony is a commit-reveal protocol driven by a coordinator, usually an
+// air-gapped machine:
+//
+//  1. Every participant creates a Contribution on their own device and hands its
+//     Commitment to the coordinator
+//  2. Once every commitment is recorded the transcript is fixed, and participants
+//     reveal their contribution to the coordinator, which checks it against the
+//     commitment
+//  3. The coordinator combines the revealed entropy, in participant order and keyed
+//     by the transcript hash, into the BIP39 mnemonic, and issues a Report
+//
+// Since every contribution is committed before any is revealed, the seed is
+// unpredictable as long as one participant used a good entropy source. Only the
+// coordinator sees the revealed entropy: the mnemonic should go straight into
+// shamir.Split or an HSM, and the coordinator be wiped afterwards
+package ceremony
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/not-for-prod/hdwallet"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// EntropySize is the number of bytes of entropy of a contribution
+	EntropySize = 32
+	// MinParticipants is the smallest number of participants of a ceremony
+	MinParticipants = 2
+	// MaxParticipants bounds the number of participants of a ceremony
+	MaxParticipants = 255
+)
+
+// Domain separation labels of the hashes of the protocol
+const (
+	paramsDomain     = "hdwallet-ceremony/v1 params"
+	commitmentDomain = "hdwallet-ceremony/v1 commitment"
+	transcriptDomain = "hdwallet-ceremony/v1 transcript"
+	combineDomain    = "hdwallet-ceremony/v1 combine"
+	entropyDomain    = "hdwallet-ceremony/v1 entropy"
+)
+
+var (
+	// ErrCommitmentMismatch is returned when a contribution does not open its commitment
+	ErrCommitmentMismatch = errors.New("contribution does not match its commitment")
+	// ErrIncomplete is returned when a ceremony step needs every participant's commitment
+	// or contribution and some are missing
+	ErrIncomplete = errors.New("ceremony is missing participants")
+)
+
+// Params are the public parameters of a ceremony, agreed on by every participant
+// before it starts
+type Params struct {
+	// ID uniquely names the ceremony, contributions cannot be replayed across ceremonies
+	ID string `json:"id"`
+	// Participants are the participant names, in the order their entropy is combined
+	Participants []string `json:"participants"`
+	// BitSize is the entropy size of the resulting mnemonic, a multiple of 32 in [128, 256]
+	BitSize int `json:"bit_size"`
+}
+
+// Validate checks the parameters
+func (p Params) Validate() error {
+	if p.ID == "" {
+		return errors.New("ceremony ID is empty")
+	}
+	if len(p.Participants) < MinParticipants || len(p.Participants) > MaxParticipants {
+		return fmt.Errorf("%d participants, expected between %d and %d", len(p.Participants), MinParticipants, MaxParticipants)
+	}
+	seen := make(map[string]bool, len(p.Participants))
+	for _, name := range p.Participants {
+		if name == "" {
+			return errors.New("participant name is empty")
+		}
+		if seen[name] {
+			return fmt.Errorf("participant %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+	if p.BitSize%32 != 0 || p.BitSize < 128 || p.BitSize > 256 {
+		return fmt.Errorf("invalid entropy size %d, must be a multiple of 32 between 128 and 256", p.BitSize)
+	}
+
+	return nil
+}
+
+// Hash returns the digest of the parameters bound into every commitment
+func (p Params) Hash() [32]byte {
+	h := sha256.New()
+	writeField(h, []byte(paramsDomain))
+	writeField(h, []byte(p.ID))
+	writeField(h, binary.BigEndian.AppendUint32(nil, uint32(p.BitSize)))
+	writeField(h, binary.BigEndian.AppendUint32(nil, uint32(len(p.Participants))))
+	for _, name := range p.Participants {
+		writeField(h, []byte(name))
+	}
+
+	return [32]byte(h.Sum(nil))
+}
+
+// index returns the position of participant in the combination order, -1 if unknown
+func (p Params) index(participant string) int {
+	return slices.Index(p.Participants, participant)
+}
+
+// Commitment is the public commitment of a participant to their contribution
+type Commitment struct {
+	Participant string `json:"participant"`
+	// Digest is the hex SHA-256 commitment to the contribution
+	Digest string `json:"digest"`
+}
+
+// Contribution is the secret entropy of one participant
+// It is revealed to the coordinator only, after every commitment has been recorded
+type Contribution struct {
+	Participant string
+	// ParamsHash is the Params.Hash of the ceremony the contribution belongs to
+	ParamsHash [32]byte
+	Entropy    [EntropySize]byte
+	// Nonce blinds the commitment
+	Nonce [32]byte
+}
+
+// NewContribution draws a contribution of participant to the ceremony from source,
+// hdwallet.DefaultEntropySource when nil. Participants mixing in dice rolls pass an
+// hdwallet.MixedEntropySource
+func NewContribution(params Params, participant string, source hdwallet.EntropySource) (*Contribution, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if params.index(participant) < 0 {
+		return nil, fmt.Errorf("%q is not a participant of ceremony %q", participant, params.ID)
+	}
+	if source == nil {
+		source = hdwallet.DefaultEntropySource()
+	}
+
+	c := &Contribution{Participant: participant, ParamsHash: params.Hash()}
+	if _, err := io.ReadFull(source, c.Entropy[:]); err != nil {
+		return nil, fmt.Errorf("read entropy: %w", err)
+	}
+	if _, err := io.ReadFull(source, c.Nonce[:]); err != nil {
+		c.Zero()
+		return nil, fmt.Errorf("read entropy: %w", err)
+	}
+
+	return c, nil
+}
+
+// Commitment returns the commitment to the contribution, safe to publish
+func (c *Contribution) Commitment() Commitment {
+	h := sha256.New()
+	writeField(h, []byte(commitmentDomain))
+	writeField(h, c.ParamsHash[:])
+	writeField(h, []byte(c.Participant))
+	writeField(h, c.Nonce[:])
+	writeField(h, c.Entropy[:])
+
+	return Commitment{Participant: c.Participant, Digest: hex.EncodeToString(h.Sum(nil))}
+}
+
+// Zero wipes the entropy and nonce of the contribution
+func (c *Contribution) Zero() {
+	clear(c.Entropy[:])
+	clear(c.Nonce[:])
+}
+
+// Ceremony is the coordinator state of a ceremony
+// It is safe for concurrent use
+type Ceremony struct {
+	mu            sync.Mutex
+	params        Params
+	paramsHash    [32]byte
+	commitments   []Commitment
+	contributions []*Contribution
+	finalized     bool
+}
+
+// New starts a ceremony with the given parameters
+func New(params Params) (*Ceremony, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	params.Participants = slices.Clone(params.Participants)
+
+	return &Ceremony{
+		params:        params,
+		paramsHash:    params.Hash(),
+		commitments:   make([]Commitment, len(params.Participants)),
+		contributions: make([]*Contribution, len(params.Participants)),
+	}, nil
+}
+
+// Params returns the parameters of the ceremony
+func (c *Ceremony) Params() Params {
+	params := c.params
+	params.Participants = slices.Clone(params.Participants)
+
+	return params
+}
+
+// Commit records the commitment of a participant, which cannot be replaced
+func (c *Ceremony) Commit(commitment Commitment) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finalized {
+		return errors.New("ceremony is finalized")
+	}
+	i := c.params.index(commitment.Participant)
+	if i < 0 {
+		return fmt.Errorf("%q is not a participant of ceremony %q", commitment.Participant, c.params.ID)
+	}
+	digest, err := hex.DecodeString(commitment.Digest)
+	if err != nil || len(digest) != sha256.Size {
+		return fmt.Errorf("commitment of %q is not a hex SHA-256 digest", commitment.Participant)
+	}
+	if c.commitments[i].Digest != "" {
+		return fmt.Errorf("%q has already committed", commitment.Participant)
+	}
+
+	c.commitments[i] = Commitment{Participant: commitment.Participant, Digest: hex.EncodeToString(digest)}
+
+	return nil
+}
+
+// Missing returns the participants whose commitment, or once every commitment is
+// recorded whose contribution, the coordinator is waiting for
+func (c *Ceremony) Missing() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	committed := c.committed()
+	var missing []string
+	for i, name := range c.params.Participants {
+		if committed && c.contributions[i] == nil || !committed && c.commitments[i].Digest == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
+
+// committed reports whether every participant has committed
+func (c *Ceremony) committed() bool {
+	return !slices.ContainsFunc(c.commitments, func(commitment Commitment) bool {
+		return commitment.Digest == ""
+	})
+}
+
+// TranscriptHash returns the hash of the parameters and every commitment, which fixes
+// the outcome of the ceremony; it fails with ErrIncomplete before every participant
+// has committed
+func (c *Ceremony) TranscriptHash() ([32]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.committed() {
+		return [32]byte{}, fmt.Errorf("%w: waiting for commitments", ErrIncomplete)
+	}
+
+	return transcriptHash(c.paramsHash, c.commitments), nil
+}
+
+// Reveal records the contribution of a participant after checking it against their
+// commitment. The ceremony keeps a copy, the caller should Zero its own
+func (c *Ceremony) Reveal(contribution *Contribution) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finalized {
+		return errors.New("ceremony is finalized")
+	}
+	if !c.committed() {
+		return fmt.Errorf("%w: contributions are revealed once every participant has committed", ErrIncomplete)
+	}
+	i := c.params.index(contribution.Participant)
+	if i < 0 {
+		return fmt.Errorf("%q is not a participant of ceremony %q", contribution.Participant, c.params.ID)
+	}
+	if c.contributions[i] != nil {
+		return fmt.Errorf("%q has already revealed", contribution.Participant)
+	}
+	// A contribution for other parameters opens its own commitment, but recording it
+	// would make Finalize fail with no way to reveal again
+	if contribution.ParamsHash != c.paramsHash {
+		return fmt.Errorf("contribution of %q belongs to another ceremony", contribution.Participant)
+	}
+	if err := openCommitment(c.commitments[i], contribution); err != nil {
+		return err
+	}
+
+	revealed := *contribution
+	c.contributions[i] = &revealed
+
+	return nil
+}
+
+// Finalize combines the revealed contributions into the mnemonic and returns it with
+// the ceremony report. The contributions held by the ceremony are wiped
+func (c *Ceremony) Finalize() (string, *Report, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.finalized {
+		return "", nil, errors.New("ceremony is finalized")
+	}
+	if !c.committed() || slices.Contains(c.contributions, nil) {
+		return "", nil, fmt.Errorf("%w: waiting for contributions", ErrIncomplete)
+	}
+
+	mnemonic, err := Combine(c.params, c.contributions)
+	if err != nil {
+		return "", nil, err
+	}
+	report, err := newReport(c.params, c.commitments, mnemonic)
+	if err != nil {
+		return "", nil, err
+	}
+
+	c.finalized = true
+	c.wipe()
+
+	return mnemonic, report, nil
+}
+
+// Abort wipes the revealed contributions; the ceremony cannot be finalized afterwards
+func (c *Ceremony) Abort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.finalized = true
+	c.wipe()
+}
+
+// wipe zeroes and drops the revealed contributions
+func (c *Ceremony) wipe() {
+	for i, contribution := range c.contributions {
+		if contribution != nil {
+			contribution.Zero()
+			c.contributions[i] = nil
+		}
+	}
+}
+
+// Combine deterministically derives the ceremony mnemonic from every participant's
+// contribution, given in any order. The coordinator runs it through Finalize; a dry
+// run of a ceremony can rerun it to check the outcome
+//
+// The entropy is HKDF-SHA256 over the contributions in participant order, salted
+// with the transcript hash
+func Combine(params Params, contributions []*Contribution) (string, error) {
+	if err := params.Validate(); err != nil {
+		return "", err
+	}
+	paramsHash := params.Hash()
+
+	ordered := make([]*Contribution, len(params.Participants))
+	for _, contribution := range contributions {
+		i := params.index(contribution.Participant)
+		if i < 0 {
+			return "", fmt.Errorf("%q is not a participant of ceremony %q", contribution.Participant, params.ID)
+		}
+		if ordered[i] != nil {
+			return "", fmt.Errorf("%q contributed twice", contribution.Participant)
+		}
+		if contribution.ParamsHash != paramsHash {
+			return "", fmt.Errorf("contribution of %q belongs to another ceremony", contribution.Participant)
+		}
+		ordered[i] = contribution
+	}
+
+	commitments := make([]Commitment, len(ordered))
+	ikm := make([]byte, 0, len(ordered)*(4+EntropySize))
+	defer clear(ikm)
+	for i, contribution := range ordered {
+		if contribution == nil {
+			return "", fmt.Errorf("%w: no contribution of %q", ErrIncomplete, params.Participants[i])
+		}
+		commitments[i] = contribution.Commitment()
+		ikm = appendField(ikm, contribution.Entropy[:])
+	}
+	transcript := transcriptHash(paramsHash, commitments)
+
+	entropy := make([]byte, params.BitSize/8)
+	defer clear(entropy)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, transcript[:], []byte(combineDomain)), entropy); err != nil {
+		return "", err
+	}
+
+	return bip39.NewMnemonic(entropy)
+}
+
+// openCommitment checks that contribution opens commitment
+func openCommitment(commitment Commitment, contribution *Contribution) error {
+	got := contribution.Commitment()
+	if commitment.Participant != got.Participant || subtle.ConstantTimeCompare([]byte(commitment.Digest), []byte(got.Digest)) != 1 {
+		return fmt.Errorf("%w: participant %q", ErrCommitmentMismatch, contribution.Participant)
+	}
+
+	return nil
+}
+
+// transcriptHash hashes the parameters and the commitments in participant order
+func transcriptHash(paramsHash [32]byte, commitments []Commitment) [32]byte {
+	h := sha256.New()
+	writeField(h, []byte(transcriptDomain))
+	writeField(h, paramsHash[:])
+	for _, commitment := range commitments {
+		writeField(h, []byte(commitment.Participant))
+		writeField(h, []byte(commitment.Digest))
+	}
+
+	return [32]byte(h.Sum(nil))
+}
+
+// writeField writes data prefixed with its 4-byte big-endian length
+func writeField(w io.Writer, data []byte) {
+	w.Write(appendField(nil, data))
+}
+
+// appendField appends data prefixed with its 4-byte big-endian length to dst
+func appendField(dst, data []byte) []byte {
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(data)))
+
+	return append(dst, data...)
+}
@@ -0,0 +1,180 @@
+package ceremony
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/not-for-prod/hdwallet"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// reportVersion is the current Report format version
+const reportVersion = 1
+
+// Report is the public record of a finalized ceremony
+//
+// Anyone can check that it is consistent with Verify, each participant that their
+// contribution went into it with VerifyContribution, and the custodians recovering
+// the mnemonic that it is the one the ceremony produced with VerifyMnemonic
+type Report struct {
+	Version      int      `json:"version"`
+	ID           string   `json:"id"`
+	Participants []string `json:"participants"`
+	BitSize      int      `json:"bit_size"`
+	// Commitments are in participant order
+	Commitments []Commitment `json:"commitments"`
+	// TranscriptHash is the hex hash of the parameters and commitments
+	TranscriptHash string `json:"transcript_hash"`
+	// EntropyCommitment is the hex hash of the mnemonic entropy bound to the transcript
+	EntropyCommitment string `json:"entropy_commitment"`
+	// MasterFingerprint is the BIP32 fingerprint of the master key of the mnemonic
+	// without passphrase, as in hdwallet.Manifest
+	MasterFingerprint string    `json:"master_fingerprint"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// newReport builds the report of a ceremony that produced mnemonic
+func newReport(params Params, commitments []Commitment, mnemonic string) (*Report, error) {
+	transcript := transcriptHash(params.Hash(), commitments)
+
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(entropy)
+
+	seed, err := hdwallet.MnemonicToSeed(context.Background(), mnemonic, "")
+	if err != nil {
+		return nil, err
+	}
+	defer clear(seed)
+	masterFingerprint, err := hdwallet.MasterFingerprint(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Report{
+		Version:           reportVersion,
+		ID:                params.ID,
+		Participants:      slices.Clone(params.Participants),
+		BitSize:           params.BitSize,
+		Commitments:       slices.Clone(commitments),
+		TranscriptHash:    hex.EncodeToString(transcript[:]),
+		EntropyCommitment: entropyCommitment(transcript, entropy),
+		MasterFingerprint: masterFingerprint,
+		CreatedAt:         time.Now().UTC(),
+	}, nil
+}
+
+// Params returns the parameters of the reported ceremony
+func (r *Report) Params() Params {
+	return Params{ID: r.ID, Participants: slices.Clone(r.Participants), BitSize: r.BitSize}
+}
+
+// Verify checks that the report is well formed and that its transcript hash covers
+// its parameters and commitments
+func (r *Report) Verify() error {
+	if r.Version != reportVersion {
+		return fmt.Errorf("unsupported report version %d", r.Version)
+	}
+	params := r.Params()
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if len(r.Commitments) != len(r.Participants) {
+		return fmt.Errorf("report has %d commitments for %d participants", len(r.Commitments), len(r.Participants))
+	}
+	for i, commitment := range r.Commitments {
+		if commitment.Participant != r.Participants[i] {
+			return fmt.Errorf("commitment %d is from %q, expected %q", i, commitment.Participant, r.Participants[i])
+		}
+	}
+
+	transcript := transcriptHash(params.Hash(), r.Commitments)
+	if !equalHex(r.TranscriptHash, hex.EncodeToString(transcript[:])) {
+		return errors.New("transcript hash does not match the commitments")
+	}
+
+	return nil
+}
+
+// VerifyContribution checks that the report is valid and that contribution is the one
+// committed by its participant
+func (r *Report) VerifyContribution(contribution *Contribution) error {
+	if err := r.Verify(); err != nil {
+		return err
+	}
+	if contribution.ParamsHash != r.Params().Hash() {
+		return fmt.Errorf("contribution of %q belongs to another ceremony", contribution.Participant)
+	}
+	i := slices.Index(r.Participants, contribution.Participant)
+	if i < 0 {
+		return fmt.Errorf("%q is not a participant of ceremony %q", contribution.Participant, r.ID)
+	}
+
+	return openCommitment(r.Commitments[i], contribution)
+}
+
+// VerifyMnemonic checks that the report is valid and that mnemonic is the one the
+// ceremony produced, for example after recombining its Shamir shares
+func (r *Report) VerifyMnemonic(ctx context.Context, mnemonic string) error {
+	if err := r.Verify(); err != nil {
+		return err
+	}
+
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return err
+	}
+	defer clear(entropy)
+	if len(entropy)*8 != r.BitSize {
+		return fmt.Errorf("mnemonic has %d bits of entropy, the ceremony produced %d", len(entropy)*8, r.BitSize)
+	}
+
+	transcript, err := hex.DecodeString(r.TranscriptHash)
+	if err != nil {
+		return fmt.Errorf("decode transcript hash: %w", err)
+	}
+	if !equalHex(r.EntropyCommitment, entropyCommitment([32]byte(transcript), entropy)) {
+		return errors.New("mnemonic is not the one produced by the ceremony")
+	}
+
+	seed, err := hdwallet.MnemonicToSeed(ctx, mnemonic, "")
+	if err != nil {
+		return err
+	}
+	defer clear(seed)
+	masterFingerprint, err := hdwallet.MasterFingerprint(seed)
+	if err != nil {
+		return err
+	}
+	if !equalHex(r.MasterFingerprint, masterFingerprint) {
+		return fmt.Errorf("master fingerprint %s, the report records %s", masterFingerprint, r.MasterFingerprint)
+	}
+
+	return nil
+}
+
+// entropyCommitment returns the hex hash of the mnemonic entropy bound to the transcript
+func entropyCommitment(transcript [32]byte, entropy []byte) string {
+	h := sha256.New()
+	writeField(h, []byte(entropyDomain))
+	writeField(h, transcript[:])
+	writeField(h, entropy)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// equalHex compares hex digests case-insensitively in constant time
+func equalHex(a, b string) bool {
+	da, errA := hex.DecodeString(a)
+	db, errB := hex.DecodeString(b)
+
+	return errA == nil && errB == nil && subtle.ConstantTimeCompare(da, db) == 1
+}
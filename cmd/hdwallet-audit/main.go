@@ -0,0 +1,219 @@
+// Command hdwallet-audit is the auditor mode of hdwallet
+//
+// It accepts extended public keys and descriptors only, never mnemonics or private keys:
+//
+//	hdwallet-audit enumerate -coin 195 -count 20 xpub...
+//	hdwallet-audit attest -key auditor.key -statement "Q3 deposits" -coin 0 -count 1000 'wpkh(...)#...' > attestation.json
+//	hdwallet-audit verify -auditor 02ab... [-addresses published.txt] attestation.json
+//
+// The auditor key file holds the hex secp256k1 private key of the auditor, which is
+// unrelated to the wallets under audit
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/not-for-prod/hdwallet"
+	"github.com/not-for-prod/hdwallet/watchonly"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "enumerate":
+		err = enumerate(os.Args[2:])
+	case "attest":
+		err = attest(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "hdwallet-audit:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: hdwallet-audit enumerate|attest|verify [flags] args...")
+	os.Exit(2)
+}
+
+// rangeFlags are the flags selecting the sources and indexes of enumerate and attest
+type rangeFlags struct {
+	coin  uint
+	from  uint
+	count uint
+}
+
+func (r *rangeFlags) register(flags *flag.FlagSet) {
+	flags.UintVar(&r.coin, "coin", 0, "SLIP-44 coin type of the sources")
+	flags.UintVar(&r.from, "from", 0, "first address index")
+	flags.UintVar(&r.count, "count", 20, "number of addresses per source")
+}
+
+// sources parses every argument as a watch-only source
+func (r *rangeFlags) sources(args []string) ([]*watchonly.Source, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no source given")
+	}
+
+	sources := make([]*watchonly.Source, 0, len(args))
+	for i, arg := range args {
+		source, err := watchonly.ParseSource(arg, uint32(r.coin))
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %w", i+1, err)
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+func enumerate(args []string) error {
+	flags := flag.NewFlagSet("enumerate", flag.ExitOnError)
+	var r rangeFlags
+	r.register(flags)
+	flags.Parse(args)
+
+	sources, err := r.sources(flags.Args())
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		addresses, err := source.Enumerate(uint32(r.from), uint32(r.count))
+		if err != nil {
+			return err
+		}
+		fmt.Println("#", source)
+		for _, address := range addresses {
+			fmt.Printf("%s\t%s\n", address.Path, address.Address)
+		}
+	}
+
+	return nil
+}
+
+func attest(args []string) error {
+	flags := flag.NewFlagSet("attest", flag.ExitOnError)
+	var r rangeFlags
+	r.register(flags)
+	keyFile := flags.String("key", "", "file holding the hex secp256k1 private key of the auditor")
+	statement := flags.String("statement", "", "free text recorded in the report")
+	flags.Parse(args)
+
+	if *keyFile == "" {
+		return errors.New("-key is required")
+	}
+	sources, err := r.sources(flags.Args())
+	if err != nil {
+		return err
+	}
+	key, err := readAuditorKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	report := watchonly.NewReport(*statement)
+	for _, source := range sources {
+		if err := report.Add(source, uint32(r.from), uint32(r.count)); err != nil {
+			return err
+		}
+	}
+	attestation, err := watchonly.Attest(hdwallet.NewKeySigner(key), report)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(attestation)
+}
+
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	auditorKey := flags.String("auditor", "", "hex compressed public key of the auditor")
+	curve := flags.String("curve", watchonly.CurveSecp256k1, "curve of the auditor key")
+	addressFile := flags.String("addresses", "", "file of published addresses, one per line, that must all be attested")
+	flags.Parse(args)
+
+	if *auditorKey == "" || flags.NArg() != 1 {
+		return errors.New("usage: verify -auditor KEY [-addresses FILE] ATTESTATION")
+	}
+	auditor, err := watchonly.ParseAuditorKey(*curve, *auditorKey)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	var attestation watchonly.Attestation
+	if err := json.Unmarshal(data, &attestation); err != nil {
+		return fmt.Errorf("parse attestation: %w", err)
+	}
+	report, err := attestation.Verify(auditor)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("attestation valid: %d sources, issued %s\n", len(report.Entries), report.CreatedAt.Format("2006-01-02T15:04:05Z"))
+
+	if *addressFile == "" {
+		return nil
+	}
+	file, err := os.Open(*addressFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	unknown := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		address := strings.TrimSpace(scanner.Text())
+		if address == "" {
+			continue
+		}
+		if _, _, ok := report.Lookup(address); !ok {
+			fmt.Println("not attested:", address)
+			unknown++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if unknown > 0 {
+		return fmt.Errorf("%d published addresses are not attested", unknown)
+	}
+	fmt.Println("all published addresses are attested")
+
+	return nil
+}
+
+// readAuditorKey reads the hex secp256k1 private key of the auditor
+func readAuditorKey(path string) (*hdwallet.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("auditor key file must hold a 32-byte hex private key")
+	}
+
+	return hdwallet.PrivateKeyFromBytes(key)
+}
@@ -0,0 +1,217 @@
+package codec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// base58Alphabet is the Bitcoin base58 alphabet, without 0, O, I and l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Values maps characters to their base58 digit, -1 for characters outside the alphabet
+var base58Values = func() [256]int8 {
+	var values [256]int8
+	for i := range values {
+		values[i] = -1
+	}
+	for i := range len(base58Alphabet) {
+		values[base58Alphabet[i]] = int8(i)
+	}
+
+	return values
+}()
+
+// Base58Encode encodes data in base58, leading zero bytes becoming '1'
+func Base58Encode(data []byte) string {
+	return string(AppendBase58(nil, data))
+}
+
+// AppendBase58 appends the base58 encoding of data to dst
+func AppendBase58(dst, data []byte) []byte {
+	zeros := 0
+	for zeros < len(data) && data[zeros] == 0 {
+		zeros++
+	}
+
+	// log(256) / log(58) < 1.38, rounded up
+	digits := make([]byte, (len(data)-zeros)*138/100+1)
+	length := 0
+	for _, b := range data[zeros:] {
+		carry := int(b)
+		i := 0
+		for j := len(digits) - 1; (carry != 0 || i < length) && j >= 0; j-- {
+			carry += 256 * int(digits[j])
+			digits[j] = byte(carry % 58)
+			carry /= 58
+			i++
+		}
+		length = i
+	}
+
+	for range zeros {
+		dst = append(dst, '1')
+	}
+	for _, digit := range digits[len(digits)-length:] {
+		dst = append(dst, base58Alphabet[digit])
+	}
+
+	return dst
+}
+
+// Base58Decode decodes a base58 string
+// An empty string decodes to an empty slice; characters outside the alphabet,
+// whitespace included in Strict mode, are reported with a *SyntaxError
+func Base58Decode(s string, mode Mode) ([]byte, error) {
+	s = mode.prepare(s)
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+
+	// log(58) / log(256) < 0.733, rounded up
+	bytes256 := make([]byte, (len(s)-zeros)*733/1000+1)
+	length := 0
+	for i := zeros; i < len(s); i++ {
+		value := base58Values[s[i]]
+		if value < 0 {
+			return nil, syntaxError(s, i, "invalid base58 character")
+		}
+
+		carry := int(value)
+		k := 0
+		for j := len(bytes256) - 1; (carry != 0 || k < length) && j >= 0; j-- {
+			carry += 58 * int(bytes256[j])
+			bytes256[j] = byte(carry % 256)
+			carry /= 256
+			k++
+		}
+		length = k
+	}
+
+	decoded := make([]byte, zeros, zeros+length)
+
+	return append(decoded, bytes256[len(bytes256)-length:]...), nil
+}
+
+// Base58CheckEncode encodes payload, version bytes included, followed by the first
+// 4 bytes of its double SHA-256
+func Base58CheckEncode(payload []byte) string {
+	checksum := base58Checksum(payload)
+	data := append(append(make([]byte, 0, len(payload)+4), payload...), checksum[:]...)
+
+	return Base58Encode(data)
+}
+
+// Base58CheckDecode decodes a Base58Check string and returns its payload, version
+// bytes included; a wrong checksum is reported as ErrChecksum
+func Base58CheckDecode(s string, mode Mode) ([]byte, error) {
+	decoded, err := Base58Decode(s, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkBase58Check(decoded)
+}
+
+// checkBase58Check splits decoded into its payload and checksum and checks the latter
+func checkBase58Check(decoded []byte) ([]byte, error) {
+	if len(decoded) < 4 {
+		return nil, fmt.Errorf("base58check string decodes to %d bytes, shorter than its checksum", len(decoded))
+	}
+
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	want := base58Checksum(payload)
+	if !bytes.Equal(checksum, want[:]) {
+		return nil, ErrChecksum
+	}
+
+	return payload, nil
+}
+
+// Base58CheckDecoder reads the payload of a Base58Check string from an io.Reader
+//
+// A base58 string is a single number whose every byte depends on its last character,
+// so the first Read decodes the whole string and checks its checksum before returning
+// any of the payload; the caller is spared holding the string, not the payload
+type Base58CheckDecoder struct {
+	text    *textReader
+	decoded bool
+	payload []byte
+	err     error
+}
+
+// NewBase58CheckDecoder returns a decoder for the Base58Check string read from r
+func NewBase58CheckDecoder(r io.Reader, mode Mode) *Base58CheckDecoder {
+	return &Base58CheckDecoder{text: newTextReader(r, mode)}
+}
+
+// Read implements io.Reader
+func (d *Base58CheckDecoder) Read(p []byte) (int, error) {
+	if !d.decoded {
+		d.decoded = true
+		d.payload, d.err = d.decode()
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	if len(d.payload) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, d.payload)
+	d.payload = d.payload[n:]
+
+	return n, nil
+}
+
+// decode reads the string to its end and returns its checked payload
+func (d *Base58CheckDecoder) decode() ([]byte, error) {
+	zeros := 0
+	// number is little-endian so that it grows at the end
+	var number []byte
+	for {
+		c, position, err := d.text.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c >= utf8.RuneSelf || base58Values[c] < 0 {
+			return nil, &SyntaxError{Position: position, Char: c, Reason: "invalid base58 character"}
+		}
+		if c == '1' && len(number) == 0 {
+			zeros++
+			continue
+		}
+
+		carry := int(base58Values[c])
+		for i := range number {
+			carry += 58 * int(number[i])
+			number[i] = byte(carry)
+			carry >>= 8
+		}
+		for ; carry != 0; carry >>= 8 {
+			number = append(number, byte(carry))
+		}
+	}
+
+	decoded := make([]byte, zeros, zeros+len(number))
+	for i := len(number) - 1; i >= 0; i-- {
+		decoded = append(decoded, number[i])
+	}
+
+	return checkBase58Check(decoded)
+}
+
+// base58Checksum returns the first 4 bytes of SHA-256(SHA-256(payload))
+func base58Checksum(payload []byte) [4]byte {
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+
+	return [4]byte(second[:4])
+}
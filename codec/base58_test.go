@@ -0,0 +1,201 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// base58Vectors are the encoding vectors of Bitcoin Core, base58_encode_decode.json
+var base58Vectors = []struct {
+	hex     string
+	encoded string
+}{
+	{"", ""},
+	{"61", "2g"},
+	{"626262", "a3gV"},
+	{"636363", "aPEr"},
+	{"73696d706c792061206c6f6e6720737472696e67", "2cFupjhnEsSn59qHXstmK2ffpLv2"},
+	{"00eb15231dfceb60925886b67d065299925915aeb172c06647", "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+	{"516b6fcd0f", "ABnLTmg"},
+	{"bf4f89001e670274dd", "3SEo3LWLoPntC"},
+	{"572e4794", "3EFU7m"},
+	{"ecac89cad93923c02321", "EJDM8drfXA6uyA"},
+	{"10c8511e", "Rt5zm"},
+	{"00000000000000000000", "1111111111"},
+}
+
+func TestBase58Vectors(t *testing.T) {
+	for _, vector := range base58Vectors {
+		data := mustHex(t, vector.hex)
+		if got := Base58Encode(data); got != vector.encoded {
+			t.Errorf("Base58Encode(%s) = %q, want %q", vector.hex, got, vector.encoded)
+		}
+		decoded, err := Base58Decode(vector.encoded, Strict)
+		if err != nil {
+			t.Errorf("Base58Decode(%q): %v", vector.encoded, err)
+			continue
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Errorf("Base58Decode(%q) = %x, want %s", vector.encoded, decoded, vector.hex)
+		}
+	}
+}
+
+func TestBase58DecodeInvalid(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		mode     Mode
+		position int
+	}{
+		{"3SEo3LWLoPntC0", Strict, 13},
+		{"3SEoOLWLoPntC", Strict, 4},
+		{"I", Strict, 0},
+		{"abl", Strict, 2},
+		{" 3SEo3LWLoPntC", Strict, 0},
+		{"3SEo3LWLoPntC\n", Strict, 13},
+		{"3SEo 3LWLoPntC", Lenient, 4},
+	} {
+		_, err := Base58Decode(test.s, test.mode)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Base58Decode(%q) = %v, want a *SyntaxError", test.s, err)
+			continue
+		}
+		if syntaxErr.Position != test.position {
+			t.Errorf("Base58Decode(%q) reports position %d, want %d", test.s, syntaxErr.Position, test.position)
+		}
+	}
+
+	if _, err := Base58Decode(" 3SEo3LWLoPntC\n", Lenient); err != nil {
+		t.Errorf("Base58Decode in Lenient mode: %v", err)
+	}
+}
+
+func TestBase58Check(t *testing.T) {
+	const address = "1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62i"
+	payload := mustHex(t, "0065a16059864a2fdbc7c99a4723a8395bc6f188eb")
+
+	if got := Base58CheckEncode(payload); got != address {
+		t.Errorf("Base58CheckEncode = %q, want %q", got, address)
+	}
+	decoded, err := Base58CheckDecode(address, Strict)
+	if err != nil {
+		t.Fatalf("Base58CheckDecode: %v", err)
+	}
+	if !bytes.Equal(decoded, payload) {
+		t.Errorf("Base58CheckDecode = %x, want %x", decoded, payload)
+	}
+
+	if _, err := Base58CheckDecode("1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62j", Strict); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Base58CheckDecode of a mistyped address = %v, want ErrChecksum", err)
+	}
+	if _, err := Base58CheckDecode("2g", Strict); err == nil || errors.Is(err, ErrChecksum) {
+		t.Errorf("Base58CheckDecode of a string shorter than its checksum = %v", err)
+	}
+}
+
+func TestBase58CheckDecoder(t *testing.T) {
+	payload := mustHex(t, "0065a16059864a2fdbc7c99a4723a8395bc6f188eb")
+
+	for _, test := range []struct {
+		name    string
+		s       string
+		mode    Mode
+		payload []byte
+		err     error
+	}{
+		{name: "address", s: "1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62i", payload: payload},
+		{name: "whitespace lenient", s: "\t1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62i \n", mode: Lenient, payload: payload},
+		{name: "whitespace strict", s: "1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62i\n", err: &SyntaxError{Position: 34, Char: '\n'}},
+		{name: "inner whitespace", s: "1AGNa15ZQXAZU gFiqJ2i7Z2DPU2J6hW62i", mode: Lenient, err: &SyntaxError{Position: 13, Char: ' '}},
+		{name: "invalid character", s: "1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62l", err: &SyntaxError{Position: 33, Char: 'l'}},
+		{name: "mistyped", s: "1AGNa15ZQXAZUgFiqJ2i7Z2DPU2J6hW62j", err: ErrChecksum},
+		{name: "empty payload", s: Base58CheckEncode(nil), payload: []byte{}},
+		{name: "zero payload", s: Base58CheckEncode(make([]byte, 3)), payload: make([]byte, 3)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := io.ReadAll(NewBase58CheckDecoder(iotest.OneByteReader(strings.NewReader(test.s)), test.mode))
+			var syntaxErr *SyntaxError
+			switch want := test.err.(type) {
+			case nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(decoded, test.payload) {
+					t.Errorf("Base58CheckDecoder = %x, want %x", decoded, test.payload)
+				}
+			case *SyntaxError:
+				if !errors.As(err, &syntaxErr) || syntaxErr.Position != want.Position || syntaxErr.Char != want.Char {
+					t.Errorf("Base58CheckDecoder error = %v, want %q at position %d", err, want.Char, want.Position)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("Base58CheckDecoder error = %v, want %v", err, want)
+				}
+			}
+		})
+	}
+
+	if _, err := io.ReadAll(NewBase58CheckDecoder(strings.NewReader("2g"), Strict)); err == nil || errors.Is(err, ErrChecksum) {
+		t.Errorf("Base58CheckDecoder of a string shorter than its checksum = %v", err)
+	}
+}
+
+func FuzzBase58(f *testing.F) {
+	for _, vector := range base58Vectors {
+		f.Add(mustHex(f, vector.hex))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		encoded := Base58Encode(data)
+		decoded, err := Base58Decode(encoded, Strict)
+		if err != nil {
+			t.Fatalf("Base58Decode(%q): %v", encoded, err)
+		}
+		if !bytes.Equal(decoded, data) {
+			t.Fatalf("Base58Decode(Base58Encode(%x)) = %x", data, decoded)
+		}
+	})
+}
+
+func FuzzBase58Check(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0x00})
+	f.Add([]byte{0x41, 0x00, 0x00})
+	f.Add(bytes.Repeat([]byte{0xff}, 37))
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		encoded := Base58CheckEncode(payload)
+		decoded, err := Base58CheckDecode(encoded, Strict)
+		if err != nil {
+			t.Fatalf("Base58CheckDecode(%q): %v", encoded, err)
+		}
+		if !bytes.Equal(decoded, payload) {
+			t.Fatalf("Base58CheckDecode(Base58CheckEncode(%x)) = %x", payload, decoded)
+		}
+
+		streamed, err := io.ReadAll(NewBase58CheckDecoder(strings.NewReader(encoded), Strict))
+		if err != nil {
+			t.Fatalf("Base58CheckDecoder(%q): %v", encoded, err)
+		}
+		if !bytes.Equal(streamed, payload) {
+			t.Fatalf("Base58CheckDecoder(%q) = %x, want %x", encoded, streamed, payload)
+		}
+	})
+}
+
+// mustHex decodes a hex test vector, failing the test if it is malformed
+func mustHex(tb testing.TB, s string) []byte {
+	tb.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return data
+}
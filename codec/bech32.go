@@ -0,0 +1,586 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Variant is the checksum constant of a bech32 string
+type Variant int
+
+const (
+	// Bech32 is the BIP-173 checksum, used by segwit version 0 and Cosmos addresses
+	Bech32 Variant = iota
+	// Bech32m is the BIP-350 checksum, used by segwit version 1 and above
+	Bech32m
+)
+
+// String returns the name of the variant
+func (v Variant) String() string {
+	switch v {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	default:
+		return fmt.Sprintf("Variant(%d)", int(v))
+	}
+}
+
+// constant returns the value the checksum polymod must equal for v
+func (v Variant) constant() uint32 {
+	if v == Bech32m {
+		return 0x2bc830a3
+	}
+
+	return 1
+}
+
+// Bech32MaxLength is the maximum length of a bech32 string in Strict mode
+const Bech32MaxLength = 90
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Values maps lowercase characters to their 5-bit value, -1 outside the charset
+var bech32Values = func() [256]int8 {
+	var values [256]int8
+	for i := range values {
+		values[i] = -1
+	}
+	for i := range len(bech32Charset) {
+		values[bech32Charset[i]] = int8(i)
+	}
+
+	return values
+}()
+
+func bech32Polymod(checksum uint32, value byte) uint32 {
+	top := checksum >> 25
+	checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+	for i, generator := range [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3} {
+		if top>>i&1 == 1 {
+			checksum ^= generator
+		}
+	}
+
+	return checksum
+}
+
+// hrpChecksum returns the checksum state after the expanded human-readable part
+func hrpChecksum(hrp string) uint32 {
+	checksum := uint32(1)
+	for i := range len(hrp) {
+		checksum = bech32Polymod(checksum, hrp[i]>>5)
+	}
+	checksum = bech32Polymod(checksum, 0)
+	for i := range len(hrp) {
+		checksum = bech32Polymod(checksum, hrp[i]&31)
+	}
+
+	return checksum
+}
+
+// checkHRP validates a human-readable part and returns it in lowercase
+func checkHRP(hrp string) (string, error) {
+	if hrp == "" || len(hrp) > 83 {
+		return "", fmt.Errorf("human-readable part must be 1 to 83 characters, got %d", len(hrp))
+	}
+	for i := range len(hrp) {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", syntaxError(hrp, i, "invalid human-readable part character")
+		}
+	}
+	if hrp != strings.ToLower(hrp) && hrp != strings.ToUpper(hrp) {
+		return "", errors.New("human-readable part has mixed case")
+	}
+
+	return strings.ToLower(hrp), nil
+}
+
+// Bech32Encode encodes data, 8-bit bytes, as a bech32 string of the given variant
+func Bech32Encode(hrp string, data []byte, variant Variant) (string, error) {
+	values, err := ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	return Bech32EncodeValues(hrp, values, variant)
+}
+
+// Bech32EncodeValues encodes 5-bit values as a bech32 string of the given variant
+func Bech32EncodeValues(hrp string, values []byte, variant Variant) (string, error) {
+	var builder strings.Builder
+	encoder, err := NewBech32Encoder(&builder, hrp, variant)
+	if err != nil {
+		return "", err
+	}
+	if err := encoder.writeValues(values); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+// Bech32Decode decodes a bech32 or bech32m string whose data part holds 8-bit bytes
+// Padding bits must be zero and fewer than 5, as BIP-173 requires
+func Bech32Decode(s string, mode Mode) (string, []byte, Variant, error) {
+	hrp, values, variant, err := Bech32DecodeValues(s, mode)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	data, err := ConvertBits(values, 5, 8, false)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	return hrp, data, variant, nil
+}
+
+// Bech32DecodeValues decodes a bech32 or bech32m string to its lowercase
+// human-readable part and 5-bit values, and reports which checksum it carries
+func Bech32DecodeValues(s string, mode Mode) (string, []byte, Variant, error) {
+	s = mode.prepare(s)
+	if mode == Strict && len(s) > Bech32MaxLength {
+		return "", nil, 0, fmt.Errorf("bech32 string of %d characters exceeds %d", len(s), Bech32MaxLength)
+	}
+
+	// Every character is printable US-ASCII, and all of them have the same case
+	lower, upper := -1, -1
+	for i := range len(s) {
+		c := s[i]
+		switch {
+		case c < 33 || c > 126:
+			return "", nil, 0, syntaxError(s, i, "invalid bech32 character")
+		case c >= 'a' && c <= 'z':
+			lower = i
+		case c >= 'A' && c <= 'Z':
+			upper = i
+		}
+		if lower >= 0 && upper >= 0 {
+			return "", nil, 0, syntaxError(s, i, "mixed case bech32 character")
+		}
+	}
+	s = strings.ToLower(s)
+
+	separator := strings.LastIndexByte(s, '1')
+	switch {
+	case separator < 0:
+		return "", nil, 0, errors.New("bech32 string has no separator")
+	case separator == 0:
+		return "", nil, 0, errors.New("bech32 string has an empty human-readable part")
+	case len(s)-separator-1 < 6:
+		return "", nil, 0, errors.New("bech32 checksum is shorter than 6 characters")
+	}
+	hrp := s[:separator]
+	if len(hrp) > 83 {
+		return "", nil, 0, fmt.Errorf("human-readable part of %d characters exceeds 83", len(hrp))
+	}
+
+	checksum := hrpChecksum(hrp)
+	values := make([]byte, 0, len(s)-separator-1)
+	for i := separator + 1; i < len(s); i++ {
+		value := bech32Values[s[i]]
+		if value < 0 {
+			return "", nil, 0, syntaxError(s, i, "invalid bech32 data character")
+		}
+		checksum = bech32Polymod(checksum, byte(value))
+		values = append(values, byte(value))
+	}
+
+	var variant Variant
+	switch checksum {
+	case Bech32.constant():
+		variant = Bech32
+	case Bech32m.constant():
+		variant = Bech32m
+	default:
+		return "", nil, 0, ErrChecksum
+	}
+
+	return hrp, values[:len(values)-6], variant, nil
+}
+
+// ConvertBits regroups data from groups of from bits to groups of to bits
+// With pad, the last group is completed with zero bits; without, the leftover bits
+// must be fewer than from and all zero, which rejects non-canonical encodings
+func ConvertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	if from == 0 || from > 8 || to == 0 || to > 8 {
+		return nil, errors.New("bit groups must be 1 to 8 bits")
+	}
+
+	var (
+		accumulator uint32
+		bits        uint
+		out         = make([]byte, 0, (uint(len(data))*from+to-1)/to)
+		mask        = uint32(1)<<to - 1
+	)
+	for i, value := range data {
+		if uint32(value)>>from != 0 {
+			return nil, fmt.Errorf("value %d at index %d exceeds %d bits", value, i, from)
+		}
+		accumulator = accumulator<<from | uint32(value)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(accumulator>>bits&mask))
+		}
+	}
+
+	switch {
+	case pad && bits > 0:
+		out = append(out, byte(accumulator<<(to-bits)&mask))
+	case !pad && bits >= from:
+		return nil, errors.New("excess padding bits")
+	case !pad && accumulator&(uint32(1)<<bits-1) != 0:
+		return nil, errors.New("non-zero padding bits")
+	}
+
+	return out, nil
+}
+
+// Bech32Encoder writes a bech32 string incrementally: the human-readable part on
+// creation, data characters as bytes are written and the checksum on Close
+//
+// It encodes payloads too large to hold in memory twice, such as bech32 encoded
+// backups; strings over Bech32MaxLength characters decode in Lenient mode only
+type Bech32Encoder struct {
+	w           io.Writer
+	variant     Variant
+	checksum    uint32
+	accumulator uint32
+	bits        uint
+	buffer      []byte
+	closed      bool
+}
+
+// NewBech32Encoder writes hrp and the separator to w and returns an encoder for the data part
+func NewBech32Encoder(w io.Writer, hrp string, variant Variant) (*Bech32Encoder, error) {
+	if variant != Bech32 && variant != Bech32m {
+		return nil, fmt.Errorf("unknown bech32 variant %s", variant)
+	}
+	hrp, err := checkHRP(hrp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, hrp+"1"); err != nil {
+		return nil, err
+	}
+
+	return &Bech32Encoder{w: w, variant: variant, checksum: hrpChecksum(hrp)}, nil
+}
+
+// Write encodes p, 8-bit bytes, writing every complete 5-bit group
+func (e *Bech32Encoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed bech32 encoder")
+	}
+
+	e.buffer = e.buffer[:0]
+	for _, b := range p {
+		e.accumulator = e.accumulator<<8 | uint32(b)
+		e.bits += 8
+		for e.bits >= 5 {
+			e.bits -= 5
+			e.buffer = e.appendValue(e.buffer, byte(e.accumulator>>e.bits&31))
+		}
+		e.accumulator &= 1<<e.bits - 1
+	}
+	if _, err := e.w.Write(e.buffer); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// writeValues encodes 5-bit values, which cannot be mixed with Write
+func (e *Bech32Encoder) writeValues(values []byte) error {
+	e.buffer = e.buffer[:0]
+	for i, value := range values {
+		if value > 31 {
+			return fmt.Errorf("value %d at index %d exceeds 5 bits", value, i)
+		}
+		e.buffer = e.appendValue(e.buffer, value)
+	}
+	_, err := e.w.Write(e.buffer)
+
+	return err
+}
+
+// Close writes the zero padding of the last group and the checksum
+func (e *Bech32Encoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+
+	e.buffer = e.buffer[:0]
+	if e.bits > 0 {
+		e.buffer = e.appendValue(e.buffer, byte(e.accumulator<<(5-e.bits)&31))
+	}
+	checksum := e.checksum
+	for range 6 {
+		checksum = bech32Polymod(checksum, 0)
+	}
+	checksum ^= e.variant.constant()
+	for i := range 6 {
+		e.buffer = append(e.buffer, bech32Charset[checksum>>(5*(5-i))&31])
+	}
+	_, err := e.w.Write(e.buffer)
+
+	return err
+}
+
+// appendValue appends the character of a 5-bit value and adds it to the checksum
+func (e *Bech32Encoder) appendValue(dst []byte, value byte) []byte {
+	e.checksum = bech32Polymod(e.checksum, value)
+
+	return append(dst, bech32Charset[value])
+}
+
+// Bech32Decoder reads the data part of a bech32 string incrementally as 8-bit bytes,
+// the counterpart of Bech32Encoder
+//
+// The checksum covers the whole string and is only checked when it ends: Read returns
+// io.EOF once the checksum and padding are valid, and any other error means the data
+// read so far must be discarded. Strict mode rejects strings over Bech32MaxLength
+// characters as Bech32Decode does
+type Bech32Decoder struct {
+	text         *textReader
+	hrp          string
+	variant      Variant
+	checksum     uint32
+	lower, upper bool
+	held         []byte
+	accumulator  uint32
+	bits         uint
+	buffer       []byte
+	err          error
+}
+
+// NewBech32Decoder reads the human-readable part and the separator of a bech32 string
+// from r and returns a decoder for its data part
+func NewBech32Decoder(r io.Reader, mode Mode) (*Bech32Decoder, error) {
+	d := &Bech32Decoder{text: newTextReader(r, mode), held: make([]byte, 0, 7)}
+
+	// The human-readable part has at most 83 characters, so the separator is the last
+	// '1' of the first 84: any later one is an invalid data character
+	head := make([]byte, 0, 84)
+	for len(head) < cap(head) {
+		c, _, err := d.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		head = append(head, c)
+	}
+
+	separator := bytes.LastIndexByte(head, '1')
+	switch {
+	case separator < 0 && len(head) == cap(head):
+		return nil, errors.New("human-readable part exceeds 83 characters")
+	case separator < 0:
+		return nil, errors.New("bech32 string has no separator")
+	case separator == 0:
+		return nil, errors.New("bech32 string has an empty human-readable part")
+	}
+	d.hrp = string(head[:separator])
+	d.checksum = hrpChecksum(d.hrp)
+	for i := separator + 1; i < len(head); i++ {
+		if err := d.push(head[i], i); err != nil {
+			return nil, err
+		}
+	}
+
+	return d, nil
+}
+
+// HRP returns the lowercase human-readable part of the string
+func (d *Bech32Decoder) HRP() string {
+	return d.hrp
+}
+
+// Variant returns the checksum variant of the string, known once Read returned io.EOF
+func (d *Bech32Decoder) Variant() Variant {
+	return d.variant
+}
+
+// Read implements io.Reader, decoding characters until p can be filled
+func (d *Bech32Decoder) Read(p []byte) (int, error) {
+	for len(d.buffer) < len(p) && d.err == nil {
+		d.err = d.advance()
+	}
+	if d.err != nil && d.err != io.EOF {
+		d.buffer = nil
+		return 0, d.err
+	}
+
+	n := copy(p, d.buffer)
+	d.buffer = d.buffer[:copy(d.buffer, d.buffer[n:])]
+	if len(d.buffer) == 0 && d.err != nil {
+		return n, d.err
+	}
+
+	return n, nil
+}
+
+// advance decodes the next character, checking the string once it ends
+func (d *Bech32Decoder) advance() error {
+	c, position, err := d.next()
+	switch {
+	case err == io.EOF:
+		return d.finish()
+	case err != nil:
+		return err
+	}
+
+	return d.push(c, position)
+}
+
+// next returns the next character of the string in lowercase and its position
+func (d *Bech32Decoder) next() (byte, int, error) {
+	c, position, err := d.text.next()
+	if err != nil {
+		return 0, position, err
+	}
+	if d.text.mode == Strict && position >= Bech32MaxLength {
+		return 0, position, fmt.Errorf("bech32 string exceeds %d characters", Bech32MaxLength)
+	}
+
+	// Every character is printable US-ASCII, and all of them have the same case
+	switch {
+	case c < 33 || c > 126:
+		return 0, position, &SyntaxError{Position: position, Char: c, Reason: "invalid bech32 character"}
+	case c >= 'a' && c <= 'z':
+		d.lower = true
+	case c >= 'A' && c <= 'Z':
+		d.upper = true
+		c += 'a' - 'A'
+	}
+	if d.lower && d.upper {
+		return 0, position, &SyntaxError{Position: position, Char: c, Reason: "mixed case bech32 character"}
+	}
+
+	return byte(c), position, nil
+}
+
+// push adds a data character to the checksum and decodes the value 6 characters
+// before it, which can no longer be part of the checksum
+func (d *Bech32Decoder) push(c byte, position int) error {
+	value := bech32Values[c]
+	if value < 0 {
+		return &SyntaxError{Position: position, Char: rune(c), Reason: "invalid bech32 data character"}
+	}
+	d.checksum = bech32Polymod(d.checksum, byte(value))
+	d.held = append(d.held, byte(value))
+	if len(d.held) <= 6 {
+		return nil
+	}
+
+	d.accumulator = d.accumulator<<5 | uint32(d.held[0])
+	d.bits += 5
+	if d.bits >= 8 {
+		d.bits -= 8
+		d.buffer = append(d.buffer, byte(d.accumulator>>d.bits))
+	}
+	d.accumulator &= 1<<d.bits - 1
+	d.held = d.held[:copy(d.held, d.held[1:])]
+
+	return nil
+}
+
+// finish checks the checksum and padding of the ended string and returns io.EOF if they are valid
+func (d *Bech32Decoder) finish() error {
+	if len(d.held) < 6 {
+		return errors.New("bech32 checksum is shorter than 6 characters")
+	}
+	switch d.checksum {
+	case Bech32.constant():
+		d.variant = Bech32
+	case Bech32m.constant():
+		d.variant = Bech32m
+	default:
+		return ErrChecksum
+	}
+	switch {
+	case d.bits >= 5:
+		return errors.New("excess padding bits")
+	case d.accumulator != 0:
+		return errors.New("non-zero padding bits")
+	}
+
+	return io.EOF
+}
+
+// SegwitEncode encodes a segwit address: BIP-173 bech32 for witness version 0,
+// BIP-350 bech32m for versions 1 to 16
+func SegwitEncode(hrp string, version byte, program []byte) (string, error) {
+	if err := checkWitnessProgram(version, program); err != nil {
+		return "", err
+	}
+	values, err := ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	return Bech32EncodeValues(hrp, append([]byte{version}, values...), segwitVariant(version))
+}
+
+// SegwitDecode decodes a segwit address in Strict mode and checks the checksum
+// variant and program length required by its witness version
+func SegwitDecode(address string) (string, byte, []byte, error) {
+	hrp, values, variant, err := Bech32DecodeValues(address, Strict)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if len(values) == 0 {
+		return "", 0, nil, errors.New("segwit address has no witness version")
+	}
+
+	version := values[0]
+	if version > 16 {
+		return "", 0, nil, fmt.Errorf("invalid witness version %d", version)
+	}
+	if want := segwitVariant(version); variant != want {
+		return "", 0, nil, fmt.Errorf("witness version %d must use %s, not %s", version, want, variant)
+	}
+	program, err := ConvertBits(values[1:], 5, 8, false)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	if err := checkWitnessProgram(version, program); err != nil {
+		return "", 0, nil, err
+	}
+
+	return hrp, version, program, nil
+}
+
+// segwitVariant returns the checksum variant of a witness version
+func segwitVariant(version byte) Variant {
+	if version == 0 {
+		return Bech32
+	}
+
+	return Bech32m
+}
+
+// checkWitnessProgram checks the program length rules of BIP-141
+func checkWitnessProgram(version byte, program []byte) error {
+	switch {
+	case version > 16:
+		return fmt.Errorf("invalid witness version %d", version)
+	case len(program) < 2 || len(program) > 40:
+		return fmt.Errorf("invalid witness program length %d", len(program))
+	case version == 0 && len(program) != 20 && len(program) != 32:
+		return fmt.Errorf("invalid version 0 witness program length %d", len(program))
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// BIP-173 and BIP-350 test vectors
+var (
+	validBech32 = []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11" + strings.Repeat("q", 82) + "c8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	}
+	validBech32m = []string{
+		"A1LQFN3A",
+		"a1lqfn3a",
+		"an83characterlonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11sg7hg6",
+		"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx",
+		"11" + strings.Repeat("l", 82) + "ludsr8",
+		"split1checkupstagehandshakeupstreamerranterredcaperredlc445v",
+		"?1v759aa",
+	}
+	invalidBech32 = []string{
+		"\x201nwldj5",
+		"\x7f1axkwrx",
+		"\x801eym55h",
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx",
+		"pzry9x0s0muk",
+		"1pzry9x0s0muk",
+		"x1b4n0q5v",
+		"li1dgmt3",
+		"de1lg7wt\xff",
+		"A1G7SGD8",
+		"10a06t8",
+		"1qzzfhee",
+		"\x201xj0phk",
+		"\x7f1g6xzxy",
+		"\x801vctc34",
+		"an84characterslonghumanreadablepartthatcontainsthetheexcludedcharactersbioandnumber11d6pts4",
+		"qyrz8wqd2c9m",
+		"1qyrz8wqd2c9m",
+		"y1b0jsk6g",
+		"lt1igcx5c0",
+		"in1muywd",
+		"mm1crxm3i",
+		"au1s5cgom",
+		"M1VUXWEZ",
+		"16plkw9",
+		"1p2gdwpf",
+	}
+	validSegwit = []struct {
+		address      string
+		scriptPubKey string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", "00201863143c14c5166804bd19203356da136c985678cd4d27a1b8c6329604903262"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", "5128751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", "6002751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", "5210751e76e8199196d454941c45d1b3a323"},
+		{"tb1qqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesrxh6hy", "0020000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"tb1pqqqqp399et2xygdj5xreqhjjvcmzhxw4aywxecjdzew6hylgvsesf3hn0c", "5120000000c4a5cad46221b2a187905e5266362b99d5e91c6ce24d165dab93e86433"},
+		{"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", "512079be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"},
+	}
+	invalidSegwit = []string{
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd",
+		"tb1z0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqglt7rf",
+		"BC1S0XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ54WELL",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+		"tb1q0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq24jc47",
+		"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+		"BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R",
+		"bc1pw5dgrnzv",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v8n0nx0muaewav253zgeav",
+		"BC1QR508D6QEJXTDG4Y5R3ZARVARYV98GJ9P",
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vq47Zagq",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7v07qwwzcrf",
+		"tb1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vpggkg4j",
+		"bc1gmk9yu",
+	}
+)
+
+func TestBech32Vectors(t *testing.T) {
+	for variant, strs := range map[Variant][]string{Bech32: validBech32, Bech32m: validBech32m} {
+		for _, s := range strs {
+			hrp, values, got, err := Bech32DecodeValues(s, Strict)
+			if err != nil {
+				t.Errorf("Bech32DecodeValues(%q): %v", s, err)
+				continue
+			}
+			if got != variant {
+				t.Errorf("Bech32DecodeValues(%q) variant = %s, want %s", s, got, variant)
+			}
+			encoded, err := Bech32EncodeValues(hrp, values, variant)
+			if err != nil {
+				t.Errorf("Bech32EncodeValues(%q): %v", hrp, err)
+				continue
+			}
+			if encoded != strings.ToLower(s) {
+				t.Errorf("Bech32EncodeValues = %q, want %q", encoded, strings.ToLower(s))
+			}
+		}
+	}
+
+	for _, s := range invalidBech32 {
+		if _, _, _, err := Bech32DecodeValues(s, Strict); err == nil {
+			t.Errorf("Bech32DecodeValues(%q) succeeded", s)
+		}
+	}
+}
+
+func TestSegwitVectors(t *testing.T) {
+	for _, vector := range validSegwit {
+		hrp, version, program, err := SegwitDecode(vector.address)
+		if err != nil {
+			t.Errorf("SegwitDecode(%q): %v", vector.address, err)
+			continue
+		}
+		if got := hex.EncodeToString(segwitScript(version, program)); got != vector.scriptPubKey {
+			t.Errorf("SegwitDecode(%q) script = %s, want %s", vector.address, got, vector.scriptPubKey)
+		}
+		encoded, err := SegwitEncode(hrp, version, program)
+		if err != nil {
+			t.Errorf("SegwitEncode(%q): %v", vector.address, err)
+			continue
+		}
+		if encoded != strings.ToLower(vector.address) {
+			t.Errorf("SegwitEncode = %q, want %q", encoded, strings.ToLower(vector.address))
+		}
+	}
+
+	for _, address := range invalidSegwit {
+		if _, _, _, err := SegwitDecode(address); err == nil {
+			t.Errorf("SegwitDecode(%q) succeeded", address)
+		}
+	}
+}
+
+func TestBech32EncoderWrite(t *testing.T) {
+	data := bytes.Repeat([]byte{0x00, 0x5a, 0xff}, 100)
+	want, err := Bech32Encode("backup", data, Bech32m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var builder strings.Builder
+	encoder, err := NewBech32Encoder(&builder, "backup", Bech32m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for chunk := range slices.Chunk(data, 7) {
+		if _, err := encoder.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if builder.String() != want {
+		t.Errorf("Bech32Encoder wrote %q, want %q", builder.String(), want)
+	}
+
+	if _, _, _, err := Bech32Decode(want, Strict); err == nil {
+		t.Errorf("Bech32Decode of %d characters succeeded in Strict mode", len(want))
+	}
+	_, decoded, _, err := Bech32Decode(want, Lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Errorf("Bech32Decode = %x, want %x", decoded, data)
+	}
+}
+
+func TestBech32Decoder(t *testing.T) {
+	var vectors []string
+	vectors = append(vectors, validBech32...)
+	vectors = append(vectors, validBech32m...)
+	vectors = append(vectors, invalidBech32...)
+	for _, vector := range validSegwit {
+		vectors = append(vectors, vector.address)
+	}
+
+	for _, s := range vectors {
+		wantHRP, want, wantVariant, wantErr := Bech32Decode(s, Strict)
+		hrp, got, variant, err := streamBech32(s, Strict)
+		switch {
+		case (err == nil) != (wantErr == nil):
+			t.Errorf("Bech32Decoder(%q) error = %v, Bech32Decode error = %v", s, err, wantErr)
+		case err == nil && (hrp != wantHRP || !bytes.Equal(got, want) || variant != wantVariant):
+			t.Errorf("Bech32Decoder(%q) = %q, %x, %s, want %q, %x, %s", s, hrp, got, variant, wantHRP, want, wantVariant)
+		}
+	}
+}
+
+func TestBech32DecoderStream(t *testing.T) {
+	data := bytes.Repeat([]byte{0x00, 0x5a, 0xff}, 100)
+	encoded, err := Bech32Encode("backup", data, Bech32m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hrp, decoded, variant, err := streamBech32("\n  "+strings.ToUpper(encoded)+" \n", Lenient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hrp != "backup" || !bytes.Equal(decoded, data) || variant != Bech32m {
+		t.Errorf("Bech32Decoder = %q, %x, %s, want backup, %x, bech32m", hrp, decoded, variant, data)
+	}
+
+	if _, _, _, err := streamBech32(encoded, Strict); err == nil {
+		t.Errorf("Bech32Decoder of %d characters succeeded in Strict mode", len(encoded))
+	}
+
+	mistyped := []byte(encoded)
+	mistyped[300] = bech32Charset[(strings.IndexByte(bech32Charset, mistyped[300])+1)%32]
+	if _, _, _, err := streamBech32(string(mistyped), Lenient); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Bech32Decoder of a mistyped string = %v, want ErrChecksum", err)
+	}
+
+	mistyped[300] = 'b'
+	_, _, _, err = streamBech32(" "+string(mistyped), Lenient)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Position != 300 || syntaxErr.Char != 'b' {
+		t.Errorf("Bech32Decoder of an invalid character = %v, want a SyntaxError at position 300", err)
+	}
+	_, _, _, err = streamBech32(encoded[:200]+" "+encoded[200:], Lenient)
+	if !errors.As(err, &syntaxErr) || syntaxErr.Position != 200 {
+		t.Errorf("Bech32Decoder of a string with inner whitespace = %v, want a SyntaxError at position 200", err)
+	}
+}
+
+// streamBech32 decodes s with a Bech32Decoder reading its input one byte at a time
+func streamBech32(s string, mode Mode) (string, []byte, Variant, error) {
+	decoder, err := NewBech32Decoder(iotest.OneByteReader(strings.NewReader(s)), mode)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	data, err := io.ReadAll(decoder)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	return decoder.HRP(), data, decoder.Variant(), nil
+}
+
+func FuzzBech32(f *testing.F) {
+	f.Add("bc", []byte{0x75, 0x1e}, false)
+	f.Add("A", []byte{}, true)
+	f.Add("?", bytes.Repeat([]byte{0xff}, 40), true)
+
+	f.Fuzz(func(t *testing.T, hrp string, data []byte, m bool) {
+		variant := Bech32
+		if m {
+			variant = Bech32m
+		}
+		encoded, err := Bech32Encode(hrp, data, variant)
+		if err != nil {
+			return
+		}
+
+		decodedHRP, decoded, decodedVariant, err := Bech32Decode(encoded, Lenient)
+		if err != nil {
+			t.Fatalf("Bech32Decode(%q): %v", encoded, err)
+		}
+		if decodedHRP != strings.ToLower(hrp) || !bytes.Equal(decoded, data) || decodedVariant != variant {
+			t.Fatalf("Bech32Decode(%q) = %q, %x, %s, want %q, %x, %s",
+				encoded, decodedHRP, decoded, decodedVariant, strings.ToLower(hrp), data, variant)
+		}
+
+		streamedHRP, streamed, streamedVariant, err := streamBech32(encoded, Lenient)
+		if err != nil {
+			t.Fatalf("Bech32Decoder(%q): %v", encoded, err)
+		}
+		if streamedHRP != decodedHRP || !bytes.Equal(streamed, data) || streamedVariant != variant {
+			t.Fatalf("Bech32Decoder(%q) = %q, %x, %s, want %q, %x, %s",
+				encoded, streamedHRP, streamed, streamedVariant, decodedHRP, data, variant)
+		}
+	})
+}
+
+func FuzzSegwit(f *testing.F) {
+	for _, vector := range validSegwit {
+		_, version, program, _ := SegwitDecode(vector.address)
+		f.Add(version, program)
+	}
+
+	f.Fuzz(func(t *testing.T, version byte, program []byte) {
+		encoded, err := SegwitEncode("bc", version, program)
+		if err != nil {
+			return
+		}
+
+		hrp, decodedVersion, decoded, err := SegwitDecode(encoded)
+		if err != nil {
+			t.Fatalf("SegwitDecode(%q): %v", encoded, err)
+		}
+		if hrp != "bc" || decodedVersion != version || !bytes.Equal(decoded, program) {
+			t.Fatalf("SegwitDecode(%q) = %q, %d, %x, want bc, %d, %x", encoded, hrp, decodedVersion, decoded, version, program)
+		}
+	})
+}
+
+// segwitScript returns the scriptPubKey of a witness program
+func segwitScript(version byte, program []byte) []byte {
+	opcode := version
+	if version > 0 {
+		opcode = 0x50 + version
+	}
+
+	return append([]byte{opcode, byte(len(program))}, program...)
+}
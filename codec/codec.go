@@ -0,0 +1,122 @@
+// Package codec implements the text encodings of blockchain addresses: base58 and
+// Base58Check (Bitcoin, TRON, Solana, Polkadot) and bech32/bech32m (BIP-173 and
+// BIP-350, used by segwit and Cosmos addresses)
+//
+// Decoders report the position of the first invalid character with a *SyntaxError,
+// so user interfaces can point at the typo, and checksum failures with ErrChecksum.
+// Strict mode follows the specifications to the letter; Lenient mode accepts the
+// input of tools that stretch them, such as bech32 strings longer than 90 characters
+package codec
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrChecksum is returned when the checksum of an encoded string does not match its payload
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Mode selects how strictly decoders validate their input
+type Mode int
+
+const (
+	// Strict rejects anything the specifications do not allow, including surrounding
+	// whitespace and bech32 strings longer than 90 characters
+	Strict Mode = iota
+	// Lenient trims surrounding whitespace and lifts the bech32 length limit
+	Lenient
+)
+
+// prepare applies mode to s before decoding
+func (m Mode) prepare(s string) string {
+	if m == Lenient {
+		return strings.TrimSpace(s)
+	}
+
+	return s
+}
+
+// SyntaxError reports an invalid character of an encoded string
+type SyntaxError struct {
+	// Position is the byte offset of the character in the decoded string
+	Position int
+	Char     rune
+	Reason   string
+}
+
+// Error implements error
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s %q at position %d", e.Reason, e.Char, e.Position)
+}
+
+// syntaxError returns the SyntaxError of the character of s at position
+func syntaxError(s string, position int, reason string) *SyntaxError {
+	char, _ := utf8.DecodeRuneInString(s[position:])
+
+	return &SyntaxError{Position: position, Char: char, Reason: reason}
+}
+
+// textReader reads the characters of an encoded string for the streaming decoders,
+// applying mode as prepare does
+type textReader struct {
+	r        *bufio.Reader
+	mode     Mode
+	position int
+	started  bool
+}
+
+func newTextReader(r io.Reader, mode Mode) *textReader {
+	return &textReader{r: bufio.NewReader(r), mode: mode}
+}
+
+// next returns the next character and its position, or io.EOF after the last one
+// In Lenient mode, whitespace before the first character is skipped and whitespace
+// running to the end of the input ends the string; whitespace followed by more
+// characters is returned like any other character
+func (t *textReader) next() (rune, int, error) {
+	for {
+		c, size, err := t.r.ReadRune()
+		if err != nil {
+			return 0, t.position, err
+		}
+		if t.mode == Lenient && unicode.IsSpace(c) {
+			if !t.started {
+				continue
+			}
+			trailing, err := t.trailingSpace()
+			if err != nil {
+				return 0, t.position, err
+			}
+			if trailing {
+				return 0, t.position, io.EOF
+			}
+		}
+
+		t.started = true
+		position := t.position
+		t.position += size
+
+		return c, position, nil
+	}
+}
+
+// trailingSpace consumes the whitespace following a whitespace character and reports
+// whether it runs to the end of the input
+func (t *textReader) trailingSpace() (bool, error) {
+	for {
+		c, _, err := t.r.ReadRune()
+		switch {
+		case err == io.EOF:
+			return true, nil
+		case err != nil:
+			return false, err
+		case !unicode.IsSpace(c):
+			return false, t.r.UnreadRune()
+		}
+	}
+}
@@ -11,5 +11,9 @@
 package cointype
 
 const (
-	Tron = 195
+	Bitcoin  = 0
+	Ethereum = 60
+	Cosmos   = 118
+	Tron     = 195
+	Solana   = 501
 )
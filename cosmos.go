@@ -0,0 +1,13 @@
+package hdwallet
+
+import "github.com/not-for-prod/hdwallet/codec"
+
+// GenerateCosmosAddress generates the Cosmos Hub account address of a public key,
+// bech32 with the "cosmos" prefix of RIPEMD-160(SHA-256(compressed key)); other
+// chains of coin type 118 use their own prefix, see cosmos.Address
+func GenerateCosmosAddress(publicKey *PublicKey) string {
+	// A 20-byte hash always fits a bech32 string
+	address, _ := codec.Bech32Encode("cosmos", hash160(publicKey.SerializeCompressed()), codec.Bech32)
+
+	return address
+}
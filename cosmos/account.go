@@ -0,0 +1,177 @@
+package cosmos
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// Account is the hdwallet.Account of a Cosmos key on one chain
+type Account struct {
+	signer    hdwallet.Signer
+	publicKey *hdwallet.PublicKey
+	address   string
+	sequences hdwallet.NonceManager
+}
+
+var _ hdwallet.Account = (*Account)(nil)
+
+// AccountOption configures an Account
+type AccountOption func(*Account)
+
+// WithNonceManager sets the manager SignNext reserves account sequences from
+func WithNonceManager(sequences hdwallet.NonceManager) AccountOption {
+	return func(a *Account) {
+		a.sequences = sequences
+	}
+}
+
+// NewAccount returns the account of signer on the chain with the bech32 prefix hrp
+func NewAccount(signer hdwallet.Signer, hrp string, opts ...AccountOption) (*Account, error) {
+	publicKey, err := hdwallet.SignerPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	address, err := Address(publicKey, hrp)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{signer: signer, publicKey: publicKey, address: address}
+	for _, opt := range opts {
+		opt(account)
+	}
+
+	return account, nil
+}
+
+// Coin implements hdwallet.Account
+func (a *Account) Coin() uint32 {
+	return CoinType
+}
+
+// Address returns the bech32 address of the account
+func (a *Account) Address() string {
+	return a.address
+}
+
+// PublicKey implements hdwallet.Account
+func (a *Account) PublicKey() *hdwallet.PublicKey {
+	return a.publicKey
+}
+
+// SignDigest returns the 64-byte low-S r || s signature of digest used by Cosmos SDK chains
+func (a *Account) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	signature, err := hdwallet.SignWithContext(ctx, a.signer, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	recoverable, err := hdwallet.RecoverableSignature(a.publicKey, digest, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	return recoverable[:64], nil
+}
+
+// SignBytes signs sign bytes of any mode, which are hashed with SHA-256
+func (a *Account) SignBytes(ctx context.Context, signBytes []byte) ([]byte, error) {
+	digest := sha256.Sum256(signBytes)
+
+	return a.SignDigest(ctx, digest[:])
+}
+
+// Sign signs tx with the given mode and returns the encoded TxRaw ready for broadcast
+//
+// Policies see the MsgSend transfers of tx as its signing intent, replacing any declared
+// in ctx; other messages leave the intent without amount, see bodyIntent, and
+// transactions without messages keep the declared one
+func (a *Account) Sign(ctx context.Context, tx *Tx, signer SignerData, mode SignMode) ([]byte, error) {
+	body := tx.BodyBytes()
+	authInfo := tx.AuthInfoBytes(a.publicKey, signer.Sequence, mode)
+
+	var signBytes []byte
+	switch mode {
+	case SignModeDirect:
+		signBytes = SignDoc(body, authInfo, signer.ChainID, signer.AccountNumber)
+	case SignModeLegacyAminoJSON:
+		var err error
+		if signBytes, err = tx.AminoSignDoc(signer); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sign mode %d", mode)
+	}
+
+	ctx, err := withBodyIntent(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := a.SignBytes(ctx, signBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return TxRaw(body, authInfo, signature), nil
+}
+
+// SignNext reserves the next sequence of the account on signer.ChainID from the manager
+// set with WithNonceManager and signs tx with it like Sign
+//
+// The sequence is released when signing fails. Once signed it stays consumed; release
+// it with the manager (see SequenceKey) if the transaction is never broadcast
+func (a *Account) SignNext(ctx context.Context, tx *Tx, signer SignerData, mode SignMode) ([]byte, error) {
+	if a.sequences == nil {
+		return nil, errors.New("account has no nonce manager")
+	}
+
+	key := SequenceKey(signer.ChainID, a.address)
+	sequence, err := a.sequences.Reserve(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("reserve sequence: %w", err)
+	}
+	signer.Sequence = sequence
+
+	raw, err := a.Sign(ctx, tx, signer, mode)
+	if err != nil {
+		if releaseErr := a.sequences.Release(ctx, key, sequence); releaseErr != nil {
+			return nil, errors.Join(err, releaseErr)
+		}
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// SignTransaction signs an encoded SIGN_MODE_DIRECT SignDoc, as produced by SignDoc or
+// another SDK client, and returns the encoded TxRaw; its intent is declared as by Sign
+func (a *Account) SignTransaction(ctx context.Context, payload []byte) ([]byte, error) {
+	body, authInfo, err := parseSignDoc(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx, err = withBodyIntent(ctx, body); err != nil {
+		return nil, err
+	}
+	signature, err := a.SignBytes(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return TxRaw(body, authInfo, signature), nil
+}
+
+// withBodyIntent declares the transfers of body in ctx, see bodyIntent
+func withBodyIntent(ctx context.Context, body []byte) (context.Context, error) {
+	intent, err := bodyIntent(body)
+	if err != nil || intent == nil {
+		return ctx, err
+	}
+
+	return hdwallet.WithSigningIntent(ctx, *intent), nil
+}
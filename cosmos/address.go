@@ -0,0 +1,141 @@
+// Package cosmos signs Cosmos SDK transactions with the secp256k1 keys hdwallet derives
+//
+// Like the tron package, messages are encoded with protowire directly following the
+// field numbers of the cosmos-sdk protocol definitions, so no generated code is
+// needed. Transactions are signed with SIGN_MODE_DIRECT (the protobuf SignDoc) or,
+// for chains and hardware wallets that need it, SIGN_MODE_LEGACY_AMINO_JSON
+package cosmos
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/not-for-prod/hdwallet"
+	"github.com/not-for-prod/hdwallet/codec"
+	"golang.org/x/crypto/ripemd160"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// CoinType is the SLIP-0044 coin type of the Cosmos Hub, shared by most Cosmos chains
+const CoinType = 118
+
+// PubKeyTypeURL is the type URL of secp256k1 public keys in google.protobuf.Any
+const PubKeyTypeURL = "/cosmos.crypto.secp256k1.PubKey"
+
+// aminoPubKeyType is the amino JSON type of secp256k1 public keys
+const aminoPubKeyType = "tendermint/PubKeySecp256k1"
+
+// Address returns the bech32 account address of publicKey with the chain's prefix,
+// such as "cosmos" or "osmo": RIPEMD-160(SHA-256(compressed key))
+func Address(publicKey *hdwallet.PublicKey, hrp string) (string, error) {
+	sum := sha256.Sum256(publicKey.SerializeCompressed())
+	hash := ripemd160.New()
+	hash.Write(sum[:])
+
+	return codec.Bech32Encode(hrp, hash.Sum(nil), codec.Bech32)
+}
+
+// PubKeyAny encodes publicKey as the google.protobuf.Any of a cosmos.crypto.secp256k1.PubKey,
+// as carried by SignerInfo and returned by the auth module
+func PubKeyAny(publicKey *hdwallet.PublicKey) []byte {
+	var key []byte
+	key = protowire.AppendTag(key, 1, protowire.BytesType)
+	key = protowire.AppendBytes(key, publicKey.SerializeCompressed())
+
+	return marshalAny(PubKeyTypeURL, key)
+}
+
+// ParsePubKeyAny decodes a secp256k1 public key from its google.protobuf.Any encoding
+func ParsePubKeyAny(data []byte) (*hdwallet.PublicKey, error) {
+	typeURL, value, err := parseAny(data)
+	if err != nil {
+		return nil, err
+	}
+	if typeURL != PubKeyTypeURL {
+		return nil, fmt.Errorf("unsupported public key type %q", typeURL)
+	}
+
+	var key []byte
+	for len(value) > 0 {
+		number, wireType, n := protowire.ConsumeTag(value)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		value = value[n:]
+		if number == 1 && wireType == protowire.BytesType {
+			if key, n = protowire.ConsumeBytes(value); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+		} else if n = protowire.ConsumeFieldValue(number, wireType, value); n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		value = value[n:]
+	}
+	if key == nil {
+		return nil, errors.New("public key is empty")
+	}
+
+	publicKey, format, err := hdwallet.ParsePublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if format != hdwallet.PublicKeyCompressed {
+		return nil, fmt.Errorf("%s public key must be compressed, got %s", PubKeyTypeURL, format)
+	}
+
+	return publicKey, nil
+}
+
+// AminoPubKey returns the amino JSON form of publicKey,
+// {"type":"tendermint/PubKeySecp256k1","value":"<base64>"}
+func AminoPubKey(publicKey *hdwallet.PublicKey) ([]byte, error) {
+	return json.Marshal(aminoJSON{
+		Type:  aminoPubKeyType,
+		Value: base64.StdEncoding.EncodeToString(publicKey.SerializeCompressed()),
+	})
+}
+
+// aminoJSON is the {"type": ..., "value": ...} envelope of amino JSON
+type aminoJSON struct {
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+func marshalAny(typeURL string, value []byte) []byte {
+	var out []byte
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendString(out, typeURL)
+	out = protowire.AppendTag(out, 2, protowire.BytesType)
+
+	return protowire.AppendBytes(out, value)
+}
+
+func parseAny(data []byte) (string, []byte, error) {
+	var typeURL string
+	var value []byte
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return "", nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case number == 1 && wireType == protowire.BytesType:
+			typeURL, n = protowire.ConsumeString(data)
+		case number == 2 && wireType == protowire.BytesType:
+			value, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+		}
+		if n < 0 {
+			return "", nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+
+	return typeURL, value, nil
+}
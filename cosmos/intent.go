@@ -0,0 +1,121 @@
+package cosmos
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/not-for-prod/hdwallet"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// bodyIntent describes the transfers of the MsgSend messages of an encoded TxBody,
+// named by their denomination; it is nil when the body sends nothing. Bodies sending
+// several denominations cannot be described by one intent and are rejected
+//
+// Other messages (MsgMultiSend, IBC MsgTransfer, authz MsgExec, ...) may move funds
+// the parser does not read, so bodies carrying one get an intent without amount,
+// which LimitPolicy denies
+func bodyIntent(body []byte) (*hdwallet.SigningIntent, error) {
+	var intent *hdwallet.SigningIntent
+	var destinations []string
+	unknown := false
+	err := forEachBytesField(body, 1, func(message []byte) error {
+		typeURL, value, err := parseAny(message)
+		if err != nil {
+			return err
+		}
+		if typeURL != (MsgSend{}).TypeURL() {
+			unknown = true
+			return nil
+		}
+
+		var to string
+		err = forEachBytesField(value, 2, func(address []byte) error {
+			to = string(address)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		destinations = append(destinations, to)
+
+		return forEachBytesField(value, 3, func(coin []byte) error {
+			var denom, amount string
+			err := forEachBytesField(coin, 1, func(value []byte) error {
+				denom = string(value)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			err = forEachBytesField(coin, 2, func(value []byte) error {
+				amount = string(value)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			parsed, ok := new(big.Int).SetString(amount, 10)
+			if !ok {
+				return fmt.Errorf("invalid amount %q", amount)
+			}
+			switch {
+			case intent == nil:
+				intent = &hdwallet.SigningIntent{Asset: denom, Amount: parsed}
+			case intent.Asset != denom:
+				return fmt.Errorf("transaction sends %s and %s, sign them separately", intent.Asset, denom)
+			default:
+				intent.Amount.Add(intent.Amount, parsed)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decode transaction body: %w", err)
+	}
+	if unknown {
+		if intent == nil {
+			intent = &hdwallet.SigningIntent{}
+		}
+		intent.Amount = nil
+	}
+	if intent != nil {
+		intent.Destination = strings.Join(destinations, ",")
+	}
+
+	return intent, nil
+}
+
+// forEachBytesField calls fn with every value of the length-delimited field number of message
+func forEachBytesField(message []byte, number protowire.Number, fn func([]byte) error) error {
+	for len(message) > 0 {
+		fieldNumber, wireType, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		message = message[n:]
+
+		if fieldNumber == number && wireType == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(message)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			if err := fn(value); err != nil {
+				return err
+			}
+			message = message[n:]
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(fieldNumber, wireType, message)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		message = message[n:]
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package cosmos
+
+import (
+	"encoding/json"
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Coin is an amount of one denomination; Amount is a decimal integer string, as
+// token amounts routinely exceed 64 bits
+type Coin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+// Msg is a transaction message
+type Msg interface {
+	// TypeURL returns the protobuf type URL, such as "/cosmos.bank.v1beta1.MsgSend"
+	TypeURL() string
+	// Marshal returns the protobuf encoding of the message
+	Marshal() []byte
+	// AminoJSON returns the amino JSON {"type": ..., "value": ...} form of the
+	// message, used by SIGN_MODE_LEGACY_AMINO_JSON
+	AminoJSON() ([]byte, error)
+}
+
+// RawMsg is a message encoded by the caller, for message types this package does not know
+type RawMsg struct {
+	Type  string
+	Value []byte
+	// Amino is the amino JSON form, only needed for SIGN_MODE_LEGACY_AMINO_JSON
+	Amino json.RawMessage
+}
+
+// TypeURL implements Msg
+func (m RawMsg) TypeURL() string {
+	return m.Type
+}
+
+// Marshal implements Msg
+func (m RawMsg) Marshal() []byte {
+	return m.Value
+}
+
+// AminoJSON implements Msg
+func (m RawMsg) AminoJSON() ([]byte, error) {
+	if m.Amino == nil {
+		return nil, errors.New("message " + m.Type + " has no amino JSON form")
+	}
+
+	return m.Amino, nil
+}
+
+// MsgSend is a cosmos.bank.v1beta1.MsgSend transfer
+type MsgSend struct {
+	FromAddress string `json:"from_address"`
+	ToAddress   string `json:"to_address"`
+	Amount      []Coin `json:"amount"`
+}
+
+// TypeURL implements Msg
+func (m MsgSend) TypeURL() string {
+	return "/cosmos.bank.v1beta1.MsgSend"
+}
+
+// Marshal implements Msg
+func (m MsgSend) Marshal() []byte {
+	var out []byte
+	out = appendString(out, 1, m.FromAddress)
+	out = appendString(out, 2, m.ToAddress)
+	for _, coin := range m.Amount {
+		out = protowire.AppendTag(out, 3, protowire.BytesType)
+		out = protowire.AppendBytes(out, coin.marshal())
+	}
+
+	return out
+}
+
+// AminoJSON implements Msg
+func (m MsgSend) AminoJSON() ([]byte, error) {
+	if m.Amount == nil {
+		m.Amount = []Coin{}
+	}
+
+	return json.Marshal(aminoJSON{Type: "cosmos-sdk/MsgSend", Value: m})
+}
+
+// MsgDelegate is a cosmos.staking.v1beta1.MsgDelegate staking amount to a validator
+type MsgDelegate struct {
+	DelegatorAddress string `json:"delegator_address"`
+	ValidatorAddress string `json:"validator_address"`
+	Amount           Coin   `json:"amount"`
+}
+
+// TypeURL implements Msg
+func (m MsgDelegate) TypeURL() string {
+	return "/cosmos.staking.v1beta1.MsgDelegate"
+}
+
+// Marshal implements Msg
+func (m MsgDelegate) Marshal() []byte {
+	return marshalDelegation(m.DelegatorAddress, m.ValidatorAddress, m.Amount)
+}
+
+// AminoJSON implements Msg
+func (m MsgDelegate) AminoJSON() ([]byte, error) {
+	return json.Marshal(aminoJSON{Type: "cosmos-sdk/MsgDelegate", Value: m})
+}
+
+// MsgUndelegate is a cosmos.staking.v1beta1.MsgUndelegate starting the unbonding of amount
+type MsgUndelegate struct {
+	DelegatorAddress string `json:"delegator_address"`
+	ValidatorAddress string `json:"validator_address"`
+	Amount           Coin   `json:"amount"`
+}
+
+// TypeURL implements Msg
+func (m MsgUndelegate) TypeURL() string {
+	return "/cosmos.staking.v1beta1.MsgUndelegate"
+}
+
+// Marshal implements Msg
+func (m MsgUndelegate) Marshal() []byte {
+	return marshalDelegation(m.DelegatorAddress, m.ValidatorAddress, m.Amount)
+}
+
+// AminoJSON implements Msg
+func (m MsgUndelegate) AminoJSON() ([]byte, error) {
+	return json.Marshal(aminoJSON{Type: "cosmos-sdk/MsgUndelegate", Value: m})
+}
+
+// marshalDelegation encodes the delegator, validator, amount layout shared by staking messages
+func marshalDelegation(delegator, validator string, amount Coin) []byte {
+	var out []byte
+	out = appendString(out, 1, delegator)
+	out = appendString(out, 2, validator)
+	out = protowire.AppendTag(out, 3, protowire.BytesType)
+
+	return protowire.AppendBytes(out, amount.marshal())
+}
+
+// marshal encodes the coin as a cosmos.base.v1beta1.Coin
+func (c Coin) marshal() []byte {
+	var out []byte
+	out = appendString(out, 1, c.Denom)
+
+	return appendString(out, 2, c.Amount)
+}
+
+// appendString appends a string field, omitted when empty as proto3 does
+func appendString(out []byte, number protowire.Number, s string) []byte {
+	if s == "" {
+		return out
+	}
+	out = protowire.AppendTag(out, number, protowire.BytesType)
+
+	return protowire.AppendString(out, s)
+}
@@ -0,0 +1,9 @@
+package cosmos
+
+import "github.com/not-for-prod/hdwallet"
+
+// SequenceKey returns the hdwallet.NonceManager key of the account sequence of address
+// on chainID, for SignerData.Sequence
+func SequenceKey(chainID, address string) hdwallet.NonceKey {
+	return hdwallet.NonceKey{Coin: CoinType, Chain: chainID, Address: address}
+}
@@ -0,0 +1,231 @@
+package cosmos
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/not-for-prod/hdwallet"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SignMode is a cosmos.tx.signing.v1beta1.SignMode
+type SignMode int32
+
+const (
+	SignModeDirect          SignMode = 1
+	SignModeLegacyAminoJSON SignMode = 127
+)
+
+// Fee is the fee of a transaction and the gas it may consume
+type Fee struct {
+	Amount   []Coin
+	GasLimit uint64
+	// Payer and Granter are optional, see the x/feegrant module
+	Payer   string
+	Granter string
+}
+
+// SignerData identifies the signing account on its chain, from the auth module's
+// account query
+type SignerData struct {
+	ChainID       string
+	AccountNumber uint64
+	Sequence      uint64
+}
+
+// Tx is an unsigned transaction with a single signer
+type Tx struct {
+	Msgs          []Msg
+	Memo          string
+	TimeoutHeight uint64
+	Fee           Fee
+}
+
+// BodyBytes returns the encoded cosmos.tx.v1beta1.TxBody
+func (t *Tx) BodyBytes() []byte {
+	var out []byte
+	for _, msg := range t.Msgs {
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, marshalAny(msg.TypeURL(), msg.Marshal()))
+	}
+	out = appendString(out, 2, t.Memo)
+	out = appendUint(out, 3, t.TimeoutHeight)
+
+	return out
+}
+
+// AuthInfoBytes returns the encoded cosmos.tx.v1beta1.AuthInfo declaring publicKey as
+// the signer at sequence with the given sign mode
+func (t *Tx) AuthInfoBytes(publicKey *hdwallet.PublicKey, sequence uint64, mode SignMode) []byte {
+	var single []byte
+	single = appendUint(single, 1, uint64(mode))
+	var modeInfo []byte
+	modeInfo = protowire.AppendTag(modeInfo, 1, protowire.BytesType)
+	modeInfo = protowire.AppendBytes(modeInfo, single)
+
+	var signerInfo []byte
+	signerInfo = protowire.AppendTag(signerInfo, 1, protowire.BytesType)
+	signerInfo = protowire.AppendBytes(signerInfo, PubKeyAny(publicKey))
+	signerInfo = protowire.AppendTag(signerInfo, 2, protowire.BytesType)
+	signerInfo = protowire.AppendBytes(signerInfo, modeInfo)
+	signerInfo = appendUint(signerInfo, 3, sequence)
+
+	var fee []byte
+	for _, coin := range t.Fee.Amount {
+		fee = protowire.AppendTag(fee, 1, protowire.BytesType)
+		fee = protowire.AppendBytes(fee, coin.marshal())
+	}
+	fee = appendUint(fee, 2, t.Fee.GasLimit)
+	fee = appendString(fee, 3, t.Fee.Payer)
+	fee = appendString(fee, 4, t.Fee.Granter)
+
+	var out []byte
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendBytes(out, signerInfo)
+	out = protowire.AppendTag(out, 2, protowire.BytesType)
+
+	return protowire.AppendBytes(out, fee)
+}
+
+// SignDoc returns the SIGN_MODE_DIRECT sign bytes, the encoded cosmos.tx.v1beta1.SignDoc
+func SignDoc(bodyBytes, authInfoBytes []byte, chainID string, accountNumber uint64) []byte {
+	var out []byte
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendBytes(out, bodyBytes)
+	out = protowire.AppendTag(out, 2, protowire.BytesType)
+	out = protowire.AppendBytes(out, authInfoBytes)
+	out = appendString(out, 3, chainID)
+
+	return appendUint(out, 4, accountNumber)
+}
+
+// parseSignDoc returns the body and auth info bytes of an encoded SignDoc
+func parseSignDoc(data []byte) ([]byte, []byte, error) {
+	var body, authInfo []byte
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch {
+		case number == 1 && wireType == protowire.BytesType:
+			body, n = protowire.ConsumeBytes(data)
+		case number == 2 && wireType == protowire.BytesType:
+			authInfo, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+		}
+		if n < 0 {
+			return nil, nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	if body == nil || authInfo == nil {
+		return nil, nil, errors.New("sign doc has no body or auth info")
+	}
+
+	return body, authInfo, nil
+}
+
+// AminoSignDoc returns the SIGN_MODE_LEGACY_AMINO_JSON sign bytes: the StdSignDoc as
+// compact JSON with sorted keys, integers as strings
+func (t *Tx) AminoSignDoc(signer SignerData) ([]byte, error) {
+	msgs := make([]json.RawMessage, 0, len(t.Msgs))
+	for _, msg := range t.Msgs {
+		amino, err := msg.AminoJSON()
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, amino)
+	}
+
+	amount := t.Fee.Amount
+	if amount == nil {
+		amount = []Coin{}
+	}
+	doc := stdSignDoc{
+		AccountNumber: strconv.FormatUint(signer.AccountNumber, 10),
+		ChainID:       signer.ChainID,
+		Fee: stdFee{
+			Amount:  amount,
+			Gas:     strconv.FormatUint(t.Fee.GasLimit, 10),
+			Payer:   t.Fee.Payer,
+			Granter: t.Fee.Granter,
+		},
+		Memo:     t.Memo,
+		Msgs:     msgs,
+		Sequence: strconv.FormatUint(signer.Sequence, 10),
+	}
+	if t.TimeoutHeight != 0 {
+		doc.TimeoutHeight = strconv.FormatUint(t.TimeoutHeight, 10)
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return sortJSON(encoded)
+}
+
+// stdSignDoc is the amino JSON document signed in SIGN_MODE_LEGACY_AMINO_JSON
+type stdSignDoc struct {
+	AccountNumber string            `json:"account_number"`
+	ChainID       string            `json:"chain_id"`
+	Fee           stdFee            `json:"fee"`
+	Memo          string            `json:"memo"`
+	Msgs          []json.RawMessage `json:"msgs"`
+	Sequence      string            `json:"sequence"`
+	TimeoutHeight string            `json:"timeout_height,omitempty"`
+}
+
+type stdFee struct {
+	Amount  []Coin `json:"amount"`
+	Gas     string `json:"gas"`
+	Payer   string `json:"payer,omitempty"`
+	Granter string `json:"granter,omitempty"`
+}
+
+// sortJSON re-encodes a JSON document with object keys sorted, as the SDK's
+// MustSortJSON does; numbers are kept verbatim
+func sortJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(value)
+}
+
+// TxRaw returns the encoded cosmos.tx.v1beta1.TxRaw, the bytes broadcast with
+// BroadcastTx or /cosmos/tx/v1beta1/txs
+func TxRaw(bodyBytes, authInfoBytes []byte, signatures ...[]byte) []byte {
+	var out []byte
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendBytes(out, bodyBytes)
+	out = protowire.AppendTag(out, 2, protowire.BytesType)
+	out = protowire.AppendBytes(out, authInfoBytes)
+	for _, signature := range signatures {
+		out = protowire.AppendTag(out, 3, protowire.BytesType)
+		out = protowire.AppendBytes(out, signature)
+	}
+
+	return out
+}
+
+// appendUint appends a varint field, omitted when zero as proto3 does
+func appendUint(out []byte, number protowire.Number, n uint64) []byte {
+	if n == 0 {
+		return out
+	}
+	out = protowire.AppendTag(out, number, protowire.VarintType)
+
+	return protowire.AppendVarint(out, n)
+}
@@ -0,0 +1,202 @@
+package hdwallet
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// AddressFormat encodes a public key as a chain address,
+// for example GenerateTronAddress or GenerateEthereumAddress
+type AddressFormat func(publicKey *PublicKey) string
+
+// DepositStore persists the assignment of external identifiers (user IDs, account
+// numbers) to address indexes
+//
+// AssignDepositIndex must be atomic: when several allocator instances share one
+// store (a database table with unique constraints on both columns, for example),
+// two identifiers must never receive the same index and one identifier must never
+// receive two indexes
+type DepositStore interface {
+	// DepositIndex returns the index assigned to id, if any
+	DepositIndex(id string) (uint32, bool, error)
+	// DepositID returns the identifier an index is assigned to, if any
+	DepositID(index uint32) (string, bool, error)
+	// AssignDepositIndex returns the index of id, assigning the lowest never
+	// assigned index when id has none yet
+	AssignDepositIndex(id string) (uint32, error)
+}
+
+// DepositAddress is the deposit address issued to an external identifier
+type DepositAddress struct {
+	ID        string
+	Index     uint32
+	Address   string
+	PublicKey *PublicKey
+}
+
+// DepositAllocator issues per-user deposit addresses from a single account xpub
+//
+// Addresses are derived watch-only on the receiving chain (xpub/0/index), so the
+// service issuing them never holds private keys; the wallet owning the account
+// xpub (see Wallet.AccountXPub) derives the matching keys to sweep deposits
+//
+// The allocator is safe for concurrent use; uniqueness of assignments across
+// processes is guaranteed by the DepositStore
+type DepositAllocator struct {
+	chain  *bip32.Key
+	format AddressFormat
+	store  DepositStore
+}
+
+// NewDepositAllocator returns an allocator deriving addresses below xpub, an account
+// level extended public key, encoded with format
+// Keys at any other depth are rejected: a master or chain xpub would issue addresses
+// the wallet never derives for its accounts
+func NewDepositAllocator(xpub string, format AddressFormat, store DepositStore) (*DepositAllocator, error) {
+	account, err := bip32.B58Deserialize(xpub)
+	if err != nil {
+		return nil, fmt.Errorf("parse xpub: %w", err)
+	}
+	if account.IsPrivate {
+		return nil, errors.New("deposit allocator requires an extended public key, not a private one")
+	}
+	if account.Depth != 3 {
+		return nil, fmt.Errorf("xpub at depth %d is not an account key (m/44'/coin'/account', depth 3)", account.Depth)
+	}
+	if format == nil {
+		return nil, errors.New("address format is required")
+	}
+	if store == nil {
+		return nil, errors.New("deposit store is required")
+	}
+
+	// External (receiving) chain, BIP44 chain 0
+	chain, err := account.NewChildKey(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DepositAllocator{
+		chain:  chain,
+		format: format,
+		store:  store,
+	}, nil
+}
+
+// Allocate returns the deposit address of id, assigning a fresh index on first use
+// Repeated calls with the same id return the same address
+func (a *DepositAllocator) Allocate(id string) (DepositAddress, error) {
+	if id == "" {
+		return DepositAddress{}, errors.New("deposit identifier is empty")
+	}
+
+	index, err := a.store.AssignDepositIndex(id)
+	if err != nil {
+		return DepositAddress{}, fmt.Errorf("assign deposit index for %q: %w", id, err)
+	}
+
+	return a.address(id, index)
+}
+
+// Lookup returns the deposit address already assigned to id
+func (a *DepositAllocator) Lookup(id string) (DepositAddress, bool, error) {
+	index, ok, err := a.store.DepositIndex(id)
+	if err != nil || !ok {
+		return DepositAddress{}, false, err
+	}
+
+	deposit, err := a.address(id, index)
+	if err != nil {
+		return DepositAddress{}, false, err
+	}
+
+	return deposit, true, nil
+}
+
+// Owner returns the identifier an index is assigned to, used to credit incoming deposits
+func (a *DepositAllocator) Owner(index uint32) (string, bool, error) {
+	return a.store.DepositID(index)
+}
+
+// address derives the deposit address at index
+func (a *DepositAllocator) address(id string, index uint32) (DepositAddress, error) {
+	if index >= HardenedOffset {
+		return DepositAddress{}, fmt.Errorf("deposit index %d exhausts the non-hardened range", index)
+	}
+
+	child, err := a.chain.NewChildKey(index)
+	if err != nil {
+		return DepositAddress{}, fmt.Errorf("derive deposit index %d: %w", index, err)
+	}
+
+	publicKey, err := PublicKeyFromBytes(child.Key)
+	if err != nil {
+		return DepositAddress{}, err
+	}
+
+	return DepositAddress{
+		ID:        id,
+		Index:     index,
+		Address:   a.format(publicKey),
+		PublicKey: publicKey,
+	}, nil
+}
+
+// MemoryDepositStore is a DepositStore kept in process memory
+type MemoryDepositStore struct {
+	mu      sync.Mutex
+	indexes map[string]uint32
+	ids     map[uint32]string
+	next    uint32
+}
+
+// NewMemoryDepositStore returns an empty MemoryDepositStore
+func NewMemoryDepositStore() *MemoryDepositStore {
+	return &MemoryDepositStore{
+		indexes: make(map[string]uint32),
+		ids:     make(map[uint32]string),
+	}
+}
+
+// DepositIndex implements DepositStore
+func (s *MemoryDepositStore) DepositIndex(id string) (uint32, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index, ok := s.indexes[id]
+
+	return index, ok, nil
+}
+
+// DepositID implements DepositStore
+func (s *MemoryDepositStore) DepositID(index uint32) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.ids[index]
+
+	return id, ok, nil
+}
+
+// AssignDepositIndex implements DepositStore
+func (s *MemoryDepositStore) AssignDepositIndex(id string) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if index, ok := s.indexes[id]; ok {
+		return index, nil
+	}
+	if s.next >= HardenedOffset {
+		return 0, errors.New("deposit index space exhausted")
+	}
+
+	index := s.next
+	s.indexes[id] = index
+	s.ids[index] = id
+	s.next++
+
+	return index, nil
+}
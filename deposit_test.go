@@ -0,0 +1,54 @@
+package hdwallet
+
+import (
+	"testing"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+func TestNewDepositAllocatorDepth(t *testing.T) {
+	wallet := newTestWallet(t, 60)
+	xpub, err := wallet.AccountXPub(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allocator, err := NewDepositAllocator(xpub, GenerateEthereumAddress, NewMemoryDepositStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit, err := allocator.Allocate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"; deposit.Address != want {
+		t.Errorf("Allocate = %s, want %s", deposit.Address, want)
+	}
+
+	account, err := bip32.B58Deserialize(xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := account.NewChildKey(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master, err := bip32.NewMasterKey(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name string
+		key  *bip32.Key
+	}{
+		{"master", master.PublicKey()},
+		{"chain", chain},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewDepositAllocator(test.key.B58Serialize(), GenerateEthereumAddress, NewMemoryDepositStore()); err == nil {
+				t.Errorf("NewDepositAllocator accepted a %s xpub", test.name)
+			}
+		})
+	}
+}
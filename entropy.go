@@ -0,0 +1,293 @@
+package hdwallet
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// EntropySource provides the randomness used for mnemonic entropy, salts and nonces
+//
+// Read must fill p completely or return an error; short reads are treated as failures
+// High-assurance deployments implement it on top of their hardware RNG and install it
+// with SetDefaultEntropySource, optionally wrapped in a HealthTestedSource
+type EntropySource interface {
+	Read(p []byte) (int, error)
+}
+
+// ErrEntropyHealth is returned once a HealthTestedSource has detected a failing noise source
+var ErrEntropyHealth = errors.New("entropy source failed health test")
+
+// SystemEntropy is the operating system CSPRNG (crypto/rand)
+var SystemEntropy EntropySource = rand.Reader
+
+// defaultEntropy holds the source used by functions that do not take one explicitly
+var defaultEntropy atomic.Value
+
+func init() {
+	defaultEntropy.Store(&entropyHolder{SystemEntropy})
+}
+
+// entropyHolder gives atomic.Value a single concrete type for every source
+type entropyHolder struct {
+	source EntropySource
+}
+
+// DefaultEntropySource returns the source used by GenerateMnemonic and EncryptSecret
+func DefaultEntropySource() EntropySource {
+	return defaultEntropy.Load().(*entropyHolder).source
+}
+
+// SetDefaultEntropySource replaces the source used by GenerateMnemonic and EncryptSecret
+// Passing nil restores SystemEntropy
+func SetDefaultEntropySource(source EntropySource) {
+	if source == nil {
+		source = SystemEntropy
+	}
+	defaultEntropy.Store(&entropyHolder{source})
+}
+
+// readEntropy fills p from source, rejecting short reads
+func readEntropy(source EntropySource, p []byte) error {
+	if _, err := io.ReadFull(source, p); err != nil {
+		return fmt.Errorf("read entropy: %w", err)
+	}
+
+	return nil
+}
+
+// DeviceEntropySource reads entropy from a file or character device such as /dev/hwrng
+type DeviceEntropySource struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenEntropyDevice opens the device or file at path
+func OpenEntropyDevice(path string) (*DeviceEntropySource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open entropy device: %w", err)
+	}
+
+	return &DeviceEntropySource{file: file}, nil
+}
+
+// Read implements EntropySource, p is filled completely or an error is returned
+func (s *DeviceEntropySource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, err := io.ReadFull(s.file, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return n, fmt.Errorf("entropy device exhausted after %d of %d bytes", n, len(p))
+	}
+
+	return n, err
+}
+
+// Close closes the device
+func (s *DeviceEntropySource) Close() error {
+	return s.file.Close()
+}
+
+const (
+	// healthFalsePositiveLog2 is -log2(alpha), the false positive probability of the
+	// health tests, 2^-20 as recommended by NIST SP 800-90B section 4.4
+	healthFalsePositiveLog2 = 20
+	// aptWindow is the adaptive proportion test window for non-binary samples
+	aptWindow = 512
+	// startupSamples is the number of samples tested before the first output
+	startupSamples = 1024
+)
+
+// HealthTestedSource runs the continuous health tests of NIST SP 800-90B section 4.4
+// over every byte read from a noise source
+//
+// The repetition count test detects a source stuck on one value, the adaptive
+// proportion test detects a source producing one value far too often. Once a test
+// fails the source is latched into the failed state and every further Read returns
+// ErrEntropyHealth, as the standard requires
+//
+// Samples are bytes and minEntropy is the assessed min-entropy per byte in bits
+// The tests only make sense for raw noise sources; output of a conditioned DRBG
+// such as crypto/rand always passes them
+type HealthTestedSource struct {
+	mu     sync.Mutex
+	source EntropySource
+	failed error
+
+	rctCutoff int
+	rctLast   byte
+	rctCount  int
+
+	aptCutoff int
+	aptValue  byte
+	aptCount  int
+	aptSeen   int
+}
+
+// NewHealthTestedSource wraps source and runs the start-up tests on 1024 samples
+// minEntropy is the assessed min-entropy per byte, between 0 (exclusive) and 8 bits
+func NewHealthTestedSource(source EntropySource, minEntropy float64) (*HealthTestedSource, error) {
+	if minEntropy <= 0 || minEntropy > 8 {
+		return nil, fmt.Errorf("min-entropy %.2f must be in (0, 8] bits per byte", minEntropy)
+	}
+
+	s := &HealthTestedSource{
+		source:    source,
+		rctCutoff: 1 + int(math.Ceil(healthFalsePositiveLog2/minEntropy)),
+		aptCutoff: 1 + criticalBinomial(aptWindow, math.Exp2(-minEntropy), 1-math.Exp2(-healthFalsePositiveLog2)),
+	}
+
+	// Start-up samples are tested and discarded
+	startup := make([]byte, startupSamples)
+	if _, err := s.Read(startup); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Read implements EntropySource, returning ErrEntropyHealth when a health test fails
+func (s *HealthTestedSource) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failed != nil {
+		return 0, s.failed
+	}
+
+	if err := readEntropy(s.source, p); err != nil {
+		return 0, err
+	}
+
+	for _, sample := range p {
+		if err := s.test(sample); err != nil {
+			s.failed = err
+			wipeBytes(p)
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Err returns the health test failure latched by the source, nil while it is healthy
+func (s *HealthTestedSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failed
+}
+
+// test feeds one sample to the repetition count and adaptive proportion tests
+func (s *HealthTestedSource) test(sample byte) error {
+	// Repetition count test (SP 800-90B 4.4.1)
+	if s.rctCount > 0 && sample == s.rctLast {
+		s.rctCount++
+		if s.rctCount >= s.rctCutoff {
+			return fmt.Errorf("%w: value repeated %d times", ErrEntropyHealth, s.rctCount)
+		}
+	} else {
+		s.rctLast = sample
+		s.rctCount = 1
+	}
+
+	// Adaptive proportion test (SP 800-90B 4.4.2)
+	if s.aptSeen == 0 {
+		s.aptValue = sample
+		s.aptCount = 1
+	} else if sample == s.aptValue {
+		s.aptCount++
+		if s.aptCount >= s.aptCutoff {
+			return fmt.Errorf("%w: value seen %d times in %d samples", ErrEntropyHealth, s.aptCount, aptWindow)
+		}
+	}
+	s.aptSeen++
+	if s.aptSeen == aptWindow {
+		s.aptSeen = 0
+	}
+
+	return nil
+}
+
+// criticalBinomial returns the smallest k such that P(X <= k) >= quantile for X ~ Binomial(n, p)
+func criticalBinomial(n int, p, quantile float64) int {
+	cumulative := 0.0
+	for k := 0; k <= n; k++ {
+		cumulative += math.Exp(logBinomial(n, k) + float64(k)*math.Log(p) + float64(n-k)*math.Log1p(-p))
+		if cumulative >= quantile {
+			return k
+		}
+	}
+
+	return n
+}
+
+// logBinomial returns ln(n choose k)
+func logBinomial(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+
+	return a - b - c
+}
+
+// entropyMixSalt domain-separates the HKDF used by MixedEntropySource
+const entropyMixSalt = "hdwallet-entropy-mix/v1"
+
+// MixedEntropySource combines a system source with caller-supplied entropy (dice rolls,
+// hardware token output) through HKDF-SHA256
+//
+// Every Read draws fresh bytes from the system source and extracts the output from
+// system bytes || user entropy, so the result is unpredictable as long as either
+// input is: a backdoored RNG cannot weaken wallets whose owners rolled dice, and
+// poorly rolled dice do not weaken the system RNG
+type MixedEntropySource struct {
+	system EntropySource
+	user   []byte
+}
+
+// NewMixedEntropySource returns a source mixing userEntropy into system
+func NewMixedEntropySource(system EntropySource, userEntropy []byte) (*MixedEntropySource, error) {
+	if len(userEntropy) == 0 {
+		return nil, errors.New("user entropy is empty")
+	}
+
+	return &MixedEntropySource{system: system, user: append([]byte(nil), userEntropy...)}, nil
+}
+
+// Read implements EntropySource
+func (s *MixedEntropySource) Read(p []byte) (int, error) {
+	// At least 256 bits from the system source, however short the request
+	system := make([]byte, max(len(p), 32))
+	defer wipeBytes(system)
+	if err := readEntropy(s.system, system); err != nil {
+		return 0, err
+	}
+
+	ikm := append(system, s.user...)
+	defer wipeBytes(ikm)
+
+	info := binary.BigEndian.AppendUint32(nil, uint32(len(p)))
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, []byte(entropyMixSalt), info), p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close wipes the user entropy held by the source
+func (s *MixedEntropySource) Close() error {
+	wipeBytes(s.user)
+	return nil
+}
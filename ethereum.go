@@ -0,0 +1,47 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// GenerateEthereumAddress generates an EIP-55 checksummed Ethereum address from a secp256k1 public key
+// The address is the last 20 bytes of the Keccak-256 hash of the uncompressed public key
+// coordinates, the same 20 bytes TRON prefixes with 0x41 (see GenerateTronAddress)
+//
+// Example: 0x9858EfFD232B4033E47d90003D41EC34EcaEda94
+func GenerateEthereumAddress(publicKey *PublicKey) string {
+	hash := keccak256(publicKey.SerializeUncompressed()[1:])
+
+	return ChecksumEthereumAddress(hash[len(hash)-20:])
+}
+
+// ChecksumEthereumAddress encodes a 20-byte address in the EIP-55 mixed-case form:
+// a hex letter is upper-cased when the matching nibble of Keccak-256(lowercase hex) is 8 or more
+func ChecksumEthereumAddress(address []byte) string {
+	lower := hex.EncodeToString(address)
+	hash := keccak256([]byte(lower))
+
+	encoded := []byte(lower)
+	for i, c := range encoded {
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+		if c >= 'a' && nibble >= 8 {
+			encoded[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(encoded)
+}
+
+func keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,40 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/not-for-prod/hdwallet/internal/abi"
+)
+
+// WordLength is the size of an ABI-encoded static parameter
+const WordLength = abi.WordLength
+
+// Selector returns the 4-byte selector of a function signature such as "transfer(address,uint256)"
+func Selector(signature string) [4]byte {
+	return abi.Selector(signature)
+}
+
+// AddressWord encodes an address parameter, left-padded with zeros to 32 bytes
+func AddressWord(address Address) [WordLength]byte {
+	var word [WordLength]byte
+	copy(word[WordLength-AddressLength:], address[:])
+
+	return word
+}
+
+// Uint256Word encodes a non-negative integer as a uint256 parameter
+func Uint256Word(n *big.Int) ([WordLength]byte, error) {
+	return abi.Uint256Word(n)
+}
+
+// wordAddress decodes an address parameter, rejecting non-zero padding
+func wordAddress(word []byte) (Address, error) {
+	var address Address
+	if !abi.IsZero(word[:WordLength-AddressLength]) {
+		return address, fmt.Errorf("address parameter %x has non-zero padding", word)
+	}
+	copy(address[:], word[WordLength-AddressLength:])
+
+	return address, nil
+}
@@ -0,0 +1,286 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// CoinType is the SLIP-0044 coin type of Ethereum
+const CoinType = 60
+
+// Asset is the asset name of ether in the signing intents of SignTransaction;
+// tokens are named by their contract address
+const Asset = "ETH"
+
+// Account is the hdwallet.Account of an Ethereum key
+type Account struct {
+	signer    hdwallet.Signer
+	publicKey *hdwallet.PublicKey
+	address   Address
+	nonces    hdwallet.NonceManager
+}
+
+var _ hdwallet.Account = (*Account)(nil)
+
+// AccountOption configures an Account
+type AccountOption func(*Account)
+
+// WithNonceManager sets the manager SignNext reserves transaction nonces from
+func WithNonceManager(nonces hdwallet.NonceManager) AccountOption {
+	return func(a *Account) {
+		a.nonces = nonces
+	}
+}
+
+// NewAccount returns the Ethereum account of signer, typically Wallet.Signer or an HSM signer
+func NewAccount(signer hdwallet.Signer, opts ...AccountOption) (*Account, error) {
+	publicKey, err := hdwallet.SignerPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{
+		signer:    signer,
+		publicKey: publicKey,
+		address:   PublicKeyToAddress(publicKey),
+	}
+	for _, opt := range opts {
+		opt(account)
+	}
+
+	return account, nil
+}
+
+// Coin implements hdwallet.Account
+func (a *Account) Coin() uint32 {
+	return CoinType
+}
+
+// Address returns the EIP-55 checksummed address of the account
+func (a *Account) Address() string {
+	return a.address.Hex()
+}
+
+// PublicKey implements hdwallet.Account
+func (a *Account) PublicKey() *hdwallet.PublicKey {
+	return a.publicKey
+}
+
+// SignDigest returns the 65-byte r || s || v signature of digest where v is the
+// recovery ID (0 to 3, in practice 0 or 1); add 27 for personal_sign style signatures
+func (a *Account) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	signature, err := hdwallet.SignWithContext(ctx, a.signer, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdwallet.RecoverableSignature(a.publicKey, digest, signature)
+}
+
+// SignTransaction signs the unsigned encoding of a transaction, as returned by
+// EncodeUnsigned, and returns the signed transaction ready for eth_sendRawTransaction
+//
+// Typed transactions (EIP-2930, EIP-1559) get yParity, r and s appended to their
+// fields; legacy transactions get v = chainID*2 + 35 + recovery ID with EIP-155,
+// 27 + recovery ID without
+//
+// Policies see the transfer of the transaction as its signing intent, replacing any
+// declared in ctx: the ether it sends, or the tokens moved or approved by an ERC-20
+// transfer, transferFrom, approve or increaseAllowance call. Other contract calls get
+// an intent without amount, which LimitPolicy denies
+func (a *Account) SignTransaction(ctx context.Context, payload []byte) ([]byte, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("empty transaction")
+	}
+
+	var prefix, list []byte
+	switch {
+	case payload[0] == AccessListTxType || payload[0] == DynamicFeeTxType:
+		prefix, list = payload[:1], payload[1:]
+	case payload[0] >= 0xc0:
+		list = payload
+	default:
+		return nil, fmt.Errorf("unsupported transaction type %d", payload[0])
+	}
+
+	fields, err := rlpListItems(list)
+	if err != nil {
+		return nil, fmt.Errorf("decode transaction: %w", err)
+	}
+
+	var v func(recoveryID byte) ([]byte, error)
+	switch {
+	case prefix != nil && (payload[0] == AccessListTxType && len(fields) == 8 ||
+		payload[0] == DynamicFeeTxType && len(fields) == 9):
+		v = func(recoveryID byte) ([]byte, error) {
+			return rlpUint(uint64(recoveryID)), nil
+		}
+	case prefix == nil && len(fields) == 6:
+		v = func(recoveryID byte) ([]byte, error) {
+			return rlpUint(27 + uint64(recoveryID)), nil
+		}
+	case prefix == nil && len(fields) == 9:
+		// EIP-155: the signed list ends with chainID, 0, 0 which the signature replaces
+		chainID, err := rlpDecodeBig(fields[6])
+		if err != nil {
+			return nil, fmt.Errorf("decode chain ID: %w", err)
+		}
+		if !isEmptyItem(fields[7]) || !isEmptyItem(fields[8]) {
+			return nil, errors.New("invalid EIP-155 transaction: r and s placeholders must be empty")
+		}
+		fields = fields[:6]
+		v = func(recoveryID byte) ([]byte, error) {
+			n := new(big.Int).Lsh(chainID, 1)
+			return rlpBig(n.Add(n, big.NewInt(35+int64(recoveryID))))
+		}
+	default:
+		return nil, fmt.Errorf("unexpected transaction field count %d", len(fields))
+	}
+
+	// to, value and data follow the fee fields, which differ between types
+	to := 3
+	switch {
+	case prefix != nil && payload[0] == AccessListTxType:
+		to = 4
+	case prefix != nil:
+		to = 5
+	}
+	intent, err := transactionIntent(fields[to], fields[to+1], fields[to+2])
+	if err != nil {
+		return nil, fmt.Errorf("decode transaction: %w", err)
+	}
+
+	digest := Keccak256(payload)
+	signature, err := a.SignDigest(hdwallet.WithSigningIntent(ctx, intent), digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	vItem, err := v(signature[64])
+	if err != nil {
+		return nil, err
+	}
+	r, err := rlpBig(new(big.Int).SetBytes(signature[:32]))
+	if err != nil {
+		return nil, err
+	}
+	s, err := rlpBig(new(big.Int).SetBytes(signature[32:64]))
+	if err != nil {
+		return nil, err
+	}
+	fields = append(fields, vItem, r, s)
+
+	return append(append([]byte(nil), prefix...), rlpList(fields...)...), nil
+}
+
+// SignNext reserves the next nonce of the account on the chain of tx from the manager
+// set with WithNonceManager, sets it as the nonce of tx and signs tx like SignTransaction
+//
+// The nonce is released when signing fails. Once signed it stays consumed; release it
+// with the manager (see NonceKey) if the transaction is never broadcast
+func (a *Account) SignNext(ctx context.Context, tx Transaction) ([]byte, error) {
+	if a.nonces == nil {
+		return nil, errors.New("account has no nonce manager")
+	}
+	chainID, nonce := tx.nonceOf()
+	if chainID == nil {
+		return nil, errors.New("transaction has no chain ID")
+	}
+
+	key := NonceKey(chainID, a.address)
+	reserved, err := a.nonces.Reserve(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("reserve nonce: %w", err)
+	}
+	*nonce = reserved
+
+	raw, err := a.signUnsigned(ctx, tx)
+	if err != nil {
+		if releaseErr := a.nonces.Release(ctx, key, reserved); releaseErr != nil {
+			return nil, errors.Join(err, releaseErr)
+		}
+		return nil, err
+	}
+
+	return raw, nil
+}
+
+// signUnsigned encodes and signs tx
+func (a *Account) signUnsigned(ctx context.Context, tx Transaction) ([]byte, error) {
+	unsigned, err := tx.EncodeUnsigned()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.SignTransaction(ctx, unsigned)
+}
+
+// transactionIntent describes the transfer made by the to, value and data items of a
+// transaction: ether sent without call data or to a created contract, or the tokens
+// moved or approved by an ERC-20 call without value. Other calls may move funds that
+// cannot be read from the transaction, so their intent has no amount and LimitPolicy
+// denies them
+func transactionIntent(toItem, valueItem, dataItem []byte) (hdwallet.SigningIntent, error) {
+	to, list, rest, err := rlpSplit(toItem)
+	if err != nil || list || len(rest) != 0 || (len(to) != 0 && len(to) != AddressLength) {
+		return hdwallet.SigningIntent{}, errors.New("invalid recipient")
+	}
+	value, err := rlpDecodeBig(valueItem)
+	if err != nil {
+		return hdwallet.SigningIntent{}, fmt.Errorf("invalid value: %w", err)
+	}
+	data, list, rest, err := rlpSplit(dataItem)
+	if err != nil || list || len(rest) != 0 {
+		return hdwallet.SigningIntent{}, errors.New("invalid data")
+	}
+
+	if len(to) == 0 {
+		return hdwallet.SigningIntent{Asset: Asset, Amount: value}, nil
+	}
+	contract := Address(to)
+	if len(data) == 0 {
+		return hdwallet.SigningIntent{Asset: Asset, Amount: value, Destination: contract.Hex()}, nil
+	}
+	if value.Sign() == 0 {
+		if intent, ok := tokenCallIntent(contract, data); ok {
+			return intent, nil
+		}
+	}
+
+	return hdwallet.SigningIntent{Asset: contract.Hex(), Destination: contract.Hex()}, nil
+}
+
+// tokenCallIntent describes an ERC-20 call to contract moving tokens or granting an
+// allowance over them; ok is false for any other call data
+func tokenCallIntent(contract Address, data []byte) (hdwallet.SigningIntent, bool) {
+	var destination Address
+	var amount *big.Int
+	var err error
+	switch {
+	case bytes.HasPrefix(data, TransferSelector[:]):
+		destination, amount, err = DecodeERC20Transfer(data)
+	case bytes.HasPrefix(data, TransferFromSelector[:]):
+		_, destination, amount, err = DecodeERC20TransferFrom(data)
+	case bytes.HasPrefix(data, ApproveSelector[:]):
+		destination, amount, err = DecodeERC20Approve(data)
+	case bytes.HasPrefix(data, IncreaseAllowanceSelector[:]):
+		destination, amount, err = decodeAddressAmountCall(data, IncreaseAllowanceSelector)
+	default:
+		return hdwallet.SigningIntent{}, false
+	}
+	if err != nil {
+		return hdwallet.SigningIntent{}, false
+	}
+
+	return hdwallet.SigningIntent{Asset: contract.Hex(), Amount: amount, Destination: destination.Hex()}, true
+}
+
+// isEmptyItem reports whether item is the encoding of zero, the empty string
+func isEmptyItem(item []byte) bool {
+	return len(item) == 1 && item[0] == 0x80
+}
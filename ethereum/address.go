@@ -0,0 +1,84 @@
+// Package ethereum provides Ethereum helpers for keys derived by hdwallet:
+// address parsing, contract address prediction, transaction signing, ERC-20 calls
+// and EIP-2612 permits
+package ethereum
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/not-for-prod/hdwallet"
+	"golang.org/x/crypto/sha3"
+)
+
+// AddressLength is the length of an Ethereum address in bytes
+const AddressLength = 20
+
+// Address is a 20-byte Ethereum account or contract address
+type Address [AddressLength]byte
+
+// ParseAddress parses a hex address with or without 0x prefix
+// All-lowercase and all-uppercase addresses are accepted as is; mixed-case
+// addresses must carry a valid EIP-55 checksum, which catches typos
+func ParseAddress(s string) (Address, error) {
+	raw := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(raw) != 2*AddressLength {
+		return Address{}, fmt.Errorf("invalid address %q: wrong length", s)
+	}
+
+	var address Address
+	if _, err := hex.Decode(address[:], []byte(raw)); err != nil {
+		return Address{}, fmt.Errorf("invalid address %q: %w", s, err)
+	}
+
+	if raw != strings.ToLower(raw) && raw != strings.ToUpper(raw) && "0x"+raw != address.Hex() {
+		return Address{}, fmt.Errorf("invalid address %q: EIP-55 checksum mismatch", s)
+	}
+
+	return address, nil
+}
+
+// PublicKeyToAddress returns the address of a public key derived by hdwallet
+func PublicKeyToAddress(publicKey *hdwallet.PublicKey) Address {
+	return addressFromHash(Keccak256(publicKey.SerializeUncompressed()[1:]))
+}
+
+// Hex returns the EIP-55 checksummed form of the address
+func (a Address) Hex() string {
+	return hdwallet.ChecksumEthereumAddress(a[:])
+}
+
+// String implements fmt.Stringer
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *Address) UnmarshalText(text []byte) error {
+	address, err := ParseAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = address
+
+	return nil
+}
+
+// Keccak256 returns the legacy Keccak-256 hash of the concatenated inputs
+func Keccak256(data ...[]byte) [32]byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+
+	var sum [32]byte
+	hash.Sum(sum[:0])
+
+	return sum
+}
@@ -0,0 +1,48 @@
+package ethereum
+
+import "encoding/hex"
+
+// CreateAddress returns the address of a contract deployed with CREATE by deployer
+// at the given account nonce: Keccak-256(rlp([deployer, nonce]))[12:]
+func CreateAddress(deployer Address, nonce uint64) Address {
+	return addressFromHash(Keccak256(rlpList(rlpBytes(deployer[:]), rlpUint(nonce))))
+}
+
+// Create2Address returns the address of a contract deployed with CREATE2 (EIP-1014):
+// Keccak-256(0xff || deployer || salt || Keccak-256(initCode))[12:]
+func Create2Address(deployer Address, salt [32]byte, initCodeHash [32]byte) Address {
+	return addressFromHash(Keccak256([]byte{0xff}, deployer[:], salt[:], initCodeHash[:]))
+}
+
+// Create2AddressFromInitCode is Create2Address hashing initCode
+func Create2AddressFromInitCode(deployer Address, salt [32]byte, initCode []byte) Address {
+	return Create2Address(deployer, salt, Keccak256(initCode))
+}
+
+// ERC-1167 minimal proxy creation code around the 20-byte implementation address
+var (
+	minimalProxyPrefix, _ = hex.DecodeString("3d602d80600a3d3981f3363d3d373d3d3d363d73")
+	minimalProxySuffix, _ = hex.DecodeString("5af43d82803e903d91602b57fd5bf3")
+)
+
+// MinimalProxyInitCode returns the ERC-1167 creation code of a clone delegating to implementation
+func MinimalProxyInitCode(implementation Address) []byte {
+	code := make([]byte, 0, len(minimalProxyPrefix)+AddressLength+len(minimalProxySuffix))
+	code = append(code, minimalProxyPrefix...)
+	code = append(code, implementation[:]...)
+
+	return append(code, minimalProxySuffix...)
+}
+
+// PredictMinimalProxyAddress returns the address of an ERC-1167 clone of implementation
+// deployed by factory with CREATE2 and salt, as OpenZeppelin Clones.cloneDeterministic does
+func PredictMinimalProxyAddress(factory, implementation Address, salt [32]byte) Address {
+	return Create2AddressFromInitCode(factory, salt, MinimalProxyInitCode(implementation))
+}
+
+func addressFromHash(hash [32]byte) Address {
+	var address Address
+	copy(address[:], hash[12:])
+
+	return address
+}
@@ -0,0 +1,78 @@
+package ethereum
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Domain is an EIP-712 domain; only the fields that are set are part of the
+// EIP712Domain type, so it must match the contract's DOMAIN_SEPARATOR exactly
+type Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract *Address
+	Salt              *[32]byte
+}
+
+// Separator returns the domain separator, hashStruct(EIP712Domain)
+func (d Domain) Separator() ([32]byte, error) {
+	var fields []string
+	var words [][32]byte
+	if d.Name != "" {
+		fields = append(fields, "string name")
+		words = append(words, Keccak256([]byte(d.Name)))
+	}
+	if d.Version != "" {
+		fields = append(fields, "string version")
+		words = append(words, Keccak256([]byte(d.Version)))
+	}
+	if d.ChainID != nil {
+		word, err := Uint256Word(d.ChainID)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		fields = append(fields, "uint256 chainId")
+		words = append(words, word)
+	}
+	if d.VerifyingContract != nil {
+		fields = append(fields, "address verifyingContract")
+		words = append(words, AddressWord(*d.VerifyingContract))
+	}
+	if d.Salt != nil {
+		fields = append(fields, "bytes32 salt")
+		words = append(words, *d.Salt)
+	}
+	if len(fields) == 0 {
+		return [32]byte{}, errors.New("EIP-712 domain has no field")
+	}
+
+	return HashStruct("EIP712Domain("+strings.Join(fields, ",")+")", words...), nil
+}
+
+// HashStruct returns the EIP-712 hashStruct of a struct whose members are all encoded
+// in one word each: Keccak-256(typeHash || encodeData), typeHash being Keccak-256 of
+// typeSignature such as "Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"
+func HashStruct(typeSignature string, words ...[32]byte) [32]byte {
+	typeHash := Keccak256([]byte(typeSignature))
+
+	data := make([][]byte, 0, len(words)+1)
+	data = append(data, typeHash[:])
+	for _, word := range words {
+		data = append(data, word[:])
+	}
+
+	return Keccak256(data...)
+}
+
+// TypedDataHash returns the digest signed for a typed message,
+// Keccak-256(0x19 || 0x01 || domainSeparator || hashStruct(message))
+func TypedDataHash(domain Domain, structHash [32]byte) ([32]byte, error) {
+	separator, err := domain.Separator()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return Keccak256([]byte{0x19, 0x01}, separator[:], structHash[:]), nil
+}
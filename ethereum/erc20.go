@@ -0,0 +1,174 @@
+package ethereum
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/not-for-prod/hdwallet/internal/abi"
+)
+
+// Function selectors of the ERC-20 and EIP-2612 calls
+var (
+	TransferSelector     = Selector("transfer(address,uint256)")
+	TransferFromSelector = Selector("transferFrom(address,address,uint256)")
+	ApproveSelector      = Selector("approve(address,uint256)")
+	// IncreaseAllowanceSelector is the OpenZeppelin extension raising an allowance
+	IncreaseAllowanceSelector = Selector("increaseAllowance(address,uint256)")
+	PermitSelector            = Selector("permit(address,address,uint256,uint256,uint8,bytes32,bytes32)")
+)
+
+// PermitType is the EIP-712 type signature of EIP-2612 permits
+const PermitType = "Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"
+
+// ERC20TransferData returns the call data of transfer(to, amount)
+func ERC20TransferData(to Address, amount *big.Int) ([]byte, error) {
+	return addressAmountCall(TransferSelector, to, amount)
+}
+
+// ERC20TransferFromData returns the call data of transferFrom(from, to, amount)
+func ERC20TransferFromData(from, to Address, amount *big.Int) ([]byte, error) {
+	amountWord, err := Uint256Word(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return abi.EncodeCall(TransferFromSelector, AddressWord(from), AddressWord(to), amountWord), nil
+}
+
+// ERC20ApproveData returns the call data of approve(spender, amount)
+func ERC20ApproveData(spender Address, amount *big.Int) ([]byte, error) {
+	return addressAmountCall(ApproveSelector, spender, amount)
+}
+
+// DecodeERC20Transfer decodes transfer call data to its recipient and amount
+func DecodeERC20Transfer(data []byte) (Address, *big.Int, error) {
+	return decodeAddressAmountCall(data, TransferSelector)
+}
+
+// DecodeERC20TransferFrom decodes transferFrom call data to its owner, recipient and amount
+func DecodeERC20TransferFrom(data []byte) (Address, Address, *big.Int, error) {
+	words, err := abi.DecodeCall(data, TransferFromSelector, 3)
+	if err != nil {
+		return Address{}, Address{}, nil, err
+	}
+	from, err := wordAddress(words[0])
+	if err != nil {
+		return Address{}, Address{}, nil, err
+	}
+	to, err := wordAddress(words[1])
+	if err != nil {
+		return Address{}, Address{}, nil, err
+	}
+
+	return from, to, new(big.Int).SetBytes(words[2]), nil
+}
+
+// DecodeERC20Approve decodes approve call data to its spender and amount
+func DecodeERC20Approve(data []byte) (Address, *big.Int, error) {
+	return decodeAddressAmountCall(data, ApproveSelector)
+}
+
+// Permit is an EIP-2612 approval signed off-chain by Owner and submitted by anyone
+type Permit struct {
+	Owner   Address
+	Spender Address
+	Value   *big.Int
+	// Nonce is the token's nonces(owner) at signing time
+	Nonce    *big.Int
+	Deadline *big.Int
+}
+
+// PermitSignature is a permit signature split as the permit function takes it
+type PermitSignature struct {
+	V uint8
+	R [32]byte
+	S [32]byte
+}
+
+// StructHash returns hashStruct(Permit)
+func (p Permit) StructHash() ([32]byte, error) {
+	words := [][32]byte{AddressWord(p.Owner), AddressWord(p.Spender)}
+	for _, n := range []*big.Int{p.Value, p.Nonce, p.Deadline} {
+		word, err := Uint256Word(n)
+		if err != nil {
+			return [32]byte{}, err
+		}
+		words = append(words, word)
+	}
+
+	return HashStruct(PermitType, words...), nil
+}
+
+// Digest returns the EIP-712 digest of the permit under the token's domain, whose
+// name and version must match the token's (usually its name and "1")
+func (p Permit) Digest(domain Domain) ([32]byte, error) {
+	structHash, err := p.StructHash()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return TypedDataHash(domain, structHash)
+}
+
+// SignPermit signs the permit with account, which must be the permit owner
+func SignPermit(ctx context.Context, account *Account, domain Domain, permit Permit) (PermitSignature, error) {
+	if account.address != permit.Owner {
+		return PermitSignature{}, errors.New("account is not the permit owner")
+	}
+
+	digest, err := permit.Digest(domain)
+	if err != nil {
+		return PermitSignature{}, err
+	}
+	signature, err := account.SignDigest(ctx, digest[:])
+	if err != nil {
+		return PermitSignature{}, err
+	}
+
+	var result PermitSignature
+	copy(result.R[:], signature[:32])
+	copy(result.S[:], signature[32:64])
+	result.V = 27 + signature[64]
+
+	return result, nil
+}
+
+// PermitData returns the call data of permit(owner, spender, value, deadline, v, r, s)
+func PermitData(permit Permit, signature PermitSignature) ([]byte, error) {
+	value, err := Uint256Word(permit.Value)
+	if err != nil {
+		return nil, err
+	}
+	deadline, err := Uint256Word(permit.Deadline)
+	if err != nil {
+		return nil, err
+	}
+	var v [WordLength]byte
+	v[WordLength-1] = signature.V
+
+	return abi.EncodeCall(PermitSelector, AddressWord(permit.Owner), AddressWord(permit.Spender),
+		value, deadline, v, signature.R, signature.S), nil
+}
+
+func addressAmountCall(selector [4]byte, address Address, amount *big.Int) ([]byte, error) {
+	amountWord, err := Uint256Word(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return abi.EncodeCall(selector, AddressWord(address), amountWord), nil
+}
+
+func decodeAddressAmountCall(data []byte, selector [4]byte) (Address, *big.Int, error) {
+	words, err := abi.DecodeCall(data, selector, 2)
+	if err != nil {
+		return Address{}, nil, err
+	}
+	address, err := wordAddress(words[0])
+	if err != nil {
+		return Address{}, nil, err
+	}
+
+	return address, new(big.Int).SetBytes(words[1]), nil
+}
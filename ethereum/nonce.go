@@ -0,0 +1,13 @@
+package ethereum
+
+import (
+	"math/big"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// NonceKey returns the hdwallet.NonceManager key of the account nonce of from on the
+// EIP-155 chain chainID, for the Nonce field of transactions
+func NonceKey(chainID *big.Int, from Address) hdwallet.NonceKey {
+	return hdwallet.NonceKey{Coin: CoinType, Chain: chainID.String(), Address: from.Hex()}
+}
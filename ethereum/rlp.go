@@ -0,0 +1,148 @@
+package ethereum
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+// rlpBytes encodes a byte string: a single byte below 0x80 is its own encoding,
+// longer strings are prefixed with their length
+func rlpBytes(data []byte) []byte {
+	if len(data) == 1 && data[0] < 0x80 {
+		return []byte{data[0]}
+	}
+
+	return append(rlpHeader(0x80, len(data)), data...)
+}
+
+// rlpList encodes the concatenation of already encoded items as a list
+func rlpList(items ...[]byte) []byte {
+	var payload []byte
+	for _, item := range items {
+		payload = append(payload, item...)
+	}
+
+	return append(rlpHeader(0xc0, len(payload)), payload...)
+}
+
+// rlpUint encodes an integer as its minimal big-endian bytes, zero is the empty string
+func rlpUint(n uint64) []byte {
+	encoded := binary.BigEndian.AppendUint64(nil, n)
+	for len(encoded) > 0 && encoded[0] == 0 {
+		encoded = encoded[1:]
+	}
+
+	return rlpBytes(encoded)
+}
+
+// rlpBig encodes a non-negative integer, nil encodes as zero
+func rlpBig(n *big.Int) ([]byte, error) {
+	if n == nil {
+		return rlpBytes(nil), nil
+	}
+	if n.Sign() < 0 {
+		return nil, errors.New("rlp: negative integer")
+	}
+
+	return rlpBytes(n.Bytes()), nil
+}
+
+// rlpHeader returns the prefix of a string (offset 0x80) or list (offset 0xc0) of size bytes
+func rlpHeader(offset byte, size int) []byte {
+	if size <= 55 {
+		return []byte{offset + byte(size)}
+	}
+
+	length := binary.BigEndian.AppendUint64(nil, uint64(size))
+	for length[0] == 0 {
+		length = length[1:]
+	}
+
+	return append([]byte{offset + 55 + byte(len(length))}, length...)
+}
+
+// rlpSplit returns the content of the first item of data, whether it is a list,
+// and the bytes following the item
+func rlpSplit(data []byte) (content []byte, list bool, rest []byte, err error) {
+	if len(data) == 0 {
+		return nil, false, nil, errors.New("rlp: unexpected end of input")
+	}
+
+	prefix := data[0]
+	var offset, size int
+	switch {
+	case prefix < 0x80:
+		return data[:1], false, data[1:], nil
+	case prefix <= 0xb7:
+		offset, size = 1, int(prefix-0x80)
+	case prefix < 0xc0:
+		offset, size, err = rlpLongSize(data, int(prefix-0xb7))
+	case prefix <= 0xf7:
+		offset, size, list = 1, int(prefix-0xc0), true
+	default:
+		offset, size, err = rlpLongSize(data, int(prefix-0xf7))
+		list = true
+	}
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if size > len(data)-offset {
+		return nil, false, nil, errors.New("rlp: item exceeds input")
+	}
+
+	return data[offset : offset+size], list, data[offset+size:], nil
+}
+
+// rlpLongSize decodes the big-endian size of a long string or list header
+func rlpLongSize(data []byte, sizeLength int) (int, int, error) {
+	if sizeLength > 4 || len(data) < 1+sizeLength {
+		return 0, 0, errors.New("rlp: invalid size")
+	}
+
+	size := 0
+	for _, b := range data[1 : 1+sizeLength] {
+		size = size<<8 | int(b)
+	}
+	if size <= 55 || data[1] == 0 {
+		return 0, 0, errors.New("rlp: non-canonical size")
+	}
+
+	return 1 + sizeLength, size, nil
+}
+
+// rlpListItems returns the encoded items of data, which must be exactly one list
+func rlpListItems(data []byte) ([][]byte, error) {
+	content, list, rest, err := rlpSplit(data)
+	if err != nil {
+		return nil, err
+	}
+	if !list || len(rest) != 0 {
+		return nil, errors.New("rlp: expected a single list")
+	}
+
+	var items [][]byte
+	for len(content) > 0 {
+		_, _, next, err := rlpSplit(content)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, content[:len(content)-len(next)])
+		content = next
+	}
+
+	return items, nil
+}
+
+// rlpDecodeBig decodes an encoded integer item
+func rlpDecodeBig(item []byte) (*big.Int, error) {
+	content, list, rest, err := rlpSplit(item)
+	if err != nil {
+		return nil, err
+	}
+	if list || len(rest) != 0 || (len(content) > 0 && content[0] == 0) {
+		return nil, errors.New("rlp: invalid integer")
+	}
+
+	return new(big.Int).SetBytes(content), nil
+}
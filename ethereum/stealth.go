@@ -0,0 +1,188 @@
+package ethereum
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// StealthSchemeID is the ERC-5564 scheme implemented here: secp256k1 with view tags
+const StealthSchemeID = 1
+
+// stealthMetaAddressPrefix prefixes ERC-5564 meta-addresses on Ethereum
+const stealthMetaAddressPrefix = "st:eth:0x"
+
+// StealthKeys are the spending and viewing keys of a stealth payment recipient
+//
+// The viewing key only detects payments and can be handed to a scanning service;
+// the spending key is required to move the funds
+type StealthKeys struct {
+	Spending *hdwallet.PrivateKey
+	Viewing  *hdwallet.PrivateKey
+}
+
+// DeriveStealthKeys derives stealth keys from a wallet account reserved for stealth
+// payments: the spending key at chain 0 and the viewing key at chain 1, index 0
+func DeriveStealthKeys(wallet *hdwallet.Wallet, account uint32) (*StealthKeys, error) {
+	spending, err := wallet.DeriveKey(account, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	viewing, err := wallet.DeriveKey(account, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StealthKeys{Spending: spending, Viewing: viewing}, nil
+}
+
+// MetaAddress returns the public meta-address of the keys
+func (k *StealthKeys) MetaAddress() StealthMetaAddress {
+	return StealthMetaAddress{Spending: k.Spending.PublicKey(), Viewing: k.Viewing.PublicKey()}
+}
+
+// StealthMetaAddress is what a recipient publishes so senders can derive one-time addresses
+type StealthMetaAddress struct {
+	Spending *hdwallet.PublicKey
+	Viewing  *hdwallet.PublicKey
+}
+
+// String encodes the meta-address as "st:eth:0x" || spending || viewing (compressed keys)
+func (m StealthMetaAddress) String() string {
+	return stealthMetaAddressPrefix +
+		hex.EncodeToString(m.Spending.SerializeCompressed()) +
+		hex.EncodeToString(m.Viewing.SerializeCompressed())
+}
+
+// ParseStealthMetaAddress parses a meta-address produced by StealthMetaAddress.String
+func ParseStealthMetaAddress(s string) (StealthMetaAddress, error) {
+	if !strings.HasPrefix(s, stealthMetaAddressPrefix) {
+		return StealthMetaAddress{}, fmt.Errorf("stealth meta-address must start with %q", stealthMetaAddressPrefix)
+	}
+
+	raw, err := hex.DecodeString(s[len(stealthMetaAddressPrefix):])
+	if err != nil {
+		return StealthMetaAddress{}, fmt.Errorf("decode stealth meta-address: %w", err)
+	}
+	if len(raw) != 2*secp256k1.PubKeyBytesLenCompressed {
+		return StealthMetaAddress{}, errors.New("stealth meta-address must hold two compressed public keys")
+	}
+
+	spending, err := hdwallet.PublicKeyFromBytes(raw[:secp256k1.PubKeyBytesLenCompressed])
+	if err != nil {
+		return StealthMetaAddress{}, fmt.Errorf("spending key: %w", err)
+	}
+	viewing, err := hdwallet.PublicKeyFromBytes(raw[secp256k1.PubKeyBytesLenCompressed:])
+	if err != nil {
+		return StealthMetaAddress{}, fmt.Errorf("viewing key: %w", err)
+	}
+
+	return StealthMetaAddress{Spending: spending, Viewing: viewing}, nil
+}
+
+// Announcement is the data a sender publishes through the ERC-5564 announcer contract
+type Announcement struct {
+	StealthAddress Address
+	// EphemeralPublicKey is the compressed ephemeral public key
+	EphemeralPublicKey []byte
+	// ViewTag is the first byte of the hashed shared secret, the first byte of the
+	// announcement metadata; it lets recipients skip most announcements with one hash
+	ViewTag byte
+}
+
+// GenerateStealthAddress derives a one-time address for the recipient of meta,
+// drawing the ephemeral key from random (hdwallet.DefaultEntropySource when nil)
+func GenerateStealthAddress(meta StealthMetaAddress, random io.Reader) (Announcement, error) {
+	if random == nil {
+		random = hdwallet.DefaultEntropySource()
+	}
+
+	ephemeral, err := secp256k1.GeneratePrivateKeyFromRand(random)
+	if err != nil {
+		return Announcement{}, err
+	}
+	defer ephemeral.Zero()
+
+	hashed := hashedSharedSecret(&ephemeral.Key, meta.Viewing.Secp256k1())
+	stealthKey := addScalarBase(meta.Spending, hashed)
+
+	return Announcement{
+		StealthAddress:     PublicKeyToAddress(stealthKey),
+		EphemeralPublicKey: ephemeral.PubKey().SerializeCompressed(),
+		ViewTag:            hashed[0],
+	}, nil
+}
+
+// Scan checks whether an announcement pays the keys and returns the private key
+// controlling the stealth address when it does
+// Only the viewing key is needed to detect payments, see ScanViewOnly
+func (k *StealthKeys) Scan(announcement Announcement) (*hdwallet.PrivateKey, bool, error) {
+	hashed, ok, err := scan(&k.Viewing.Secp256k1().Key, k.Spending.PublicKey(), announcement)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	// p_stealth = p_spend + s_h mod n
+	var scalar secp256k1.ModNScalar
+	scalar.SetByteSlice(hashed[:])
+	scalar.Add(&k.Spending.Secp256k1().Key)
+
+	return hdwallet.PrivateKeyFromSecp256k1(secp256k1.NewPrivateKey(&scalar)), true, nil
+}
+
+// ScanViewOnly reports whether an announcement pays the owner of spending,
+// using the viewing key only
+func ScanViewOnly(viewing *hdwallet.PrivateKey, spending *hdwallet.PublicKey, announcement Announcement) (bool, error) {
+	_, ok, err := scan(&viewing.Secp256k1().Key, spending, announcement)
+	return ok, err
+}
+
+func scan(viewing *secp256k1.ModNScalar, spending *hdwallet.PublicKey, announcement Announcement) ([32]byte, bool, error) {
+	ephemeral, err := secp256k1.ParsePubKey(announcement.EphemeralPublicKey)
+	if err != nil {
+		return [32]byte{}, false, fmt.Errorf("ephemeral public key: %w", err)
+	}
+
+	hashed := hashedSharedSecret(viewing, ephemeral)
+	if hashed[0] != announcement.ViewTag {
+		return [32]byte{}, false, nil
+	}
+
+	address := PublicKeyToAddress(addScalarBase(spending, hashed))
+	if !bytes.Equal(address[:], announcement.StealthAddress[:]) {
+		return [32]byte{}, false, nil
+	}
+
+	return hashed, true, nil
+}
+
+// hashedSharedSecret returns Keccak-256 of the compressed ECDH point scalar * point
+func hashedSharedSecret(scalar *secp256k1.ModNScalar, point *secp256k1.PublicKey) [32]byte {
+	var jacobian, shared secp256k1.JacobianPoint
+	point.AsJacobian(&jacobian)
+	secp256k1.ScalarMultNonConst(scalar, &jacobian, &shared)
+	shared.ToAffine()
+
+	return Keccak256(secp256k1.NewPublicKey(&shared.X, &shared.Y).SerializeCompressed())
+}
+
+// addScalarBase returns point + scalar * G
+func addScalarBase(point *hdwallet.PublicKey, scalar [32]byte) *hdwallet.PublicKey {
+	var s secp256k1.ModNScalar
+	s.SetBytes(&scalar)
+
+	var base, jacobian, sum secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&s, &base)
+	point.Secp256k1().AsJacobian(&jacobian)
+	secp256k1.AddNonConst(&jacobian, &base, &sum)
+	sum.ToAffine()
+
+	return hdwallet.PublicKeyFromSecp256k1(secp256k1.NewPublicKey(&sum.X, &sum.Y))
+}
@@ -0,0 +1,85 @@
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+func testStealthKeys(t *testing.T, account uint32) *StealthKeys {
+	t.Helper()
+	wallet, err := hdwallet.NewWallet(
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "", CoinType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := DeriveStealthKeys(wallet, account)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return keys
+}
+
+func TestStealthAddress(t *testing.T) {
+	keys := testStealthKeys(t, 0)
+	meta, err := ParseStealthMetaAddress(keys.MetaAddress().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 8 {
+		announcement, err := GenerateStealthAddress(meta, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		key, ok, err := keys.Scan(announcement)
+		if err != nil || !ok {
+			t.Fatalf("Scan = %v, %v, want a match", ok, err)
+		}
+		if got := PublicKeyToAddress(key.PublicKey()); got != announcement.StealthAddress {
+			t.Fatalf("Scan returned the key of %s, want %s", got.Hex(), announcement.StealthAddress.Hex())
+		}
+
+		ok, err = ScanViewOnly(keys.Viewing, keys.Spending.PublicKey(), announcement)
+		if err != nil || !ok {
+			t.Fatalf("ScanViewOnly = %v, %v, want a match", ok, err)
+		}
+
+		wrongTag := announcement
+		wrongTag.ViewTag ^= 0xff
+		if ok, err = ScanViewOnly(keys.Viewing, keys.Spending.PublicKey(), wrongTag); err != nil || ok {
+			t.Fatalf("ScanViewOnly with a wrong view tag = %v, %v, want no match", ok, err)
+		}
+		if _, ok, err = keys.Scan(wrongTag); err != nil || ok {
+			t.Fatalf("Scan with a wrong view tag = %v, %v, want no match", ok, err)
+		}
+	}
+}
+
+func TestStealthAddressOtherRecipient(t *testing.T) {
+	keys := testStealthKeys(t, 0)
+	other := testStealthKeys(t, 1)
+
+	announcement, err := GenerateStealthAddress(keys.MetaAddress(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := other.Scan(announcement); err != nil || ok {
+		t.Errorf("Scan by another recipient = %v, %v, want no match", ok, err)
+	}
+
+	// A matching view tag with another stealth address is still rejected
+	forged := announcement
+	forged.StealthAddress[0] ^= 1
+	if _, ok, err := keys.Scan(forged); err != nil || ok {
+		t.Errorf("Scan of a forged address = %v, %v, want no match", ok, err)
+	}
+
+	forged = announcement
+	forged.EphemeralPublicKey = []byte{2}
+	if _, _, err := keys.Scan(forged); err == nil {
+		t.Error("Scan accepted an invalid ephemeral public key")
+	}
+}
@@ -0,0 +1,216 @@
+package ethereum
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// EIP-2718 transaction types
+const (
+	AccessListTxType = 0x01
+	DynamicFeeTxType = 0x02
+)
+
+// AccessTuple is an EIP-2930 access list entry
+type AccessTuple struct {
+	Address     Address
+	StorageKeys [][32]byte
+}
+
+// Transaction is an unsigned *LegacyTransaction, *AccessListTransaction or
+// *DynamicFeeTransaction
+type Transaction interface {
+	SigningHash() ([32]byte, error)
+	EncodeUnsigned() ([]byte, error)
+	// nonceOf returns the chain ID of the transaction and its nonce field
+	nonceOf() (*big.Int, *uint64)
+}
+
+var (
+	_ Transaction = (*LegacyTransaction)(nil)
+	_ Transaction = (*AccessListTransaction)(nil)
+	_ Transaction = (*DynamicFeeTransaction)(nil)
+)
+
+// LegacyTransaction is a pre-EIP-2718 transaction
+type LegacyTransaction struct {
+	// ChainID enables EIP-155 replay protection, nil signs the pre-EIP-155 form
+	ChainID  *big.Int
+	Nonce    uint64
+	GasPrice *big.Int
+	Gas      uint64
+	// To is nil for contract creation
+	To    *Address
+	Value *big.Int
+	Data  []byte
+}
+
+// SigningHash returns the digest signed by the sender, Keccak-256 of EncodeUnsigned
+func (t *LegacyTransaction) SigningHash() ([32]byte, error) {
+	return signingHash(t.EncodeUnsigned())
+}
+
+func (t *LegacyTransaction) nonceOf() (*big.Int, *uint64) {
+	return t.ChainID, &t.Nonce
+}
+
+// EncodeUnsigned returns rlp([nonce, gasPrice, gas, to, value, data, chainID, 0, 0])
+// with EIP-155, without the last three fields otherwise
+func (t *LegacyTransaction) EncodeUnsigned() ([]byte, error) {
+	var fields rlpFields
+	fields.uint(t.Nonce)
+	fields.big("gas price", t.GasPrice)
+	fields.uint(t.Gas)
+	fields.to(t.To)
+	fields.big("value", t.Value)
+	fields.bytes(t.Data)
+	if t.ChainID != nil {
+		fields.big("chain id", t.ChainID)
+		fields.uint(0)
+		fields.uint(0)
+	}
+
+	return fields.encode(nil)
+}
+
+// AccessListTransaction is an EIP-2930 (type 1) transaction
+type AccessListTransaction struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasPrice   *big.Int
+	Gas        uint64
+	To         *Address
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple
+}
+
+// SigningHash returns the digest signed by the sender, Keccak-256 of EncodeUnsigned
+func (t *AccessListTransaction) SigningHash() ([32]byte, error) {
+	return signingHash(t.EncodeUnsigned())
+}
+
+func (t *AccessListTransaction) nonceOf() (*big.Int, *uint64) {
+	return t.ChainID, &t.Nonce
+}
+
+// EncodeUnsigned returns 0x01 || rlp([chainID, nonce, gasPrice, gas, to, value, data, accessList])
+func (t *AccessListTransaction) EncodeUnsigned() ([]byte, error) {
+	var fields rlpFields
+	fields.big("chain id", t.ChainID)
+	fields.uint(t.Nonce)
+	fields.big("gas price", t.GasPrice)
+	fields.uint(t.Gas)
+	fields.to(t.To)
+	fields.big("value", t.Value)
+	fields.bytes(t.Data)
+	fields.accessList(t.AccessList)
+
+	return fields.encode([]byte{AccessListTxType})
+}
+
+// DynamicFeeTransaction is an EIP-1559 (type 2) transaction
+type DynamicFeeTransaction struct {
+	ChainID *big.Int
+	Nonce   uint64
+	// GasTipCap is maxPriorityFeePerGas, GasFeeCap is maxFeePerGas
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *Address
+	Value      *big.Int
+	Data       []byte
+	AccessList []AccessTuple
+}
+
+// SigningHash returns the digest signed by the sender, Keccak-256 of EncodeUnsigned
+func (t *DynamicFeeTransaction) SigningHash() ([32]byte, error) {
+	return signingHash(t.EncodeUnsigned())
+}
+
+func (t *DynamicFeeTransaction) nonceOf() (*big.Int, *uint64) {
+	return t.ChainID, &t.Nonce
+}
+
+// EncodeUnsigned returns 0x02 || rlp([chainID, nonce, maxPriorityFeePerGas, maxFeePerGas,
+// gas, to, value, data, accessList])
+func (t *DynamicFeeTransaction) EncodeUnsigned() ([]byte, error) {
+	var fields rlpFields
+	fields.big("chain id", t.ChainID)
+	fields.uint(t.Nonce)
+	fields.big("max priority fee per gas", t.GasTipCap)
+	fields.big("max fee per gas", t.GasFeeCap)
+	fields.uint(t.Gas)
+	fields.to(t.To)
+	fields.big("value", t.Value)
+	fields.bytes(t.Data)
+	fields.accessList(t.AccessList)
+
+	return fields.encode([]byte{DynamicFeeTxType})
+}
+
+// TransactionHash returns the hash identifying a signed transaction, the Keccak-256
+// of its raw encoding as sent to eth_sendRawTransaction (type byte included for typed transactions)
+func TransactionHash(raw []byte) [32]byte {
+	return Keccak256(raw)
+}
+
+// rlpFields collects the encoded fields of a transaction, keeping the first error
+type rlpFields struct {
+	items [][]byte
+	err   error
+}
+
+func (f *rlpFields) uint(n uint64) {
+	f.items = append(f.items, rlpUint(n))
+}
+
+func (f *rlpFields) bytes(data []byte) {
+	f.items = append(f.items, rlpBytes(data))
+}
+
+func (f *rlpFields) big(name string, n *big.Int) {
+	item, err := rlpBig(n)
+	if err != nil && f.err == nil {
+		f.err = fmt.Errorf("%s: %w", name, err)
+	}
+	f.items = append(f.items, item)
+}
+
+// to encodes the recipient, the empty string for contract creation
+func (f *rlpFields) to(to *Address) {
+	if to == nil {
+		f.bytes(nil)
+		return
+	}
+	f.bytes(to[:])
+}
+
+func (f *rlpFields) accessList(list []AccessTuple) {
+	tuples := make([][]byte, 0, len(list))
+	for _, tuple := range list {
+		keys := make([][]byte, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			keys = append(keys, rlpBytes(key[:]))
+		}
+		tuples = append(tuples, rlpList(rlpBytes(tuple.Address[:]), rlpList(keys...)))
+	}
+	f.items = append(f.items, rlpList(tuples...))
+}
+
+// encode returns prefix || rlp(fields)
+func (f *rlpFields) encode(prefix []byte) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return append(prefix, rlpList(f.items...)...), nil
+}
+
+func signingHash(unsigned []byte, err error) ([32]byte, error) {
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return Keccak256(unsigned), nil
+}
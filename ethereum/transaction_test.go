@@ -0,0 +1,124 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// eip155Transaction is the example transaction of EIP-155
+func eip155Transaction() *LegacyTransaction {
+	to := Address(bytes.Repeat([]byte{0x35}, 20))
+
+	return &LegacyTransaction{
+		ChainID:  big.NewInt(1),
+		Nonce:    9,
+		GasPrice: big.NewInt(20_000_000_000),
+		Gas:      21000,
+		To:       &to,
+		Value:    new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+	}
+}
+
+func TestLegacyTransactionEIP155(t *testing.T) {
+	tx := eip155Transaction()
+
+	unsigned, err := tx.EncodeUnsigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantUnsigned = "ec098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a764000080018080"
+	if got := hex.EncodeToString(unsigned); got != wantUnsigned {
+		t.Errorf("EncodeUnsigned = %s, want %s", got, wantUnsigned)
+	}
+
+	hash, err := tx.SigningHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantHash = "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53"
+	if got := hex.EncodeToString(hash[:]); got != wantHash {
+		t.Errorf("SigningHash = %s, want %s", got, wantHash)
+	}
+
+	key, err := hdwallet.PrivateKeyFromBytes(bytes.Repeat([]byte{0x46}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := NewAccount(hdwallet.NewKeySigner(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sign the specification's encoding rather than ours, checked above
+	signed, err := account.SignTransaction(context.Background(), mustHex(t, wantUnsigned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantSigned = "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025" +
+		"a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276" +
+		"a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+	if got := hex.EncodeToString(signed); got != wantSigned {
+		t.Errorf("SignTransaction = %s, want %s", got, wantSigned)
+	}
+}
+
+// The EIP-1559 encoding below is spelled out field by field from the specification
+func TestDynamicFeeTransaction(t *testing.T) {
+	to := Address(bytes.Repeat([]byte{0x35}, 20))
+	tx := &DynamicFeeTransaction{
+		ChainID:   big.NewInt(1),
+		Nonce:     0,
+		GasTipCap: big.NewInt(1_000_000_000),
+		GasFeeCap: big.NewInt(50_000_000_000),
+		Gas:       21000,
+		To:        &to,
+		Value:     new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+		AccessList: []AccessTuple{
+			{Address: to, StorageKeys: [][32]byte{{31: 0x01}}},
+		},
+	}
+
+	unsigned, err := tx.EncodeUnsigned()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := strings.Repeat("35", 20)
+	wantUnsigned := "02" + "f869" +
+		"01" + // chainId
+		"80" + // nonce
+		"843b9aca00" + // maxPriorityFeePerGas
+		"850ba43b7400" + // maxFeePerGas
+		"825208" + // gasLimit
+		"94" + address + // destination
+		"880de0b6b3a7640000" + // amount
+		"80" + // data
+		"f838" + "f7" + "94" + address + "e1" + "a0" + strings.Repeat("00", 31) + "01" // accessList
+	if got := hex.EncodeToString(unsigned); got != wantUnsigned {
+		t.Errorf("EncodeUnsigned = %s, want %s", got, wantUnsigned)
+	}
+
+	hash, err := tx.SigningHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	const wantHash = "51810113047aab37725207e015f69a86d1e9eab7334d517539a16efc8dc26c71"
+	if got := hex.EncodeToString(hash[:]); got != wantHash {
+		t.Errorf("SigningHash = %s, want %s", got, wantHash)
+	}
+}
+
+// mustHex decodes a hex test vector, failing the test if it is malformed
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
@@ -31,6 +31,6 @@ func main() {
 	// Generate TRON address
 	address := hdwallet.GenerateTronAddress(publicKey)
 	fmt.Printf("TRON Address: %s\n", address)
-	fmt.Printf("Private Key: %x\n", privateKey.Serialize())
+	fmt.Printf("Private Key: %x\n", privateKey.Bytes())
 	fmt.Printf("Public Key: %x\n", publicKey.SerializeCompressed())
 }
@@ -3,15 +3,32 @@ module github.com/not-for-prod/hdwallet
 go 1.24.1
 
 require (
-	github.com/btcsuite/btcd/btcutil v1.1.6
+	github.com/ChainSafe/go-schnorrkel v1.0.0
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0
+	github.com/go-piv/piv-go v1.11.0
+	github.com/google/go-tpm v0.9.8
+	github.com/kilic/bls12-381 v0.1.0
+	github.com/miekg/pkcs11 v1.1.2
+	github.com/prometheus/client_golang v1.22.0
 	github.com/tyler-smith/go-bip32 v1.0.0
 	github.com/tyler-smith/go-bip39 v1.1.0
 	golang.org/x/crypto v0.39.0
+	google.golang.org/protobuf v1.36.9
 )
 
 require (
 	github.com/FactomProject/basen v0.0.0-20150613233007-fe3947df716e // indirect
 	github.com/FactomProject/btcutilecc v0.0.0-20130527213604-d3a63a5752ec // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/cosmos/go-bip39 v0.0.0-20180819234021-555e2067c45d // indirect
+	github.com/gtank/merlin v0.1.1 // indirect
+	github.com/gtank/ristretto255 v0.1.2 // indirect
+	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/mimoo/StrobeGo v0.0.0-20181016162300-f8f6d4d2b643 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.62.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 )
@@ -0,0 +1,81 @@
+// Package abi encodes the static parameters of Solidity ABI calls, shared by the
+// ethereum and tron packages, whose contracts use the same ABI
+package abi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// WordLength is the size of an ABI-encoded static parameter
+const WordLength = 32
+
+// maxUint256 bounds the values that fit in a uint256 parameter
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Selector returns the 4-byte selector of a function signature such as "transfer(address,uint256)",
+// the first 4 bytes of its Keccak-256
+func Selector(signature string) [4]byte {
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(signature))
+
+	var selector [4]byte
+	copy(selector[:], hash.Sum(nil))
+
+	return selector
+}
+
+// Uint256Word encodes a non-negative integer as a uint256 parameter
+func Uint256Word(n *big.Int) ([WordLength]byte, error) {
+	if n == nil || n.Sign() < 0 || n.Cmp(maxUint256) > 0 {
+		return [WordLength]byte{}, fmt.Errorf("value %v does not fit in uint256", n)
+	}
+
+	var word [WordLength]byte
+	n.FillBytes(word[:])
+
+	return word, nil
+}
+
+// EncodeCall returns selector followed by the given static parameters
+func EncodeCall(selector [4]byte, words ...[WordLength]byte) []byte {
+	data := make([]byte, 0, len(selector)+len(words)*WordLength)
+	data = append(data, selector[:]...)
+	for _, word := range words {
+		data = append(data, word[:]...)
+	}
+
+	return data
+}
+
+// DecodeCall checks the selector of data and splits its count static parameters
+func DecodeCall(data []byte, selector [4]byte, count int) ([][]byte, error) {
+	if len(data) != len(selector)+count*WordLength {
+		return nil, fmt.Errorf("call data must be %d bytes, got %d", len(selector)+count*WordLength, len(data))
+	}
+	if !bytes.Equal(data[:len(selector)], selector[:]) {
+		return nil, fmt.Errorf("unexpected selector %x, want %x", data[:len(selector)], selector)
+	}
+
+	words := make([][]byte, count)
+	for i := range words {
+		offset := len(selector) + i*WordLength
+		words[i] = data[offset : offset+WordLength]
+	}
+
+	return words, nil
+}
+
+// IsZero reports whether data, typically the padding of a parameter, is all zeros
+func IsZero(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,110 @@
+package hdwallet
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// DerivedAddress is an address yielded by an address iterator
+type DerivedAddress struct {
+	// Path is the full derivation path, empty for addresses derived from an xpub of unknown origin
+	Path      DerivationPath
+	Index     uint32
+	Address   string
+	PublicKey *PublicKey
+}
+
+// Addresses returns an iterator over the addresses of account and chain, starting at index start
+//
+// Addresses are derived lazily, one per iteration step, so consumers can stream
+// them (into an indexer subscription, for example) and stop at any point without
+// materializing a slice. Iteration ends after the last non-hardened index or after
+// yielding an error. Only public keys are derived: the chain node is neutered before
+// the loop, so no private key material is produced per address
+func (w *Wallet) Addresses(account, chain, start uint32, format AddressFormat) iter.Seq2[DerivedAddress, error] {
+	return func(yield func(DerivedAddress, error) bool) {
+		if err := checkBIP44Indexes(w.coin, account, chain, start); err != nil {
+			yield(DerivedAddress{}, err)
+			return
+		}
+
+		var chainKey *bip32.Key
+		err := w.withMasterKey(func(masterKey *bip32.Key) error {
+			accountKey, err := DeriveAccountKey(masterKey, w.coin, account)
+			if err != nil {
+				return err
+			}
+			// The neutered copy shares the chain code, which the chain key no longer needs
+			defer wipeKey(accountKey)
+			chainKey, err = accountKey.PublicKey().NewChildKey(chain)
+
+			return err
+		})
+		if err != nil {
+			yield(DerivedAddress{}, err)
+			return
+		}
+
+		yieldAddresses(chainKey, start, format, func(index uint32) DerivationPath {
+			return BIP44Path(w.coin, account, chain, index)
+		}, yield)
+	}
+}
+
+// XPubAddresses returns an iterator over the addresses of chain below an account
+// level extended public key, starting at index start; it is the watch-only
+// counterpart of Wallet.Addresses and yields addresses without a Path
+func XPubAddresses(xpub string, chain, start uint32, format AddressFormat) iter.Seq2[DerivedAddress, error] {
+	return func(yield func(DerivedAddress, error) bool) {
+		account, err := bip32.B58Deserialize(xpub)
+		if err != nil {
+			yield(DerivedAddress{}, fmt.Errorf("parse xpub: %w", err))
+			return
+		}
+
+		chainKey, err := account.PublicKey().NewChildKey(chain)
+		if err != nil {
+			yield(DerivedAddress{}, err)
+			return
+		}
+
+		yieldAddresses(chainKey, start, format, func(uint32) DerivationPath { return nil }, yield)
+	}
+}
+
+// yieldAddresses derives and yields the children of chainKey from start until yield
+// returns false, an error occurs or the non-hardened range ends
+func yieldAddresses(chainKey *bip32.Key, start uint32, format AddressFormat,
+	path func(index uint32) DerivationPath, yield func(DerivedAddress, error) bool) {
+	if format == nil {
+		yield(DerivedAddress{}, errors.New("address format is required"))
+		return
+	}
+
+	for index := start; index < HardenedOffset; index++ {
+		child, err := chainKey.NewChildKey(index)
+		if err != nil {
+			yield(DerivedAddress{}, fmt.Errorf("derive index %d: %w", index, err))
+			return
+		}
+
+		publicKey, err := PublicKeyFromBytes(child.Key)
+		if err != nil {
+			yield(DerivedAddress{}, err)
+			return
+		}
+
+		address := DerivedAddress{
+			Path:      path(index),
+			Index:     index,
+			Address:   format(publicKey),
+			PublicKey: publicKey,
+		}
+		if !yield(address, nil) {
+			return
+		}
+	}
+}
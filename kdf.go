@@ -0,0 +1,265 @@
+package hdwallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDFName identifies a password based key derivation function
+type KDFName string
+
+const (
+	KDFArgon2id KDFName = "argon2id"
+	KDFScrypt   KDFName = "scrypt"
+	KDFPBKDF2   KDFName = "pbkdf2"
+)
+
+// Limits applied to KDF parameters read from untrusted exports, so a crafted
+// file cannot make decryption allocate unbounded memory or run for hours
+const (
+	maxArgon2Memory     = 1024 * 1024 // KiB (1 GiB)
+	maxArgon2Time       = 64
+	maxScryptN          = 1 << 22
+	maxScryptMemory     = 1 << 30 // bytes, 128·N·r
+	maxPBKDF2Iterations = 10_000_000
+)
+
+// KDFParams describes how an encryption key is stretched from a password
+//
+// The parameters, including the salt, are stored next to the ciphertext of every
+// encrypted export, so files written with different KDFs or cost settings can
+// always be decrypted and security teams can raise costs without breaking old exports
+type KDFParams struct {
+	Name   KDFName `json:"name"`
+	Salt   []byte  `json:"salt"`
+	KeyLen uint32  `json:"dklen"`
+
+	// Argon2id: Time is the number of passes, Memory is in KiB, Threads is the parallelism
+	Time    uint32 `json:"t,omitempty"`
+	Memory  uint32 `json:"m,omitempty"`
+	Threads uint8  `json:"p,omitempty"`
+
+	// scrypt: N is the CPU/memory cost (power of two), R the block size, P the parallelism
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"parallel,omitempty"`
+
+	// PBKDF2: Iterations of PRF, which is "hmac-sha256" or "hmac-sha512"
+	Iterations uint32 `json:"c,omitempty"`
+	PRF        string `json:"prf,omitempty"`
+}
+
+// DefaultArgon2idParams returns the second recommended Argon2id option of RFC 9106
+// (t=3, m=64 MiB, p=4), suitable for interactive decryption on servers and laptops
+func DefaultArgon2idParams() KDFParams {
+	return KDFParams{Name: KDFArgon2id, KeyLen: 32, Time: 3, Memory: 64 * 1024, Threads: 4}
+}
+
+// DefaultScryptParams returns the scrypt costs used by Ethereum keystore V3 files (N=2^18, r=8, p=1)
+func DefaultScryptParams() KDFParams {
+	return KDFParams{Name: KDFScrypt, KeyLen: 32, N: 1 << 18, R: 8, P: 1}
+}
+
+// DefaultPBKDF2Params returns PBKDF2-HMAC-SHA256 with 600000 iterations (OWASP 2023 guidance)
+func DefaultPBKDF2Params() KDFParams {
+	return KDFParams{Name: KDFPBKDF2, KeyLen: 32, Iterations: 600_000, PRF: "hmac-sha256"}
+}
+
+// Validate checks that the parameters are complete and within safe bounds
+func (p KDFParams) Validate() error {
+	if len(p.Salt) < 16 {
+		return errors.New("kdf salt must be at least 16 bytes")
+	}
+	if p.KeyLen < 16 || p.KeyLen > 64 {
+		return fmt.Errorf("kdf key length %d out of range", p.KeyLen)
+	}
+
+	switch p.Name {
+	case KDFArgon2id:
+		if p.Time == 0 || p.Time > maxArgon2Time {
+			return fmt.Errorf("argon2id time %d out of range", p.Time)
+		}
+		if p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+			return fmt.Errorf("argon2id memory %d KiB out of range", p.Memory)
+		}
+		if p.Threads == 0 {
+			return errors.New("argon2id threads must be positive")
+		}
+	case KDFScrypt:
+		if p.N <= 1 || p.N&(p.N-1) != 0 || p.N > maxScryptN {
+			return fmt.Errorf("scrypt N %d must be a power of two up to %d", p.N, maxScryptN)
+		}
+		if p.R <= 0 || p.P <= 0 || uint64(p.R)*uint64(p.P) >= 1<<30 {
+			return fmt.Errorf("scrypt r=%d p=%d out of range", p.R, p.P)
+		}
+		if memory := 128 * uint64(p.N) * uint64(p.R); memory > maxScryptMemory {
+			return fmt.Errorf("scrypt N=%d r=%d needs %d MiB, more than %d MiB", p.N, p.R, memory>>20, maxScryptMemory>>20)
+		}
+	case KDFPBKDF2:
+		if p.Iterations == 0 || p.Iterations > maxPBKDF2Iterations {
+			return fmt.Errorf("pbkdf2 iterations %d out of range", p.Iterations)
+		}
+		if _, err := pbkdf2PRF(p.PRF); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown kdf %q", p.Name)
+	}
+
+	return nil
+}
+
+// DeriveKey stretches password into a KeyLen-byte key
+func (p KDFParams) DeriveKey(password []byte) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch p.Name {
+	case KDFArgon2id:
+		return argon2.IDKey(password, p.Salt, p.Time, p.Memory, p.Threads, p.KeyLen), nil
+	case KDFScrypt:
+		return scrypt.Key(password, p.Salt, p.N, p.R, p.P, int(p.KeyLen))
+	default:
+		prf, _ := pbkdf2PRF(p.PRF)
+		return pbkdf2.Key(password, p.Salt, int(p.Iterations), int(p.KeyLen), prf), nil
+	}
+}
+
+// withSalt returns a copy of p with a fresh random salt when none is set
+func (p KDFParams) withSalt() (KDFParams, error) {
+	if len(p.Salt) > 0 {
+		return p, nil
+	}
+
+	p.Salt = make([]byte, 32)
+	if err := readEntropy(DefaultEntropySource(), p.Salt); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+func pbkdf2PRF(name string) (func() hash.Hash, error) {
+	switch name {
+	case "hmac-sha256":
+		return sha256.New, nil
+	case "hmac-sha512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("unknown pbkdf2 prf %q", name)
+	}
+}
+
+// encryptedSecretVersion is the current EncryptedSecret format version
+const encryptedSecretVersion = 1
+
+// EncryptedSecret is a password encrypted export of secret material
+// (seed, mnemonic entropy, xprv, backup payloads) that records its KDF parameters
+type EncryptedSecret struct {
+	Version    int       `json:"version"`
+	KDF        KDFParams `json:"kdf"`
+	Cipher     string    `json:"cipher"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// EncryptSecret encrypts secret with AES-256-GCM under a key stretched from password with params
+// A random salt is generated when params has none; use DefaultArgon2idParams for new exports
+func EncryptSecret(secret, password []byte, params KDFParams) (*EncryptedSecret, error) {
+	params, err := params.withSalt()
+	if err != nil {
+		return nil, err
+	}
+	params.KeyLen = 32
+
+	key, err := params.DeriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(key)
+
+	aead, err := newSecretAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if err = readEntropy(DefaultEntropySource(), nonce); err != nil {
+		return nil, err
+	}
+
+	encrypted := &EncryptedSecret{
+		Version: encryptedSecretVersion,
+		KDF:     params,
+		Cipher:  "aes-256-gcm",
+		Nonce:   nonce,
+	}
+	encrypted.Ciphertext = aead.Seal(nil, nonce, secret, encrypted.additionalData())
+
+	return encrypted, nil
+}
+
+// Decrypt returns the secret, failing when the password is wrong or the export was modified
+func (e *EncryptedSecret) Decrypt(password []byte) ([]byte, error) {
+	if e.Version != encryptedSecretVersion {
+		return nil, fmt.Errorf("unsupported encrypted secret version %d", e.Version)
+	}
+	if e.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported cipher %q", e.Cipher)
+	}
+	if e.KDF.KeyLen != 32 {
+		return nil, fmt.Errorf("unexpected key length %d for %s", e.KDF.KeyLen, e.Cipher)
+	}
+
+	key, err := e.KDF.DeriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(key)
+
+	aead, err := newSecretAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce length")
+	}
+
+	secret, err := aead.Open(nil, e.Nonce, e.Ciphertext, e.additionalData())
+	if err != nil {
+		return nil, errors.New("wrong password or corrupted export")
+	}
+
+	return secret, nil
+}
+
+// additionalData binds the format version and cipher to the ciphertext
+func (e *EncryptedSecret) additionalData() []byte {
+	return []byte(fmt.Sprintf("hdwallet-encrypted-secret/v%d/%s", e.Version, e.Cipher))
+}
+
+func newSecretAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// wipeBytes overwrites secret material with zeros
+func wipeBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
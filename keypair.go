@@ -0,0 +1,157 @@
+package hdwallet
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Curve names the signature scheme of a KeyPair
+type Curve string
+
+const (
+	CurveSecp256k1 Curve = "secp256k1"
+	CurveEd25519   Curve = "ed25519"
+	CurveSr25519   Curve = "sr25519"
+	CurveBLS12381  Curve = "bls12-381"
+)
+
+// KeyPair is a private key of any supported curve with its public key, letting
+// multi-chain code sign and verify without switching on the curve
+//
+// Sign takes the message itself; each curve hashes it as its scheme defines:
+//
+//	secp256k1  ECDSA over SHA-256(message), 64-byte low-S r || s
+//	ed25519    RFC 8032 Ed25519, 64 bytes
+//	sr25519    Schnorrkel in the Substrate signing context, 64 bytes
+//	bls12-381  Ethereum proof-of-possession scheme, 96-byte compressed G2 point
+//
+// Chains that sign their own digests (Keccak-256 transactions, for example) keep
+// using Signer. NewSecp256k1KeyPair and NewEd25519KeyPair build KeyPairs here,
+// the sr25519 and bls packages return theirs from their key types
+type KeyPair interface {
+	// Curve returns the curve tag of the key
+	Curve() Curve
+	// PublicKeyBytes returns the public key in the chain's usual encoding:
+	// 33-byte compressed secp256k1, 32-byte ed25519 and sr25519, 48-byte BLS G1
+	PublicKeyBytes() []byte
+	// PrivateKeyBytes returns the 32-byte secret: the secp256k1 and BLS scalars,
+	// the ed25519 seed and the sr25519 secret scalar
+	PrivateKeyBytes() []byte
+	// Sign signs message
+	Sign(message []byte) ([]byte, error)
+	// Verify reports whether signature is a valid signature of message by the key
+	Verify(message, signature []byte) bool
+}
+
+// secp256k1KeyPair is the KeyPair of a secp256k1 PrivateKey
+type secp256k1KeyPair struct {
+	key *PrivateKey
+}
+
+// NewSecp256k1KeyPair returns the KeyPair of key
+func NewSecp256k1KeyPair(key *PrivateKey) KeyPair {
+	return secp256k1KeyPair{key: key}
+}
+
+// Curve implements KeyPair
+func (k secp256k1KeyPair) Curve() Curve {
+	return CurveSecp256k1
+}
+
+// PublicKeyBytes implements KeyPair
+func (k secp256k1KeyPair) PublicKeyBytes() []byte {
+	return k.key.PublicKey().SerializeCompressed()
+}
+
+// PrivateKeyBytes implements KeyPair
+func (k secp256k1KeyPair) PrivateKeyBytes() []byte {
+	return k.key.Bytes()
+}
+
+// Sign implements KeyPair
+func (k secp256k1KeyPair) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	signature, err := k.key.Sign(digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return signature.Compact(), nil
+}
+
+// Verify implements KeyPair
+func (k secp256k1KeyPair) Verify(message, signature []byte) bool {
+	if len(signature) != 64 {
+		return false
+	}
+	parsed, err := ParseCompactSignature(signature)
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256(message)
+
+	return k.key.PublicKey().Verify(digest[:], parsed)
+}
+
+// ed25519KeyPair is the KeyPair of an ed25519 private key
+type ed25519KeyPair struct {
+	key ed25519.PrivateKey
+}
+
+// NewEd25519KeyPair returns the KeyPair of key, for example the PrivateKey of a
+// SLIP-10 solana.Key
+func NewEd25519KeyPair(key ed25519.PrivateKey) (KeyPair, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("ed25519 private key has %d bytes, expected %d", len(key), ed25519.PrivateKeySize)
+	}
+
+	return ed25519KeyPair{key: key}, nil
+}
+
+// Curve implements KeyPair
+func (k ed25519KeyPair) Curve() Curve {
+	return CurveEd25519
+}
+
+// PublicKeyBytes implements KeyPair
+func (k ed25519KeyPair) PublicKeyBytes() []byte {
+	return append([]byte(nil), k.key.Public().(ed25519.PublicKey)...)
+}
+
+// PrivateKeyBytes implements KeyPair
+func (k ed25519KeyPair) PrivateKeyBytes() []byte {
+	return k.key.Seed()
+}
+
+// Sign implements KeyPair
+func (k ed25519KeyPair) Sign(message []byte) ([]byte, error) {
+	return ed25519.Sign(k.key, message), nil
+}
+
+// Verify implements KeyPair
+func (k ed25519KeyPair) Verify(message, signature []byte) bool {
+	return ed25519.Verify(k.key.Public().(ed25519.PublicKey), message, signature)
+}
+
+// KeyPairFromBytes parses a 32-byte secret of curve, as returned by PrivateKeyBytes,
+// for the curves implemented in this package; sr25519 and BLS keys come from their packages
+func KeyPairFromBytes(curve Curve, secret []byte) (KeyPair, error) {
+	switch curve {
+	case CurveSecp256k1:
+		key, err := PrivateKeyFromBytes(secret)
+		if err != nil {
+			return nil, err
+		}
+		return NewSecp256k1KeyPair(key), nil
+	case CurveEd25519:
+		if len(secret) != ed25519.SeedSize {
+			return nil, fmt.Errorf("ed25519 seed has %d bytes, expected %d", len(secret), ed25519.SeedSize)
+		}
+		return NewEd25519KeyPair(ed25519.NewKeyFromSeed(secret))
+	case CurveSr25519, CurveBLS12381:
+		return nil, fmt.Errorf("%s keys are created by their package", curve)
+	default:
+		return nil, fmt.Errorf("unknown curve %q", curve)
+	}
+}
@@ -1,7 +1,9 @@
 package hdwallet
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/tyler-smith/go-bip32"
@@ -24,8 +26,11 @@ import (
 // - account: Account index (usually 0 for first account)
 // - chain: 0 for external chain (receiving), 1 for internal chain (change)
 // - address: Address index (0, 1, 2, ... for sequential addresses)
-func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uint32) (*secp256k1.PrivateKey,
-	*secp256k1.PublicKey, error) {
+//
+// Package functions are not reported to an Auditor nor checked by a Policy; derive
+// keys through a Wallet created with WithAuditor and WithPolicy when they must be
+func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uint32) (*PrivateKey,
+	*PublicKey, error) {
 
 	// Step 1: Validate mnemonic phrase integrity
 	// Comprehensive BIP39 validation includes:
@@ -47,9 +52,39 @@ func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uin
 	//
 	// The empty passphrase ("") is standard for most wallet implementations
 	// Using a passphrase creates a completely different wallet tree
-	seed := bip39.NewSeed(mnemonic, "")
+	//
+	// The stretch is delegated to DefaultSeedKDF, see SetDefaultSeedKDF
+	seed, err := MnemonicToSeed(context.Background(), mnemonic, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipeBytes(seed)
+
+	return GenerateKeysFromSeed(seed, coin, account, chain, address)
+}
+
+// GenerateKeysFromSeed converts a BIP39 seed into secp256k1 private/public key pair
+// using the same BIP44 derivation parameters as GenerateKeysFromMnemonic
+//
+// It is useful when the seed is not kept as a mnemonic, for example when it is
+// stored sealed by a hardware module and only released for the duration of a derivation
+//
+// Parameters:
+// - seed: BIP39 seed (usually 64 bytes produced by bip39.NewSeed)
+// - coin, account, chain, address: BIP44 path levels, see GenerateKeysFromMnemonic
+func GenerateKeysFromSeed(seed []byte, coin, account, chain, address uint32) (*PrivateKey,
+	*PublicKey, error) {
+	start := time.Now()
+	privateKey, publicKey, err := generateKeysFromSeed(seed, coin, account, chain, address)
+	observe(DefaultMetrics(), MetricDeriveKey, coinLabel(coin), start, err)
+
+	return privateKey, publicKey, err
+}
+
+func generateKeysFromSeed(seed []byte, coin, account, chain, address uint32) (*PrivateKey,
+	*PublicKey, error) {
 
-	// Step 3: Generate BIP32 master key from seed
+	// Step 1: Generate BIP32 master key from seed
 	// Creates the root node of the hierarchical deterministic key tree
 	// Master key structure:
 	// - Private key: 32 bytes of key material
@@ -62,7 +97,7 @@ func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uin
 		return nil, nil, err
 	}
 
-	// Step 4: Derive specific key using flexible BIP44 path
+	// Step 2: Derive specific key using flexible BIP44 path
 	// Full derivation path: m/44'/coin'/account'/chain/address
 	//
 	// IMPORTANT: The caller must provide properly formatted parameters:
@@ -87,7 +122,7 @@ func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uin
 		return nil, nil, err
 	}
 
-	// Step 5: Convert BIP32 key to secp256k1 cryptographic key pair
+	// Step 3: Convert BIP32 key to secp256k1 cryptographic key pair
 	// secp256k1 elliptic curve is used by most major cryptocurrencies
 	// Curve properties:
 	// - Prime field: p = 2^256 - 2^32 - 2^9 - 2^8 - 2^7 - 2^6 - 2^4 - 1
@@ -97,7 +132,7 @@ func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uin
 	// Create secp256k1 private key from the 32-byte BIP32 key material
 	// The private key must be in range [1, n-1] where n is the curve order
 	// This is virtually guaranteed with proper entropy but should be validated in production
-	privateKey := secp256k1.PrivKeyFromBytes(key.Key)
+	privateKey := PrivateKeyFromSecp256k1(secp256k1.PrivKeyFromBytes(key.Key))
 
 	// Derive the corresponding public key using elliptic curve point multiplication
 	// Public key = private key × generator point G
@@ -105,7 +140,7 @@ func GenerateKeysFromMnemonic(mnemonic string, coin, account, chain, address uin
 	// - Computationally easy: private key → public key
 	// - Computationally infeasible: public key → private key (discrete logarithm problem)
 	// - Deterministic: same private key always produces same public key
-	publicKey := privateKey.PubKey()
+	publicKey := privateKey.PublicKey()
 
 	// Return the cryptographic key pair
 	// Applications can use these keys for:
@@ -0,0 +1,368 @@
+package hdwallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// keystoreVersion is the Web3 Secret Storage version written and read
+const keystoreVersion = 3
+
+// ErrKeystorePassword is returned when a keystore MAC does not match, which is a
+// wrong password far more often than a corrupted file
+var ErrKeystorePassword = errors.New("wrong keystore password")
+
+// KeystoreV3 is an Ethereum keystore (Web3 Secret Storage V3) file
+//
+// Keystores are written with scrypt or PBKDF2 for interoperability with other
+// wallets, or with Argon2id ("kdf": "argon2id", kdfparams t, m, p, dklen and salt),
+// which only this package and tools following the same convention can read
+type KeystoreV3 struct {
+	Version int            `json:"version"`
+	ID      string         `json:"id"`
+	Address string         `json:"address"`
+	Crypto  KeystoreCrypto `json:"crypto"`
+}
+
+// KeystoreCrypto is the encrypted key of a KeystoreV3 with its KDF parameters
+type KeystoreCrypto struct {
+	// Cipher is "aes-128-ctr", keyed with the first half of the derived key
+	Cipher     string
+	IV         []byte
+	Ciphertext []byte
+	KDF        KDFParams
+	// MAC is Keccak-256 of the second half of the derived key and the ciphertext
+	MAC []byte
+}
+
+// keystoreCryptoJSON is the wire form of KeystoreCrypto
+type keystoreCryptoJSON struct {
+	Cipher       string `json:"cipher"`
+	CipherParams struct {
+		IV string `json:"iv"`
+	} `json:"cipherparams"`
+	Ciphertext string            `json:"ciphertext"`
+	KDF        KDFName           `json:"kdf"`
+	KDFParams  keystoreKDFParams `json:"kdfparams"`
+	MAC        string            `json:"mac"`
+}
+
+// keystoreKDFParams holds the kdfparams of every supported KDF, with the field names
+// of the keystore format
+type keystoreKDFParams struct {
+	DKLen uint32 `json:"dklen"`
+	Salt  string `json:"salt"`
+	// scrypt
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	// P is the scrypt parallelism and the Argon2id threads
+	P int `json:"p,omitempty"`
+	// pbkdf2
+	C   uint32 `json:"c,omitempty"`
+	PRF string `json:"prf,omitempty"`
+	// argon2id
+	T uint32 `json:"t,omitempty"`
+	M uint32 `json:"m,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler with the keystore field names and hex encoding
+func (c KeystoreCrypto) MarshalJSON() ([]byte, error) {
+	wire := keystoreCryptoJSON{
+		Cipher:     c.Cipher,
+		Ciphertext: hex.EncodeToString(c.Ciphertext),
+		KDF:        c.KDF.Name,
+		MAC:        hex.EncodeToString(c.MAC),
+	}
+	wire.CipherParams.IV = hex.EncodeToString(c.IV)
+
+	params := keystoreKDFParams{DKLen: c.KDF.KeyLen, Salt: hex.EncodeToString(c.KDF.Salt)}
+	switch c.KDF.Name {
+	case KDFScrypt:
+		params.N, params.R, params.P = c.KDF.N, c.KDF.R, c.KDF.P
+	case KDFPBKDF2:
+		params.C, params.PRF = c.KDF.Iterations, c.KDF.PRF
+	case KDFArgon2id:
+		params.T, params.M, params.P = c.KDF.Time, c.KDF.Memory, int(c.KDF.Threads)
+	default:
+		return nil, fmt.Errorf("unknown kdf %q", c.KDF.Name)
+	}
+	wire.KDFParams = params
+
+	return json.Marshal(wire)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (c *KeystoreCrypto) UnmarshalJSON(data []byte) error {
+	var wire keystoreCryptoJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	var decoded KeystoreCrypto
+	var err error
+	decoded.Cipher = wire.Cipher
+	if decoded.IV, err = hex.DecodeString(wire.CipherParams.IV); err != nil {
+		return fmt.Errorf("decode keystore iv: %w", err)
+	}
+	if decoded.Ciphertext, err = hex.DecodeString(wire.Ciphertext); err != nil {
+		return fmt.Errorf("decode keystore ciphertext: %w", err)
+	}
+	if decoded.MAC, err = hex.DecodeString(wire.MAC); err != nil {
+		return fmt.Errorf("decode keystore mac: %w", err)
+	}
+
+	params := wire.KDFParams
+	decoded.KDF = KDFParams{Name: wire.KDF, KeyLen: params.DKLen}
+	if decoded.KDF.Salt, err = hex.DecodeString(params.Salt); err != nil {
+		return fmt.Errorf("decode keystore salt: %w", err)
+	}
+	switch wire.KDF {
+	case KDFScrypt:
+		decoded.KDF.N, decoded.KDF.R, decoded.KDF.P = params.N, params.R, params.P
+	case KDFPBKDF2:
+		decoded.KDF.Iterations, decoded.KDF.PRF = params.C, params.PRF
+	case KDFArgon2id:
+		if params.P < 1 || params.P > 255 {
+			return fmt.Errorf("argon2id threads %d out of range", params.P)
+		}
+		decoded.KDF.Time, decoded.KDF.Memory, decoded.KDF.Threads = params.T, params.M, uint8(params.P)
+	default:
+		return fmt.Errorf("unknown kdf %q", wire.KDF)
+	}
+
+	*c = decoded
+
+	return nil
+}
+
+// EncryptKeystore encrypts key into a keystore file under a key stretched from
+// password with params; a random salt is generated when params has none
+// DefaultScryptParams gives files every Ethereum wallet imports
+func EncryptKeystore(key *PrivateKey, password []byte, params KDFParams) (*KeystoreV3, error) {
+	params, err := params.withSalt()
+	if err != nil {
+		return nil, err
+	}
+	params.KeyLen = 32
+
+	derived, err := params.DeriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(derived)
+
+	iv := make([]byte, aes.BlockSize)
+	if err = readEntropy(DefaultEntropySource(), iv); err != nil {
+		return nil, err
+	}
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	secret := key.Bytes()
+	defer wipeBytes(secret)
+	ciphertext, err := keystoreCTR(derived[:16], iv, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeystoreV3{
+		Version: keystoreVersion,
+		ID:      id,
+		Address: keystoreAddress(GenerateEthereumAddress(key.PublicKey())),
+		Crypto: KeystoreCrypto{
+			Cipher:     "aes-128-ctr",
+			IV:         iv,
+			Ciphertext: ciphertext,
+			KDF:        params,
+			MAC:        keccak256(derived[16:32], ciphertext),
+		},
+	}, nil
+}
+
+// Decrypt returns the private key of the keystore, failing with ErrKeystorePassword
+// when the MAC does not match. The KDF parameters are bounded (see KDFParams.Validate),
+// so untrusted files cannot demand unbounded memory or time
+func (k *KeystoreV3) Decrypt(password []byte) (*PrivateKey, error) {
+	if k.Version != keystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported keystore cipher %q", k.Crypto.Cipher)
+	}
+	if len(k.Crypto.IV) != aes.BlockSize {
+		return nil, errors.New("invalid keystore iv length")
+	}
+	if k.Crypto.KDF.KeyLen < 32 {
+		return nil, fmt.Errorf("keystore key length %d is below 32", k.Crypto.KDF.KeyLen)
+	}
+
+	derived, err := k.Crypto.KDF.DeriveKey(password)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(derived)
+
+	if subtle.ConstantTimeCompare(keccak256(derived[16:32], k.Crypto.Ciphertext), k.Crypto.MAC) != 1 {
+		return nil, ErrKeystorePassword
+	}
+
+	secret, err := keystoreCTR(derived[:16], k.Crypto.IV, k.Crypto.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(secret)
+
+	key, err := PrivateKeyFromBytes(secret)
+	if err != nil {
+		return nil, fmt.Errorf("keystore key: %w", err)
+	}
+	if k.Address != "" && keystoreAddress(k.Address) != keystoreAddress(GenerateEthereumAddress(key.PublicKey())) {
+		key.Zero()
+		return nil, fmt.Errorf("keystore key does not match address %s", k.Address)
+	}
+
+	return key, nil
+}
+
+// keystoreCTR encrypts or decrypts data with AES-128-CTR
+func keystoreCTR(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(out, data)
+
+	return out, nil
+}
+
+// keystoreAddress returns the lower case hex form of an address without 0x, as keystores store it
+func keystoreAddress(address string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(address, "0x"), "0X"))
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	id := make([]byte, 16)
+	if err := readEntropy(DefaultEntropySource(), id); err != nil {
+		return "", err
+	}
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]), nil
+}
+
+// MemoryKeystore holds private keys in process memory, each encrypted as a keystore
+// under its own password, so a memory dump of an idle signer reveals no key
+//
+// Keys are stretched with the KDF parameters given to NewMemoryKeystore; imported
+// keystores keep their own parameters. A MemoryKeystore is safe for concurrent use
+type MemoryKeystore struct {
+	mu     sync.RWMutex
+	params KDFParams
+	keys   map[string]*KeystoreV3
+}
+
+// NewMemoryKeystore returns an empty keystore encrypting new keys with params, for
+// example DefaultArgon2idParams
+func NewMemoryKeystore(params KDFParams) *MemoryKeystore {
+	params.Salt = nil
+
+	return &MemoryKeystore{params: params, keys: make(map[string]*KeystoreV3)}
+}
+
+// Add encrypts key under password and returns its Ethereum address
+func (s *MemoryKeystore) Add(key *PrivateKey, password []byte) (string, error) {
+	keystore, err := EncryptKeystore(key, password, s.params)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[keystore.Address] = keystore
+
+	return GenerateEthereumAddress(key.PublicKey()), nil
+}
+
+// Import adds a keystore file, replacing the key of the same address
+func (s *MemoryKeystore) Import(keystore *KeystoreV3) error {
+	if keystore.Version != keystoreVersion {
+		return fmt.Errorf("unsupported keystore version %d", keystore.Version)
+	}
+	if keystore.Address == "" {
+		return errors.New("keystore has no address")
+	}
+	if err := keystore.Crypto.KDF.Validate(); err != nil {
+		return err
+	}
+
+	imported := *keystore
+	imported.Address = keystoreAddress(keystore.Address)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[imported.Address] = &imported
+
+	return nil
+}
+
+// Export returns the keystore file of address
+func (s *MemoryKeystore) Export(address string) (*KeystoreV3, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keystore, ok := s.keys[keystoreAddress(address)]
+	if !ok {
+		return nil, false
+	}
+	exported := *keystore
+
+	return &exported, true
+}
+
+// Key decrypts the key of address with password; the caller should Zero it after use
+func (s *MemoryKeystore) Key(address string, password []byte) (*PrivateKey, error) {
+	keystore, ok := s.Export(address)
+	if !ok {
+		return nil, fmt.Errorf("no key for address %s", address)
+	}
+
+	return keystore.Decrypt(password)
+}
+
+// Remove deletes the key of address
+func (s *MemoryKeystore) Remove(address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, keystoreAddress(address))
+}
+
+// Addresses returns the EIP-55 addresses of the keys held, sorted
+func (s *MemoryKeystore) Addresses() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addresses := make([]string, 0, len(s.keys))
+	for address := range s.keys {
+		if raw, err := hex.DecodeString(address); err == nil && len(raw) == 20 {
+			address = ChecksumEthereumAddress(raw)
+		}
+		addresses = append(addresses, address)
+	}
+	slices.Sort(addresses)
+
+	return addresses
+}
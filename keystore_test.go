@@ -0,0 +1,188 @@
+package hdwallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// Test vectors of the Web3 Secret Storage Definition, password "testpassword"
+const (
+	keystorePBKDF2 = `{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+			"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+			"kdf": "pbkdf2",
+			"kdfparams": {
+				"c": 262144,
+				"dklen": 32,
+				"prf": "hmac-sha256",
+				"salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+			},
+			"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`
+	keystoreScrypt = `{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"},
+			"ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+			"kdf": "scrypt",
+			"kdfparams": {
+				"dklen": 32,
+				"n": 262144,
+				"p": 8,
+				"r": 1,
+				"salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+			},
+			"mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`
+	keystoreVectorKey = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+)
+
+// testArgon2idParams are cheap Argon2id costs for round trips
+func testArgon2idParams() KDFParams {
+	return KDFParams{Name: KDFArgon2id, KeyLen: 32, Time: 1, Memory: 64, Threads: 1}
+}
+
+func TestKeystoreV3Vectors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		keystore string
+	}{
+		{"pbkdf2", keystorePBKDF2},
+		{"scrypt", keystoreScrypt},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var keystore KeystoreV3
+			if err := json.Unmarshal([]byte(test.keystore), &keystore); err != nil {
+				t.Fatal(err)
+			}
+
+			key, err := keystore.Decrypt([]byte("testpassword"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(key.Bytes()); got != keystoreVectorKey {
+				t.Errorf("Decrypt = %s, want %s", got, keystoreVectorKey)
+			}
+
+			if _, err = keystore.Decrypt([]byte("wrongpassword")); !errors.Is(err, ErrKeystorePassword) {
+				t.Errorf("Decrypt with a wrong password = %v, want %v", err, ErrKeystorePassword)
+			}
+		})
+	}
+}
+
+func TestKeystoreV3Argon2id(t *testing.T) {
+	key, err := PrivateKeyFromBytes(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keystore, err := EncryptKeystore(key, []byte("correct horse"), testArgon2idParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded, err := json.Marshal(keystore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded KeystoreV3
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Crypto.KDF.Name != KDFArgon2id || decoded.Crypto.KDF.Memory != 64 {
+		t.Errorf("decoded KDF = %+v", decoded.Crypto.KDF)
+	}
+
+	decrypted, err := decoded.Decrypt([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), key.Bytes()) {
+		t.Error("Decrypt returned another key")
+	}
+	if _, err = decoded.Decrypt([]byte("battery staple")); !errors.Is(err, ErrKeystorePassword) {
+		t.Errorf("Decrypt with a wrong password = %v, want %v", err, ErrKeystorePassword)
+	}
+}
+
+func TestMemoryKeystore(t *testing.T) {
+	store := NewMemoryKeystore(testArgon2idParams())
+	key, err := PrivateKeyFromBytes(bytes.Repeat([]byte{0x24}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := store.Add(key, []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address != GenerateEthereumAddress(key.PublicKey()) {
+		t.Errorf("Add = %s, want %s", address, GenerateEthereumAddress(key.PublicKey()))
+	}
+
+	exported, ok := store.Export(address)
+	if !ok {
+		t.Fatal("Export found no keystore")
+	}
+	other := NewMemoryKeystore(testArgon2idParams())
+	if err = other.Import(exported); err != nil {
+		t.Fatal(err)
+	}
+	got, err := other.Key(address, []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), key.Bytes()) {
+		t.Error("Key returned another key")
+	}
+
+	store.Remove(address)
+	if len(store.Addresses()) != 0 {
+		t.Errorf("Addresses after Remove = %v", store.Addresses())
+	}
+}
+
+func TestKDFParamsValidate(t *testing.T) {
+	salt := make([]byte, 16)
+	withSalt := func(params KDFParams) KDFParams {
+		params.Salt = salt
+		return params
+	}
+
+	for _, test := range []struct {
+		name   string
+		params KDFParams
+		valid  bool
+	}{
+		{"argon2id default", withSalt(DefaultArgon2idParams()), true},
+		{"scrypt default", withSalt(DefaultScryptParams()), true},
+		{"pbkdf2 default", withSalt(DefaultPBKDF2Params()), true},
+		{"argon2id 1 GiB", KDFParams{Name: KDFArgon2id, Salt: salt, KeyLen: 32, Time: 1, Memory: 1 << 20, Threads: 4}, true},
+		{"argon2id over 1 GiB", KDFParams{Name: KDFArgon2id, Salt: salt, KeyLen: 32, Time: 1, Memory: 1<<20 + 1, Threads: 4}, false},
+		{"argon2id 4 GiB", KDFParams{Name: KDFArgon2id, Salt: salt, KeyLen: 32, Time: 1, Memory: 4 << 20, Threads: 4}, false},
+		{"argon2id no threads", KDFParams{Name: KDFArgon2id, Salt: salt, KeyLen: 32, Time: 1, Memory: 64}, false},
+		{"scrypt 1 GiB", KDFParams{Name: KDFScrypt, Salt: salt, KeyLen: 32, N: 1 << 20, R: 8, P: 1}, true},
+		{"scrypt over 1 GiB", KDFParams{Name: KDFScrypt, Salt: salt, KeyLen: 32, N: 1 << 21, R: 8, P: 1}, false},
+		{"scrypt N not a power of two", KDFParams{Name: KDFScrypt, Salt: salt, KeyLen: 32, N: 3 << 10, R: 8, P: 1}, false},
+		{"pbkdf2 unknown prf", KDFParams{Name: KDFPBKDF2, Salt: salt, KeyLen: 32, Iterations: 1000, PRF: "hmac-md5"}, false},
+		{"short salt", KDFParams{Name: KDFPBKDF2, Salt: salt[:8], KeyLen: 32, Iterations: 1000, PRF: "hmac-sha256"}, false},
+		{"unknown kdf", KDFParams{Name: "bcrypt", Salt: salt, KeyLen: 32}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.params.Validate(); (err == nil) != test.valid {
+				t.Errorf("Validate = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// Package libsecp256k1 is an hdwallet.Secp256k1Backend built on bitcoin-core's
+// libsecp256k1, for high-volume signing and verification services where cgo is
+// acceptable; the pure Go backend stays the default.
+//
+// Install replaces the default backend, after which hdwallet.PrivateKey,
+// hdwallet.PublicKey, KeySigner and VerifyDigest use libsecp256k1. Signatures are
+// identical to those of the pure Go backend (RFC 6979 nonces, low S), so both can be
+// mixed freely across a fleet.
+//
+// The library must be built with the recovery and ECDH modules
+// (./configure --enable-module-recovery --enable-module-ecdh) and be found by
+// pkg-config. The backend uses cgo, therefore it is only compiled with the
+// libsecp256k1 build tag:
+//
+//	go build -tags libsecp256k1
+package libsecp256k1
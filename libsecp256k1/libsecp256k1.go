@@ -0,0 +1,154 @@
+//go:build libsecp256k1
+
+package libsecp256k1
+
+/*
+#cgo pkg-config: libsecp256k1
+#include <secp256k1.h>
+#include <secp256k1_ecdh.h>
+#include <secp256k1_recovery.h>
+*/
+import "C"
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// context is shared by every operation; libsecp256k1 contexts are safe for
+// concurrent use once created and randomized
+var context = newContext()
+
+func newContext() *C.secp256k1_context {
+	ctx := C.secp256k1_context_create(C.SECP256K1_CONTEXT_NONE)
+
+	// Randomization blinds signing against side channels, failing to seed it is not fatal
+	var seed [32]byte
+	if _, err := rand.Read(seed[:]); err == nil {
+		C.secp256k1_context_randomize(ctx, cBytes(seed[:]))
+	}
+
+	return ctx
+}
+
+// Backend is the libsecp256k1 hdwallet.Secp256k1Backend
+type Backend struct{}
+
+var _ hdwallet.Secp256k1Backend = Backend{}
+
+// Install makes Backend the default hdwallet.Secp256k1Backend
+func Install() {
+	hdwallet.SetDefaultSecp256k1Backend(Backend{})
+}
+
+// Sign implements hdwallet.Secp256k1Backend
+func (Backend) Sign(key *hdwallet.PrivateKey, digest []byte) (*hdwallet.Signature, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	secretKey := key.Bytes()
+	defer clear(secretKey)
+
+	// A nil nonce function selects RFC 6979, as the pure Go backend uses
+	var signature C.secp256k1_ecdsa_recoverable_signature
+	if C.secp256k1_ecdsa_sign_recoverable(context, &signature, cBytes(digest), cBytes(secretKey), nil, nil) != 1 {
+		return nil, errors.New("libsecp256k1: signing failed")
+	}
+
+	var compact [64]byte
+	var recoveryID C.int
+	C.secp256k1_ecdsa_recoverable_signature_serialize_compact(context, cBytes(compact[:]), &recoveryID, &signature)
+
+	return hdwallet.NewSignature([32]byte(compact[:32]), [32]byte(compact[32:]), int(recoveryID))
+}
+
+// Verify implements hdwallet.Secp256k1Backend
+// High-S signatures are normalized first, matching the pure Go backend
+func (Backend) Verify(publicKey *hdwallet.PublicKey, digest []byte, signature *hdwallet.Signature) bool {
+	if len(digest) != 32 {
+		return false
+	}
+
+	pubkey, err := parsePublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+	var parsed C.secp256k1_ecdsa_signature
+	if C.secp256k1_ecdsa_signature_parse_compact(context, &parsed, cBytes(signature.Compact())) != 1 {
+		return false
+	}
+	C.secp256k1_ecdsa_signature_normalize(context, &parsed, &parsed)
+
+	return C.secp256k1_ecdsa_verify(context, &parsed, cBytes(digest), &pubkey) == 1
+}
+
+// Recover implements hdwallet.Secp256k1Backend
+func (Backend) Recover(digest []byte, signature *hdwallet.Signature) (*hdwallet.PublicKey, error) {
+	recoveryID, ok := signature.RecoveryID()
+	if !ok {
+		return nil, errors.New("signature has no recovery ID")
+	}
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	var parsed C.secp256k1_ecdsa_recoverable_signature
+	if C.secp256k1_ecdsa_recoverable_signature_parse_compact(context, &parsed, cBytes(signature.Compact()), C.int(recoveryID)) != 1 {
+		return nil, errors.New("libsecp256k1: invalid recoverable signature")
+	}
+	var pubkey C.secp256k1_pubkey
+	if C.secp256k1_ecdsa_recover(context, &pubkey, &parsed, cBytes(digest)) != 1 {
+		return nil, errors.New("libsecp256k1: public key recovery failed")
+	}
+
+	return serializePublicKey(&pubkey)
+}
+
+// ECDH implements hdwallet.Secp256k1Backend
+// A nil hash function selects SHA-256 of the compressed shared point
+func (Backend) ECDH(key *hdwallet.PrivateKey, publicKey *hdwallet.PublicKey) ([]byte, error) {
+	pubkey, err := parsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	secretKey := key.Bytes()
+	defer clear(secretKey)
+
+	secret := make([]byte, 32)
+	if C.secp256k1_ecdh(context, cBytes(secret), &pubkey, cBytes(secretKey), nil, nil) != 1 {
+		return nil, errors.New("libsecp256k1: ECDH failed")
+	}
+
+	return secret, nil
+}
+
+// parsePublicKey converts publicKey to the libsecp256k1 representation
+func parsePublicKey(publicKey *hdwallet.PublicKey) (C.secp256k1_pubkey, error) {
+	var pubkey C.secp256k1_pubkey
+	serialized := publicKey.SerializeCompressed()
+	if C.secp256k1_ec_pubkey_parse(context, &pubkey, cBytes(serialized), C.size_t(len(serialized))) != 1 {
+		return pubkey, errors.New("libsecp256k1: invalid public key")
+	}
+
+	return pubkey, nil
+}
+
+// serializePublicKey converts a libsecp256k1 public key to an hdwallet.PublicKey
+func serializePublicKey(pubkey *C.secp256k1_pubkey) (*hdwallet.PublicKey, error) {
+	var serialized [33]byte
+	length := C.size_t(len(serialized))
+	C.secp256k1_ec_pubkey_serialize(context, cBytes(serialized[:]), &length, pubkey, C.SECP256K1_EC_COMPRESSED)
+
+	return hdwallet.PublicKeyFromBytes(serialized[:length])
+}
+
+// cBytes passes a non-empty Go byte slice to C; the slice holds no Go pointers
+func cBytes(data []byte) *C.uchar {
+	return (*C.uchar)(unsafe.Pointer(&data[0]))
+}
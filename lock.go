@@ -0,0 +1,177 @@
+package hdwallet
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+)
+
+var (
+	// ErrLocked is returned by every key derivation of a locked Wallet
+	ErrLocked = errors.New("wallet is locked")
+	// ErrPassphraseMismatch is returned by Unlock when the passphrase yields another
+	// master key than the one expected with WithMasterFingerprint
+	ErrPassphraseMismatch = errors.New("passphrase does not match the wallet")
+	// ErrNoMnemonic is returned by Unlock for wallets created from a seed, which keep
+	// no mnemonic to derive the master key from again once locked
+	ErrNoMnemonic = errors.New("wallet has no mnemonic to unlock from")
+)
+
+// WithLockTimeout locks the wallet after it has not derived a key for timeout
+func WithLockTimeout(timeout time.Duration) WalletOption {
+	return func(w *Wallet) {
+		w.lockTimeout = timeout
+	}
+}
+
+// WithMasterFingerprint makes Unlock reject passphrases whose master key does not have
+// the hex BIP32 fingerprint (as in Manifest.MasterFingerprint); a mistyped BIP39
+// passphrase otherwise silently opens an empty wallet
+func WithMasterFingerprint(fingerprint string) WalletOption {
+	return func(w *Wallet) {
+		w.fingerprint = fingerprint
+	}
+}
+
+// NewLockedWallet creates a locked Wallet for coin from a BIP39 mnemonic
+//
+// The passphrase is given to Unlock instead, which runs the SeedKDF seed stretch once
+// and caches the master key until Lock is called or the WithLockTimeout idle timeout
+// expires. Derivations fail with ErrLocked while the wallet is locked
+func NewLockedWallet(mnemonic string, coin uint32, opts ...WalletOption) (*Wallet, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("invalid mnemonic")
+	}
+
+	w, err := newWallet(coin, opts)
+	if err != nil {
+		return nil, err
+	}
+	w.mnemonic = []byte(mnemonic)
+
+	return w, nil
+}
+
+// Unlock derives the master key from the mnemonic and passphrase and caches it
+// Unlocking an unlocked wallet replaces its master key and restarts the timeout
+// Wallets created from a seed fail with ErrNoMnemonic
+func (w *Wallet) Unlock(passphrase string) error {
+	return w.UnlockContext(context.Background(), passphrase)
+}
+
+// UnlockContext is Unlock with a context passed to the SeedKDF, which may be remote
+func (w *Wallet) UnlockContext(ctx context.Context, passphrase string) error {
+	w.keyMu.RLock()
+	mnemonic := w.mnemonic
+	w.keyMu.RUnlock()
+	if mnemonic == nil {
+		return ErrNoMnemonic
+	}
+
+	// BIP39: PBKDF2-HMAC-SHA512(mnemonic, "mnemonic" || passphrase, 2048), computed
+	// outside the lock as it is the expensive part
+	seed, err := stretchSeed(ctx, w.seedKDF, mnemonic, []byte(passphrase))
+	if err != nil {
+		return err
+	}
+	defer wipeBytes(seed)
+
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return err
+	}
+	if w.fingerprint != "" && fingerprint(masterKey.PublicKey().Key) != w.fingerprint {
+		wipeKey(masterKey)
+		return ErrPassphraseMismatch
+	}
+
+	w.setMasterKey(masterKey)
+
+	return nil
+}
+
+// Lock wipes the cached master key; derivations fail with ErrLocked until the next Unlock
+func (w *Wallet) Lock() {
+	w.keyMu.Lock()
+	defer w.keyMu.Unlock()
+
+	w.lock()
+}
+
+// Locked reports whether the wallet is locked
+func (w *Wallet) Locked() bool {
+	w.keyMu.RLock()
+	defer w.keyMu.RUnlock()
+
+	return w.masterKey == nil
+}
+
+// setMasterKey installs masterKey and starts a new lock session
+func (w *Wallet) setMasterKey(masterKey *bip32.Key) {
+	w.keyMu.Lock()
+	defer w.keyMu.Unlock()
+
+	w.lock()
+	w.masterKey = masterKey
+	w.lastUsed.Store(time.Now().UnixNano())
+	if w.lockTimeout > 0 {
+		w.scheduleLock(w.session, w.lockTimeout)
+	}
+}
+
+// lock wipes the master key and ends the current session, w.keyMu must be held
+func (w *Wallet) lock() {
+	if w.masterKey != nil {
+		wipeKey(w.masterKey)
+		w.masterKey = nil
+	}
+	if w.lockTimer != nil {
+		w.lockTimer.Stop()
+		w.lockTimer = nil
+	}
+	// Timers of the ended session that already fired become no-ops
+	w.session++
+}
+
+// scheduleLock locks the wallet after delay unless it was used in the meantime,
+// w.keyMu must be held
+func (w *Wallet) scheduleLock(session uint64, delay time.Duration) {
+	w.lockTimer = time.AfterFunc(delay, func() {
+		w.keyMu.Lock()
+		defer w.keyMu.Unlock()
+
+		if w.session != session {
+			return
+		}
+		idle := time.Since(time.Unix(0, w.lastUsed.Load()))
+		if idle < w.lockTimeout {
+			w.scheduleLock(session, w.lockTimeout-idle)
+			return
+		}
+		w.lock()
+	})
+}
+
+// withMasterKey calls fn with the master key, which stays valid until fn returns,
+// and records the use for the idle timeout
+func (w *Wallet) withMasterKey(fn func(masterKey *bip32.Key) error) error {
+	w.keyMu.RLock()
+	defer w.keyMu.RUnlock()
+
+	if w.masterKey == nil {
+		return ErrLocked
+	}
+	// Recorded once derivation is done, so slow derivations do not count as idle time
+	defer func() { w.lastUsed.Store(time.Now().UnixNano()) }()
+
+	return fn(w.masterKey)
+}
+
+// wipeKey overwrites the private material of an extended key
+func wipeKey(key *bip32.Key) {
+	wipeBytes(key.Key)
+	wipeBytes(key.ChainCode)
+}
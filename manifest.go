@@ -0,0 +1,240 @@
+package hdwallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/tyler-smith/go-bip32"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Address format names used in export manifests
+const (
+	AddressFormatTron              = "tron-base58check"
+	AddressFormatEthereum          = "ethereum-eip55"
+	AddressFormatBitcoinP2PKH      = "bitcoin-p2pkh"
+	AddressFormatBitcoinP2SHP2WPKH = "bitcoin-p2sh-p2wpkh"
+	AddressFormatBitcoinP2WPKH     = "bitcoin-p2wpkh"
+	AddressFormatBitcoinP2TR       = "bitcoin-p2tr"
+	AddressFormatCosmos            = "cosmos-bech32"
+)
+
+// addressFormats maps manifest format names to their encoders
+var addressFormats = map[string]AddressFormat{
+	AddressFormatTron:              GenerateTronAddress,
+	AddressFormatEthereum:          GenerateEthereumAddress,
+	AddressFormatBitcoinP2PKH:      GenerateBitcoinP2PKHAddress,
+	AddressFormatBitcoinP2SHP2WPKH: GenerateBitcoinP2SHP2WPKHAddress,
+	AddressFormatBitcoinP2WPKH:     GenerateBitcoinP2WPKHAddress,
+	AddressFormatBitcoinP2TR:       GenerateBitcoinP2TRAddress,
+	AddressFormatCosmos:            GenerateCosmosAddress,
+}
+
+// addressOrigin is the coin type and purpose the keys of an address format are derived under
+type addressOrigin struct {
+	coin    uint32
+	purpose uint32
+}
+
+// addressOrigins is the origin of each address format
+var addressOrigins = map[string]addressOrigin{
+	AddressFormatTron:              {coin: 195, purpose: Purpose},
+	AddressFormatEthereum:          {coin: 60, purpose: Purpose},
+	AddressFormatBitcoinP2PKH:      {coin: 0, purpose: Purpose},
+	AddressFormatBitcoinP2SHP2WPKH: {coin: 0, purpose: BIP49Purpose},
+	AddressFormatBitcoinP2WPKH:     {coin: 0, purpose: BIP84Purpose},
+	AddressFormatBitcoinP2TR:       {coin: 0, purpose: BIP86Purpose},
+	AddressFormatCosmos:            {coin: 118, purpose: Purpose},
+}
+
+// defaultAddressFormats is the address format of each supported SLIP-44 coin type
+var defaultAddressFormats = map[uint32]string{
+	0:   AddressFormatBitcoinP2WPKH,
+	60:  AddressFormatEthereum,
+	118: AddressFormatCosmos,
+	195: AddressFormatTron,
+}
+
+// addressFormatFor returns the address format of coin derived under purpose,
+// the default format of coin when purpose is zero
+func addressFormatFor(coin, purpose uint32) (string, error) {
+	if purpose == 0 {
+		if format := defaultAddressFormats[coin]; format != "" {
+			return format, nil
+		}
+		return "", fmt.Errorf("no default address format for coin %d", coin)
+	}
+
+	for format, origin := range addressOrigins {
+		if origin == (addressOrigin{coin: coin, purpose: purpose}) {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("no address format for coin %d under purpose %d'", coin, purpose)
+}
+
+// accountPath returns the path of an account whose addresses are in format
+func accountPath(format string, account uint32) (DerivationPath, error) {
+	origin, ok := addressOrigins[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown address format %q", format)
+	}
+
+	return DerivationPath{origin.purpose + HardenedOffset, origin.coin + HardenedOffset, account + HardenedOffset}, nil
+}
+
+// manifestVersion is the current Manifest format version
+const manifestVersion = 1
+
+// ManifestAccount selects an account to include in an export manifest
+type ManifestAccount struct {
+	Coin    uint32
+	Account uint32
+	// AddressFormat is one of the AddressFormat constants, empty for the default format
+	// of Coin; it sets the purpose of the account path, m/84' for AddressFormatBitcoinP2WPKH
+	AddressFormat string
+}
+
+// Manifest is a watch-only description of the accounts of one seed across coins,
+// suitable for auditors and watch-only infrastructure. It contains no private material
+type Manifest struct {
+	Version int `json:"version"`
+	// MasterFingerprint is the BIP32 fingerprint of the master key, the origin of every path
+	MasterFingerprint string          `json:"master_fingerprint"`
+	CreatedAt         time.Time       `json:"created_at"`
+	Accounts          []ManifestEntry `json:"accounts"`
+}
+
+// ManifestEntry describes a single account of a Manifest
+type ManifestEntry struct {
+	Coin    uint32         `json:"coin"`
+	Account uint32         `json:"account"`
+	Path    DerivationPath `json:"path"`
+	XPub    string         `json:"xpub"`
+	// Fingerprint is the BIP32 fingerprint of the account key,
+	// ParentFingerprint the one of the coin level key it is derived from
+	Fingerprint       string `json:"fingerprint"`
+	ParentFingerprint string `json:"parent_fingerprint"`
+	AddressFormat     string `json:"address_format"`
+	// FirstAddress is the receiving address at index 0, so consumers can check
+	// that they derive addresses from the xpub the same way
+	FirstAddress string `json:"first_address"`
+}
+
+// ExportManifest builds the manifest of the given accounts derived from seed
+// Marshal the result with encoding/json to obtain the manifest document
+func ExportManifest(seed []byte, accounts ...ManifestAccount) (*Manifest, error) {
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Version:           manifestVersion,
+		MasterFingerprint: fingerprint(masterKey.PublicKey().Key),
+		CreatedAt:         time.Now().UTC(),
+		Accounts:          make([]ManifestEntry, 0, len(accounts)),
+	}
+
+	for _, account := range accounts {
+		entry, err := manifestEntry(masterKey, account)
+		if err != nil {
+			return nil, fmt.Errorf("coin %d account %d: %w", account.Coin, account.Account, err)
+		}
+		manifest.Accounts = append(manifest.Accounts, entry)
+	}
+
+	return manifest, nil
+}
+
+func manifestEntry(masterKey *bip32.Key, account ManifestAccount) (ManifestEntry, error) {
+	formatName := account.AddressFormat
+	if formatName == "" {
+		var err error
+		if formatName, err = addressFormatFor(account.Coin, 0); err != nil {
+			return ManifestEntry{}, err
+		}
+	}
+	format, ok := addressFormats[formatName]
+	if !ok {
+		return ManifestEntry{}, fmt.Errorf("unknown address format %q", formatName)
+	}
+	if coin := addressOrigins[formatName].coin; coin != account.Coin {
+		return ManifestEntry{}, fmt.Errorf("address format %s is for coin %d", formatName, coin)
+	}
+
+	path, err := accountPath(formatName, account.Account)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	accountKey, err := DerivePath(masterKey, path)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+	defer wipeKey(accountKey)
+	xpub := accountKey.PublicKey()
+
+	first, err := firstAddress(xpub, format)
+	if err != nil {
+		return ManifestEntry{}, err
+	}
+
+	return ManifestEntry{
+		Coin:              account.Coin,
+		Account:           account.Account,
+		Path:              path,
+		XPub:              xpub.String(),
+		Fingerprint:       fingerprint(xpub.Key),
+		ParentFingerprint: hex.EncodeToString(xpub.FingerPrint),
+		AddressFormat:     formatName,
+		FirstAddress:      first,
+	}, nil
+}
+
+// firstAddress derives the receiving address at index 0 below an account xpub
+func firstAddress(xpub *bip32.Key, format AddressFormat) (string, error) {
+	chainKey, err := xpub.NewChildKey(0)
+	if err != nil {
+		return "", err
+	}
+	child, err := chainKey.NewChildKey(0)
+	if err != nil {
+		return "", err
+	}
+
+	publicKey, err := PublicKeyFromBytes(child.Key)
+	if err != nil {
+		return "", err
+	}
+
+	return format(publicKey), nil
+}
+
+// MasterFingerprint returns the hex BIP32 fingerprint of the master key of seed,
+// as in Manifest.MasterFingerprint
+func MasterFingerprint(seed []byte) (string, error) {
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return "", err
+	}
+
+	return fingerprint(masterKey.PublicKey().Key), nil
+}
+
+// fingerprint returns the hex BIP32 fingerprint of a compressed public key,
+// the first 4 bytes of RIPEMD-160(SHA-256(key))
+func fingerprint(compressedKey []byte) string {
+	return hex.EncodeToString(hash160(compressedKey)[:4])
+}
+
+// hash160 returns RIPEMD-160(SHA-256(data))
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	hash := ripemd160.New()
+	hash.Write(sum[:])
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,97 @@
+package hdwallet
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// MetricOperation is the kind of operation reported to Metrics
+type MetricOperation string
+
+const (
+	MetricGenerateMnemonic MetricOperation = "generate_mnemonic"
+	MetricDeriveKey        MetricOperation = "derive_key"
+	MetricDerivePublicKey  MetricOperation = "derive_public_key"
+	MetricSign             MetricOperation = "sign"
+)
+
+// Observation is a completed operation reported to Metrics
+type Observation struct {
+	Operation MetricOperation
+	// Coin is the SLIP-0044 coin type as a decimal string, empty when not applicable
+	Coin     string
+	Duration time.Duration
+	// Err is the error the operation failed with, nil on success
+	Err error
+}
+
+// Result classifies the outcome for metric labels: "ok", "denied" for policy denials
+// and "error" otherwise, so label cardinality stays bounded
+func (o Observation) Result() string {
+	switch {
+	case o.Err == nil:
+		return "ok"
+	case errors.Is(o.Err, ErrPolicyDenied):
+		return "denied"
+	default:
+		return "error"
+	}
+}
+
+// Metrics receives an Observation after every mnemonic generation, derivation and signature
+// Implementations must be safe for concurrent use and should not block;
+// the prometheus subpackage provides an adapter
+type Metrics interface {
+	Observe(observation Observation)
+}
+
+// MetricsFunc adapts a function to the Metrics interface
+type MetricsFunc func(observation Observation)
+
+// Observe calls f(observation)
+func (f MetricsFunc) Observe(observation Observation) {
+	f(observation)
+}
+
+// defaultMetrics holds the Metrics used by package functions and wallets without WithMetrics
+var defaultMetrics atomic.Pointer[metricsHolder]
+
+type metricsHolder struct {
+	metrics Metrics
+}
+
+// DefaultMetrics returns the Metrics installed with SetDefaultMetrics, nil when none is
+func DefaultMetrics() Metrics {
+	if holder := defaultMetrics.Load(); holder != nil {
+		return holder.metrics
+	}
+
+	return nil
+}
+
+// SetDefaultMetrics installs metrics for package functions (GenerateMnemonic,
+// GenerateKeysFromMnemonic...) and for wallets created without WithMetrics
+// Passing nil disables instrumentation
+func SetDefaultMetrics(metrics Metrics) {
+	defaultMetrics.Store(&metricsHolder{metrics})
+}
+
+// observe reports an operation started at start, doing nothing when metrics is nil
+func observe(metrics Metrics, operation MetricOperation, coin string, start time.Time, err error) {
+	if metrics == nil {
+		return
+	}
+
+	metrics.Observe(Observation{
+		Operation: operation,
+		Coin:      coin,
+		Duration:  time.Since(start),
+		Err:       err,
+	})
+}
+
+func coinLabel(coin uint32) string {
+	return strconv.FormatUint(uint64(coin), 10)
+}
@@ -1,6 +1,11 @@
 package hdwallet
 
-import "github.com/tyler-smith/go-bip39"
+import (
+	"fmt"
+	"time"
+
+	"github.com/tyler-smith/go-bip39"
+)
 
 // GenerateMnemonic creates a new BIP39 mnemonic phrase for wallet seed generation
 // BIP39 defines a method for generating deterministic wallets using human-readable words
@@ -11,7 +16,36 @@ import "github.com/tyler-smith/go-bip39"
 // - Mnemonic phrases should be stored securely and never transmitted over insecure channels
 // - Loss of the mnemonic means permanent loss of wallet access
 // - The mnemonic can regenerate the entire wallet hierarchy across different applications
+//
+// Entropy is read from DefaultEntropySource, see GenerateMnemonicFromSource
 func GenerateMnemonic(bitSize int) (string, error) {
+	return GenerateMnemonicFromSource(DefaultEntropySource(), bitSize)
+}
+
+// GenerateMnemonicWithUserEntropy is GenerateMnemonic mixing userEntropy (for example
+// the text of 99 dice rolls, or the output of a hardware token) into DefaultEntropySource
+// with HKDF, see MixedEntropySource. The mnemonic stays secure if either input is
+func GenerateMnemonicWithUserEntropy(userEntropy []byte, bitSize int) (string, error) {
+	source, err := NewMixedEntropySource(DefaultEntropySource(), userEntropy)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	return GenerateMnemonicFromSource(source, bitSize)
+}
+
+// GenerateMnemonicFromSource is GenerateMnemonic reading entropy from source
+// (a hardware RNG, or a HealthTestedSource wrapping one)
+func GenerateMnemonicFromSource(source EntropySource, bitSize int) (string, error) {
+	start := time.Now()
+	mnemonic, err := generateMnemonic(source, bitSize)
+	observe(DefaultMetrics(), MetricGenerateMnemonic, "", start, err)
+
+	return mnemonic, err
+}
+
+func generateMnemonic(source EntropySource, bitSize int) (string, error) {
 	// Step 1: Generate cryptographically secure entropy
 	// 128 bits of entropy = 12 word mnemonic phrase
 	// Other common options:
@@ -22,7 +56,7 @@ func GenerateMnemonic(bitSize int) (string, error) {
 	//
 	// 128 bits provides 2^128 possible combinations, which is cryptographically secure
 	// against brute force attacks (approximately 10^38 combinations)
-	entropy, err := bip39.NewEntropy(bitSize)
+	entropy, err := newMnemonicEntropy(source, bitSize)
 	if err != nil {
 		// Entropy generation failure is a critical error that should never happen
 		// in normal operation. It could indicate:
@@ -60,3 +94,17 @@ func GenerateMnemonic(bitSize int) (string, error) {
 	// - Import into any BIP39-compatible wallet
 	return mnemonic, nil
 }
+
+// newMnemonicEntropy reads bitSize bits from source, bitSize must be a multiple of 32 in [128, 256]
+func newMnemonicEntropy(source EntropySource, bitSize int) ([]byte, error) {
+	if bitSize%32 != 0 || bitSize < 128 || bitSize > 256 {
+		return nil, fmt.Errorf("invalid entropy size %d, must be a multiple of 32 between 128 and 256", bitSize)
+	}
+
+	entropy := make([]byte, bitSize/8)
+	if err := readEntropy(source, entropy); err != nil {
+		return nil, err
+	}
+
+	return entropy, nil
+}
@@ -0,0 +1,201 @@
+package hdwallet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// minWordPrefix is the shortest abbreviation accepted by AllowWordPrefixes; the first
+// four letters identify every word of the BIP39 wordlists
+const minWordPrefix = 4
+
+// MnemonicParseOption configures NormalizeMnemonic
+type MnemonicParseOption func(*mnemonicParser)
+
+type mnemonicParser struct {
+	prefixes bool
+}
+
+// AllowWordPrefixes accepts words abbreviated to their first four or more letters,
+// as stamped on steel backup plates, when the abbreviation names a single word
+func AllowWordPrefixes() MnemonicParseOption {
+	return func(p *mnemonicParser) {
+		p.prefixes = true
+	}
+}
+
+// NormalizeMnemonic canonicalizes a mnemonic typed or pasted by a user and validates it
+// against the BIP39 wordlist and checksum, returning the words lower case and separated
+// by single spaces, the form seeds must be computed from
+//
+// Tolerated variants are extra whitespace and line breaks, commas and semicolons
+// between words, numbered lists ("1. abandon", "2) ability", "#3 able"), mixed case
+// and full-width characters. Errors name the first word that is not in the wordlist
+func NormalizeMnemonic(input string, opts ...MnemonicParseOption) (string, error) {
+	var parser mnemonicParser
+	for _, opt := range opts {
+		opt(&parser)
+	}
+
+	tokens := strings.FieldsFunc(foldWidth(input), func(r rune) bool {
+		return unicode.IsSpace(r) || r == ',' || r == ';'
+	})
+
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.ToLower(stripNumbering(token))
+		if token == "" {
+			continue
+		}
+
+		word, err := parser.resolve(token)
+		if err != nil {
+			return "", fmt.Errorf("word %d: %w", len(words)+1, err)
+		}
+		words = append(words, word)
+	}
+
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return "", fmt.Errorf("mnemonic has %d words, expected 12, 15, 18, 21 or 24", len(words))
+	}
+
+	mnemonic := strings.Join(words, " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return "", errors.New("invalid mnemonic checksum, check the order and spelling of the words")
+	}
+
+	return mnemonic, nil
+}
+
+// resolve returns the wordlist word of token, expanding abbreviations when allowed
+func (p mnemonicParser) resolve(token string) (string, error) {
+	if _, ok := bip39.GetWordIndex(token); ok {
+		return token, nil
+	}
+	if !p.prefixes || utf8.RuneCountInString(token) < minWordPrefix {
+		return "", fmt.Errorf("%q is not in the BIP39 wordlist", token)
+	}
+
+	var match string
+	for _, word := range bip39.GetWordList() {
+		if !strings.HasPrefix(word, token) {
+			continue
+		}
+		if match != "" {
+			return "", fmt.Errorf("%q abbreviates several BIP39 words", token)
+		}
+		match = word
+	}
+	if match == "" {
+		return "", fmt.Errorf("%q is not in the BIP39 wordlist", token)
+	}
+
+	return match, nil
+}
+
+// foldWidth maps full-width ASCII variants and the ideographic space to their ASCII forms
+func foldWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '\uff01' && r <= '\uff5e':
+			return r - 0xfee0
+		case r == '\u3000':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+// stripNumbering removes a list number such as "1.", "2)", "3:" or "#4" from the start of token
+func stripNumbering(token string) string {
+	rest := strings.TrimPrefix(token, "#")
+	digits := strings.TrimLeft(rest, "0123456789")
+	if len(digits) == len(rest) {
+		return token
+	}
+
+	return strings.TrimLeft(digits, ".):")
+}
+
+// MnemonicFormatOption configures FormatMnemonic
+type MnemonicFormatOption func(*mnemonicFormat)
+
+type mnemonicFormat struct {
+	perLine  int
+	numbered bool
+}
+
+// WithWordsPerLine breaks the output into lines of n words, aligned in columns
+func WithWordsPerLine(n int) MnemonicFormatOption {
+	return func(f *mnemonicFormat) {
+		f.perLine = n
+	}
+}
+
+// WithNumbering prefixes every word with its 1-based position, "1. abandon"
+func WithNumbering() MnemonicFormatOption {
+	return func(f *mnemonicFormat) {
+		f.numbered = true
+	}
+}
+
+// FormatMnemonic formats mnemonic for display, on a single line unless WithWordsPerLine
+// is given, for example with WithWordsPerLine(3) and WithNumbering:
+//
+//  1. abandon   2. ability   3. able
+//  4. about     5. above     6. absent
+//
+// The output is read back by NormalizeMnemonic
+func FormatMnemonic(mnemonic string, opts ...MnemonicFormatOption) string {
+	var format mnemonicFormat
+	for _, opt := range opts {
+		opt(&format)
+	}
+
+	words := strings.Fields(mnemonic)
+	grouped := format.perLine > 0 && format.perLine < len(words)
+	numberWidth := 0
+	if grouped {
+		numberWidth = len(strconv.Itoa(len(words)))
+	}
+	entries := make([]string, len(words))
+	width := 0
+	for i, word := range words {
+		entries[i] = word
+		if format.numbered {
+			entries[i] = fmt.Sprintf("%*d. %s", numberWidth, i+1, word)
+		}
+		width = max(width, utf8.RuneCountInString(entries[i]))
+	}
+
+	if !grouped {
+		return strings.Join(entries, " ")
+	}
+
+	var builder strings.Builder
+	for i, entry := range entries {
+		switch {
+		case i == 0:
+		case i%format.perLine == 0:
+			builder.WriteByte('\n')
+		default:
+			builder.WriteString("  ")
+		}
+		builder.WriteString(entry)
+
+		// Pad to the column width unless the entry ends its line
+		if (i+1)%format.perLine != 0 && i != len(entries)-1 {
+			builder.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(entry)))
+		}
+	}
+
+	return builder.String()
+}
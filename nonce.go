@@ -0,0 +1,205 @@
+package hdwallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNonceGap is returned by NonceManager.Release for a nonce that is not the last
+// one reserved: later transactions depend on it, so it must be used (for example by
+// a zero-value self transfer) instead of returned
+var ErrNonceGap = errors.New("nonce is not the last reserved one")
+
+// NonceKey identifies the counter of one address on one chain
+type NonceKey struct {
+	Coin uint32
+	// Chain tells apart networks sharing a coin type: the EIP-155 chain ID
+	// for Ethereum, the chain ID ("cosmoshub-4") for Cosmos chains
+	Chain   string
+	Address string
+}
+
+// String formats the key as coin/chain/address
+func (k NonceKey) String() string {
+	return fmt.Sprintf("%d/%s/%s", k.Coin, k.Chain, k.Address)
+}
+
+// NonceStore persists the next nonce of each key
+//
+// CompareAndSwapNonce must be atomic: when several processes share one store (a
+// database row updated with a conditional UPDATE, for example), this is what keeps
+// two signers from reserving the same nonce
+type NonceStore interface {
+	// LoadNonce returns the next nonce of key, false when key has no counter yet
+	LoadNonce(key NonceKey) (uint64, bool, error)
+	// CompareAndSwapNonce sets the next nonce of key to next if it is still old,
+	// or still missing when exists is false, and reports whether it did
+	CompareAndSwapNonce(key NonceKey, old uint64, exists bool, next uint64) (bool, error)
+}
+
+// NonceSource returns the next nonce of an address according to the chain, such as
+// eth_getTransactionCount with the "pending" tag or the sequence of a Cosmos account
+type NonceSource interface {
+	ChainNonce(ctx context.Context, key NonceKey) (uint64, error)
+}
+
+// NonceSourceFunc adapts a function to NonceSource
+type NonceSourceFunc func(ctx context.Context, key NonceKey) (uint64, error)
+
+// ChainNonce implements NonceSource
+func (f NonceSourceFunc) ChainNonce(ctx context.Context, key NonceKey) (uint64, error) {
+	return f(ctx, key)
+}
+
+// NonceManager hands out per-address nonces so concurrent signers never build two
+// transactions with the same one
+type NonceManager interface {
+	// Reserve returns the next nonce of key and consumes it
+	Reserve(ctx context.Context, key NonceKey) (uint64, error)
+	// Release gives back a reserved nonce whose transaction was never broadcast;
+	// only the last reserved nonce can be released, see ErrNonceGap
+	Release(ctx context.Context, key NonceKey, nonce uint64) error
+	// Advance moves the next nonce of key up to next; counters never move backwards
+	Advance(ctx context.Context, key NonceKey, next uint64) error
+	// Refresh advances key to the chain's next nonce, catching up with transactions
+	// sent by other wallets; it does nothing without a chain backend
+	Refresh(ctx context.Context, key NonceKey) error
+}
+
+// StoreNonceManager is a NonceManager keeping its counters in a NonceStore
+// Counters start at the chain's next nonce when a NonceSource is set, at zero otherwise
+type StoreNonceManager struct {
+	store  NonceStore
+	source NonceSource
+}
+
+var _ NonceManager = (*StoreNonceManager)(nil)
+
+// NewNonceManager returns a manager over store; source may be nil
+func NewNonceManager(store NonceStore, source NonceSource) (*StoreNonceManager, error) {
+	if store == nil {
+		return nil, errors.New("nonce store is required")
+	}
+
+	return &StoreNonceManager{store: store, source: source}, nil
+}
+
+// Reserve implements NonceManager
+func (m *StoreNonceManager) Reserve(ctx context.Context, key NonceKey) (uint64, error) {
+	var reserved uint64
+	err := m.update(ctx, key, func(next uint64, exists bool) (uint64, bool, error) {
+		if !exists && m.source != nil {
+			chain, err := m.source.ChainNonce(ctx, key)
+			if err != nil {
+				return 0, false, fmt.Errorf("query chain nonce of %s: %w", key, err)
+			}
+			next = chain
+		}
+		reserved = next
+
+		return next + 1, true, nil
+	})
+
+	return reserved, err
+}
+
+// Release implements NonceManager
+func (m *StoreNonceManager) Release(ctx context.Context, key NonceKey, nonce uint64) error {
+	return m.update(ctx, key, func(next uint64, exists bool) (uint64, bool, error) {
+		if !exists || next != nonce+1 {
+			return 0, false, fmt.Errorf("release nonce %d of %s: %w", nonce, key, ErrNonceGap)
+		}
+
+		return nonce, true, nil
+	})
+}
+
+// Advance implements NonceManager
+func (m *StoreNonceManager) Advance(ctx context.Context, key NonceKey, next uint64) error {
+	return m.update(ctx, key, func(current uint64, exists bool) (uint64, bool, error) {
+		if exists && current >= next {
+			return current, false, nil
+		}
+
+		return next, true, nil
+	})
+}
+
+// Refresh implements NonceManager
+func (m *StoreNonceManager) Refresh(ctx context.Context, key NonceKey) error {
+	if m.source == nil {
+		return nil
+	}
+
+	chain, err := m.source.ChainNonce(ctx, key)
+	if err != nil {
+		return fmt.Errorf("query chain nonce of %s: %w", key, err)
+	}
+
+	return m.Advance(ctx, key, chain)
+}
+
+// update applies fn to the counter of key until the compare-and-swap succeeds;
+// fn returns the new value and whether to write it
+func (m *StoreNonceManager) update(ctx context.Context, key NonceKey,
+	fn func(next uint64, exists bool) (uint64, bool, error)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		current, exists, err := m.store.LoadNonce(key)
+		if err != nil {
+			return fmt.Errorf("load nonce of %s: %w", key, err)
+		}
+		next, write, err := fn(current, exists)
+		if err != nil || !write {
+			return err
+		}
+
+		swapped, err := m.store.CompareAndSwapNonce(key, current, exists, next)
+		if err != nil {
+			return fmt.Errorf("store nonce of %s: %w", key, err)
+		}
+		if swapped {
+			return nil
+		}
+	}
+}
+
+// MemoryNonceStore is a NonceStore kept in process memory
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[NonceKey]uint64
+}
+
+// NewMemoryNonceStore returns an empty MemoryNonceStore
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[NonceKey]uint64)}
+}
+
+// LoadNonce implements NonceStore
+func (s *MemoryNonceStore) LoadNonce(key NonceKey) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, ok := s.nonces[key]
+
+	return next, ok, nil
+}
+
+// CompareAndSwapNonce implements NonceStore
+func (s *MemoryNonceStore) CompareAndSwapNonce(key NonceKey, old uint64, exists bool, next uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.nonces[key]
+	if ok != exists || (ok && current != old) {
+		return false, nil
+	}
+	s.nonces[key] = next
+
+	return true, nil
+}
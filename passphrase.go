@@ -0,0 +1,480 @@
+package hdwallet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tyler-smith/go-bip39/wordlists"
+)
+
+// ErrWeakPassphrase is returned (wrapped) by CheckPassphrase when a policy rejects a passphrase
+var ErrWeakPassphrase = errors.New("weak passphrase")
+
+// PassphraseStrength is the estimated resistance of a BIP39 passphrase ("25th word") to guessing
+//
+// The estimate follows the approach of zxcvbn: the passphrase is split into the
+// cheapest sequence of patterns an attacker would try (common passwords, BIP39
+// words, keyboard walks, sequences, repeats, years, personal information) with
+// brute force for the remaining characters, and EntropyBits is log2 of the number
+// of guesses needed. Scores are tuned for offline attacks, because a passphrase
+// only costs 2048 PBKDF2-HMAC-SHA512 iterations per guess once the mnemonic leaks
+type PassphraseStrength struct {
+	// Score ranges from 0 (trivially guessable) to 4 (very strong)
+	Score int
+	// EntropyBits is log2 of the estimated number of guesses
+	EntropyBits float64
+	// Warnings explain which patterns weakened the passphrase
+	Warnings []string
+}
+
+// Passphrase scores are assigned from these entropy thresholds (bits)
+const (
+	passphraseScore1Bits = 28
+	passphraseScore2Bits = 40
+	passphraseScore3Bits = 56
+	passphraseScore4Bits = 72
+)
+
+// Patterns detected by the estimator and the warning reported for each of them
+const (
+	warnCommon   = "contains a commonly used password"
+	warnBIP39    = "contains words from the BIP39 wordlist"
+	warnPersonal = "contains personal information"
+	warnKeyboard = "contains a keyboard pattern"
+	warnSequence = "contains a character sequence"
+	warnRepeat   = "contains repeated characters"
+	warnYear     = "contains a year"
+	warnShort    = "is shorter than 12 characters"
+	warnEmpty    = "is empty, the wallet is protected by the mnemonic alone"
+)
+
+// maxEstimatedLength bounds the work of the estimator, longer passphrases are
+// estimated on their prefix, which can only underestimate their strength
+const maxEstimatedLength = 128
+
+// commonPasswords are frequently used passwords in rank order (most common first)
+var commonPasswords = []string{
+	"password", "123456", "12345678", "qwerty", "123456789", "12345", "1234", "111111",
+	"1234567", "dragon", "123123", "baseball", "abc123", "football", "monkey", "letmein",
+	"shadow", "master", "666666", "qwertyuiop", "123321", "mustang", "1234567890", "michael",
+	"654321", "superman", "1qaz2wsx", "7777777", "121212", "000000", "qazwsx", "123qwe",
+	"killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter", "buster",
+	"soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars", "klaster", "112233",
+	"george", "computer", "michelle", "jessica", "pepper", "zxcvbn", "555555", "131313",
+	"freedom", "whatever", "princess", "welcome", "admin", "login", "passw0rd", "hello",
+	"secret", "love", "money", "summer", "winter", "flower", "access", "changeme",
+	"default", "guest", "root", "test", "pass", "god", "ninja", "solo",
+	"bitcoin", "satoshi", "ethereum", "crypto", "wallet", "tron", "hodl", "moon",
+	"lambo", "blockchain", "ledger", "trezor", "mnemonic", "passphrase", "seed", "nakamoto",
+}
+
+// keyboardRows are QWERTY rows used to detect keyboard walks
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+// leetSubstitutions maps common character substitutions back to letters
+var leetSubstitutions = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'}, '5': {'s'},
+	'7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+var (
+	commonPasswordRanks = rankWords(commonPasswords)
+	// The wordlist is alphabetical, not ordered by frequency, and mnemonic words are
+	// drawn uniformly: every word costs log2(2048) = 11 bits
+	bip39Ranks = uniformRanks(wordlists.English)
+)
+
+// EstimatePassphrase estimates the strength of passphrase
+// userInputs are words an attacker may know about the owner (name, e-mail, service name)
+// and are treated as a very small dictionary
+func EstimatePassphrase(passphrase string, userInputs ...string) PassphraseStrength {
+	runes := []rune(passphrase)
+	if len(runes) == 0 {
+		return PassphraseStrength{Warnings: []string{warnEmpty}}
+	}
+	if len(runes) > maxEstimatedLength {
+		runes = runes[:maxEstimatedLength]
+	}
+
+	userRanks := rankWords(userInputs)
+	matches := findPassphrasePatterns(runes, userRanks)
+
+	// Minimal guessing cost over all segmentations: best[i] is the cheapest way to
+	// produce the first i characters, either with a pattern ending at i or with one
+	// more brute-forced character
+	bruteBits := math.Log2(float64(passphraseCardinality(runes)))
+	best := make([]float64, len(runes)+1)
+	via := make([]*passphraseMatch, len(runes)+1)
+	for i := 1; i <= len(runes); i++ {
+		best[i] = best[i-1] + bruteBits
+		via[i] = nil
+		for j := range matches {
+			match := &matches[j]
+			if match.end != i {
+				continue
+			}
+			if cost := best[match.start] + match.bits; cost < best[i] {
+				best[i] = cost
+				via[i] = match
+			}
+		}
+	}
+
+	// Collect the warnings of the patterns on the cheapest path
+	warningSet := make(map[string]struct{})
+	for i := len(runes); i > 0; {
+		if via[i] == nil {
+			i--
+			continue
+		}
+		if via[i].warning != "" {
+			warningSet[via[i].warning] = struct{}{}
+		}
+		i = via[i].start
+	}
+	if len(runes) < 12 {
+		warningSet[warnShort] = struct{}{}
+	}
+
+	warnings := make([]string, 0, len(warningSet))
+	for warning := range warningSet {
+		warnings = append(warnings, warning)
+	}
+	sort.Strings(warnings)
+
+	bits := best[len(runes)]
+
+	return PassphraseStrength{
+		Score:       passphraseScore(bits),
+		EntropyBits: bits,
+		Warnings:    warnings,
+	}
+}
+
+// PassphrasePolicy decides whether a passphrase is acceptable given its estimated strength
+// Returning an error rejects the passphrase; wallet UIs can plug in their own rules
+type PassphrasePolicy func(passphrase string, strength PassphraseStrength) error
+
+// MinimumScorePolicy rejects passphrases scoring below score
+func MinimumScorePolicy(score int) PassphrasePolicy {
+	return func(_ string, strength PassphraseStrength) error {
+		if strength.Score < score {
+			return fmt.Errorf("%w: score %d is below the required %d", ErrWeakPassphrase, strength.Score, score)
+		}
+		return nil
+	}
+}
+
+// MinimumEntropyPolicy rejects passphrases estimated below bits of entropy
+func MinimumEntropyPolicy(bits float64) PassphrasePolicy {
+	return func(_ string, strength PassphraseStrength) error {
+		if strength.EntropyBits < bits {
+			return fmt.Errorf("%w: %.1f bits of entropy is below the required %.1f",
+				ErrWeakPassphrase, strength.EntropyBits, bits)
+		}
+		return nil
+	}
+}
+
+// CheckPassphrase estimates passphrase with userInputs (see EstimatePassphrase) and
+// applies policies in order. With no policies, MinimumScorePolicy(3) is applied
+func CheckPassphrase(passphrase string, userInputs []string, policies ...PassphrasePolicy) (PassphraseStrength, error) {
+	strength := EstimatePassphrase(passphrase, userInputs...)
+	if len(policies) == 0 {
+		policies = []PassphrasePolicy{MinimumScorePolicy(3)}
+	}
+
+	for _, policy := range policies {
+		if err := policy(passphrase, strength); err != nil {
+			return strength, err
+		}
+	}
+
+	return strength, nil
+}
+
+// passphraseMatch is a pattern covering runes[start:end]
+type passphraseMatch struct {
+	start, end int
+	bits       float64
+	warning    string
+}
+
+// findPassphrasePatterns returns every dictionary, keyboard, sequence, repeat and year match
+func findPassphrasePatterns(runes []rune, userRanks map[string]int) []passphraseMatch {
+	var matches []passphraseMatch
+
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	dictionaries := []struct {
+		ranks   map[string]int
+		warning string
+	}{
+		{commonPasswordRanks, warnCommon},
+		{bip39Ranks, warnBIP39},
+		{userRanks, warnPersonal},
+	}
+
+	for start := 0; start < len(runes); start++ {
+		for end := start + 3; end <= len(runes); end++ {
+			word := lower[start:end]
+
+			// Dictionary words, as typed and with l33t substitutions undone
+			for _, dictionary := range dictionaries {
+				for _, candidate := range unleet(word) {
+					rank, ok := dictionary.ranks[string(candidate.word)]
+					if !ok {
+						continue
+					}
+					bits := math.Log2(float64(rank)) + caseVariationBits(runes[start:end]) + float64(candidate.substitutions)
+					matches = append(matches, passphraseMatch{start, end, bits, dictionary.warning})
+				}
+			}
+
+			if end-start >= 4 && isKeyboardWalk(word) {
+				bits := math.Log2(float64(len(keyboardRows)*2*11)) + math.Log2(float64(end-start))
+				matches = append(matches, passphraseMatch{start, end, bits, warnKeyboard})
+			}
+		}
+
+		matches = append(matches, sequenceMatches(lower, start)...)
+		matches = append(matches, repeatMatches(runes, start)...)
+
+		// Word separators of multi-word passphrases are predictable
+		if strings.ContainsRune(" -_.", runes[start]) {
+			matches = append(matches, passphraseMatch{start, start + 1, 2, ""})
+		}
+
+		if start+4 <= len(runes) && isYear(runes[start:start+4]) {
+			matches = append(matches, passphraseMatch{start, start + 4, math.Log2(200), warnYear})
+		}
+	}
+
+	return matches
+}
+
+type unleeted struct {
+	word          []rune
+	substitutions int
+}
+
+// unleet returns word and its variants with l33t substitutions replaced by letters
+func unleet(word []rune) []unleeted {
+	variants := []unleeted{{word: word}}
+	for i, r := range word {
+		replacements, ok := leetSubstitutions[r]
+		if !ok {
+			continue
+		}
+
+		// Bound the combinations, passphrases are rarely l33ted with ambiguous characters
+		if len(variants) > 16 {
+			break
+		}
+
+		var next []unleeted
+		for _, variant := range variants {
+			next = append(next, variant)
+			for _, replacement := range replacements {
+				replaced := append([]rune(nil), variant.word...)
+				replaced[i] = replacement
+				next = append(next, unleeted{word: replaced, substitutions: variant.substitutions + 1})
+			}
+		}
+		variants = next
+	}
+
+	return variants
+}
+
+// caseVariationBits is the cost of guessing the capitalization of a dictionary word
+func caseVariationBits(word []rune) float64 {
+	upper := 0
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 0
+	case upper == letters, upper == 1 && unicode.IsUpper(word[0]):
+		// ALL CAPS or Capitalized: one extra guess
+		return 1
+	default:
+		return math.Min(float64(upper), float64(letters-upper)) + 1
+	}
+}
+
+// isKeyboardWalk reports whether word is a run of adjacent keys on one keyboard row
+func isKeyboardWalk(word []rune) bool {
+	text := string(word)
+	for _, row := range keyboardRows {
+		if strings.Contains(row, text) || strings.Contains(reverseString(row), text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sequenceMatches returns runs like "abcd", "9876" starting at start
+func sequenceMatches(lower []rune, start int) []passphraseMatch {
+	var matches []passphraseMatch
+	for _, step := range []rune{1, -1} {
+		end := start + 1
+		for end < len(lower) && lower[end]-lower[end-1] == step && sameClass(lower[end], lower[start]) {
+			end++
+		}
+		if end-start < 3 {
+			continue
+		}
+
+		base := 26.0
+		if unicode.IsDigit(lower[start]) {
+			base = 10
+		}
+		bits := math.Log2(base) + math.Log2(float64(end-start))
+		if step < 0 {
+			bits++
+		}
+		matches = append(matches, passphraseMatch{start, end, bits, warnSequence})
+	}
+
+	return matches
+}
+
+// repeatMatches returns repetitions of a block ("aaaa", "abcabc") starting at start
+func repeatMatches(runes []rune, start int) []passphraseMatch {
+	var matches []passphraseMatch
+	for block := 1; start+2*block <= len(runes); block++ {
+		end := start + block
+		for end+block <= len(runes) && string(runes[end:end+block]) == string(runes[start:start+block]) {
+			end += block
+		}
+
+		count := (end - start) / block
+		if count < 2 || (block == 1 && count < 3) {
+			continue
+		}
+
+		blockBits := float64(block) * math.Log2(float64(passphraseCardinality(runes[start:start+block])))
+		bits := blockBits + math.Log2(float64(count))
+		matches = append(matches, passphraseMatch{start, end, bits, warnRepeat})
+	}
+
+	return matches
+}
+
+// isYear reports whether four runes form a year between 1900 and 2099
+func isYear(runes []rune) bool {
+	text := string(runes)
+	return len(text) == 4 && (strings.HasPrefix(text, "19") || strings.HasPrefix(text, "20")) &&
+		unicode.IsDigit(runes[2]) && unicode.IsDigit(runes[3])
+}
+
+// passphraseCardinality is the size of the alphabet a brute-force attacker must cover
+func passphraseCardinality(runes []rune) int {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range runes {
+		switch {
+		case r > unicode.MaxASCII:
+			other = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	cardinality := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			cardinality += class.size
+		}
+	}
+
+	return cardinality
+}
+
+// passphraseScore maps entropy bits to a 0-4 score
+func passphraseScore(bits float64) int {
+	switch {
+	case bits < passphraseScore1Bits:
+		return 0
+	case bits < passphraseScore2Bits:
+		return 1
+	case bits < passphraseScore3Bits:
+		return 2
+	case bits < passphraseScore4Bits:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// rankWords maps lowercased words to their 1-based rank
+func rankWords(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if _, ok := ranks[word]; !ok && word != "" {
+			ranks[word] = i + 1
+		}
+	}
+
+	return ranks
+}
+
+// uniformRanks maps lowercased words to the size of the list, for lists whose words
+// are equally likely
+func uniformRanks(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for _, word := range words {
+		if word != "" {
+			ranks[strings.ToLower(word)] = len(words)
+		}
+	}
+
+	return ranks
+}
+
+func sameClass(a, b rune) bool {
+	return unicode.IsDigit(a) == unicode.IsDigit(b) && unicode.IsLetter(a) == unicode.IsLetter(b)
+}
+
+func reverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
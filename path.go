@@ -0,0 +1,219 @@
+package hdwallet
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DerivationPath is a BIP32 derivation path as a list of child indexes
+// Hardened levels carry the HardenedOffset bit, exactly as passed to bip32.Key.NewChildKey
+type DerivationPath []uint32
+
+// HardenedNotation is the marker written after hardened indexes
+// Both markers are accepted by ParseDerivationPath; descriptors and shells
+// prefer 'h' because the apostrophe needs quoting
+type HardenedNotation byte
+
+const (
+	HardenedApostrophe HardenedNotation = '\''
+	HardenedH          HardenedNotation = 'h'
+)
+
+// BIP44Path returns the path m/44'/coin'/account'/chain/address
+// coin and account are hardened by this function, callers pass plain indexes
+// Every index must be below HardenedOffset; the Wallet and derivation functions reject others
+func BIP44Path(coin, account, chain, address uint32) DerivationPath {
+	return DerivationPath{
+		Purpose + HardenedOffset,
+		coin + HardenedOffset,
+		account + HardenedOffset,
+		chain,
+		address,
+	}
+}
+
+// checkBIP44Indexes rejects indexes of m/44'/coin'/account'/chain/address at or above
+// HardenedOffset: hardening coin or account would wrap them to another account, and
+// chain or address would silently become hardened
+func checkBIP44Indexes(coin, account, chain, address uint32) error {
+	switch {
+	case coin >= HardenedOffset:
+		return fmt.Errorf("coin type %d out of range", coin)
+	case account >= HardenedOffset:
+		return fmt.Errorf("account %d out of range", account)
+	case chain >= HardenedOffset:
+		return fmt.Errorf("chain %d out of range", chain)
+	case address >= HardenedOffset:
+		return fmt.Errorf("address %d out of range", address)
+	}
+
+	return nil
+}
+
+// checkedBIP44Path is BIP44Path for indexes passed by callers, checked by checkBIP44Indexes
+func checkedBIP44Path(coin, account, chain, address uint32) (DerivationPath, error) {
+	if err := checkBIP44Indexes(coin, account, chain, address); err != nil {
+		return nil, err
+	}
+
+	return BIP44Path(coin, account, chain, address), nil
+}
+
+// ParseDerivationPath parses paths such as "m/44'/195'/0'/0/0", "m/44h/195h/0h"
+// or "44H/195H" (the "m/" prefix is optional); "m" alone is the empty path
+func ParseDerivationPath(s string) (DerivationPath, error) {
+	trimmed := trimPathPrefix(strings.TrimSpace(s))
+	if trimmed == "" {
+		return DerivationPath{}, nil
+	}
+
+	levels := strings.Split(trimmed, "/")
+	path := make(DerivationPath, 0, len(levels))
+	for i, level := range levels {
+		hardened := false
+		if n := len(level); n > 0 {
+			switch level[n-1] {
+			case '\'', 'h', 'H':
+				hardened = true
+				level = level[:n-1]
+			}
+		}
+
+		// ParseUint would accept "+1"; indexes are plain decimal digits
+		if level == "" || strings.TrimLeft(level, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid derivation path %q: bad level %d", s, i+1)
+		}
+		// Indexes are below 2^31, the hardened bit comes from the marker only
+		index, err := strconv.ParseUint(level, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derivation path %q: index %s out of range", s, level)
+		}
+
+		if hardened {
+			path = append(path, uint32(index)+HardenedOffset)
+		} else {
+			path = append(path, uint32(index))
+		}
+	}
+
+	return path, nil
+}
+
+// trimPathPrefix removes the "m/" or "M/" prefix of a path; "m" and "M" alone are the
+// empty path. Other prefixes, such as "m44" or "/44", are left to fail level parsing
+func trimPathPrefix(s string) string {
+	if s == "m" || s == "M" {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(s, "m/"); ok {
+		return rest
+	}
+	if rest, ok := strings.CutPrefix(s, "M/"); ok {
+		return rest
+	}
+
+	return s
+}
+
+// NormalizePath parses s and returns its canonical String form
+func NormalizePath(s string) (string, error) {
+	path, err := ParseDerivationPath(s)
+	if err != nil {
+		return "", err
+	}
+
+	return path.String(), nil
+}
+
+// String formats the path in canonical form, "m/44'/195'/0'/0/0"
+func (p DerivationPath) String() string {
+	return p.Format(HardenedApostrophe)
+}
+
+// Format formats the path with the given hardened marker, for example "m/44h/195h/0h/0/0"
+func (p DerivationPath) Format(notation HardenedNotation) string {
+	var builder strings.Builder
+	builder.WriteString("m")
+	for _, index := range p {
+		builder.WriteByte('/')
+		if index >= HardenedOffset {
+			builder.WriteString(strconv.FormatUint(uint64(index-HardenedOffset), 10))
+			builder.WriteByte(byte(notation))
+		} else {
+			builder.WriteString(strconv.FormatUint(uint64(index), 10))
+		}
+	}
+
+	return builder.String()
+}
+
+// MarshalText implements encoding.TextMarshaler, paths are encoded in their String form
+func (p DerivationPath) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting every ParseDerivationPath form
+func (p *DerivationPath) UnmarshalText(text []byte) error {
+	path, err := ParseDerivationPath(string(text))
+	if err != nil {
+		return err
+	}
+	*p = path
+
+	return nil
+}
+
+// Equal reports whether both paths have the same levels
+func (p DerivationPath) Equal(other DerivationPath) bool {
+	return slices.Equal(p, other)
+}
+
+// Contains reports whether other is p or a descendant of p
+func (p DerivationPath) Contains(other DerivationPath) bool {
+	return len(p) <= len(other) && p.Equal(other[:len(p)])
+}
+
+// IsAncestor reports whether p is a strict ancestor of other
+func (p DerivationPath) IsAncestor(other DerivationPath) bool {
+	return len(p) < len(other) && p.Contains(other)
+}
+
+// RelativeTo returns the levels of p below ancestor, for example
+// m/44'/195'/0'/0/5 relative to m/44'/195'/0' is 0/5
+func (p DerivationPath) RelativeTo(ancestor DerivationPath) (DerivationPath, error) {
+	if !ancestor.Contains(p) {
+		return nil, fmt.Errorf("%s is not below %s", p, ancestor)
+	}
+
+	return slices.Clone(p[len(ancestor):]), nil
+}
+
+// Parent returns the path without its last level
+func (p DerivationPath) Parent() (DerivationPath, error) {
+	if len(p) == 0 {
+		return nil, errors.New("the master key has no parent")
+	}
+
+	return slices.Clone(p[:len(p)-1]), nil
+}
+
+// Child returns a copy of the path extended with index
+func (p DerivationPath) Child(index uint32) DerivationPath {
+	return append(slices.Clone(p), index)
+}
+
+// ComparePaths orders paths level by level, ancestors before their descendants,
+// for use with slices.SortFunc
+func ComparePaths(a, b DerivationPath) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := cmp.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return cmp.Compare(len(a), len(b))
+}
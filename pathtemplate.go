@@ -0,0 +1,205 @@
+package hdwallet
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Standard placeholder names of path templates
+const (
+	PlaceholderCoin    = "coin"
+	PlaceholderAccount = "account"
+	PlaceholderChain   = "chain"
+	PlaceholderIndex   = "index"
+)
+
+// BIP44Template is the BIP44 path scheme, BIP44Path as a template
+const BIP44Template = "m/44'/{coin}'/{account}'/{chain}/{index}"
+
+// PathValues are the values substituted for the placeholders of a PathTemplate,
+// plain indexes below HardenedOffset; the template decides which levels are hardened
+type PathValues map[string]uint32
+
+// PathTemplate is a derivation path with named placeholders, such as
+// "m/44'/{coin}'/{account}'/0/{index}", declared once in configuration and
+// expanded into DerivationPath values
+//
+// A level is either a fixed index or a single placeholder, both optionally hardened
+// with any ParseDerivationPath marker. A placeholder may appear on several levels,
+// which then receive the same value
+type PathTemplate struct {
+	levels []templateLevel
+}
+
+// templateLevel is one level of a template, a placeholder when name is set
+type templateLevel struct {
+	name     string
+	index    uint32
+	hardened bool
+}
+
+// ParsePathTemplate parses a path template
+func ParsePathTemplate(s string) (PathTemplate, error) {
+	trimmed := trimPathPrefix(strings.TrimSpace(s))
+	if trimmed == "" {
+		return PathTemplate{}, nil
+	}
+
+	parts := strings.Split(trimmed, "/")
+	levels := make([]templateLevel, 0, len(parts))
+	for i, part := range parts {
+		var level templateLevel
+		if n := len(part); n > 0 {
+			switch part[n-1] {
+			case '\'', 'h', 'H':
+				// A placeholder ends with its closing brace, the marker is never part of a name
+				level.hardened = true
+				part = part[:n-1]
+			}
+		}
+
+		if name, ok := strings.CutPrefix(part, "{"); ok {
+			name, ok = strings.CutSuffix(name, "}")
+			if !ok || !validPlaceholder(name) {
+				return PathTemplate{}, fmt.Errorf("invalid path template %q: bad placeholder at level %d", s, i+1)
+			}
+			level.name = name
+		} else {
+			if part == "" || strings.TrimLeft(part, "0123456789") != "" {
+				return PathTemplate{}, fmt.Errorf("invalid path template %q: bad level %d", s, i+1)
+			}
+			index, err := strconv.ParseUint(part, 10, 31)
+			if err != nil {
+				return PathTemplate{}, fmt.Errorf("invalid path template %q: index %s out of range", s, part)
+			}
+			level.index = uint32(index)
+		}
+		levels = append(levels, level)
+	}
+
+	return PathTemplate{levels: levels}, nil
+}
+
+// validPlaceholder reports whether name is a lower case identifier
+func validPlaceholder(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+
+	return strings.TrimLeft(name, "abcdefghijklmnopqrstuvwxyz0123456789_") == ""
+}
+
+// Placeholders returns the placeholder names of the template in order of first appearance
+func (t PathTemplate) Placeholders() []string {
+	var names []string
+	for _, level := range t.levels {
+		if level.name != "" && !slices.Contains(names, level.name) {
+			names = append(names, level.name)
+		}
+	}
+
+	return names
+}
+
+// Expand substitutes values into the template
+// Every placeholder needs a value below HardenedOffset, and values for names the
+// template does not have are rejected as likely configuration mistakes
+func (t PathTemplate) Expand(values PathValues) (DerivationPath, error) {
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		value := values[name]
+		if !slices.ContainsFunc(t.levels, func(level templateLevel) bool { return level.name == name }) {
+			return nil, fmt.Errorf("path template %s has no placeholder {%s}", t, name)
+		}
+		if value >= HardenedOffset {
+			return nil, fmt.Errorf("value %d of {%s} is not below 2^31, the template decides hardening", value, name)
+		}
+	}
+
+	path := make(DerivationPath, len(t.levels))
+	for i, level := range t.levels {
+		index := level.index
+		if level.name != "" {
+			value, ok := values[level.name]
+			if !ok {
+				return nil, fmt.Errorf("path template %s: missing value of {%s}", t, level.name)
+			}
+			index = value
+		}
+		if level.hardened {
+			index += HardenedOffset
+		}
+		path[i] = index
+	}
+
+	return path, nil
+}
+
+// Match reports whether path is an expansion of the template and returns the
+// placeholder values it was expanded with
+func (t PathTemplate) Match(path DerivationPath) (PathValues, bool) {
+	if len(path) != len(t.levels) {
+		return nil, false
+	}
+
+	values := make(PathValues)
+	for i, level := range t.levels {
+		index := path[i]
+		if (index >= HardenedOffset) != level.hardened {
+			return nil, false
+		}
+		if level.hardened {
+			index -= HardenedOffset
+		}
+
+		if level.name == "" {
+			if index != level.index {
+				return nil, false
+			}
+			continue
+		}
+		if value, ok := values[level.name]; ok && value != index {
+			return nil, false
+		}
+		values[level.name] = index
+	}
+
+	return values, true
+}
+
+// String formats the template in canonical form, "m/44'/{coin}'/{account}'/0/{index}"
+func (t PathTemplate) String() string {
+	var builder strings.Builder
+	builder.WriteString("m")
+	for _, level := range t.levels {
+		builder.WriteByte('/')
+		if level.name != "" {
+			builder.WriteString("{" + level.name + "}")
+		} else {
+			builder.WriteString(strconv.FormatUint(uint64(level.index), 10))
+		}
+		if level.hardened {
+			builder.WriteByte(byte(HardenedApostrophe))
+		}
+	}
+
+	return builder.String()
+}
+
+// MarshalText implements encoding.TextMarshaler, templates are encoded in their String form
+func (t PathTemplate) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (t *PathTemplate) UnmarshalText(text []byte) error {
+	template, err := ParsePathTemplate(string(text))
+	if err != nil {
+		return err
+	}
+	*t = template
+
+	return nil
+}
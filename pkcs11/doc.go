@@ -0,0 +1,13 @@
+// Package pkcs11 maps secp256k1 keys held by a PKCS#11 hardware security module
+// into the hdwallet.Signer interface, for custodial deployments whose compliance
+// requirements mandate that signing keys never exist outside certified hardware.
+//
+// The backend is generic: any vendor module exposing CKK_EC keys on the secp256k1
+// curve with the CKM_ECDSA mechanism can be used (Thales Luna, Utimaco, AWS CloudHSM,
+// YubiHSM 2, SoftHSM for testing). Keys are addressed by their CKA_LABEL.
+//
+// The backend uses cgo to load the vendor module, therefore it is only compiled
+// with the pkcs11 build tag:
+//
+//	go build -tags pkcs11
+package pkcs11
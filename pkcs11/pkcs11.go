@@ -0,0 +1,261 @@
+//go:build pkcs11
+
+package pkcs11
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	p11 "github.com/miekg/pkcs11"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// secp256k1Params is the DER encoded curve OID 1.3.132.0.10 used as CKA_EC_PARAMS
+var secp256k1Params = []byte{0x06, 0x05, 0x2b, 0x81, 0x04, 0x00, 0x0a}
+
+// Config describes how to reach the token holding the keys
+type Config struct {
+	// ModulePath is the path of the vendor PKCS#11 shared library
+	ModulePath string
+	// Slot is the slot ID of the token
+	Slot uint
+	// PIN is the user PIN of the token
+	PIN string
+}
+
+// Module is a logged in session with a PKCS#11 token
+// PKCS#11 sessions are not safe for concurrent use, so all operations of a Module
+// and of the Signers it returns are serialized
+type Module struct {
+	mu      sync.Mutex
+	ctx     *p11.Ctx
+	session p11.SessionHandle
+}
+
+// Open loads the PKCS#11 module, opens a session on the configured slot and logs in as user
+func Open(cfg Config) (*Module, error) {
+	ctx := p11.New(cfg.ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("load pkcs11 module %s", cfg.ModulePath)
+	}
+
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("initialize pkcs11 module: %w", err)
+	}
+
+	session, err := ctx.OpenSession(cfg.Slot, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+	if err != nil {
+		_ = ctx.Finalize()
+		ctx.Destroy()
+		return nil, fmt.Errorf("open session on slot %d: %w", cfg.Slot, err)
+	}
+
+	if err = ctx.Login(session, p11.CKU_USER, cfg.PIN); err != nil {
+		_ = ctx.CloseSession(session)
+		_ = ctx.Finalize()
+		ctx.Destroy()
+		return nil, fmt.Errorf("login to slot %d: %w", cfg.Slot, err)
+	}
+
+	return &Module{ctx: ctx, session: session}, nil
+}
+
+// Close logs out, closes the session and unloads the module
+// Signers obtained from the Module must not be used afterwards
+func (m *Module) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_ = m.ctx.Logout(m.session)
+	err := m.ctx.CloseSession(m.session)
+	if finalizeErr := m.ctx.Finalize(); err == nil {
+		err = finalizeErr
+	}
+	m.ctx.Destroy()
+
+	return err
+}
+
+// GenerateKey generates a new non-extractable secp256k1 key pair on the token labeled label
+func (m *Module) GenerateKey(label string) (*Signer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	public := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PUBLIC_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_VERIFY, true),
+		p11.NewAttribute(p11.CKA_EC_PARAMS, secp256k1Params),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+	}
+	private := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+		p11.NewAttribute(p11.CKA_TOKEN, true),
+		p11.NewAttribute(p11.CKA_PRIVATE, true),
+		p11.NewAttribute(p11.CKA_SIGN, true),
+		p11.NewAttribute(p11.CKA_SENSITIVE, true),
+		p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+	}
+
+	publicHandle, privateHandle, err := m.ctx.GenerateKeyPair(m.session,
+		[]*p11.Mechanism{p11.NewMechanism(p11.CKM_EC_KEY_PAIR_GEN, nil)}, public, private)
+	if err != nil {
+		return nil, fmt.Errorf("generate key %q: %w", label, err)
+	}
+
+	publicKey, err := m.readPublicKey(publicHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{module: m, handle: privateHandle, public: publicKey}, nil
+}
+
+// Signer returns a Signer for the existing secp256k1 key pair labeled label
+// Both the private key and its public key object must be present on the token
+func (m *Module) Signer(label string) (*Signer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	privateHandle, err := m.findObject(p11.CKO_PRIVATE_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+
+	publicHandle, err := m.findObject(p11.CKO_PUBLIC_KEY, label)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := m.readPublicKey(publicHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signer{module: m, handle: privateHandle, public: publicKey}, nil
+}
+
+// findObject returns the single EC object of class labeled label
+func (m *Module) findObject(class uint, label string) (p11.ObjectHandle, error) {
+	template := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, class),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_EC),
+		p11.NewAttribute(p11.CKA_LABEL, label),
+	}
+
+	if err := m.ctx.FindObjectsInit(m.session, template); err != nil {
+		return 0, fmt.Errorf("find key %q: %w", label, err)
+	}
+
+	objects, _, err := m.ctx.FindObjects(m.session, 2)
+	if finalizeErr := m.ctx.FindObjectsFinal(m.session); err == nil {
+		err = finalizeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("find key %q: %w", label, err)
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, fmt.Errorf("key %q not found", label)
+	case 1:
+		return objects[0], nil
+	default:
+		return 0, fmt.Errorf("key label %q is ambiguous", label)
+	}
+}
+
+// readPublicKey reads and parses the secp256k1 point of a public key object
+func (m *Module) readPublicKey(handle p11.ObjectHandle) (*hdwallet.PublicKey, error) {
+	attributes, err := m.ctx.GetAttributeValue(m.session, handle, []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_EC_PARAMS, nil),
+		p11.NewAttribute(p11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+
+	var params, point []byte
+	for _, attribute := range attributes {
+		switch attribute.Type {
+		case p11.CKA_EC_PARAMS:
+			params = attribute.Value
+		case p11.CKA_EC_POINT:
+			point = attribute.Value
+		}
+	}
+
+	if !bytes.Equal(params, secp256k1Params) {
+		return nil, errors.New("key is not on the secp256k1 curve")
+	}
+
+	// CKA_EC_POINT is a DER OCTET STRING wrapping the SEC1 point,
+	// but several modules return the raw point instead
+	var raw []byte
+	if rest, err := asn1.Unmarshal(point, &raw); err != nil || len(rest) != 0 {
+		raw = point
+	}
+
+	return hdwallet.PublicKeyFromBytes(raw)
+}
+
+// Signer is a hdwallet.Signer whose secp256k1 private key stays inside the HSM
+type Signer struct {
+	module *Module
+	handle p11.ObjectHandle
+	public *hdwallet.PublicKey
+}
+
+var _ hdwallet.Signer = (*Signer)(nil)
+
+// Public returns the *hdwallet.PublicKey of the HSM key
+func (s *Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// SignDigest signs digest with CKM_ECDSA and returns the DER encoded signature in low-S form
+// HSMs return arbitrary S values, which Bitcoin, Ethereum and TRON reject as malleable,
+// so the signature is normalized before being returned
+func (s *Signer) SignDigest(digest []byte) ([]byte, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	s.module.mu.Lock()
+	defer s.module.mu.Unlock()
+
+	ctx, session := s.module.ctx, s.module.session
+	if err := ctx.SignInit(session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_ECDSA, nil)}, s.handle); err != nil {
+		return nil, fmt.Errorf("init signature: %w", err)
+	}
+
+	raw, err := ctx.Sign(session, digest)
+	if err != nil {
+		return nil, fmt.Errorf("sign digest: %w", err)
+	}
+	if len(raw) != 64 {
+		return nil, fmt.Errorf("unexpected signature length %d", len(raw))
+	}
+
+	// CKM_ECDSA returns r || s as two 32-byte big-endian integers
+	var r, sValue secp256k1.ModNScalar
+	if overflow := r.SetByteSlice(raw[:32]); overflow || r.IsZero() {
+		return nil, errors.New("invalid signature r value")
+	}
+	if overflow := sValue.SetByteSlice(raw[32:]); overflow || sValue.IsZero() {
+		return nil, errors.New("invalid signature s value")
+	}
+
+	// Serialize always emits the canonical low-S encoding
+	return ecdsa.NewSignature(&r, &sValue).Serialize(), nil
+}
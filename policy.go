@@ -0,0 +1,409 @@
+package hdwallet
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrPolicyDenied is matched (errors.Is) by every PolicyError
+var ErrPolicyDenied = errors.New("signing denied by policy")
+
+// DenyCode classifies why a policy denied an operation
+type DenyCode string
+
+const (
+	DenyPathNotAllowed      DenyCode = "path_not_allowed"
+	DenyKeyExportBlocked    DenyCode = "key_export_blocked"
+	DenyMissingIntent       DenyCode = "missing_intent"
+	DenyAmountLimit         DenyCode = "amount_limit"
+	DenyVelocityLimit       DenyCode = "velocity_limit"
+	DenyApprovalRejected    DenyCode = "approval_rejected"
+	DenySessionRequired     DenyCode = "session_required"
+	DenySessionInvalid      DenyCode = "session_invalid"
+	DenyOperationNotAllowed DenyCode = "operation_not_allowed"
+)
+
+// PolicyError is the structured reason of a denial
+type PolicyError struct {
+	Code DenyCode
+	// Rule names the policy that denied the operation
+	Rule    string
+	Message string
+}
+
+// Error implements error
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s (%s): %s", ErrPolicyDenied, e.Code, e.Rule, e.Message)
+}
+
+// Is makes errors.Is(err, ErrPolicyDenied) true for policy denials
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyDenied
+}
+
+// SigningIntent describes what a signature authorizes; signers only see digests,
+// so callers declare the transfer with WithSigningIntent for limits and approvals
+// The chain accounts declare the intent of the transactions they sign themselves
+type SigningIntent struct {
+	Asset string
+	// Amount is nil when the transferred amount is unknown, for example for contract
+	// calls a chain account cannot decode; LimitPolicy denies such signatures
+	Amount *big.Int
+	// Destination is the receiving address, comma separated when a transaction pays several
+	Destination string
+}
+
+type signingIntentKey struct{}
+
+// WithSigningIntent returns a copy of ctx declaring the intent of the operations performed with it
+func WithSigningIntent(ctx context.Context, intent SigningIntent) context.Context {
+	return context.WithValue(ctx, signingIntentKey{}, intent)
+}
+
+// SigningRequest is the operation a Policy decides on
+type SigningRequest struct {
+	Operation AuditOperation
+	Coin      uint32
+	Path      DerivationPath
+	// Digest is nil for key derivations
+	Digest []byte
+	// Intent is nil when the caller did not declare one
+	Intent *SigningIntent
+}
+
+// newSigningRequest builds the request of an operation, picking up the intent declared in ctx
+func newSigningRequest(ctx context.Context, operation AuditOperation, coin uint32, path DerivationPath,
+	digest []byte) SigningRequest {
+	request := SigningRequest{Operation: operation, Coin: coin, Path: path, Digest: digest}
+	if intent, ok := ctx.Value(signingIntentKey{}).(SigningIntent); ok {
+		request.Intent = &intent
+	}
+
+	return request
+}
+
+// Policy decides whether a key derivation or signature may proceed
+// Denials are returned as *PolicyError; other errors also abort the operation
+type Policy interface {
+	Evaluate(ctx context.Context, request SigningRequest) error
+}
+
+// PolicyFunc adapts a function to the Policy interface
+type PolicyFunc func(ctx context.Context, request SigningRequest) error
+
+// Evaluate calls f(ctx, request)
+func (f PolicyFunc) Evaluate(ctx context.Context, request SigningRequest) error {
+	return f(ctx, request)
+}
+
+// Policies combines policies; every policy must allow the request, evaluated in order
+type Policies []Policy
+
+// Evaluate implements Policy, returning the first denial
+// The reservations of the policies that allowed the request before it are released
+func (p Policies) Evaluate(ctx context.Context, request SigningRequest) error {
+	for i, policy := range p {
+		if err := policy.Evaluate(ctx, request); err != nil {
+			p[:i].Release(ctx, request)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Release implements PolicyReleaser, releasing request in every policy
+func (p Policies) Release(ctx context.Context, request SigningRequest) {
+	for _, policy := range slices.Backward(p) {
+		releasePolicy(ctx, policy, request)
+	}
+}
+
+// PolicyReleaser is implemented by policies that reserve state when they allow a request,
+// such as the usage counted by LimitPolicy. Release gives the reservation back when the
+// allowed operation is not carried out: a later policy denied it or signing failed
+type PolicyReleaser interface {
+	Release(ctx context.Context, request SigningRequest)
+}
+
+// releasePolicy releases request in policy if the policy keeps reservations
+func releasePolicy(ctx context.Context, policy Policy, request SigningRequest) {
+	if releaser, ok := policy.(PolicyReleaser); ok {
+		releaser.Release(ctx, request)
+	}
+}
+
+// AllowPaths only allows keys at or below one of the given paths,
+// for example AllowPaths(DerivationPath{44 + HardenedOffset, 195 + HardenedOffset, HardenedOffset})
+// confines a hot wallet to m/44'/195'/0'
+func AllowPaths(prefixes ...DerivationPath) Policy {
+	return PolicyFunc(func(_ context.Context, request SigningRequest) error {
+		for _, prefix := range prefixes {
+			if prefix.Contains(request.Path) {
+				return nil
+			}
+		}
+
+		return &PolicyError{
+			Code:    DenyPathNotAllowed,
+			Rule:    "allow_paths",
+			Message: fmt.Sprintf("%s is outside the allowed paths", request.Path),
+		}
+	})
+}
+
+// DenyKeyExport denies AuditDeriveKey operations, so private keys never leave the
+// wallet and every key usage goes through SignDigest and its policies
+func DenyKeyExport() Policy {
+	return PolicyFunc(func(_ context.Context, request SigningRequest) error {
+		if request.Operation != AuditDeriveKey {
+			return nil
+		}
+
+		return &PolicyError{
+			Code:    DenyKeyExportBlocked,
+			Rule:    "deny_key_export",
+			Message: fmt.Sprintf("private key export of %s is disabled", request.Path),
+		}
+	})
+}
+
+// Limit bounds the amounts signed for an asset
+// A zero field disables the corresponding check
+type Limit struct {
+	// MaxAmount is the largest amount of a single signature
+	MaxAmount *big.Int
+	// MaxTotal is the largest amount signed within Window
+	MaxTotal *big.Int
+	// MaxCount is the largest number of signatures within Window
+	MaxCount int
+	Window   time.Duration
+}
+
+// LimitFunc returns the limits applying to a request, typically looked up
+// from configuration by asset, path or the customer in the audit context
+type LimitFunc func(ctx context.Context, request SigningRequest) (Limit, error)
+
+// LimitPolicy enforces per-signature amount limits and velocity limits over a
+// sliding window. Usage is tracked in memory per coin and asset, across every path,
+// so rotating addresses does not reset the budget. A signature is counted when this
+// policy allows it and released again when a later policy in Policies denies it or
+// signing fails (see PolicyReleaser), so concurrent signatures cannot overrun a limit
+//
+// Signatures without a declared SigningIntent are denied, since their amount is unknown
+type LimitPolicy struct {
+	mu     sync.Mutex
+	limits LimitFunc
+	usage  map[string][]limitUsage
+	now    func() time.Time
+}
+
+type limitUsage struct {
+	at     time.Time
+	amount *big.Int
+	// digest identifies the signature on Release
+	digest []byte
+}
+
+// NewLimitPolicy returns a LimitPolicy reading limits from limits
+func NewLimitPolicy(limits LimitFunc) *LimitPolicy {
+	return &LimitPolicy{limits: limits, usage: make(map[string][]limitUsage), now: time.Now}
+}
+
+// Evaluate implements Policy; key derivations are not limited
+func (p *LimitPolicy) Evaluate(ctx context.Context, request SigningRequest) error {
+	if request.Operation != AuditSign {
+		return nil
+	}
+	if request.Intent == nil || request.Intent.Amount == nil {
+		return &PolicyError{Code: DenyMissingIntent, Rule: "limits", Message: "signature has no declared amount"}
+	}
+
+	limit, err := p.limits(ctx, request)
+	if err != nil {
+		return fmt.Errorf("load limits: %w", err)
+	}
+
+	amount := request.Intent.Amount
+	if amount.Sign() < 0 {
+		// A negative amount would lower the running total and bypass the velocity limits
+		return &PolicyError{
+			Code:    DenyAmountLimit,
+			Rule:    "limits",
+			Message: fmt.Sprintf("amount %s %s is negative", amount, request.Intent.Asset),
+		}
+	}
+	if limit.MaxAmount != nil && amount.Cmp(limit.MaxAmount) > 0 {
+		return &PolicyError{
+			Code:    DenyAmountLimit,
+			Rule:    "limits",
+			Message: fmt.Sprintf("amount %s %s exceeds the limit of %s", amount, request.Intent.Asset, limit.MaxAmount),
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := limitKey(request)
+	now := p.now()
+
+	// Drop usage that left the window; without a window nothing is kept
+	recent := p.usage[key][:0]
+	for _, usage := range p.usage[key] {
+		if limit.Window > 0 && now.Sub(usage.at) < limit.Window {
+			recent = append(recent, usage)
+		}
+	}
+
+	if limit.Window > 0 {
+		if limit.MaxCount > 0 && len(recent)+1 > limit.MaxCount {
+			p.usage[key] = recent
+			return &PolicyError{
+				Code:    DenyVelocityLimit,
+				Rule:    "limits",
+				Message: fmt.Sprintf("more than %d signatures within %s", limit.MaxCount, limit.Window),
+			}
+		}
+
+		if limit.MaxTotal != nil {
+			total := new(big.Int).Set(amount)
+			for _, usage := range recent {
+				total.Add(total, usage.amount)
+			}
+			if total.Cmp(limit.MaxTotal) > 0 {
+				p.usage[key] = recent
+				return &PolicyError{
+					Code:    DenyVelocityLimit,
+					Rule:    "limits",
+					Message: fmt.Sprintf("total %s %s within %s exceeds %s", total, request.Intent.Asset, limit.Window, limit.MaxTotal),
+				}
+			}
+		}
+
+		recent = append(recent, limitUsage{
+			at:     now,
+			amount: new(big.Int).Set(amount),
+			digest: bytes.Clone(request.Digest),
+		})
+	}
+	p.usage[key] = recent
+
+	return nil
+}
+
+// Release implements PolicyReleaser, removing the usage recorded for request
+func (p *LimitPolicy) Release(_ context.Context, request SigningRequest) {
+	if request.Operation != AuditSign || request.Intent == nil || request.Intent.Amount == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := limitKey(request)
+	usages := p.usage[key]
+	for i, usage := range slices.Backward(usages) {
+		if bytes.Equal(usage.digest, request.Digest) && usage.amount.Cmp(request.Intent.Amount) == 0 {
+			p.usage[key] = slices.Delete(usages, i, i+1)
+			return
+		}
+	}
+}
+
+// limitKey scopes the usage of request to its coin and asset
+func limitKey(request SigningRequest) string {
+	return fmt.Sprintf("%d/%s", request.Coin, request.Intent.Asset)
+}
+
+// ApprovalHook asks an external party (a human operator, a second service) to approve
+// a request; returning an error rejects it. Hooks may block until a decision is made
+// and should honour ctx cancellation
+type ApprovalHook interface {
+	Approve(ctx context.Context, request SigningRequest) error
+}
+
+// ApprovalHookFunc adapts a function to the ApprovalHook interface
+type ApprovalHookFunc func(ctx context.Context, request SigningRequest) error
+
+// Approve calls f(ctx, request)
+func (f ApprovalHookFunc) Approve(ctx context.Context, request SigningRequest) error {
+	return f(ctx, request)
+}
+
+// RequireApproval makes every signature wait for hook; derivations are not submitted
+func RequireApproval(hook ApprovalHook) Policy {
+	return PolicyFunc(func(ctx context.Context, request SigningRequest) error {
+		if request.Operation != AuditSign {
+			return nil
+		}
+
+		if err := hook.Approve(ctx, request); err != nil {
+			var denial *PolicyError
+			if errors.As(err, &denial) {
+				return err
+			}
+
+			return &PolicyError{Code: DenyApprovalRejected, Rule: "approval", Message: err.Error()}
+		}
+
+		return nil
+	})
+}
+
+// PolicySigner enforces a Policy in front of an external Signer (HSM, hardware token),
+// so signers that are not derived by a Wallet obey the same rules
+type PolicySigner struct {
+	signer Signer
+	policy Policy
+	coin   uint32
+	path   DerivationPath
+}
+
+// NewPolicySigner wraps signer; coin and path describe the key to the policy
+func NewPolicySigner(signer Signer, policy Policy, coin uint32, path DerivationPath) *PolicySigner {
+	return &PolicySigner{signer: signer, policy: policy, coin: coin, path: path}
+}
+
+// Public returns the public key of the wrapped signer
+func (s *PolicySigner) Public() crypto.PublicKey {
+	return s.signer.Public()
+}
+
+// SignDigest evaluates the policy and then signs digest with the wrapped signer
+func (s *PolicySigner) SignDigest(digest []byte) ([]byte, error) {
+	return s.SignDigestContext(context.Background(), digest)
+}
+
+// SignDigestContext is SignDigest with a context carrying the signing intent
+func (s *PolicySigner) SignDigestContext(ctx context.Context, digest []byte) ([]byte, error) {
+	request := newSigningRequest(ctx, AuditSign, s.coin, s.path, digest)
+	if err := s.policy.Evaluate(ctx, request); err != nil {
+		return nil, err
+	}
+
+	signature, err := s.sign(ctx, digest)
+	if err != nil {
+		releasePolicy(ctx, s.policy, request)
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// sign signs digest with the wrapped signer, passing ctx when it takes one
+func (s *PolicySigner) sign(ctx context.Context, digest []byte) ([]byte, error) {
+	if contextSigner, ok := s.signer.(interface {
+		SignDigestContext(ctx context.Context, digest []byte) ([]byte, error)
+	}); ok {
+		return contextSigner.SignDigestContext(ctx, digest)
+	}
+
+	return s.signer.SignDigest(digest)
+}
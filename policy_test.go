@@ -0,0 +1,235 @@
+package hdwallet
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// denyCode returns the code of a policy denial, "" when err allows the request
+func denyCode(t *testing.T, err error) DenyCode {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var denial *PolicyError
+	if !errors.As(err, &denial) {
+		t.Fatalf("error %v is not a policy denial", err)
+	}
+	if !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("error %v does not match ErrPolicyDenied", err)
+	}
+
+	return denial.Code
+}
+
+func signRequest(path DerivationPath, asset string, amount int64) SigningRequest {
+	return SigningRequest{
+		Operation: AuditSign,
+		Coin:      195,
+		Path:      path,
+		Digest:    make([]byte, 32),
+		Intent:    &SigningIntent{Asset: asset, Amount: big.NewInt(amount)},
+	}
+}
+
+func TestAllowPaths(t *testing.T) {
+	policy := AllowPaths(
+		DerivationPath{44 + HardenedOffset, 195 + HardenedOffset, HardenedOffset},
+		DerivationPath{84 + HardenedOffset},
+	)
+
+	for _, test := range []struct {
+		path string
+		want DenyCode
+	}{
+		{"m/44'/195'/0'", ""},
+		{"m/44'/195'/0'/0/7", ""},
+		{"m/84'/0'/3'/1/0", ""},
+		{"m/44'/195'/1'/0/0", DenyPathNotAllowed},
+		{"m/44'/195'/0/0/0", DenyPathNotAllowed},
+		{"m/44'/195'", DenyPathNotAllowed},
+		{"m/44'/60'/0'/0/0", DenyPathNotAllowed},
+		{"m", DenyPathNotAllowed},
+	} {
+		t.Run(test.path, func(t *testing.T) {
+			path, err := ParseDerivationPath(test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := SigningRequest{Operation: AuditSign, Coin: 195, Path: path}
+			if got := denyCode(t, policy.Evaluate(context.Background(), request)); got != test.want {
+				t.Errorf("Evaluate = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// limitStep evaluates request after advancing the clock by after
+type limitStep struct {
+	after   time.Duration
+	request SigningRequest
+	want    DenyCode
+}
+
+func TestLimitPolicy(t *testing.T) {
+	limit := Limit{MaxAmount: big.NewInt(100), MaxTotal: big.NewInt(250), MaxCount: 3, Window: time.Hour}
+	first := BIP44Path(195, 0, 0, 0)
+	second := BIP44Path(195, 0, 0, 1)
+
+	for _, test := range []struct {
+		name  string
+		steps []limitStep
+	}{
+		{
+			name: "amount",
+			steps: []limitStep{
+				{0, signRequest(first, "TRX", 100), ""},
+				{0, signRequest(first, "TRX", 101), DenyAmountLimit},
+				{0, signRequest(first, "TRX", -1), DenyAmountLimit},
+				{0, SigningRequest{Operation: AuditSign, Path: first}, DenyMissingIntent},
+				{0, SigningRequest{Operation: AuditSign, Path: first, Intent: &SigningIntent{Asset: "TRX"}}, DenyMissingIntent},
+			},
+		},
+		{
+			name: "total across paths",
+			steps: []limitStep{
+				{0, signRequest(first, "TRX", 100), ""},
+				{0, signRequest(second, "TRX", 100), ""},
+				{0, signRequest(second, "TRX", 51), DenyVelocityLimit},
+				{0, signRequest(first, "TRX", 50), ""},
+				{0, signRequest(first, "USDT", 100), ""},
+			},
+		},
+		{
+			name: "count",
+			steps: []limitStep{
+				{0, signRequest(first, "TRX", 1), ""},
+				{0, signRequest(second, "TRX", 1), ""},
+				{0, signRequest(first, "TRX", 1), ""},
+				{0, signRequest(second, "TRX", 1), DenyVelocityLimit},
+			},
+		},
+		{
+			name: "window expiry",
+			steps: []limitStep{
+				{0, signRequest(first, "TRX", 100), ""},
+				{30 * time.Minute, signRequest(first, "TRX", 100), ""},
+				{0, signRequest(first, "TRX", 100), DenyVelocityLimit},
+				// The first signature leaves the window one hour after it was made
+				{30 * time.Minute, signRequest(first, "TRX", 100), ""},
+				{0, signRequest(first, "TRX", 100), DenyVelocityLimit},
+				{2 * time.Hour, signRequest(first, "TRX", 100), ""},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			policy := NewLimitPolicy(func(context.Context, SigningRequest) (Limit, error) {
+				return limit, nil
+			})
+			policy.now = func() time.Time { return now }
+
+			for i, step := range test.steps {
+				now = now.Add(step.after)
+				if got := denyCode(t, policy.Evaluate(context.Background(), step.request)); got != step.want {
+					t.Errorf("step %d: Evaluate = %q, want %q", i, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLimitPolicyRelease(t *testing.T) {
+	limits := NewLimitPolicy(func(context.Context, SigningRequest) (Limit, error) {
+		return Limit{MaxCount: 1, Window: time.Hour}, nil
+	})
+	deny := true
+	policy := Policies{limits, PolicyFunc(func(context.Context, SigningRequest) error {
+		if deny {
+			return &PolicyError{Code: DenyApprovalRejected, Rule: "test"}
+		}
+		return nil
+	})}
+	request := signRequest(BIP44Path(195, 0, 0, 0), "TRX", 1)
+
+	if got := denyCode(t, policy.Evaluate(context.Background(), request)); got != DenyApprovalRejected {
+		t.Fatalf("Evaluate = %q, want %q", got, DenyApprovalRejected)
+	}
+	// The denied signature must not use up the only signature of the window
+	deny = false
+	if err := policy.Evaluate(context.Background(), request); err != nil {
+		t.Fatalf("Evaluate after a later denial: %v", err)
+	}
+
+	// A signature that fails after being allowed is released by its signer
+	policy.Release(context.Background(), request)
+	if err := policy.Evaluate(context.Background(), request); err != nil {
+		t.Fatalf("Evaluate after Release: %v", err)
+	}
+	if got := denyCode(t, policy.Evaluate(context.Background(), request)); got != DenyVelocityLimit {
+		t.Errorf("Evaluate = %q, want %q", got, DenyVelocityLimit)
+	}
+}
+
+func TestRequireApproval(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		operation AuditOperation
+		decision  error
+		want      DenyCode
+	}{
+		{"approved", AuditSign, nil, ""},
+		{"rejected", AuditSign, errors.New("operator rejected the transfer"), DenyApprovalRejected},
+		{"canceled", AuditSign, context.Canceled, DenyApprovalRejected},
+		{"policy denial", AuditSign, &PolicyError{Code: DenyAmountLimit, Rule: "hook"}, DenyAmountLimit},
+		{"derivation", AuditDeriveKey, errors.New("never asked"), ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			asked := false
+			policy := RequireApproval(ApprovalHookFunc(func(context.Context, SigningRequest) error {
+				asked = true
+				return test.decision
+			}))
+
+			request := signRequest(BIP44Path(195, 0, 0, 0), "TRX", 1)
+			request.Operation = test.operation
+			if got := denyCode(t, policy.Evaluate(context.Background(), request)); got != test.want {
+				t.Errorf("Evaluate = %q, want %q", got, test.want)
+			}
+			if asked != (test.operation == AuditSign) {
+				t.Errorf("hook asked = %v", asked)
+			}
+		})
+	}
+}
+
+func TestWalletReleasesFailedSignatures(t *testing.T) {
+	limits := NewLimitPolicy(func(context.Context, SigningRequest) (Limit, error) {
+		return Limit{MaxCount: 1, Window: time.Hour}, nil
+	})
+	auditErr := errors.New("audit log unavailable")
+	failing := true
+	wallet := newTestWallet(t, 195, WithPolicy(limits), WithAuditor(AuditorFunc(func(AuditEvent) error {
+		if failing {
+			return auditErr
+		}
+		return nil
+	})))
+	ctx := WithSigningIntent(context.Background(), SigningIntent{Asset: "TRX", Amount: big.NewInt(1)})
+	digest := make([]byte, 32)
+
+	if _, err := wallet.SignDigestContext(ctx, 0, 0, 0, digest); !errors.Is(err, auditErr) {
+		t.Fatalf("SignDigestContext = %v, want the audit error", err)
+	}
+	failing = false
+	if _, err := wallet.SignDigestContext(ctx, 0, 0, 1, digest); err != nil {
+		t.Fatalf("SignDigestContext after a failed signature: %v", err)
+	}
+	_, err := wallet.SignDigestContext(ctx, 0, 0, 2, digest)
+	if got := denyCode(t, err); got != DenyVelocityLimit {
+		t.Errorf("SignDigestContext = %q, want %q", got, DenyVelocityLimit)
+	}
+}
@@ -0,0 +1,69 @@
+// Package prometheus exports hdwallet operation metrics to Prometheus
+//
+//	metrics := prometheus.New("wallet")
+//	registry.MustRegister(metrics)
+//	hdwallet.SetDefaultMetrics(metrics)
+//
+// Two metric families are produced, labelled by operation, coin and result
+// ("ok", "denied", "error"):
+//
+//	<namespace>_hdwallet_operations_total          counter
+//	<namespace>_hdwallet_operation_duration_seconds histogram
+package prometheus
+
+import (
+	"github.com/not-for-prod/hdwallet"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements hdwallet.Metrics and prometheus.Collector
+type Metrics struct {
+	operations *prom.CounterVec
+	duration   *prom.HistogramVec
+}
+
+// New returns the collector; namespace prefixes the metric names and may be empty
+func New(namespace string) *Metrics {
+	labels := []string{"operation", "coin", "result"}
+
+	return &Metrics{
+		operations: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "hdwallet",
+			Name:      "operations_total",
+			Help:      "Number of mnemonic generations, key derivations and signatures.",
+		}, labels),
+		duration: prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "hdwallet",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of mnemonic generations, key derivations and signatures.",
+			// Derivations take tens of microseconds, signatures gated by approval hooks seconds
+			Buckets: prom.ExponentialBuckets(0.00001, 4, 12),
+		}, labels),
+	}
+}
+
+// Observe implements hdwallet.Metrics
+func (m *Metrics) Observe(observation hdwallet.Observation) {
+	labels := prom.Labels{
+		"operation": string(observation.Operation),
+		"coin":      observation.Coin,
+		"result":    observation.Result(),
+	}
+
+	m.operations.With(labels).Inc()
+	m.duration.With(labels).Observe(observation.Duration.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prom.Desc) {
+	m.operations.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prom.Metric) {
+	m.operations.Collect(ch)
+	m.duration.Collect(ch)
+}
@@ -0,0 +1,191 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/tyler-smith/go-bip32"
+)
+
+// tenantIndexLabel domain-separates the tenant index key derived from the master key
+const tenantIndexLabel = "hdwallet-tenant-index/v1"
+
+// maxTenantProbes bounds the collision resolution of tenant indexes; with 2^31
+// indexes a second probe is already rare, a hundred means the registry is broken
+const maxTenantProbes = 100
+
+// TenantRegistry records which tenant owns which account index
+//
+// ClaimIndex must be atomic across every Provisioner sharing the registry, so two
+// tenants whose keyed hashes collide never end up with the same account
+type TenantRegistry interface {
+	// ClaimIndex assigns index to tenant unless another tenant owns it,
+	// and returns the owner of index after the call
+	ClaimIndex(index uint32, tenant string) (owner string, err error)
+}
+
+// Provisioner derives an isolated BIP44 account per tenant of a multi-tenant service
+//
+// The account index of a tenant is a keyed hash of its identifier, so indexes are
+// stable without bookkeeping and do not reveal tenant identifiers to anyone holding
+// the xpubs. Collisions are resolved through the TenantRegistry by probing the next
+// hash. Tenants only hold their account key: they cannot derive any key of another
+// tenant or of the master tree
+type Provisioner struct {
+	masterKey *bip32.Key
+	coin      uint32
+	indexKey  []byte
+	registry  TenantRegistry
+}
+
+// NewProvisioner returns a Provisioner for coin from a BIP39 seed
+// When registry is nil a MemoryTenantRegistry is used, which only detects
+// collisions between tenants provisioned by this process
+func NewProvisioner(seed []byte, coin uint32, registry TenantRegistry) (*Provisioner, error) {
+	if coin >= HardenedOffset {
+		return nil, fmt.Errorf("coin type %d out of range", coin)
+	}
+
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The index key is bound to the seed, so indexes are reproducible after a restore
+	mac := hmac.New(sha256.New, masterKey.Key)
+	mac.Write([]byte(tenantIndexLabel))
+
+	if registry == nil {
+		registry = NewMemoryTenantRegistry()
+	}
+
+	return &Provisioner{
+		masterKey: masterKey,
+		coin:      coin,
+		indexKey:  mac.Sum(nil),
+		registry:  registry,
+	}, nil
+}
+
+// Tenant provisions, or reopens, the account of tenant
+func (p *Provisioner) Tenant(tenant string) (*Tenant, error) {
+	if tenant == "" {
+		return nil, errors.New("tenant identifier is empty")
+	}
+
+	for probe := uint32(0); probe < maxTenantProbes; probe++ {
+		index := p.tenantIndex(tenant, probe)
+
+		owner, err := p.registry.ClaimIndex(index, tenant)
+		if err != nil {
+			return nil, fmt.Errorf("claim account %d for tenant %q: %w", index, tenant, err)
+		}
+		if owner != tenant {
+			continue
+		}
+
+		accountKey, err := DeriveAccountKey(p.masterKey, p.coin, index)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Tenant{id: tenant, coin: p.coin, account: index, accountKey: accountKey}, nil
+	}
+
+	return nil, fmt.Errorf("no free account index for tenant %q", tenant)
+}
+
+// tenantIndex returns the candidate account index of tenant for a probe number
+func (p *Provisioner) tenantIndex(tenant string, probe uint32) uint32 {
+	mac := hmac.New(sha256.New, p.indexKey)
+	mac.Write(binary.BigEndian.AppendUint32(nil, probe))
+	mac.Write([]byte(tenant))
+
+	return binary.BigEndian.Uint32(mac.Sum(nil)) &^ HardenedOffset
+}
+
+// Tenant is the account subtree m/44'/coin'/index' of a single tenant
+type Tenant struct {
+	id         string
+	coin       uint32
+	account    uint32
+	accountKey *bip32.Key
+}
+
+// ID returns the tenant identifier
+func (t *Tenant) ID() string {
+	return t.id
+}
+
+// Account returns the hardened account index of the tenant (without HardenedOffset)
+func (t *Tenant) Account() uint32 {
+	return t.account
+}
+
+// Path returns the derivation path of an address of the tenant
+func (t *Tenant) Path(chain, address uint32) DerivationPath {
+	return BIP44Path(t.coin, t.account, chain, address)
+}
+
+// XPub returns the extended public key of the tenant account, the only key to export to the tenant
+func (t *Tenant) XPub() string {
+	return t.accountKey.PublicKey().String()
+}
+
+// DeriveKey derives the private key at chain/address below the tenant account
+func (t *Tenant) DeriveKey(chain, address uint32) (*PrivateKey, error) {
+	if chain >= HardenedOffset || address >= HardenedOffset {
+		return nil, errors.New("tenant keys only use non-hardened chain and address indexes")
+	}
+
+	child, err := t.accountKey.NewChildKey(chain)
+	if err != nil {
+		return nil, err
+	}
+	child, err = child.NewChildKey(address)
+	if err != nil {
+		return nil, err
+	}
+
+	return PrivateKeyFromSecp256k1(secp256k1.PrivKeyFromBytes(child.Key)), nil
+}
+
+// PublicKey derives the public key at chain/address below the tenant account
+func (t *Tenant) PublicKey(chain, address uint32) (*PublicKey, error) {
+	privateKey, err := t.DeriveKey(chain, address)
+	if err != nil {
+		return nil, err
+	}
+	defer privateKey.Zero()
+
+	return privateKey.PublicKey(), nil
+}
+
+// MemoryTenantRegistry is a TenantRegistry kept in process memory
+type MemoryTenantRegistry struct {
+	mu     sync.Mutex
+	owners map[uint32]string
+}
+
+// NewMemoryTenantRegistry returns an empty MemoryTenantRegistry
+func NewMemoryTenantRegistry() *MemoryTenantRegistry {
+	return &MemoryTenantRegistry{owners: make(map[uint32]string)}
+}
+
+// ClaimIndex implements TenantRegistry
+func (r *MemoryTenantRegistry) ClaimIndex(index uint32, tenant string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if owner, ok := r.owners[index]; ok {
+		return owner, nil
+	}
+	r.owners[index] = tenant
+
+	return tenant, nil
+}
@@ -0,0 +1,121 @@
+package hdwallet
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// PublicKeyFormat is a serialization of a secp256k1 public key
+type PublicKeyFormat int
+
+const (
+	// PublicKeyCompressed is the 33-byte SEC1 form 0x02/0x03 || X
+	PublicKeyCompressed PublicKeyFormat = iota
+	// PublicKeyUncompressed is the 65-byte SEC1 form 0x04 || X || Y
+	PublicKeyUncompressed
+	// PublicKeyRaw is the 64-byte X || Y form without prefix, used by Ethereum and TRON hashing
+	PublicKeyRaw
+	// PublicKeyXOnly is the 32-byte BIP340 form, X of the point with even Y
+	PublicKeyXOnly
+)
+
+// String returns the name of the format
+func (f PublicKeyFormat) String() string {
+	switch f {
+	case PublicKeyCompressed:
+		return "compressed"
+	case PublicKeyUncompressed:
+		return "uncompressed"
+	case PublicKeyRaw:
+		return "raw"
+	case PublicKeyXOnly:
+		return "x-only"
+	default:
+		return fmt.Sprintf("PublicKeyFormat(%d)", int(f))
+	}
+}
+
+// ParsePublicKey parses a public key in any PublicKeyFormat, detected from its length
+// The point is checked to be on the curve; x-only keys are lifted to the point with even Y
+func ParsePublicKey(data []byte) (*PublicKey, PublicKeyFormat, error) {
+	var (
+		publicKey *secp256k1.PublicKey
+		format    PublicKeyFormat
+		err       error
+	)
+
+	switch len(data) {
+	case secp256k1.PubKeyBytesLenCompressed:
+		format = PublicKeyCompressed
+		publicKey, err = secp256k1.ParsePubKey(data)
+	case secp256k1.PubKeyBytesLenUncompressed:
+		format = PublicKeyUncompressed
+		if data[0] != secp256k1.PubKeyFormatUncompressed {
+			// Hybrid keys (0x06/0x07) are accepted by dcrd but by no chain we support
+			return nil, 0, fmt.Errorf("invalid uncompressed public key prefix 0x%02x", data[0])
+		}
+		publicKey, err = secp256k1.ParsePubKey(data)
+	case 64:
+		format = PublicKeyRaw
+		publicKey, err = secp256k1.ParsePubKey(append([]byte{secp256k1.PubKeyFormatUncompressed}, data...))
+	case 32:
+		// BIP340 lifts X to the point with even Y, which is the compressed 0x02 form
+		format = PublicKeyXOnly
+		publicKey, err = secp256k1.ParsePubKey(append([]byte{secp256k1.PubKeyFormatCompressedEven}, data...))
+	default:
+		return nil, 0, fmt.Errorf("invalid public key length %d", len(data))
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("parse %s public key: %w", format, err)
+	}
+
+	return &PublicKey{key: publicKey}, format, nil
+}
+
+// ParsePublicKeyHex is ParsePublicKey for hex input, with or without a 0x prefix
+func ParsePublicKeyHex(s string) (*PublicKey, PublicKeyFormat, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, 0, fmt.Errorf("decode public key: %w", err)
+	}
+
+	return ParsePublicKey(data)
+}
+
+// SerializePublicKey encodes publicKey in the given format
+// The x-only form drops the parity of Y, so converting back yields the even-Y point
+func SerializePublicKey(publicKey *PublicKey, format PublicKeyFormat) ([]byte, error) {
+	switch format {
+	case PublicKeyCompressed:
+		return publicKey.SerializeCompressed(), nil
+	case PublicKeyUncompressed:
+		return publicKey.SerializeUncompressed(), nil
+	case PublicKeyRaw:
+		return publicKey.SerializeUncompressed()[1:], nil
+	case PublicKeyXOnly:
+		return publicKey.SerializeCompressed()[1:], nil
+	default:
+		return nil, fmt.Errorf("unknown public key format %s", format)
+	}
+}
+
+// ConvertPublicKey parses data in any format and re-encodes it in format
+func ConvertPublicKey(data []byte, format PublicKeyFormat) ([]byte, error) {
+	publicKey, _, err := ParsePublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return SerializePublicKey(publicKey, format)
+}
+
+// ValidatePublicKey checks that data encodes a point on the secp256k1 curve
+func ValidatePublicKey(data []byte) error {
+	_, _, err := ParsePublicKey(data)
+	return err
+}
@@ -0,0 +1,147 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// MaxChallengeAttempts is the number of wrong answers after which a MnemonicChallenge
+// is exhausted and a new one must be issued
+const MaxChallengeAttempts = 3
+
+var (
+	// ErrChallengeFailed is returned by MnemonicChallenge.Verify for wrong answers
+	ErrChallengeFailed = errors.New("recovery phrase words do not match")
+	// ErrChallengeExhausted is returned once MaxChallengeAttempts wrong answers were given
+	ErrChallengeExhausted = errors.New("too many wrong answers, issue a new challenge")
+)
+
+// MnemonicChallenge asks the user to re-enter some words of a freshly generated
+// mnemonic, confirming that the phrase was written down before it is discarded
+//
+// The challenge does not contain the words: it holds their positions and a salted
+// commitment to them, so it can be kept in a session store while the mnemonic itself
+// is forgotten right after being displayed. The commitment can still be brute forced
+// in 2048^len(Positions) hashes, keep it on the server side
+type MnemonicChallenge struct {
+	// Positions are the 1-based word numbers to ask for, in ascending order
+	Positions  []int  `json:"positions"`
+	Salt       []byte `json:"salt"`
+	Commitment []byte `json:"commitment"`
+	// Attempts counts the wrong answers given so far
+	Attempts int `json:"attempts"`
+}
+
+// NewMnemonicChallenge picks count distinct random word positions of mnemonic,
+// reading randomness from DefaultEntropySource
+func NewMnemonicChallenge(mnemonic string, count int) (*MnemonicChallenge, error) {
+	return NewMnemonicChallengeFromSource(DefaultEntropySource(), mnemonic, count)
+}
+
+// NewMnemonicChallengeFromSource is NewMnemonicChallenge reading randomness from source
+func NewMnemonicChallengeFromSource(source EntropySource, mnemonic string, count int) (*MnemonicChallenge, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("invalid mnemonic")
+	}
+
+	words := strings.Fields(mnemonic)
+	if count < 1 || count > len(words) {
+		return nil, fmt.Errorf("challenge word count %d out of range [1, %d]", count, len(words))
+	}
+
+	// Partial Fisher-Yates shuffle of the word numbers
+	positions := make([]int, len(words))
+	for i := range positions {
+		positions[i] = i + 1
+	}
+	for i := range count {
+		j, err := rand.Int(source, big.NewInt(int64(len(words)-i)))
+		if err != nil {
+			return nil, fmt.Errorf("read entropy: %w", err)
+		}
+		k := i + int(j.Int64())
+		positions[i], positions[k] = positions[k], positions[i]
+	}
+	positions = positions[:count]
+	slices.Sort(positions)
+
+	challenge := &MnemonicChallenge{Positions: positions, Salt: make([]byte, 32)}
+	if err := readEntropy(source, challenge.Salt); err != nil {
+		return nil, err
+	}
+
+	expected := make([]string, count)
+	for i, position := range positions {
+		expected[i] = words[position-1]
+	}
+	challenge.Commitment = challenge.commit(expected)
+
+	return challenge, nil
+}
+
+// Prompt returns an English instruction such as "Enter words 3, 7 and 11 of your recovery phrase"
+// It is empty for a challenge without positions
+func (c *MnemonicChallenge) Prompt() string {
+	numbers := make([]string, len(c.Positions))
+	for i, position := range c.Positions {
+		numbers[i] = strconv.Itoa(position)
+	}
+
+	switch len(numbers) {
+	case 0:
+		// A zero challenge asks for nothing
+		return ""
+	case 1:
+		return fmt.Sprintf("Enter word %s of your recovery phrase", numbers[0])
+	default:
+		last := len(numbers) - 1
+		return fmt.Sprintf("Enter words %s and %s of your recovery phrase", strings.Join(numbers[:last], ", "), numbers[last])
+	}
+}
+
+// Verify checks the words entered for Positions, in the same order
+// Answers are compared case-insensitively and ignoring surrounding spaces; which of
+// the words is wrong is deliberately not reported
+func (c *MnemonicChallenge) Verify(answers []string) error {
+	if c.Attempts >= MaxChallengeAttempts {
+		return ErrChallengeExhausted
+	}
+	if len(answers) != len(c.Positions) {
+		return fmt.Errorf("expected %d words, got %d", len(c.Positions), len(answers))
+	}
+
+	if !hmac.Equal(c.commit(answers), c.Commitment) {
+		c.Attempts++
+		if c.Attempts >= MaxChallengeAttempts {
+			return ErrChallengeExhausted
+		}
+		return ErrChallengeFailed
+	}
+
+	return nil
+}
+
+// commit returns SHA-256(salt || positions || words), words being length-prefixed and normalized
+func (c *MnemonicChallenge) commit(words []string) []byte {
+	hash := sha256.New()
+	hash.Write([]byte("hdwallet-mnemonic-challenge/v1"))
+	hash.Write(c.Salt)
+	for i, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		_ = binary.Write(hash, binary.BigEndian, uint32(c.Positions[i]))
+		_ = binary.Write(hash, binary.BigEndian, uint32(len(word)))
+		hash.Write([]byte(word))
+	}
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,359 @@
+// Package recovery searches for a partially known BIP39 mnemonic: missing or
+// misspelled words and swapped neighbours, checked against a known address
+//
+// Candidates failing the BIP39 checksum are discarded before the expensive PBKDF2
+// stretch, which runs on parallel workers; a search can be stopped with its context
+package recovery
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tyler-smith/go-bip39"
+)
+
+// ErrNotFound is returned when no candidate matches the target
+var ErrNotFound = errors.New("no candidate mnemonic matches the target")
+
+// DefaultMaxCandidates bounds the searched space unless Search.MaxCandidates is set,
+// about a day of work on a large server
+const DefaultMaxCandidates = 1 << 36
+
+// Slot is one word position of the phrase and the words it may hold
+type Slot struct {
+	Candidates []string
+}
+
+// ParsePhrase parses a space separated phrase template where each word is
+//
+//	word       a known word; a word missing from the wordlist expands to the similar words
+//	word?      an uncertain word, expanded to the similar words of the wordlist
+//	a|b|c      one of the listed words
+//	?          any word
+//
+// Similar words share their first four letters (unique in BIP39 lists) or are one edit away
+func ParsePhrase(template string) ([]Slot, error) {
+	fields := strings.Fields(strings.ToLower(template))
+	if len(fields)%3 != 0 || len(fields) < 12 || len(fields) > 24 {
+		return nil, fmt.Errorf("phrase has %d words, expected 12, 15, 18, 21 or 24", len(fields))
+	}
+
+	wordlist := bip39.GetWordList()
+	slots := make([]Slot, len(fields))
+	for i, field := range fields {
+		var candidates []string
+		switch {
+		case field == "?":
+			candidates = slices.Clone(wordlist)
+		case strings.Contains(field, "|"):
+			for _, word := range strings.Split(field, "|") {
+				if _, ok := bip39.GetWordIndex(word); !ok {
+					return nil, fmt.Errorf("word %d: %q is not in the wordlist", i+1, word)
+				}
+				candidates = append(candidates, word)
+			}
+		case strings.HasSuffix(field, "?"):
+			candidates = similarWords(strings.TrimSuffix(field, "?"), wordlist)
+		default:
+			if _, ok := bip39.GetWordIndex(field); ok {
+				candidates = []string{field}
+			} else {
+				candidates = similarWords(field, wordlist)
+			}
+		}
+
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("word %d: no wordlist word resembles %q", i+1, field)
+		}
+		slots[i] = Slot{Candidates: slices.Compact(candidates)}
+	}
+
+	return slots, nil
+}
+
+// similarWords returns the words of wordlist resembling word, word itself included
+func similarWords(word string, wordlist []string) []string {
+	var similar []string
+	for _, candidate := range wordlist {
+		samePrefix := len(word) >= 4 && len(candidate) >= 4 && word[:4] == candidate[:4]
+		if candidate == word || samePrefix || oneEditAway(word, candidate) {
+			similar = append(similar, candidate)
+		}
+	}
+
+	return similar
+}
+
+// oneEditAway reports whether a and b differ by one insertion, deletion,
+// substitution or transposition of adjacent letters
+func oneEditAway(a, b string) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	switch len(a) - len(b) {
+	case 0:
+		var diff []int
+		for i := range a {
+			if a[i] != b[i] {
+				diff = append(diff, i)
+			}
+		}
+		return len(diff) == 1 ||
+			len(diff) == 2 && diff[1] == diff[0]+1 && a[diff[0]] == b[diff[1]] && a[diff[1]] == b[diff[0]]
+	case 1:
+		i := 0
+		for i < len(b) && a[i] == b[i] {
+			i++
+		}
+		return a[i+1:] == b[i:]
+	default:
+		return false
+	}
+}
+
+// Target recognizes the wallet being recovered
+type Target interface {
+	// Match reports whether the seed of a candidate mnemonic is the wallet's
+	Match(seed []byte) (bool, error)
+}
+
+// TargetFunc adapts a function to the Target interface
+type TargetFunc func(seed []byte) (bool, error)
+
+// Match calls f(seed)
+func (f TargetFunc) Match(seed []byte) (bool, error) {
+	return f(seed)
+}
+
+// Search describes a recovery search
+type Search struct {
+	Slots []Slot
+	// Passphrases are tried for every candidate, the empty passphrase when none are given
+	Passphrases []string
+	// Swaps also tries every variant of the phrase with two neighbouring words exchanged
+	Swaps  bool
+	Target Target
+	// Workers is the number of parallel PBKDF2 workers, GOMAXPROCS when zero
+	Workers int
+	// MaxCandidates refuses searches over larger spaces, DefaultMaxCandidates when zero
+	MaxCandidates uint64
+}
+
+// Result is a found mnemonic
+type Result struct {
+	Mnemonic   string
+	Passphrase string
+	// Checked is the number of checksum-valid candidates whose seed was computed
+	Checked uint64
+}
+
+// Space returns the number of phrases to enumerate, before the checksum filter
+// discards most of them, and false when it does not fit in a uint64
+func (s *Search) Space() (uint64, bool) {
+	total := uint64(len(s.variants()))
+	for _, slot := range s.Slots {
+		hi, lo := bits.Mul64(total, uint64(len(slot.Candidates)))
+		if hi != 0 {
+			return math.MaxUint64, false
+		}
+		total = lo
+	}
+
+	return total, true
+}
+
+// Run searches until a candidate matches the target, the space is exhausted
+// (ErrNotFound) or ctx is done (ctx.Err())
+func (s *Search) Run(ctx context.Context) (*Result, error) {
+	if s.Target == nil {
+		return nil, errors.New("search has no target")
+	}
+	if n := len(s.Slots); n%3 != 0 || n < 12 || n > 24 {
+		return nil, fmt.Errorf("phrase has %d words, expected 12, 15, 18, 21 or 24", n)
+	}
+
+	limit := s.MaxCandidates
+	if limit == 0 {
+		limit = DefaultMaxCandidates
+	}
+	space, ok := s.Space()
+	if !ok || space > limit {
+		return nil, fmt.Errorf("search space of %d phrases exceeds the limit of %d", space, limit)
+	}
+
+	slots, err := s.indexSlots()
+	if err != nil {
+		return nil, err
+	}
+	variants := s.variants()
+	passphrases := s.Passphrases
+	if len(passphrases) == 0 {
+		passphrases = []string{""}
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		once    sync.Once
+		result  *Result
+		failure error
+		checked atomic.Uint64
+	)
+	finish := func(r *Result, err error) {
+		once.Do(func() {
+			result, failure = r, err
+			cancel()
+		})
+	}
+
+	perVariant := space / uint64(len(variants))
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			words := make([]int, len(slots))
+			// Workers take interleaved candidates so they progress through the space evenly
+			for step, n := 0, uint64(worker); n < space; step, n = step+1, n+uint64(workers) {
+				if step%1024 == 0 && ctx.Err() != nil {
+					return
+				}
+
+				order := variants[n/perVariant]
+				rest := n % perVariant
+				for i := len(order) - 1; i >= 0; i-- {
+					candidates := slots[order[i]]
+					words[i] = candidates[rest%uint64(len(candidates))]
+					rest /= uint64(len(candidates))
+				}
+				if !validChecksum(words) {
+					continue
+				}
+
+				mnemonic := phrase(words)
+				for _, passphrase := range passphrases {
+					checked.Add(1)
+					match, err := s.Target.Match(bip39.NewSeed(mnemonic, passphrase))
+					if err != nil {
+						finish(nil, err)
+						return
+					}
+					if match {
+						finish(&Result{Mnemonic: mnemonic, Passphrase: passphrase}, nil)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case result != nil:
+		result.Checked = checked.Load()
+		return result, nil
+	case failure != nil:
+		return nil, failure
+	case parent.Err() != nil:
+		return nil, parent.Err()
+	default:
+		return nil, ErrNotFound
+	}
+}
+
+// indexSlots converts the slot words to wordlist indexes
+func (s *Search) indexSlots() ([][]int, error) {
+	slots := make([][]int, len(s.Slots))
+	for i, slot := range s.Slots {
+		if len(slot.Candidates) == 0 {
+			return nil, fmt.Errorf("word %d has no candidates", i+1)
+		}
+		for _, word := range slot.Candidates {
+			index, ok := bip39.GetWordIndex(word)
+			if !ok {
+				return nil, fmt.Errorf("word %d: %q is not in the wordlist", i+1, word)
+			}
+			slots[i] = append(slots[i], index)
+		}
+	}
+
+	return slots, nil
+}
+
+// variants returns the slot orders to try: the phrase as given and, with Swaps,
+// each exchange of neighbouring slots that actually changes the phrase
+func (s *Search) variants() [][]int {
+	identity := make([]int, len(s.Slots))
+	for i := range identity {
+		identity[i] = i
+	}
+	variants := [][]int{identity}
+
+	if s.Swaps {
+		for i := 0; i+1 < len(s.Slots); i++ {
+			if slices.Equal(s.Slots[i].Candidates, s.Slots[i+1].Candidates) {
+				continue
+			}
+			swapped := slices.Clone(identity)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			variants = append(variants, swapped)
+		}
+	}
+
+	return variants
+}
+
+// validChecksum checks the BIP39 checksum of a phrase given as wordlist indexes
+func validChecksum(words []int) bool {
+	totalBits := len(words) * 11
+	checksumBits := totalBits / 33
+	entropy := make([]byte, (totalBits-checksumBits)/8)
+
+	var (
+		acc      uint64
+		accBits  int
+		out      int
+		checksum uint64
+	)
+	for _, word := range words {
+		acc = acc<<11 | uint64(word)
+		accBits += 11
+		for accBits >= 8 && out < len(entropy) {
+			accBits -= 8
+			entropy[out] = byte(acc >> accBits)
+			out++
+		}
+	}
+	checksum = acc & (1<<accBits - 1)
+
+	hash := sha256.Sum256(entropy)
+
+	return uint64(hash[0]>>(8-checksumBits)) == checksum
+}
+
+func phrase(words []int) string {
+	wordlist := bip39.GetWordList()
+	parts := make([]string, len(words))
+	for i, word := range words {
+		parts[i] = wordlist[word]
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,157 @@
+package recovery
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// AddressTarget matches wallets with Address among the first Indexes receiving
+// addresses (chain 0) of their first Accounts accounts of Coin under Purpose,
+// m/purpose'/coin'/account'/0/index
+type AddressTarget struct {
+	purpose  uint32
+	coin     uint32
+	address  string
+	format   hdwallet.AddressFormat
+	accounts uint32
+	indexes  uint32
+}
+
+// NewAddressTarget returns the target of address, which is validated and normalized
+// with hdwallet.NormalizeAddress, at paths under purpose (hdwallet.Purpose,
+// hdwallet.BIP84Purpose, ...); format must produce addresses of coin
+//
+// The address, and the addresses produced by format, must be of the kind derived
+// under purpose (see hdwallet.AddressPurpose): a bc1q address is only found at
+// m/84', and a search anywhere else would silently find nothing
+func NewAddressTarget(coin, purpose uint32, address string, format hdwallet.AddressFormat, accounts, indexes uint32) (*AddressTarget, error) {
+	normalized, err := hdwallet.NormalizeAddress(coin, address)
+	if err != nil {
+		return nil, err
+	}
+	addressPurpose, err := hdwallet.AddressPurpose(coin, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if addressPurpose != purpose {
+		return nil, fmt.Errorf("%s is derived under purpose %d', not %d'", normalized, addressPurpose, purpose)
+	}
+
+	// Format a fixed key to check that format produces addresses of the same kind
+	var one secp256k1.ModNScalar
+	one.SetInt(1)
+	sample := format(hdwallet.PublicKeyFromSecp256k1(secp256k1.NewPrivateKey(&one).PubKey()))
+	formatPurpose, err := hdwallet.AddressPurpose(coin, sample)
+	if err != nil {
+		return nil, fmt.Errorf("format does not produce addresses of coin %d: %w", coin, err)
+	}
+	if formatPurpose != purpose {
+		return nil, fmt.Errorf("format produces addresses derived under purpose %d', not %d'", formatPurpose, purpose)
+	}
+
+	if accounts == 0 || indexes == 0 || accounts > hdwallet.HardenedOffset || indexes > hdwallet.HardenedOffset {
+		return nil, fmt.Errorf("invalid scan range of %d accounts and %d indexes", accounts, indexes)
+	}
+
+	return &AddressTarget{
+		purpose:  purpose,
+		coin:     coin,
+		address:  normalized,
+		format:   format,
+		accounts: accounts,
+		indexes:  indexes,
+	}, nil
+}
+
+// Match implements Target
+//
+// Derivation is done directly on secp256k1 scalars rather than with bip32.Key,
+// as it runs once per checksum-valid candidate
+func (t *AddressTarget) Match(seed []byte) (bool, error) {
+	master, chainCode := masterKey(seed)
+
+	purpose, purposeCode, err := childKey(&master, chainCode, t.purpose+hdwallet.HardenedOffset)
+	if err != nil {
+		return false, err
+	}
+	coin, coinCode, err := childKey(&purpose, purposeCode, t.coin+hdwallet.HardenedOffset)
+	if err != nil {
+		return false, err
+	}
+
+	for account := range t.accounts {
+		accountKey, accountCode, err := childKey(&coin, coinCode, account+hdwallet.HardenedOffset)
+		if err != nil {
+			return false, err
+		}
+		chain, chainCode, err := childKey(&accountKey, accountCode, 0)
+		if err != nil {
+			return false, err
+		}
+
+		chainPublic := secp256k1.NewPrivateKey(&chain).PubKey().SerializeCompressed()
+		for index := range t.indexes {
+			child, _, err := deriveChild(&chain, chainPublic, chainCode, index)
+			if err != nil {
+				// BIP32 skips invalid children, wallets never use them
+				continue
+			}
+			if t.format(hdwallet.PublicKeyFromSecp256k1(secp256k1.NewPrivateKey(&child).PubKey())) == t.address {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// masterKey returns the BIP32 master private key and chain code of seed
+func masterKey(seed []byte) (secp256k1.ModNScalar, []byte) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	// A master key out of range has probability below 2^-127 and is rejected by bip32 too;
+	// the overflowed value simply never matches
+	var key secp256k1.ModNScalar
+	key.SetByteSlice(sum[:32])
+
+	return key, sum[32:]
+}
+
+// childKey is BIP32 CKDpriv
+func childKey(parent *secp256k1.ModNScalar, chainCode []byte, index uint32) (secp256k1.ModNScalar, []byte, error) {
+	if index < hdwallet.HardenedOffset {
+		return deriveChild(parent, secp256k1.NewPrivateKey(parent).PubKey().SerializeCompressed(), chainCode, index)
+	}
+
+	parentBytes := parent.Bytes()
+
+	return deriveChild(parent, append([]byte{0x00}, parentBytes[:]...), chainCode, index)
+}
+
+// deriveChild computes parse256(IL) + parent with I = HMAC-SHA512(chainCode, data || index),
+// data being the compressed parent public key for non-hardened indexes
+func deriveChild(parent *secp256k1.ModNScalar, data, chainCode []byte, index uint32) (secp256k1.ModNScalar, []byte, error) {
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(data)
+	_ = binary.Write(mac, binary.BigEndian, index)
+	sum := mac.Sum(nil)
+
+	var child secp256k1.ModNScalar
+	if overflow := child.SetByteSlice(sum[:32]); overflow {
+		return child, nil, errors.New("invalid child key")
+	}
+	child.Add(parent)
+	if child.IsZero() {
+		return child, nil, errors.New("invalid child key")
+	}
+
+	return child, sum[32:], nil
+}
@@ -0,0 +1,381 @@
+package hdwallet
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secpecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// PrivateKey is a secp256k1 private key
+//
+// PrivateKey, PublicKey and Signature are the package's own key types: their
+// operations go through the Secp256k1Backend installed with SetDefaultSecp256k1Backend,
+// and consumers built on them keep working when the backend changes. The exported
+// API takes and returns them; keys of code using dcrd directly convert with
+// PrivateKeyFromSecp256k1 and Secp256k1
+type PrivateKey struct {
+	key *secp256k1.PrivateKey
+}
+
+// PrivateKeyFromBytes parses a 32-byte big-endian private key
+// Zero and values not below the curve order are rejected instead of being reduced
+func PrivateKeyFromBytes(data []byte) (*PrivateKey, error) {
+	if len(data) != secp256k1.PrivKeyBytesLen {
+		return nil, fmt.Errorf("private key must be %d bytes, got %d", secp256k1.PrivKeyBytesLen, len(data))
+	}
+
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(data); overflow || scalar.IsZero() {
+		scalar.Zero()
+		return nil, errors.New("private key out of range")
+	}
+
+	return &PrivateKey{key: secp256k1.NewPrivateKey(&scalar)}, nil
+}
+
+// PrivateKeyFromSecp256k1 wraps a dcrd private key; both values share the key, so
+// Zero wipes the dcrd key too
+func PrivateKeyFromSecp256k1(key *secp256k1.PrivateKey) *PrivateKey {
+	return &PrivateKey{key: key}
+}
+
+// Secp256k1 returns the dcrd form of the key, sharing its memory
+func (k *PrivateKey) Secp256k1() *secp256k1.PrivateKey {
+	return k.key
+}
+
+// Bytes returns the 32-byte big-endian private key
+func (k *PrivateKey) Bytes() []byte {
+	return k.key.Serialize()
+}
+
+// PublicKey returns the public key of k
+func (k *PrivateKey) PublicKey() *PublicKey {
+	return &PublicKey{key: k.key.PubKey()}
+}
+
+// Public implements Signer, returning a *PublicKey
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return k.PublicKey()
+}
+
+// Sign signs a 32-byte digest with the default backend
+// The signature is deterministic (RFC 6979), low-S and carries its recovery ID
+func (k *PrivateKey) Sign(digest []byte) (*Signature, error) {
+	return DefaultSecp256k1Backend().Sign(k, digest)
+}
+
+// SignDigest implements Signer, returning the DER encoded signature
+func (k *PrivateKey) SignDigest(digest []byte) ([]byte, error) {
+	signature, err := k.Sign(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature.DER(), nil
+}
+
+// ECDH returns the shared secret of k and publicKey, SHA-256 of the compressed
+// shared point as computed by libsecp256k1's secp256k1_ecdh
+func (k *PrivateKey) ECDH(publicKey *PublicKey) ([]byte, error) {
+	return DefaultSecp256k1Backend().ECDH(k, publicKey)
+}
+
+// Zero wipes the private key from memory
+func (k *PrivateKey) Zero() {
+	k.key.Zero()
+}
+
+// PublicKey is a secp256k1 public key, see PrivateKey
+type PublicKey struct {
+	key *secp256k1.PublicKey
+}
+
+// Verifier checks signatures of message digests
+// It is implemented by *PublicKey, and by remote or hardware verifiers
+type Verifier interface {
+	Verify(digest []byte, signature *Signature) bool
+}
+
+var _ Verifier = (*PublicKey)(nil)
+
+// PublicKeyFromBytes parses a public key in any PublicKeyFormat, see ParsePublicKey
+func PublicKeyFromBytes(data []byte) (*PublicKey, error) {
+	publicKey, _, err := ParsePublicKey(data)
+
+	return publicKey, err
+}
+
+// PublicKeyFromSecp256k1 wraps a dcrd public key
+func PublicKeyFromSecp256k1(publicKey *secp256k1.PublicKey) *PublicKey {
+	return &PublicKey{key: publicKey}
+}
+
+// Secp256k1 returns the dcrd form of the key
+func (p *PublicKey) Secp256k1() *secp256k1.PublicKey {
+	return p.key
+}
+
+// SerializeCompressed returns the 33-byte SEC1 compressed form
+func (p *PublicKey) SerializeCompressed() []byte {
+	return p.key.SerializeCompressed()
+}
+
+// SerializeUncompressed returns the 65-byte SEC1 uncompressed form
+func (p *PublicKey) SerializeUncompressed() []byte {
+	return p.key.SerializeUncompressed()
+}
+
+// Serialize encodes the key in format, see SerializePublicKey
+func (p *PublicKey) Serialize(format PublicKeyFormat) ([]byte, error) {
+	return SerializePublicKey(p, format)
+}
+
+// Verify reports whether signature is a valid signature of digest by p, using the default backend
+func (p *PublicKey) Verify(digest []byte, signature *Signature) bool {
+	return DefaultSecp256k1Backend().Verify(p, digest, signature)
+}
+
+// Equal reports whether both keys are the same point
+func (p *PublicKey) Equal(other *PublicKey) bool {
+	return p.key.IsEqual(other.key)
+}
+
+// String returns the hex compressed form of the key
+func (p *PublicKey) String() string {
+	return hex.EncodeToString(p.key.SerializeCompressed())
+}
+
+// RecoverPublicKey returns the public key that produced signature over digest,
+// which must carry a recovery ID (see ParseCompactSignature and PrivateKey.Sign)
+func RecoverPublicKey(digest []byte, signature *Signature) (*PublicKey, error) {
+	return DefaultSecp256k1Backend().Recover(digest, signature)
+}
+
+// Signature is a secp256k1 ECDSA signature, optionally carrying its recovery ID
+type Signature struct {
+	r, s        secp256k1.ModNScalar
+	recoveryID  byte
+	recoverable bool
+}
+
+// NewSignature returns the signature (r, s), with recoveryID when it is 0 to 3
+// and without one when it is negative
+func NewSignature(r, s [32]byte, recoveryID int) (*Signature, error) {
+	signature := &Signature{}
+	if overflow := signature.r.SetBytes(&r); overflow != 0 || signature.r.IsZero() {
+		return nil, errors.New("signature r out of range")
+	}
+	if overflow := signature.s.SetBytes(&s); overflow != 0 || signature.s.IsZero() {
+		return nil, errors.New("signature s out of range")
+	}
+	if recoveryID > 3 {
+		return nil, fmt.Errorf("invalid recovery ID %d", recoveryID)
+	}
+	if recoveryID >= 0 {
+		signature.recoveryID = byte(recoveryID)
+		signature.recoverable = true
+	}
+
+	return signature, nil
+}
+
+// ParseDERSignature parses a strict DER encoded signature, as returned by Signer
+func ParseDERSignature(der []byte) (*Signature, error) {
+	parsed, err := secpecdsa.ParseDERSignature(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return SignatureFromSecp256k1(parsed), nil
+}
+
+// ParseCompactSignature parses a 64-byte r || s signature or a 65-byte r || s || v
+// signature of Ethereum and TRON, where v is the recovery ID (0 to 3) optionally offset by 27
+func ParseCompactSignature(data []byte) (*Signature, error) {
+	recoveryID := -1
+	switch len(data) {
+	case 64:
+	case 65:
+		recoveryID = int(data[64])
+		if recoveryID >= 27 {
+			recoveryID -= 27
+		}
+		if recoveryID > 3 {
+			return nil, fmt.Errorf("invalid recovery ID %d", data[64])
+		}
+	default:
+		return nil, fmt.Errorf("compact signature must be 64 or 65 bytes, got %d", len(data))
+	}
+
+	return NewSignature([32]byte(data[:32]), [32]byte(data[32:64]), recoveryID)
+}
+
+// SignatureFromSecp256k1 converts a dcrd signature, which has no recovery ID
+func SignatureFromSecp256k1(signature *secpecdsa.Signature) *Signature {
+	return &Signature{r: signature.R(), s: signature.S()}
+}
+
+// Secp256k1 returns the dcrd form of the signature
+func (sig *Signature) Secp256k1() *secpecdsa.Signature {
+	return secpecdsa.NewSignature(&sig.r, &sig.s)
+}
+
+// R returns the 32-byte big-endian r value
+func (sig *Signature) R() [32]byte {
+	return sig.r.Bytes()
+}
+
+// S returns the 32-byte big-endian s value
+func (sig *Signature) S() [32]byte {
+	return sig.s.Bytes()
+}
+
+// RecoveryID returns the recovery ID, if the signature carries one
+func (sig *Signature) RecoveryID() (byte, bool) {
+	return sig.recoveryID, sig.recoverable
+}
+
+// IsLowS reports whether s is at most half the curve order, as Bitcoin and Ethereum require
+func (sig *Signature) IsLowS() bool {
+	return !sig.s.IsOverHalfOrder()
+}
+
+// Normalize returns the low-S form of the signature; negating s flips the
+// parity of the recovery ID
+func (sig *Signature) Normalize() *Signature {
+	normalized := *sig
+	if sig.s.IsOverHalfOrder() {
+		normalized.s.Negate()
+		normalized.recoveryID ^= 1
+	}
+
+	return &normalized
+}
+
+// DER returns the DER encoding of the signature
+func (sig *Signature) DER() []byte {
+	return sig.Secp256k1().Serialize()
+}
+
+// Compact returns the 64-byte r || s form
+func (sig *Signature) Compact() []byte {
+	r, s := sig.R(), sig.S()
+
+	return append(r[:], s[:]...)
+}
+
+// Recoverable returns the 65-byte r || s || v form with v the raw recovery ID
+func (sig *Signature) Recoverable() ([]byte, error) {
+	if !sig.recoverable {
+		return nil, errors.New("signature has no recovery ID")
+	}
+
+	return append(sig.Compact(), sig.recoveryID), nil
+}
+
+// Secp256k1Backend implements the secp256k1 operations of PrivateKey, PublicKey
+// and RecoverPublicKey
+//
+// GoSecp256k1Backend is the default; builds with cgo can install the libsecp256k1
+// backend for faster signing and verification. Backends must sign deterministically
+// (RFC 6979) and return low-S signatures with their recovery ID, so that every
+// backend produces the same signatures
+type Secp256k1Backend interface {
+	Sign(key *PrivateKey, digest []byte) (*Signature, error)
+	Verify(publicKey *PublicKey, digest []byte, signature *Signature) bool
+	Recover(digest []byte, signature *Signature) (*PublicKey, error)
+	ECDH(key *PrivateKey, publicKey *PublicKey) ([]byte, error)
+}
+
+// GoSecp256k1Backend is the pure Go Secp256k1Backend built on dcrd
+type GoSecp256k1Backend struct{}
+
+var _ Secp256k1Backend = GoSecp256k1Backend{}
+
+// Sign implements Secp256k1Backend
+func (GoSecp256k1Backend) Sign(key *PrivateKey, digest []byte) (*Signature, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	// SignCompact returns header || r || s with header = 27 + recovery ID
+	compact := secpecdsa.SignCompact(key.key, digest, false)
+
+	return NewSignature([32]byte(compact[1:33]), [32]byte(compact[33:65]), int(compact[0]-27))
+}
+
+// Verify implements Secp256k1Backend
+// Like dcrd, it accepts high-S signatures; callers needing low-S check IsLowS
+func (GoSecp256k1Backend) Verify(publicKey *PublicKey, digest []byte, signature *Signature) bool {
+	if len(digest) != 32 {
+		return false
+	}
+
+	return signature.Secp256k1().Verify(digest, publicKey.key)
+}
+
+// Recover implements Secp256k1Backend
+func (GoSecp256k1Backend) Recover(digest []byte, signature *Signature) (*PublicKey, error) {
+	recoveryID, ok := signature.RecoveryID()
+	if !ok {
+		return nil, errors.New("signature has no recovery ID")
+	}
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	compact := append([]byte{27 + recoveryID}, signature.Compact()...)
+	publicKey, _, err := secpecdsa.RecoverCompact(compact, digest)
+	if err != nil {
+		return nil, fmt.Errorf("recover public key: %w", err)
+	}
+
+	return &PublicKey{key: publicKey}, nil
+}
+
+// ECDH implements Secp256k1Backend
+func (GoSecp256k1Backend) ECDH(key *PrivateKey, publicKey *PublicKey) ([]byte, error) {
+	var point, shared secp256k1.JacobianPoint
+	publicKey.key.AsJacobian(&point)
+	secp256k1.ScalarMultNonConst(&key.key.Key, &point, &shared)
+	shared.ToAffine()
+
+	compressed := secp256k1.NewPublicKey(&shared.X, &shared.Y).SerializeCompressed()
+	sum := sha256.Sum256(compressed)
+	wipeBytes(compressed)
+
+	return sum[:], nil
+}
+
+// defaultSecp256k1Backend holds the backend used by the key types and by KeySigner
+var defaultSecp256k1Backend atomic.Pointer[secp256k1BackendHolder]
+
+type secp256k1BackendHolder struct {
+	backend Secp256k1Backend
+}
+
+// DefaultSecp256k1Backend returns the backend installed with SetDefaultSecp256k1Backend,
+// GoSecp256k1Backend when none is
+func DefaultSecp256k1Backend() Secp256k1Backend {
+	if holder := defaultSecp256k1Backend.Load(); holder != nil {
+		return holder.backend
+	}
+
+	return GoSecp256k1Backend{}
+}
+
+// SetDefaultSecp256k1Backend installs backend for the key types, KeySigner and VerifyDigest
+// Passing nil restores GoSecp256k1Backend
+func SetDefaultSecp256k1Backend(backend Secp256k1Backend) {
+	if backend == nil {
+		backend = GoSecp256k1Backend{}
+	}
+	defaultSecp256k1Backend.Store(&secp256k1BackendHolder{backend})
+}
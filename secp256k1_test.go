@@ -0,0 +1,51 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestParseCompactSignature(t *testing.T) {
+	key, err := PrivateKeyFromBytes(bytes.Repeat([]byte{0x01}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("compact"))
+	signature, err := key.Sign(digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	recoverable, err := signature.Recoverable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for v := range 256 {
+		data := append(recoverable[:64:64], byte(v))
+		parsed, err := ParseCompactSignature(data)
+		valid := v <= 3 || v >= 27 && v <= 30
+		if valid != (err == nil) {
+			t.Errorf("ParseCompactSignature with v = %d: error = %v, want valid %t", v, err, valid)
+			continue
+		}
+		if !valid {
+			continue
+		}
+		if recoveryID, ok := parsed.RecoveryID(); !ok || int(recoveryID) != v%27 {
+			t.Errorf("ParseCompactSignature with v = %d: recovery ID = %d, %t, want %d", v, recoveryID, ok, v%27)
+		}
+	}
+
+	parsed, err := ParseCompactSignature(append(recoverable[:64:64], 27+recoverable[64]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := RecoverPublicKey(digest[:], parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !recovered.Equal(key.PublicKey()) {
+		t.Error("RecoverPublicKey of a signature offset by 27 recovered another key")
+	}
+}
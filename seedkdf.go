@@ -0,0 +1,130 @@
+package hdwallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SeedLength is the length of a BIP39 seed
+const SeedLength = 64
+
+// SeedKDF stretches a BIP39 mnemonic and passphrase into the 64-byte seed,
+// PBKDF2-HMAC-SHA512(mnemonic, "mnemonic" || passphrase, 2048 iterations)
+//
+// Constrained devices can delegate the 2048 HMAC-SHA512 rounds to a hardware
+// accelerator or a trusted remote service by installing their own implementation
+// with SetDefaultSeedKDF or WithSeedKDF; CheckSeedKDF verifies it against the BIP39
+// test vectors first. Implementations must not retain mnemonic or passphrase
+type SeedKDF interface {
+	Seed(ctx context.Context, mnemonic, passphrase []byte) ([]byte, error)
+}
+
+// SeedKDFFunc adapts a function to the SeedKDF interface
+type SeedKDFFunc func(ctx context.Context, mnemonic, passphrase []byte) ([]byte, error)
+
+// Seed calls f(ctx, mnemonic, passphrase)
+func (f SeedKDFFunc) Seed(ctx context.Context, mnemonic, passphrase []byte) ([]byte, error) {
+	return f(ctx, mnemonic, passphrase)
+}
+
+// PBKDF2SeedKDF computes the seed in process, exactly as bip39.NewSeed does
+type PBKDF2SeedKDF struct{}
+
+// Seed implements SeedKDF
+func (PBKDF2SeedKDF) Seed(_ context.Context, mnemonic, passphrase []byte) ([]byte, error) {
+	salt := append([]byte("mnemonic"), passphrase...)
+	defer wipeBytes(salt)
+
+	return pbkdf2.Key(mnemonic, salt, 2048, SeedLength, sha512.New), nil
+}
+
+// defaultSeedKDF holds the SeedKDF used by package functions and wallets without WithSeedKDF
+var defaultSeedKDF atomic.Pointer[seedKDFHolder]
+
+type seedKDFHolder struct {
+	kdf SeedKDF
+}
+
+// DefaultSeedKDF returns the SeedKDF installed with SetDefaultSeedKDF, PBKDF2SeedKDF when none is
+func DefaultSeedKDF() SeedKDF {
+	if holder := defaultSeedKDF.Load(); holder != nil {
+		return holder.kdf
+	}
+
+	return PBKDF2SeedKDF{}
+}
+
+// SetDefaultSeedKDF installs kdf for package functions (GenerateKeysFromMnemonic,
+// MnemonicToSeed) and for wallets created without WithSeedKDF
+// Passing nil restores PBKDF2SeedKDF
+func SetDefaultSeedKDF(kdf SeedKDF) {
+	if kdf == nil {
+		kdf = PBKDF2SeedKDF{}
+	}
+	defaultSeedKDF.Store(&seedKDFHolder{kdf})
+}
+
+// WithSeedKDF makes NewWallet and Unlock compute the seed with kdf instead of DefaultSeedKDF
+func WithSeedKDF(kdf SeedKDF) WalletOption {
+	return func(w *Wallet) {
+		w.seedKDF = kdf
+	}
+}
+
+// MnemonicToSeed returns the BIP39 seed of mnemonic and passphrase computed by DefaultSeedKDF
+// The mnemonic is not validated, see bip39.IsMnemonicValid
+func MnemonicToSeed(ctx context.Context, mnemonic, passphrase string) ([]byte, error) {
+	return stretchSeed(ctx, DefaultSeedKDF(), []byte(mnemonic), []byte(passphrase))
+}
+
+// stretchSeed runs kdf and checks the length of its result
+func stretchSeed(ctx context.Context, kdf SeedKDF, mnemonic, passphrase []byte) ([]byte, error) {
+	seed, err := kdf.Seed(ctx, mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("derive seed: %w", err)
+	}
+	if len(seed) != SeedLength {
+		wipeBytes(seed)
+		return nil, fmt.Errorf("derive seed: got %d bytes, want %d", len(seed), SeedLength)
+	}
+
+	return seed, nil
+}
+
+// seedKDFVectors are test vectors of the BIP39 reference implementation (passphrase "TREZOR")
+var seedKDFVectors = []struct {
+	mnemonic string
+	seed     string
+}{
+	{
+		mnemonic: "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		seed:     "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		mnemonic: "legal winner thank year wave sausage worth useful legal winner thank yellow",
+		seed:     "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+}
+
+// CheckSeedKDF runs kdf on the BIP39 test vectors, so a hardware or remote backend
+// producing non-standard seeds is rejected before any wallet depends on it
+func CheckSeedKDF(ctx context.Context, kdf SeedKDF) error {
+	for i, vector := range seedKDFVectors {
+		seed, err := stretchSeed(ctx, kdf, []byte(vector.mnemonic), []byte("TREZOR"))
+		if err != nil {
+			return fmt.Errorf("seed kdf vector %d: %w", i, err)
+		}
+		want, _ := hex.DecodeString(vector.seed)
+		if !bytes.Equal(seed, want) {
+			return fmt.Errorf("seed kdf vector %d: seed mismatch", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,320 @@
+package hdwallet
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSessionTTL is the lifetime of session tokens issued without an explicit one
+	DefaultSessionTTL = 5 * time.Minute
+	// MaxSessionTTL is the longest lifetime of a session token
+	MaxSessionTTL = time.Hour
+)
+
+const (
+	// sessionTokenPrefix versions the token format
+	sessionTokenPrefix = "hws1"
+	// sessionMACDomain domain-separates the token MAC from other uses of the key
+	sessionMACDomain = "hdwallet-session/v1"
+)
+
+var (
+	// ErrSessionInvalid is returned for malformed tokens and tokens not issued under the key
+	ErrSessionInvalid = errors.New("invalid session token")
+	// ErrSessionExpired is returned for tokens past their expiry
+	ErrSessionExpired = errors.New("session token expired")
+	// ErrSessionRevoked is returned for tokens whose session was revoked
+	ErrSessionRevoked = errors.New("session token revoked")
+)
+
+// SessionGrant is what a session token authorizes: the operations a client may
+// request and the paths it may request them on
+type SessionGrant struct {
+	// ID identifies the session for revocation and audit logs, set by Issue
+	ID string `json:"id"`
+	// Subject names the client the session was issued to
+	Subject string `json:"subject,omitempty"`
+	// Operations lists the allowed operations, for example only AuditSign
+	Operations []AuditOperation `json:"operations"`
+	// Paths allows keys at or below each path, as AllowPaths does
+	Paths []DerivationPath `json:"paths,omitempty"`
+	// Templates allows the expansions of each template
+	Templates []PathTemplate `json:"templates,omitempty"`
+	IssuedAt  time.Time      `json:"issued_at"`
+	ExpiresAt time.Time      `json:"expires_at"`
+}
+
+// allowsPath reports whether path is below one of the granted paths or matches a granted template
+func (g *SessionGrant) allowsPath(path DerivationPath) bool {
+	if path == nil {
+		return false
+	}
+	for _, prefix := range g.Paths {
+		if prefix.Contains(path) {
+			return true
+		}
+	}
+	for _, template := range g.Templates {
+		if _, ok := template.Match(path); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+type sessionTokenKey struct{}
+
+// WithSessionToken returns a copy of ctx presenting token to the SessionIssuer policy,
+// typically taken by a remote signer adapter from the request, see SessionIssuer.Handler
+func WithSessionToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, sessionTokenKey{}, token)
+}
+
+// RevocationStore records revoked sessions
+//
+// Signer processes sharing a session key must share the store (a database table or a
+// cache with expiry, for example), or a session revoked on one of them stays valid on
+// the others until its tokens expire
+type RevocationStore interface {
+	// RevokeSession records that session id is revoked; the record may be dropped
+	// after until, when every token of the session has expired
+	RevokeSession(id string, until time.Time) error
+	// SessionRevoked reports whether session id is revoked
+	SessionRevoked(id string) (bool, error)
+}
+
+// MemoryRevocationStore is a RevocationStore kept in process memory, for signers
+// running as a single process
+type MemoryRevocationStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryRevocationStore returns an empty MemoryRevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: make(map[string]time.Time), now: time.Now}
+}
+
+// RevokeSession implements RevocationStore, dropping expired revocations
+func (s *MemoryRevocationStore) RevokeSession(id string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for revokedID, expiry := range s.revoked {
+		if now.After(expiry) {
+			delete(s.revoked, revokedID)
+		}
+	}
+	s.revoked[id] = until
+
+	return nil
+}
+
+// SessionRevoked implements RevocationStore
+func (s *MemoryRevocationStore) SessionRevoked(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.revoked[id]
+
+	return ok, nil
+}
+
+// SessionIssuer issues short-lived session tokens for remote signer deployments and
+// enforces them as a Policy: every derivation and signature must present a valid
+// token, with WithSessionToken or Handler, whose grant covers the requested operation
+// and path, so a client cannot reach keys outside its grant
+//
+// Tokens are HMAC-SHA256 authenticated and stateless; revoked sessions are recorded
+// in a RevocationStore until their tokens would have expired. Rotate the key to
+// revoke every session at once
+type SessionIssuer struct {
+	key         []byte
+	revocations RevocationStore
+	now         func() time.Time
+}
+
+// NewSessionIssuer returns an issuer authenticating tokens with key, at least 32
+// random bytes. Every signer process accepting the tokens needs the key and the same
+// revocations store; nil keeps revocations in the memory of this process
+func NewSessionIssuer(key []byte, revocations RevocationStore) (*SessionIssuer, error) {
+	if len(key) < 32 {
+		return nil, fmt.Errorf("session key has %d bytes, expected at least 32", len(key))
+	}
+	if revocations == nil {
+		revocations = NewMemoryRevocationStore()
+	}
+
+	return &SessionIssuer{
+		key:         append([]byte(nil), key...),
+		revocations: revocations,
+		now:         time.Now,
+	}, nil
+}
+
+// Issue returns a token for grant valid for ttl (DefaultSessionTTL when zero, at most
+// MaxSessionTTL) and the grant as issued, with its ID and validity set
+func (i *SessionIssuer) Issue(grant SessionGrant, ttl time.Duration) (string, SessionGrant, error) {
+	if ttl == 0 {
+		ttl = DefaultSessionTTL
+	}
+	if ttl < 0 || ttl > MaxSessionTTL {
+		return "", SessionGrant{}, fmt.Errorf("session lifetime %s is not within (0, %s]", ttl, MaxSessionTTL)
+	}
+	if len(grant.Operations) == 0 {
+		return "", SessionGrant{}, errors.New("session grant allows no operation")
+	}
+	if len(grant.Paths) == 0 && len(grant.Templates) == 0 {
+		return "", SessionGrant{}, errors.New("session grant allows no path")
+	}
+
+	id := make([]byte, 16)
+	if err := readEntropy(DefaultEntropySource(), id); err != nil {
+		return "", SessionGrant{}, err
+	}
+
+	now := i.now().UTC().Truncate(time.Second)
+	grant.ID = hex.EncodeToString(id)
+	grant.Operations = slices.Clone(grant.Operations)
+	grant.Paths = slices.Clone(grant.Paths)
+	grant.Templates = slices.Clone(grant.Templates)
+	grant.IssuedAt = now
+	grant.ExpiresAt = now.Add(ttl)
+
+	payload, err := json.Marshal(grant)
+	if err != nil {
+		return "", SessionGrant{}, fmt.Errorf("encode session grant: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := base64.RawURLEncoding.EncodeToString(i.mac(encoded))
+
+	return sessionTokenPrefix + "." + encoded + "." + mac, grant, nil
+}
+
+// Verify returns the grant of token, failing with ErrSessionInvalid, ErrSessionExpired
+// or ErrSessionRevoked
+func (i *SessionIssuer) Verify(token string) (*SessionGrant, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != sessionTokenPrefix {
+		return nil, ErrSessionInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(mac, i.mac(parts[1])) {
+		return nil, ErrSessionInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+
+	var grant SessionGrant
+	if err = json.Unmarshal(payload, &grant); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSessionInvalid, err)
+	}
+
+	now := i.now()
+	if !now.Before(grant.ExpiresAt) {
+		return nil, fmt.Errorf("%w at %s", ErrSessionExpired, grant.ExpiresAt.Format(time.RFC3339))
+	}
+
+	revoked, err := i.revocations.SessionRevoked(grant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("check session revocation: %w", err)
+	}
+	if revoked {
+		return nil, ErrSessionRevoked
+	}
+
+	return &grant, nil
+}
+
+// Revoke rejects the tokens of session id from now on
+func (i *SessionIssuer) Revoke(id string) error {
+	// No token outlives MaxSessionTTL, the revocation can be dropped after it
+	if err := i.revocations.RevokeSession(id, i.now().Add(MaxSessionTTL)); err != nil {
+		return fmt.Errorf("revoke session %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Handler authenticates the requests of a remote signer adapter served over HTTP
+// (JSON-RPC, for example): it rejects requests without a valid bearer token with
+// 401 Unauthorized and passes the token of the others to next with WithSessionToken,
+// so the wallets behind next evaluate it against their operations
+func (i *SessionIssuer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "session token required", http.StatusUnauthorized)
+			return
+		}
+		token = strings.TrimSpace(token)
+		if _, err := i.Verify(token); err != nil {
+			if !errors.Is(err, ErrSessionInvalid) && !errors.Is(err, ErrSessionExpired) && !errors.Is(err, ErrSessionRevoked) {
+				http.Error(w, "session check failed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithSessionToken(r.Context(), token)))
+	})
+}
+
+// Evaluate implements Policy, denying requests without a valid token in ctx or
+// outside the grant of the token
+func (i *SessionIssuer) Evaluate(ctx context.Context, request SigningRequest) error {
+	token, ok := ctx.Value(sessionTokenKey{}).(string)
+	if !ok || token == "" {
+		return &PolicyError{Code: DenySessionRequired, Rule: "session", Message: "no session token presented"}
+	}
+
+	grant, err := i.Verify(token)
+	if err != nil {
+		return &PolicyError{Code: DenySessionInvalid, Rule: "session", Message: err.Error()}
+	}
+
+	if !slices.Contains(grant.Operations, request.Operation) {
+		return &PolicyError{
+			Code:    DenyOperationNotAllowed,
+			Rule:    "session",
+			Message: fmt.Sprintf("session %s does not allow %s", grant.ID, request.Operation),
+		}
+	}
+	if !grant.allowsPath(request.Path) {
+		return &PolicyError{
+			Code:    DenyPathNotAllowed,
+			Rule:    "session",
+			Message: fmt.Sprintf("%s is outside the paths of session %s", request.Path, grant.ID),
+		}
+	}
+
+	return nil
+}
+
+// mac authenticates the encoded payload of a token
+func (i *SessionIssuer) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(sessionMACDomain + "." + payload))
+
+	return mac.Sum(nil)
+}
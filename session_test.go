@@ -0,0 +1,158 @@
+package hdwallet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSessionIssuer(t *testing.T) *SessionIssuer {
+	t.Helper()
+	issuer, err := NewSessionIssuer(bytes.Repeat([]byte{7}, 32), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return issuer
+}
+
+func testGrant() SessionGrant {
+	return SessionGrant{
+		Subject:    "deposit-service",
+		Operations: []AuditOperation{AuditSign},
+		Paths:      []DerivationPath{{44 + HardenedOffset, 195 + HardenedOffset, HardenedOffset}},
+	}
+}
+
+func TestSessionIssuerVerify(t *testing.T) {
+	issuer := newTestSessionIssuer(t)
+	token, grant, err := issuer.Issue(testGrant(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewSessionIssuer(bytes.Repeat([]byte{8}, 32), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	payload := []byte(parts[1])
+	payload[len(payload)/2] ^= 1
+
+	for _, test := range []struct {
+		name   string
+		issuer *SessionIssuer
+		token  string
+		want   error
+	}{
+		{"valid", issuer, token, nil},
+		{"tampered payload", issuer, parts[0] + "." + string(payload) + "." + parts[2], ErrSessionInvalid},
+		{"tampered mac", issuer, parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2])), ErrSessionInvalid},
+		{"other key", other, token, ErrSessionInvalid},
+		{"other version", issuer, "hws2." + parts[1] + "." + parts[2], ErrSessionInvalid},
+		{"truncated", issuer, parts[0] + "." + parts[1], ErrSessionInvalid},
+		{"empty", issuer, "", ErrSessionInvalid},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			verified, err := test.issuer.Verify(test.token)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("Verify = %v, want %v", err, test.want)
+			}
+			if err == nil && (verified.ID != grant.ID || verified.Subject != grant.Subject) {
+				t.Errorf("Verify = %+v, want %+v", verified, grant)
+			}
+		})
+	}
+}
+
+func TestSessionIssuerExpiry(t *testing.T) {
+	issuer := newTestSessionIssuer(t)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	issuer.now = func() time.Time { return now }
+
+	token, _, err := issuer.Issue(testGrant(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(time.Minute - time.Second)
+	if _, err = issuer.Verify(token); err != nil {
+		t.Fatalf("Verify before expiry: %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err = issuer.Verify(token); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Verify at expiry = %v, want %v", err, ErrSessionExpired)
+	}
+
+	for _, ttl := range []time.Duration{-time.Second, MaxSessionTTL + time.Second} {
+		if _, _, err = issuer.Issue(testGrant(), ttl); err == nil {
+			t.Errorf("Issue accepted a lifetime of %s", ttl)
+		}
+	}
+}
+
+func TestSessionIssuerRevoke(t *testing.T) {
+	revocations := NewMemoryRevocationStore()
+	issuer, err := NewSessionIssuer(bytes.Repeat([]byte{7}, 32), revocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A second signer process sharing the key and the revocation store
+	peer, err := NewSessionIssuer(bytes.Repeat([]byte{7}, 32), revocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, grant, err := issuer.Issue(testGrant(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _, err := issuer.Issue(testGrant(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = issuer.Revoke(grant.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, verifier := range []*SessionIssuer{issuer, peer} {
+		if _, err = verifier.Verify(revoked); !errors.Is(err, ErrSessionRevoked) {
+			t.Errorf("Verify of the revoked session = %v, want %v", err, ErrSessionRevoked)
+		}
+		if _, err = verifier.Verify(kept); err != nil {
+			t.Errorf("Verify of another session: %v", err)
+		}
+	}
+}
+
+func TestSessionIssuerEvaluate(t *testing.T) {
+	issuer := newTestSessionIssuer(t)
+	token, _, err := issuer.Issue(testGrant(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithSessionToken(context.Background(), token)
+
+	for _, test := range []struct {
+		name    string
+		ctx     context.Context
+		request SigningRequest
+		want    DenyCode
+	}{
+		{"granted", ctx, SigningRequest{Operation: AuditSign, Path: BIP44Path(195, 0, 0, 3)}, ""},
+		{"no token", context.Background(), SigningRequest{Operation: AuditSign, Path: BIP44Path(195, 0, 0, 3)}, DenySessionRequired},
+		{"bad token", WithSessionToken(context.Background(), "hws1.e30.AAAA"), SigningRequest{Operation: AuditSign, Path: BIP44Path(195, 0, 0, 3)}, DenySessionInvalid},
+		{"operation", ctx, SigningRequest{Operation: AuditDeriveKey, Path: BIP44Path(195, 0, 0, 3)}, DenyOperationNotAllowed},
+		{"path", ctx, SigningRequest{Operation: AuditSign, Path: BIP44Path(195, 1, 0, 3)}, DenyPathNotAllowed},
+		{"no path", ctx, SigningRequest{Operation: AuditSign}, DenyPathNotAllowed},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := denyCode(t, issuer.Evaluate(test.ctx, test.request)); got != test.want {
+				t.Errorf("Evaluate = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
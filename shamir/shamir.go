@@ -0,0 +1,293 @@
+// Package shamir implements M-of-N Shamir secret sharing of arbitrary byte secrets
+// (seeds, xprv strings, keystore passwords, backup encryption keys) for operational
+// key ceremonies.
+//
+// Secrets are split byte-wise over GF(2^8) with the AES reduction polynomial.
+// Every share carries a checksum that detects transcription errors in that share,
+// and the shared payload contains a digest of the secret, so a recombination with
+// a wrong or foreign share is detected instead of silently producing garbage.
+// Fewer than threshold shares reveal nothing about the secret, including its digest.
+//
+// Arithmetic is implemented without secret dependent table lookups or branches.
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// shareVersion is the version byte of the share encoding
+	shareVersion = 1
+	// setIDSize is the size of the random identifier shared by all shares of one split
+	setIDSize = 4
+	// checksumSize is the size of the per-share checksum
+	checksumSize = 4
+	// digestSize is the size of the secret digest appended to the shared payload
+	digestSize = 4
+)
+
+var (
+	// ErrChecksum is returned when a share is corrupted
+	ErrChecksum = errors.New("share checksum mismatch")
+	// ErrDigest is returned when combined shares do not reproduce the original secret
+	ErrDigest = errors.New("secret digest mismatch, a share is wrong or belongs to another split")
+	// ErrNotEnoughShares is returned when fewer shares than the threshold are combined
+	ErrNotEnoughShares = errors.New("not enough shares")
+)
+
+// Share is one of the N pieces of a split secret
+type Share struct {
+	// SetID identifies the split, shares of different splits cannot be combined
+	SetID [setIDSize]byte
+	// Threshold is the number of shares required to recover the secret
+	Threshold uint8
+	// Index is the x coordinate of the share (1 to 255)
+	Index uint8
+	// Value holds the y coordinates, one byte per payload byte
+	Value []byte
+}
+
+// Split divides secret into n shares, any threshold of which recover it
+func Split(secret []byte, threshold, n int) ([]Share, error) {
+	return SplitWithRand(rand.Reader, secret, threshold, n)
+}
+
+// SplitWithRand is Split drawing the polynomial coefficients and set ID from random
+func SplitWithRand(random io.Reader, secret []byte, threshold, n int) ([]Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret is empty")
+	}
+	if threshold < 1 || threshold > n {
+		return nil, fmt.Errorf("threshold %d must be between 1 and the number of shares %d", threshold, n)
+	}
+	if n > 255 {
+		return nil, fmt.Errorf("at most 255 shares are supported, got %d", n)
+	}
+
+	// The digest travels inside the shared payload: it lets Combine detect bad shares
+	// while staying information-theoretically hidden below the threshold
+	digest := sha256.Sum256(secret)
+	payload := append(append([]byte(nil), secret...), digest[:digestSize]...)
+	defer wipe(payload)
+
+	var setID [setIDSize]byte
+	if _, err := io.ReadFull(random, setID[:]); err != nil {
+		return nil, err
+	}
+
+	shares := make([]Share, n)
+	for i := range shares {
+		shares[i] = Share{
+			SetID:     setID,
+			Threshold: uint8(threshold),
+			Index:     uint8(i + 1),
+			Value:     make([]byte, len(payload)),
+		}
+	}
+
+	// For every payload byte build a random polynomial of degree threshold-1 whose
+	// constant term is the byte, and evaluate it at x = 1..n
+	coefficients := make([]byte, threshold)
+	defer wipe(coefficients)
+	for position, b := range payload {
+		coefficients[0] = b
+		if _, err := io.ReadFull(random, coefficients[1:]); err != nil {
+			return nil, err
+		}
+
+		for i := range shares {
+			shares[i].Value[position] = evaluate(coefficients, shares[i].Index)
+		}
+	}
+
+	return shares, nil
+}
+
+// Combine recovers the secret from at least threshold shares of the same split
+// Extra shares beyond the threshold are used to cross-check the result
+func Combine(shares []Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnoughShares
+	}
+
+	first := shares[0]
+	threshold := int(first.Threshold)
+	if threshold < 1 {
+		return nil, fmt.Errorf("invalid share threshold %d", threshold)
+	}
+	if len(shares) < threshold {
+		return nil, fmt.Errorf("%w: have %d, need %d", ErrNotEnoughShares, len(shares), threshold)
+	}
+	if len(first.Value) <= digestSize {
+		return nil, errors.New("share value too short")
+	}
+
+	seen := make(map[uint8]bool, len(shares))
+	for _, share := range shares {
+		if share.SetID != first.SetID {
+			return nil, errors.New("shares belong to different splits")
+		}
+		if share.Threshold != first.Threshold || len(share.Value) != len(first.Value) {
+			return nil, errors.New("shares have inconsistent parameters")
+		}
+		if share.Index == 0 {
+			return nil, errors.New("share index 0 is invalid")
+		}
+		if seen[share.Index] {
+			return nil, fmt.Errorf("duplicate share index %d", share.Index)
+		}
+		seen[share.Index] = true
+	}
+
+	payload := interpolate(shares[:threshold])
+	defer wipe(payload)
+
+	// Any additional share must agree with the polynomial defined by the first ones
+	for _, extra := range shares[threshold:] {
+		subset := append(append([]Share(nil), shares[:threshold-1]...), extra)
+		check := interpolate(subset)
+		equal := subtle.ConstantTimeCompare(check, payload) == 1
+		wipe(check)
+		if !equal {
+			return nil, ErrDigest
+		}
+	}
+
+	secret := payload[:len(payload)-digestSize]
+	digest := sha256.Sum256(secret)
+	if subtle.ConstantTimeCompare(digest[:digestSize], payload[len(payload)-digestSize:]) != 1 {
+		return nil, ErrDigest
+	}
+
+	return append([]byte(nil), secret...), nil
+}
+
+// interpolate evaluates at x = 0 the Lagrange polynomial through shares
+func interpolate(shares []Share) []byte {
+	result := make([]byte, len(shares[0].Value))
+	for i, share := range shares {
+		// basis = prod over j != i of x_j / (x_j - x_i); subtraction is XOR in GF(2^8)
+		basis := byte(1)
+		for j, other := range shares {
+			if i == j {
+				continue
+			}
+			basis = mul(basis, mul(other.Index, inverse(other.Index^share.Index)))
+		}
+
+		for position, y := range share.Value {
+			result[position] ^= mul(y, basis)
+		}
+	}
+
+	return result
+}
+
+// MarshalText encodes the share as hex: version | set ID | threshold | index | value | checksum
+func (s Share) MarshalText() ([]byte, error) {
+	body := s.body()
+	checksum := sha256.Sum256(body)
+	body = append(body, checksum[:checksumSize]...)
+
+	encoded := make([]byte, hex.EncodedLen(len(body)))
+	hex.Encode(encoded, body)
+
+	return encoded, nil
+}
+
+// UnmarshalText decodes a share produced by MarshalText and verifies its checksum
+func (s *Share) UnmarshalText(text []byte) error {
+	text = bytes.TrimSpace(text)
+	raw := make([]byte, hex.DecodedLen(len(text)))
+	if _, err := hex.Decode(raw, text); err != nil {
+		return fmt.Errorf("decode share: %w", err)
+	}
+
+	if len(raw) < 1+setIDSize+2+digestSize+1+checksumSize {
+		return errors.New("share too short")
+	}
+
+	body, checksum := raw[:len(raw)-checksumSize], raw[len(raw)-checksumSize:]
+	expected := sha256.Sum256(body)
+	if subtle.ConstantTimeCompare(expected[:checksumSize], checksum) != 1 {
+		return ErrChecksum
+	}
+	if body[0] != shareVersion {
+		return fmt.Errorf("unsupported share version %d", body[0])
+	}
+	if body[1+setIDSize] < 1 {
+		return errors.New("invalid share threshold 0")
+	}
+
+	copy(s.SetID[:], body[1:1+setIDSize])
+	s.Threshold = body[1+setIDSize]
+	s.Index = body[2+setIDSize]
+	s.Value = append([]byte(nil), body[3+setIDSize:]...)
+
+	return nil
+}
+
+// String returns the text encoding of the share
+func (s Share) String() string {
+	text, _ := s.MarshalText()
+	return string(text)
+}
+
+func (s Share) body() []byte {
+	body := make([]byte, 0, 3+setIDSize+len(s.Value))
+	body = append(body, shareVersion)
+	body = append(body, s.SetID[:]...)
+	body = append(body, s.Threshold, s.Index)
+
+	return append(body, s.Value...)
+}
+
+// evaluate computes the polynomial with the given coefficients at x (Horner's rule)
+func evaluate(coefficients []byte, x byte) byte {
+	var result byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = mul(result, x) ^ coefficients[i]
+	}
+
+	return result
+}
+
+// mul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x + 1 without branches
+func mul(a, b byte) byte {
+	var product byte
+	for i := 0; i < 8; i++ {
+		product ^= -(b & 1) & a
+		carry := -(a >> 7)
+		a = (a << 1) ^ (0x1b & carry)
+		b >>= 1
+	}
+
+	return product
+}
+
+// inverse returns a^254, the multiplicative inverse of a non-zero element
+func inverse(a byte) byte {
+	result := byte(1)
+	power := a
+	for exponent := 254; exponent > 0; exponent >>= 1 {
+		if exponent&1 == 1 {
+			result = mul(result, power)
+		}
+		power = mul(power, power)
+	}
+
+	return result
+}
+
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
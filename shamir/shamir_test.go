@@ -0,0 +1,172 @@
+package shamir
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"math/rand/v2"
+	"testing"
+)
+
+// testRand returns a deterministic randomness source, so failures are reproducible
+func testRand() *rand.ChaCha8 {
+	return rand.NewChaCha8([32]byte{1})
+}
+
+// subsets returns the shares selected by every bit mask of at least size bits
+func subsets(shares []Share, size int) [][]Share {
+	var result [][]Share
+	for mask := uint(1); mask < 1<<len(shares); mask++ {
+		if bits.OnesCount(mask) < size {
+			continue
+		}
+		var subset []Share
+		for i := range shares {
+			if mask&(1<<i) != 0 {
+				subset = append(subset, shares[i])
+			}
+		}
+		result = append(result, subset)
+	}
+
+	return result
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi")
+	random := testRand()
+
+	for n := 1; n <= 5; n++ {
+		for threshold := 1; threshold <= n; threshold++ {
+			shares, err := SplitWithRand(random, secret, threshold, n)
+			if err != nil {
+				t.Fatalf("%d-of-%d: %v", threshold, n, err)
+			}
+			if len(shares) != n {
+				t.Fatalf("%d-of-%d: got %d shares", threshold, n, len(shares))
+			}
+
+			for _, subset := range subsets(shares, threshold) {
+				recovered, err := Combine(subset)
+				if err != nil {
+					t.Fatalf("%d-of-%d with %d shares: %v", threshold, n, len(subset), err)
+				}
+				if !bytes.Equal(recovered, secret) {
+					t.Fatalf("%d-of-%d with %d shares: recovered %q", threshold, n, len(subset), recovered)
+				}
+			}
+		}
+	}
+}
+
+func TestCombineBelowThreshold(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	shares, err := SplitWithRand(testRand(), secret, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Combine(shares[:2]); !errors.Is(err, ErrNotEnoughShares) {
+		t.Errorf("Combine of 2 shares = %v, want %v", err, ErrNotEnoughShares)
+	}
+
+	// Relabelling the shares with a lower threshold interpolates a wrong polynomial,
+	// which the digest inside the payload detects
+	for _, subset := range subsets(shares, 2) {
+		if len(subset) != 2 {
+			continue
+		}
+		forged := make([]Share, len(subset))
+		for i, share := range subset {
+			forged[i] = share
+			forged[i].Threshold = 2
+		}
+		if _, err = Combine(forged); !errors.Is(err, ErrDigest) {
+			t.Errorf("Combine of shares %d and %d = %v, want %v", subset[0].Index, subset[1].Index, err, ErrDigest)
+		}
+	}
+}
+
+func TestCombineForeignShare(t *testing.T) {
+	random := testRand()
+	shares, err := SplitWithRand(random, []byte("first secret"), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := SplitWithRand(random, []byte("other secret"), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Combine([]Share{shares[0], other[1]}); err == nil {
+		t.Error("Combine accepted shares of different splits")
+	}
+
+	// An extra share that disagrees with the others is detected
+	wrong := shares[2]
+	wrong.Value = append([]byte(nil), wrong.Value...)
+	wrong.Value[0] ^= 1
+	if _, err = Combine([]Share{shares[0], shares[1], wrong}); !errors.Is(err, ErrDigest) {
+		t.Errorf("Combine with a wrong extra share = %v, want %v", err, ErrDigest)
+	}
+}
+
+func TestShareText(t *testing.T) {
+	shares, err := SplitWithRand(testRand(), []byte("keystore password"), 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := shares[1].MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Share
+	if err = decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.SetID != shares[1].SetID || decoded.Threshold != 2 || decoded.Index != 2 ||
+		!bytes.Equal(decoded.Value, shares[1].Value) {
+		t.Errorf("UnmarshalText = %+v, want %+v", decoded, shares[1])
+	}
+
+	// Every single-character transcription error is caught by the checksum
+	for i := range text {
+		corrupted := bytes.Clone(text)
+		if corrupted[i] == '0' {
+			corrupted[i] = '1'
+		} else {
+			corrupted[i] = '0'
+		}
+		if err = new(Share).UnmarshalText(corrupted); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("UnmarshalText with character %d changed = %v, want %v", i, err, ErrChecksum)
+		}
+	}
+}
+
+func TestZeroThreshold(t *testing.T) {
+	if _, err := Split([]byte("secret"), 0, 3); err == nil {
+		t.Error("Split accepted a zero threshold")
+	}
+
+	shares, err := SplitWithRand(testRand(), []byte("secret"), 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range shares {
+		shares[i].Threshold = 0
+	}
+	if _, err = Combine(shares); err == nil {
+		t.Error("Combine accepted shares with a zero threshold")
+	}
+
+	// A share encoded with threshold 0 and a valid checksum
+	body := shares[0].body()
+	checksum := sha256.Sum256(body)
+	text := hex.EncodeToString(append(body, checksum[:checksumSize]...))
+	if err = new(Share).UnmarshalText([]byte(text)); err == nil || errors.Is(err, ErrChecksum) {
+		t.Errorf("UnmarshalText of a zero threshold share = %v, want a threshold error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package hdwallet
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Signer signs pre-computed message digests without exposing the private key
+//
+// It is implemented by keys derived in memory by this package (KeySigner) as well
+// as by hardware backends such as HSMs and security tokens, so that code producing
+// signatures does not depend on where the key material lives
+type Signer interface {
+	// Public returns the public key matching the signing key
+	// secp256k1 keys of this module return *PublicKey, NIST curve keys (for example on
+	// PIV tokens) return *ecdsa.PublicKey; third party signers may return the dcrd
+	// *secp256k1.PublicKey, which SignerPublicKey and VerifyDigest accept too
+	Public() crypto.PublicKey
+
+	// SignDigest signs a 32-byte message digest and returns an ASN.1 DER encoded ECDSA signature
+	// The digest must be computed by the caller with the hash function required by the target chain
+	// (SHA-256 for TRON and Bitcoin transactions, Keccak-256 for Ethereum)
+	SignDigest(digest []byte) ([]byte, error)
+}
+
+// KeySigner is a Signer backed by an in-memory secp256k1 private key
+// Its signatures are not audited, wrap it with NewAuditedSigner when they must be
+type KeySigner struct {
+	key *PrivateKey
+}
+
+// NewKeySigner returns a Signer for a private key produced by GenerateKeysFromMnemonic
+func NewKeySigner(key *PrivateKey) *KeySigner {
+	return &KeySigner{key: key}
+}
+
+// Public returns the *PublicKey of the signer
+func (s *KeySigner) Public() crypto.PublicKey {
+	return s.key.PublicKey()
+}
+
+// SignDigest signs digest using deterministic nonces (RFC 6979) and returns the DER encoded signature
+// The signature is always in canonical low-S form as required by Bitcoin, Ethereum and TRON
+func (s *KeySigner) SignDigest(digest []byte) ([]byte, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	return s.key.SignDigest(digest)
+}
+
+// VerifyDigest reports whether signature is a valid DER encoded ECDSA signature of digest by publicKey
+// Both secp256k1 public keys and NIST curve *ecdsa.PublicKey values are supported,
+// so it can be used with the output of any Signer
+func VerifyDigest(publicKey crypto.PublicKey, digest, signature []byte) bool {
+	switch key := publicKey.(type) {
+	case *secp256k1.PublicKey:
+		return VerifyDigest(PublicKeyFromSecp256k1(key), digest, signature)
+	case *PublicKey:
+		parsed, err := ParseDERSignature(signature)
+		if err != nil {
+			return false
+		}
+		return key.Verify(digest, parsed)
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest, signature)
+	default:
+		return false
+	}
+}
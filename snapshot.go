@@ -0,0 +1,229 @@
+package hdwallet
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// snapshotVersion is the current Snapshot format version
+const snapshotVersion = 1
+
+// Snapshot is the derived public state of a wallet: its accounts, their xpubs and
+// address high-water marks, and optionally its labels. It contains no private
+// material and no timestamps, so two snapshots of the same state are identical
+// and services sharing a seed can compare theirs with Diff
+type Snapshot struct {
+	Version           int               `json:"version"`
+	Coin              uint32            `json:"coin"`
+	MasterFingerprint string            `json:"master_fingerprint"`
+	CurrentAccount    uint32            `json:"current_account"`
+	Accounts          []SnapshotAccount `json:"accounts"`
+	Labels            []Label           `json:"labels,omitempty"`
+}
+
+// SnapshotAccount is the public state of one account of a Snapshot
+type SnapshotAccount struct {
+	Account uint32         `json:"account"`
+	Path    DerivationPath `json:"path"`
+	XPub    string         `json:"xpub"`
+	// NextReceive and NextChange are the high-water marks of NextAddress on the
+	// external and internal chains
+	NextReceive uint32 `json:"next_receive"`
+	NextChange  uint32 `json:"next_change"`
+}
+
+// Snapshot captures the public state of the wallet: every account below the
+// NextAccount high-water mark or the current account, whichever is higher, and the
+// labels of labels when it is not nil. The wallet must be unlocked
+func (w *Wallet) Snapshot(labels LabelStore) (*Snapshot, error) {
+	var masterFingerprint string
+	err := w.withMasterKey(func(masterKey *bip32.Key) error {
+		masterFingerprint = fingerprint(masterKey.PublicKey().Key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	current := w.account
+	w.mu.Unlock()
+
+	coinPath := DerivationPath{Purpose + HardenedOffset, w.coin + HardenedOffset}
+	count, err := w.indexes.LoadIndex(coinPath.String())
+	if err != nil {
+		return nil, fmt.Errorf("load index of %s: %w", coinPath, err)
+	}
+	count = max(count, current+1)
+
+	snapshot := &Snapshot{
+		Version:           snapshotVersion,
+		Coin:              w.coin,
+		MasterFingerprint: masterFingerprint,
+		CurrentAccount:    current,
+		Accounts:          make([]SnapshotAccount, 0, count),
+	}
+	for account := range count {
+		entry := SnapshotAccount{
+			Account: account,
+			Path:    coinPath.Child(account + HardenedOffset),
+		}
+		if entry.XPub, err = w.AccountXPub(account); err != nil {
+			return nil, fmt.Errorf("account %d: %w", account, err)
+		}
+		if entry.NextReceive, err = w.indexes.LoadIndex(entry.Path.Child(0).String()); err != nil {
+			return nil, fmt.Errorf("load index of %s: %w", entry.Path.Child(0), err)
+		}
+		if entry.NextChange, err = w.indexes.LoadIndex(entry.Path.Child(1).String()); err != nil {
+			return nil, fmt.Errorf("load index of %s: %w", entry.Path.Child(1), err)
+		}
+		snapshot.Accounts = append(snapshot.Accounts, entry)
+	}
+
+	if labels != nil {
+		if snapshot.Labels, err = labels.Labels(); err != nil {
+			return nil, fmt.Errorf("load labels: %w", err)
+		}
+		sortLabels(snapshot.Labels)
+	}
+
+	return snapshot, nil
+}
+
+// ChangeKind is the kind of a snapshot Change
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is one difference between two snapshots
+// Field names the value, such as "master_fingerprint", "accounts/2/next_receive" or
+// "labels/addr/TUEZSdKsoDHQMeZwihtdoBiN46zxhGWYdH"; Old and New are empty for
+// added and removed values respectively
+type Change struct {
+	Kind  ChangeKind `json:"kind"`
+	Field string     `json:"field"`
+	Old   string     `json:"old,omitempty"`
+	New   string     `json:"new,omitempty"`
+}
+
+// String formats the change for logs
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s = %s", c.Field, c.New)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s = %s", c.Field, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Field, c.Old, c.New)
+	}
+}
+
+// Diff returns the changes from old to new in a deterministic order: top-level
+// fields, then accounts by index, then labels by type and reference
+// A different master fingerprint means the snapshots come from different seeds
+// (or passphrases), and every xpub differs as a consequence
+func Diff(old, new *Snapshot) []Change {
+	var changes []Change
+	modified := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, Change{Kind: ChangeModified, Field: field, Old: before, New: after})
+		}
+	}
+
+	modified("version", strconv.Itoa(old.Version), strconv.Itoa(new.Version))
+	modified("coin", formatUint(old.Coin), formatUint(new.Coin))
+	modified("master_fingerprint", old.MasterFingerprint, new.MasterFingerprint)
+	modified("current_account", formatUint(old.CurrentAccount), formatUint(new.CurrentAccount))
+
+	oldAccounts := make(map[uint32]SnapshotAccount, len(old.Accounts))
+	for _, account := range old.Accounts {
+		oldAccounts[account.Account] = account
+	}
+	newAccounts := make(map[uint32]SnapshotAccount, len(new.Accounts))
+	for _, account := range new.Accounts {
+		newAccounts[account.Account] = account
+	}
+	for _, index := range sortedKeys(oldAccounts, newAccounts, cmp.Compare[uint32]) {
+		field := "accounts/" + formatUint(index)
+		before, inOld := oldAccounts[index]
+		after, inNew := newAccounts[index]
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Kind: ChangeRemoved, Field: field, Old: before.XPub})
+		case !inOld:
+			changes = append(changes, Change{Kind: ChangeAdded, Field: field, New: after.XPub})
+		default:
+			modified(field+"/path", before.Path.String(), after.Path.String())
+			modified(field+"/xpub", before.XPub, after.XPub)
+			modified(field+"/next_receive", formatUint(before.NextReceive), formatUint(after.NextReceive))
+			modified(field+"/next_change", formatUint(before.NextChange), formatUint(after.NextChange))
+		}
+	}
+
+	oldLabels := make(map[labelKey]Label, len(old.Labels))
+	for _, label := range old.Labels {
+		oldLabels[labelKey{label.Type, label.Ref}] = label
+	}
+	newLabels := make(map[labelKey]Label, len(new.Labels))
+	for _, label := range new.Labels {
+		newLabels[labelKey{label.Type, label.Ref}] = label
+	}
+	for _, key := range sortedKeys(oldLabels, newLabels, compareLabelKeys) {
+		field := "labels/" + string(key.labelType) + "/" + key.ref
+		before, inOld := oldLabels[key]
+		after, inNew := newLabels[key]
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Kind: ChangeRemoved, Field: field, Old: labelJSON(before)})
+		case !inOld:
+			changes = append(changes, Change{Kind: ChangeAdded, Field: field, New: labelJSON(after)})
+		default:
+			modified(field, labelJSON(before), labelJSON(after))
+		}
+	}
+
+	return changes
+}
+
+// sortedKeys returns the union of the keys of a and b sorted with compare
+func sortedKeys[K comparable, V any](a, b map[K]V, compare func(K, K) int) []K {
+	keys := make([]K, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, compare)
+
+	return keys
+}
+
+func compareLabelKeys(a, b labelKey) int {
+	return cmp.Or(cmp.Compare(a.labelType, b.labelType), cmp.Compare(a.ref, b.ref))
+}
+
+// labelJSON returns the BIP-329 record of label, used as its value in changes
+func labelJSON(label Label) string {
+	encoded, err := json.Marshal(label)
+	if err != nil {
+		return fmt.Sprintf("%+v", label)
+	}
+
+	return string(encoded)
+}
+
+func formatUint(n uint32) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
@@ -0,0 +1,50 @@
+package solana
+
+import (
+	"fmt"
+
+	"github.com/not-for-prod/hdwallet/codec"
+)
+
+// PublicKeyLength is the length of a Solana public key in bytes
+const PublicKeyLength = 32
+
+// PublicKey is an ed25519 public key or program address, the Solana account address
+type PublicKey [PublicKeyLength]byte
+
+// ParsePublicKey decodes a base58 address
+func ParsePublicKey(s string) (PublicKey, error) {
+	decoded, err := codec.Base58Decode(s, codec.Strict)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("invalid Solana address %q: %w", s, err)
+	}
+	if len(decoded) != PublicKeyLength {
+		return PublicKey{}, fmt.Errorf("invalid Solana address %q: wrong length", s)
+	}
+
+	var publicKey PublicKey
+	copy(publicKey[:], decoded)
+
+	return publicKey, nil
+}
+
+// String returns the base58 address
+func (p PublicKey) String() string {
+	return codec.Base58Encode(p[:])
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p PublicKey) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *PublicKey) UnmarshalText(text []byte) error {
+	publicKey, err := ParsePublicKey(string(text))
+	if err != nil {
+		return err
+	}
+	*p = publicKey
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package solana derives Solana ed25519 keys from BIP39 mnemonics and builds, signs
+// and serializes Solana transactions (legacy and version 0 messages)
+//
+// Solana keys follow SLIP-10 for ed25519, where every level is hardened; wallets
+// such as Phantom and Solflare use m/44'/501'/account'/0'. The address of a key is
+// the base58 encoding of its 32-byte public key
+package solana
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/not-for-prod/hdwallet"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// CoinType is the SLIP-0044 coin type of Solana
+const CoinType = 501
+
+// slip10Curve is the HMAC key of the SLIP-10 ed25519 master key
+var slip10Curve = []byte("ed25519 seed")
+
+// Key is a SLIP-10 ed25519 extended private key
+type Key struct {
+	seed      [32]byte
+	chainCode [32]byte
+}
+
+// MasterKey derives the SLIP-10 ed25519 master key from a BIP39 seed
+func MasterKey(seed []byte) *Key {
+	return newKey(slip10Curve, seed)
+}
+
+// FromMnemonic derives the key at path from a BIP39 mnemonic and passphrase
+func FromMnemonic(mnemonic, passphrase, path string) (*Key, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := hdwallet.ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return MasterKey(seed).DerivePath(parsed)
+}
+
+// AccountPath returns m/44'/501'/account'/0', the path of Phantom and Solflare accounts
+func AccountPath(account uint32) string {
+	return fmt.Sprintf("m/44'/%d'/%d'/0'", CoinType, account)
+}
+
+// Child derives the hardened child at index; SLIP-10 ed25519 has no normal
+// derivation, indexes below HardenedOffset are rejected
+func (k *Key) Child(index uint32) (*Key, error) {
+	if index < hdwallet.HardenedOffset {
+		return nil, fmt.Errorf("ed25519 derivation requires hardened indexes, got %d", index)
+	}
+
+	data := make([]byte, 0, 1+32+4)
+	data = append(data, 0x00)
+	data = append(data, k.seed[:]...)
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	return newKey(k.chainCode[:], data), nil
+}
+
+// DerivePath derives the key at path below k, one hardened level at a time
+func (k *Key) DerivePath(path hdwallet.DerivationPath) (*Key, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		if key, err = key.Child(index); err != nil {
+			return nil, fmt.Errorf("derive %s: %w", path, err)
+		}
+	}
+
+	return key, nil
+}
+
+// PrivateKey returns the ed25519 private key, whose seed is the SLIP-10 key
+func (k *Key) PrivateKey() ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(k.seed[:])
+}
+
+// PublicKey returns the public key, which is also the account address
+func (k *Key) PublicKey() PublicKey {
+	var publicKey PublicKey
+	copy(publicKey[:], k.PrivateKey().Public().(ed25519.PublicKey))
+
+	return publicKey
+}
+
+// KeyPair returns the key as an hdwallet.KeyPair
+func (k *Key) KeyPair() hdwallet.KeyPair {
+	// A 64-byte key from NewKeyFromSeed is always accepted
+	keyPair, _ := hdwallet.NewEd25519KeyPair(k.PrivateKey())
+
+	return keyPair
+}
+
+// newKey splits HMAC-SHA512(hmacKey, data) into the key and its chain code
+func newKey(hmacKey, data []byte) *Key {
+	mac := hmac.New(sha512.New, hmacKey)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	key := &Key{}
+	copy(key.seed[:], sum[:32])
+	copy(key.chainCode[:], sum[32:])
+
+	return key
+}
@@ -0,0 +1,380 @@
+package solana
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxAccounts is the number of accounts a message can reference with its u8 indexes
+const maxAccounts = 256
+
+// versionPrefix marks versioned messages; legacy messages start with their header,
+// whose first byte (the signature count) is always below 0x80
+const versionPrefix = 0x80
+
+// AccountMeta is an account an instruction reads or writes
+type AccountMeta struct {
+	PublicKey  PublicKey
+	IsSigner   bool
+	IsWritable bool
+}
+
+// Instruction is a program invocation before compilation into a message
+type Instruction struct {
+	ProgramID PublicKey
+	Accounts  []AccountMeta
+	Data      []byte
+}
+
+// AddressLookupTable is an on-chain address lookup table and the addresses it
+// currently holds, as returned by getAddressLookupTable
+type AddressLookupTable struct {
+	Key       PublicKey
+	Addresses []PublicKey
+}
+
+// MessageHeader counts the signer and read-only accounts at the start of AccountKeys
+type MessageHeader struct {
+	NumRequiredSignatures       uint8
+	NumReadonlySignedAccounts   uint8
+	NumReadonlyUnsignedAccounts uint8
+}
+
+// CompiledInstruction is an instruction referencing accounts by index: static keys
+// first, then writable then read-only addresses loaded from lookup tables
+type CompiledInstruction struct {
+	ProgramIDIndex uint8
+	Accounts       []uint8
+	Data           []byte
+}
+
+// MessageAddressTableLookup loads accounts of one lookup table into a version 0 message
+type MessageAddressTableLookup struct {
+	AccountKey      PublicKey
+	WritableIndexes []uint8
+	ReadonlyIndexes []uint8
+}
+
+// Message is a Solana transaction message, the data every signer signs
+type Message struct {
+	// Versioned selects the version 0 format, the only one with address table lookups
+	Versioned           bool
+	Header              MessageHeader
+	AccountKeys         []PublicKey
+	RecentBlockhash     [32]byte
+	Instructions        []CompiledInstruction
+	AddressTableLookups []MessageAddressTableLookup
+}
+
+// NewLegacyMessage compiles instructions into a legacy message paid by payer
+func NewLegacyMessage(payer PublicKey, instructions []Instruction, recentBlockhash [32]byte) (*Message, error) {
+	return compileMessage(payer, instructions, recentBlockhash, nil, false)
+}
+
+// NewMessageV0 compiles instructions into a version 0 message paid by payer, loading
+// non-signer accounts from tables when they hold them; invoked programs and signers
+// always stay in the static account keys
+func NewMessageV0(payer PublicKey, instructions []Instruction, recentBlockhash [32]byte,
+	tables []AddressLookupTable) (*Message, error) {
+	return compileMessage(payer, instructions, recentBlockhash, tables, true)
+}
+
+// compiledKey accumulates the roles of an account across instructions
+type compiledKey struct {
+	signer   bool
+	writable bool
+	invoked  bool
+}
+
+func compileMessage(payer PublicKey, instructions []Instruction, recentBlockhash [32]byte,
+	tables []AddressLookupTable, versioned bool) (*Message, error) {
+	roles := map[PublicKey]*compiledKey{}
+	var order []PublicKey
+	add := func(key PublicKey, role compiledKey) {
+		existing, ok := roles[key]
+		if !ok {
+			existing = &compiledKey{}
+			roles[key] = existing
+			order = append(order, key)
+		}
+		existing.signer = existing.signer || role.signer
+		existing.writable = existing.writable || role.writable
+		existing.invoked = existing.invoked || role.invoked
+	}
+
+	add(payer, compiledKey{signer: true, writable: true})
+	for _, instruction := range instructions {
+		add(instruction.ProgramID, compiledKey{invoked: true})
+		for _, account := range instruction.Accounts {
+			add(account.PublicKey, compiledKey{signer: account.IsSigner, writable: account.IsWritable})
+		}
+	}
+
+	// Static keys are grouped as writable signers (payer first), read-only signers,
+	// writable non-signers and read-only non-signers
+	var writableSigners, readonlySigners, writable, readonly []PublicKey
+	for _, key := range order {
+		role := roles[key]
+		switch {
+		case role.signer && role.writable:
+			writableSigners = append(writableSigners, key)
+		case role.signer:
+			readonlySigners = append(readonlySigners, key)
+		case role.writable:
+			writable = append(writable, key)
+		default:
+			readonly = append(readonly, key)
+		}
+	}
+
+	message := &Message{Versioned: versioned, RecentBlockhash: recentBlockhash}
+	var loadedWritable, loadedReadonly []PublicKey
+	for _, table := range tables {
+		lookup := MessageAddressTableLookup{AccountKey: table.Key}
+		var found []PublicKey
+		writable, lookup.WritableIndexes, found = lookupKeys(table, writable, roles)
+		loadedWritable = append(loadedWritable, found...)
+		readonly, lookup.ReadonlyIndexes, found = lookupKeys(table, readonly, roles)
+		loadedReadonly = append(loadedReadonly, found...)
+		if len(lookup.WritableIndexes) > 0 || len(lookup.ReadonlyIndexes) > 0 {
+			message.AddressTableLookups = append(message.AddressTableLookups, lookup)
+		}
+	}
+
+	message.AccountKeys = append(append(append(writableSigners, readonlySigners...), writable...), readonly...)
+	all := append(append(append([]PublicKey(nil), message.AccountKeys...), loadedWritable...), loadedReadonly...)
+	if len(all) > maxAccounts {
+		return nil, fmt.Errorf("message references %d accounts, at most %d are allowed", len(all), maxAccounts)
+	}
+	message.Header = MessageHeader{
+		NumRequiredSignatures:       uint8(len(writableSigners) + len(readonlySigners)),
+		NumReadonlySignedAccounts:   uint8(len(readonlySigners)),
+		NumReadonlyUnsignedAccounts: uint8(len(readonly)),
+	}
+
+	indexes := make(map[PublicKey]uint8, len(all))
+	for i, key := range all {
+		indexes[key] = uint8(i)
+	}
+	for _, instruction := range instructions {
+		compiled := CompiledInstruction{
+			ProgramIDIndex: indexes[instruction.ProgramID],
+			Data:           instruction.Data,
+		}
+		for _, account := range instruction.Accounts {
+			compiled.Accounts = append(compiled.Accounts, indexes[account.PublicKey])
+		}
+		message.Instructions = append(message.Instructions, compiled)
+	}
+
+	return message, nil
+}
+
+// lookupKeys moves the keys table holds out of keys, returning the remaining keys,
+// the table indexes of the moved ones and the moved keys in index order
+func lookupKeys(table AddressLookupTable, keys []PublicKey, roles map[PublicKey]*compiledKey) ([]PublicKey, []uint8, []PublicKey) {
+	var remaining, found []PublicKey
+	var indexes []uint8
+	for _, key := range keys {
+		index := -1
+		if !roles[key].invoked {
+			for i, address := range table.Addresses[:min(len(table.Addresses), maxAccounts)] {
+				if address == key {
+					index = i
+					break
+				}
+			}
+		}
+		if index < 0 {
+			remaining = append(remaining, key)
+			continue
+		}
+		indexes = append(indexes, uint8(index))
+		found = append(found, key)
+	}
+
+	return remaining, indexes, found
+}
+
+// Serialize encodes the message in wire format, the bytes signers sign
+func (m *Message) Serialize() []byte {
+	var buf []byte
+	if m.Versioned {
+		buf = append(buf, versionPrefix)
+	}
+	buf = append(buf, m.Header.NumRequiredSignatures, m.Header.NumReadonlySignedAccounts,
+		m.Header.NumReadonlyUnsignedAccounts)
+
+	buf = appendShortVec(buf, len(m.AccountKeys))
+	for _, key := range m.AccountKeys {
+		buf = append(buf, key[:]...)
+	}
+	buf = append(buf, m.RecentBlockhash[:]...)
+
+	buf = appendShortVec(buf, len(m.Instructions))
+	for _, instruction := range m.Instructions {
+		buf = append(buf, instruction.ProgramIDIndex)
+		buf = appendShortVec(buf, len(instruction.Accounts))
+		buf = append(buf, instruction.Accounts...)
+		buf = appendShortVec(buf, len(instruction.Data))
+		buf = append(buf, instruction.Data...)
+	}
+
+	if m.Versioned {
+		buf = appendShortVec(buf, len(m.AddressTableLookups))
+		for _, lookup := range m.AddressTableLookups {
+			buf = append(buf, lookup.AccountKey[:]...)
+			buf = appendShortVec(buf, len(lookup.WritableIndexes))
+			buf = append(buf, lookup.WritableIndexes...)
+			buf = appendShortVec(buf, len(lookup.ReadonlyIndexes))
+			buf = append(buf, lookup.ReadonlyIndexes...)
+		}
+	}
+
+	return buf
+}
+
+// ParseMessage decodes a legacy or version 0 message in wire format
+func ParseMessage(data []byte) (*Message, error) {
+	r := bytes.NewReader(data)
+	message, err := readMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after message", r.Len())
+	}
+
+	return message, nil
+}
+
+func readMessage(r *bytes.Reader) (*Message, error) {
+	message := &Message{}
+
+	prefix, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("read message header: %w", err)
+	}
+	if prefix&versionPrefix != 0 {
+		if version := prefix &^ versionPrefix; version != 0 {
+			return nil, fmt.Errorf("unsupported message version %d", version)
+		}
+		message.Versioned = true
+	} else {
+		_ = r.UnreadByte()
+	}
+
+	var header [3]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("read message header: %w", err)
+	}
+	message.Header = MessageHeader{header[0], header[1], header[2]}
+
+	count, err := readShortVec(r)
+	if err != nil {
+		return nil, fmt.Errorf("read account keys: %w", err)
+	}
+	message.AccountKeys = make([]PublicKey, count)
+	for i := range message.AccountKeys {
+		if _, err = io.ReadFull(r, message.AccountKeys[i][:]); err != nil {
+			return nil, fmt.Errorf("read account key %d: %w", i, err)
+		}
+	}
+	if _, err = io.ReadFull(r, message.RecentBlockhash[:]); err != nil {
+		return nil, fmt.Errorf("read recent blockhash: %w", err)
+	}
+
+	if count, err = readShortVec(r); err != nil {
+		return nil, fmt.Errorf("read instructions: %w", err)
+	}
+	for i := 0; i < count; i++ {
+		var instruction CompiledInstruction
+		if instruction.ProgramIDIndex, err = r.ReadByte(); err != nil {
+			return nil, fmt.Errorf("read instruction %d: %w", i, err)
+		}
+		if instruction.Accounts, err = readShortVecBytes(r); err != nil {
+			return nil, fmt.Errorf("read instruction %d: %w", i, err)
+		}
+		if instruction.Data, err = readShortVecBytes(r); err != nil {
+			return nil, fmt.Errorf("read instruction %d: %w", i, err)
+		}
+		message.Instructions = append(message.Instructions, instruction)
+	}
+
+	if message.Versioned {
+		if count, err = readShortVec(r); err != nil {
+			return nil, fmt.Errorf("read address table lookups: %w", err)
+		}
+		for i := 0; i < count; i++ {
+			var lookup MessageAddressTableLookup
+			if _, err = io.ReadFull(r, lookup.AccountKey[:]); err != nil {
+				return nil, fmt.Errorf("read address table lookup %d: %w", i, err)
+			}
+			if lookup.WritableIndexes, err = readShortVecBytes(r); err != nil {
+				return nil, fmt.Errorf("read address table lookup %d: %w", i, err)
+			}
+			if lookup.ReadonlyIndexes, err = readShortVecBytes(r); err != nil {
+				return nil, fmt.Errorf("read address table lookup %d: %w", i, err)
+			}
+			message.AddressTableLookups = append(message.AddressTableLookups, lookup)
+		}
+	}
+
+	if int(message.Header.NumRequiredSignatures) > len(message.AccountKeys) {
+		return nil, errors.New("message requires more signatures than it has accounts")
+	}
+
+	return message, nil
+}
+
+// appendShortVec appends a compact-u16 length: 7 bits per byte, low bits first
+func appendShortVec(buf []byte, n int) []byte {
+	for n >= 0x80 {
+		buf = append(buf, byte(n)|0x80)
+		n >>= 7
+	}
+
+	return append(buf, byte(n))
+}
+
+// readShortVec reads a canonical compact-u16 length
+func readShortVec(r *bytes.Reader) (int, error) {
+	n := 0
+	for i := 0; i < 3; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if i > 0 && b == 0 {
+			return 0, errors.New("non-canonical compact-u16")
+		}
+		n |= int(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			if n > 0xffff {
+				return 0, errors.New("compact-u16 overflow")
+			}
+			if n > r.Len() {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return n, nil
+		}
+	}
+
+	return 0, errors.New("compact-u16 overflow")
+}
+
+func readShortVecBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readShortVec(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, n)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
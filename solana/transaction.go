@@ -0,0 +1,158 @@
+package solana
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/not-for-prod/hdwallet/codec"
+)
+
+// SignatureLength is the length of an ed25519 signature
+const SignatureLength = ed25519.SignatureSize
+
+// SystemProgramID is the address of the system program, 11111111111111111111111111111111
+var SystemProgramID PublicKey
+
+// Transaction is a message and one signature slot per required signer, in the
+// order of the message's first account keys
+type Transaction struct {
+	Signatures [][SignatureLength]byte
+	Message    *Message
+}
+
+// NewTransaction returns an unsigned transaction carrying message
+func NewTransaction(message *Message) *Transaction {
+	return &Transaction{
+		Signatures: make([][SignatureLength]byte, message.Header.NumRequiredSignatures),
+		Message:    message,
+	}
+}
+
+// Transfer returns a system program instruction moving lamports from one account to another
+func Transfer(from, to PublicKey, lamports uint64) Instruction {
+	data := binary.LittleEndian.AppendUint32(nil, 2)
+	data = binary.LittleEndian.AppendUint64(data, lamports)
+
+	return Instruction{
+		ProgramID: SystemProgramID,
+		Accounts: []AccountMeta{
+			{PublicKey: from, IsSigner: true, IsWritable: true},
+			{PublicKey: to, IsWritable: true},
+		},
+		Data: data,
+	}
+}
+
+// ParseTransaction decodes a transaction in wire format, as returned by getTransaction
+// with the base64 encoding
+func ParseTransaction(data []byte) (*Transaction, error) {
+	r := bytes.NewReader(data)
+
+	count, err := readShortVec(r)
+	if err != nil {
+		return nil, fmt.Errorf("read signatures: %w", err)
+	}
+	tx := &Transaction{Signatures: make([][SignatureLength]byte, count)}
+	for i := range tx.Signatures {
+		if _, err = io.ReadFull(r, tx.Signatures[i][:]); err != nil {
+			return nil, fmt.Errorf("read signature %d: %w", i, err)
+		}
+	}
+
+	if tx.Message, err = readMessage(r); err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes after transaction", r.Len())
+	}
+	if len(tx.Signatures) != int(tx.Message.Header.NumRequiredSignatures) {
+		return nil, fmt.Errorf("transaction has %d signatures, its message requires %d",
+			len(tx.Signatures), tx.Message.Header.NumRequiredSignatures)
+	}
+
+	return tx, nil
+}
+
+// Serialize encodes the transaction in wire format, for sendTransaction
+func (t *Transaction) Serialize() []byte {
+	buf := appendShortVec(nil, len(t.Signatures))
+	for _, signature := range t.Signatures {
+		buf = append(buf, signature[:]...)
+	}
+
+	return append(buf, t.Message.Serialize()...)
+}
+
+// Sign signs the message with each key, which must be one of its required signers
+// Signers can sign on separate machines; merge their signatures with AddSignature
+func (t *Transaction) Sign(keys ...ed25519.PrivateKey) error {
+	message := t.Message.Serialize()
+	for _, key := range keys {
+		var publicKey PublicKey
+		copy(publicKey[:], key.Public().(ed25519.PublicKey))
+
+		var signature [SignatureLength]byte
+		copy(signature[:], ed25519.Sign(key, message))
+		if err := t.setSignature(publicKey, signature, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddSignature attaches the signature of a required signer produced elsewhere
+func (t *Transaction) AddSignature(publicKey PublicKey, signature [SignatureLength]byte) error {
+	return t.setSignature(publicKey, signature, t.Message.Serialize())
+}
+
+func (t *Transaction) setSignature(publicKey PublicKey, signature [SignatureLength]byte, message []byte) error {
+	index := t.signerIndex(publicKey)
+	if index < 0 {
+		return fmt.Errorf("%s is not a required signer of the transaction", publicKey)
+	}
+	if !ed25519.Verify(publicKey[:], message, signature[:]) {
+		return fmt.Errorf("invalid signature of %s", publicKey)
+	}
+	if len(t.Signatures) != int(t.Message.Header.NumRequiredSignatures) {
+		return fmt.Errorf("transaction has %d signature slots, its message requires %d",
+			len(t.Signatures), t.Message.Header.NumRequiredSignatures)
+	}
+	t.Signatures[index] = signature
+
+	return nil
+}
+
+// Signed reports whether every required signer has signed
+func (t *Transaction) Signed() bool {
+	for _, signature := range t.Signatures {
+		if signature == [SignatureLength]byte{} {
+			return false
+		}
+	}
+
+	return len(t.Signatures) == int(t.Message.Header.NumRequiredSignatures)
+}
+
+// ID returns the transaction signature as shown by explorers, the base58 encoding
+// of the fee payer's signature
+func (t *Transaction) ID() string {
+	if len(t.Signatures) == 0 {
+		return ""
+	}
+
+	return codec.Base58Encode(t.Signatures[0][:])
+}
+
+func (t *Transaction) signerIndex(publicKey PublicKey) int {
+	for i, key := range t.Message.AccountKeys[:t.Message.Header.NumRequiredSignatures] {
+		if key == publicKey {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,54 @@
+package sr25519
+
+import (
+	"github.com/not-for-prod/hdwallet"
+)
+
+// KeyPair returns the keypair as an hdwallet.KeyPair
+func (k *Keypair) KeyPair() hdwallet.KeyPair {
+	return keyPair{keypair: k}
+}
+
+// keyPair adapts Keypair to hdwallet.KeyPair
+type keyPair struct {
+	keypair *Keypair
+}
+
+// Curve implements hdwallet.KeyPair
+func (k keyPair) Curve() hdwallet.Curve {
+	return hdwallet.CurveSr25519
+}
+
+// PublicKeyBytes implements hdwallet.KeyPair
+func (k keyPair) PublicKeyBytes() []byte {
+	publicKey := k.keypair.PublicKey()
+
+	return publicKey[:]
+}
+
+// PrivateKeyBytes implements hdwallet.KeyPair, returning the secret scalar
+// The signing nonce of the expanded secret key is not exported by schnorrkel
+func (k keyPair) PrivateKeyBytes() []byte {
+	secret := k.keypair.secret.Encode()
+
+	return secret[:]
+}
+
+// Sign implements hdwallet.KeyPair
+func (k keyPair) Sign(message []byte) ([]byte, error) {
+	signature, err := k.keypair.Sign(message)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature[:], nil
+}
+
+// Verify implements hdwallet.KeyPair
+func (k keyPair) Verify(message, signature []byte) bool {
+	if len(signature) != 64 {
+		return false
+	}
+
+	return Verify(k.keypair.PublicKey(), message, [64]byte(signature))
+}
@@ -0,0 +1,253 @@
+// Package sr25519 derives Substrate (Polkadot, Kusama and parachains) sr25519 keypairs
+// from BIP39 mnemonics, matching subkey and polkadot.js
+//
+// Substrate does not use BIP32. The mini secret key is the first 32 bytes of
+// PBKDF2-HMAC-SHA512 over the mnemonic entropy (not the phrase) with the salt
+// "mnemonic" || password, and children are derived with junctions:
+//
+//	//hard   hard junction, the child secret cannot be computed from the parent public key
+//	/soft    soft junction, the child public key can be derived from the parent public key
+//	///pass  password mixed into the seed, must be last
+//
+// A secret URI such as "phrase//polkadot//0/1///password" combines the three
+package sr25519
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	schnorrkel "github.com/ChainSafe/go-schnorrkel"
+	"github.com/not-for-prod/hdwallet/codec"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// SigningContext is the signing context Substrate runtimes verify sr25519 signatures with
+const SigningContext = "substrate"
+
+// Junction is a single step of a Substrate derivation path
+type Junction struct {
+	Hard      bool
+	ChainCode [32]byte
+}
+
+// NewJunction encodes a junction the way Substrate does: names parsing as unsigned
+// 64-bit integers are SCALE encoded as u64, other names as SCALE strings; encodings
+// longer than 32 bytes are replaced by their BLAKE2b-256 hash, shorter ones zero padded
+func NewJunction(name string, hard bool) Junction {
+	var encoded []byte
+	if n, err := strconv.ParseUint(name, 10, 64); err == nil {
+		encoded = binary.LittleEndian.AppendUint64(nil, n)
+	} else {
+		encoded = append(compactLength(len(name)), name...)
+	}
+
+	junction := Junction{Hard: hard}
+	if len(encoded) > len(junction.ChainCode) {
+		junction.ChainCode = blake2b.Sum256(encoded)
+	} else {
+		copy(junction.ChainCode[:], encoded)
+	}
+
+	return junction
+}
+
+// String formats the junction prefix, the name cannot be recovered from the chain code
+func (j Junction) String() string {
+	if j.Hard {
+		return fmt.Sprintf("//0x%x", j.ChainCode)
+	}
+
+	return fmt.Sprintf("/0x%x", j.ChainCode)
+}
+
+// ParsePath parses a derivation path like "//polkadot//0/1///password" into its
+// junctions and the optional password
+func ParsePath(path string) ([]Junction, string, error) {
+	password := ""
+	if i := strings.Index(path, "///"); i >= 0 {
+		path, password = path[:i], path[i+3:]
+	}
+
+	var junctions []Junction
+	for path != "" {
+		if path[0] != '/' {
+			return nil, "", fmt.Errorf("invalid derivation path near %q", path)
+		}
+
+		hard := strings.HasPrefix(path, "//")
+		if hard {
+			path = path[2:]
+		} else {
+			path = path[1:]
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		name := path[:end]
+		if name == "" {
+			return nil, "", errors.New("empty junction in derivation path")
+		}
+
+		junctions = append(junctions, NewJunction(name, hard))
+		path = path[end:]
+	}
+
+	return junctions, password, nil
+}
+
+// Keypair is an sr25519 secret key with its public key
+type Keypair struct {
+	secret *schnorrkel.SecretKey
+	public *schnorrkel.PublicKey
+}
+
+// FromMnemonic returns the root keypair of a BIP39 mnemonic and password
+func FromMnemonic(mnemonic, password string) (*Keypair, error) {
+	entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := pbkdf2.Key(entropy, []byte("mnemonic"+password), 2048, 64, sha512.New)
+
+	var raw [schnorrkel.MiniSecretKeySize]byte
+	copy(raw[:], seed)
+	mini, err := schnorrkel.NewMiniSecretKeyFromRaw(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKeypair(mini.ExpandEd25519())
+}
+
+// FromURI returns the keypair of a secret URI "mnemonic//hard/soft///password"
+func FromURI(uri string) (*Keypair, error) {
+	phrase, path := uri, ""
+	if i := strings.IndexByte(uri, '/'); i >= 0 {
+		phrase, path = uri[:i], uri[i:]
+	}
+
+	junctions, password, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := FromMnemonic(strings.TrimSpace(phrase), password)
+	if err != nil {
+		return nil, err
+	}
+
+	return root.Derive(junctions...)
+}
+
+// Derive applies junctions in order and returns the resulting keypair
+func (k *Keypair) Derive(junctions ...Junction) (*Keypair, error) {
+	secret := k.secret
+	for _, junction := range junctions {
+		var (
+			child *schnorrkel.ExtendedKey
+			err   error
+		)
+		if junction.Hard {
+			child, err = schnorrkel.DeriveKeyHard(secret, []byte{}, junction.ChainCode)
+		} else {
+			child, err = schnorrkel.DeriveKeySoft(secret, []byte{}, junction.ChainCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if secret, err = child.Secret(); err != nil {
+			return nil, err
+		}
+	}
+
+	return newKeypair(secret)
+}
+
+// PublicKey returns the 32-byte Ristretto encoded public key
+func (k *Keypair) PublicKey() [32]byte {
+	return k.public.Encode()
+}
+
+// Address returns the SS58 address of the public key for a network prefix
+// (0 Polkadot, 2 Kusama, 42 generic Substrate)
+func (k *Keypair) Address(prefix uint16) string {
+	return SS58Address(k.PublicKey(), prefix)
+}
+
+// Sign signs message in the Substrate signing context and returns the 64-byte signature
+func (k *Keypair) Sign(message []byte) ([64]byte, error) {
+	signature, err := k.secret.Sign(schnorrkel.NewSigningContext([]byte(SigningContext), message))
+	if err != nil {
+		return [64]byte{}, err
+	}
+
+	return signature.Encode(), nil
+}
+
+// Verify checks a signature produced by Sign against a public key
+func Verify(publicKey [32]byte, message []byte, signature [64]byte) bool {
+	public, err := schnorrkel.NewPublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+
+	var decoded schnorrkel.Signature
+	if err = decoded.Decode(signature); err != nil {
+		return false
+	}
+
+	ok, err := public.Verify(&decoded, schnorrkel.NewSigningContext([]byte(SigningContext), message))
+
+	return err == nil && ok
+}
+
+// SS58Address encodes a public key as an SS58 address:
+// base58(prefix || key || BLAKE2b-512("SS58PRE" || prefix || key)[:2])
+func SS58Address(publicKey [32]byte, prefix uint16) string {
+	var payload []byte
+	if prefix < 64 {
+		payload = []byte{byte(prefix)}
+	} else {
+		// Two-byte prefix encoding for network identifiers 64 to 16383
+		payload = []byte{
+			byte((prefix&0xfc)>>2) | 0x40,
+			byte(prefix>>8) | byte(prefix&0x03)<<6,
+		}
+	}
+	payload = append(payload, publicKey[:]...)
+
+	checksum := blake2b.Sum512(append([]byte("SS58PRE"), payload...))
+
+	return codec.Base58Encode(append(payload, checksum[:2]...))
+}
+
+func newKeypair(secret *schnorrkel.SecretKey) (*Keypair, error) {
+	public, err := secret.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keypair{secret: secret, public: public}, nil
+}
+
+// compactLength returns the SCALE compact encoding of a length
+func compactLength(n int) []byte {
+	switch {
+	case n < 1<<6:
+		return []byte{byte(n << 2)}
+	case n < 1<<14:
+		return binary.LittleEndian.AppendUint16(nil, uint16(n<<2|0b01))
+	default:
+		return binary.LittleEndian.AppendUint32(nil, uint32(n<<2|0b10))
+	}
+}
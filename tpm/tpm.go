@@ -0,0 +1,309 @@
+// Package tpm seals wallet seeds to a TPM 2.0 device so that hot-wallet material
+// is bound to specific hardware and, optionally, to a specific boot state.
+//
+// The seed is stored as a TPM sealed data object created under the storage root key
+// of the owner hierarchy. The resulting blob can only be loaded and unsealed by the
+// same TPM. When PCR indexes are supplied the object is additionally protected by a
+// PolicyPCR authorization policy, so unsealing fails once any of the selected PCRs
+// changes (different firmware, bootloader, kernel or measured configuration).
+//
+// The seed is unsealed only for the duration of a derivation and wiped afterwards.
+package tpm
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpm2/transport"
+	"github.com/google/go-tpm/tpm2/transport/linuxtpm"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// DefaultDevice is the Linux TPM resource manager device
+// The resource manager (tpmrm0) is preferred over the raw device (tpm0) because
+// it allows several processes to use the TPM concurrently
+const DefaultDevice = "/dev/tpmrm0"
+
+const (
+	// maxSealedSize is the largest payload a TPM accepts in a sealed data object (TPM2B_SENSITIVE_DATA)
+	maxSealedSize = 128
+	// maxPCRs is the number of PCR values a single TPM2_PCR_Read returns
+	maxPCRs = 8
+)
+
+// SealedSeed is a seed sealed to a particular TPM
+// It contains only TPM-encrypted material and is safe to persist on disk
+type SealedSeed struct {
+	// Public is the marshaled TPM2B_PUBLIC area of the sealed object
+	Public []byte `json:"public"`
+	// Private is the marshaled TPM2B_PRIVATE area, encrypted by the storage root key
+	Private []byte `json:"private"`
+	// PCRs lists the SHA-256 PCR indexes the seed is bound to, empty when no PCR policy is used
+	PCRs []uint `json:"pcrs,omitempty"`
+}
+
+// Sealer seals and unseals wallet seeds using a TPM 2.0 device
+type Sealer struct {
+	tpm    transport.TPM
+	closer io.Closer
+}
+
+// Open opens the TPM device at path and returns a Sealer using it
+// An empty path opens DefaultDevice. The caller must Close the Sealer when done
+func Open(path string) (*Sealer, error) {
+	if path == "" {
+		path = DefaultDevice
+	}
+
+	device, err := linuxtpm.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open tpm: %w", err)
+	}
+
+	return &Sealer{tpm: device, closer: device}, nil
+}
+
+// New returns a Sealer using an already opened TPM transport
+// (for example a TCP connection to a software TPM or a simulator)
+// The transport stays owned by the caller
+func New(t transport.TPM) *Sealer {
+	return &Sealer{tpm: t}
+}
+
+// Close releases the TPM device opened by Open
+func (s *Sealer) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	return s.closer.Close()
+}
+
+// Seal seals seed to the TPM
+// When pcrs is not empty the sealed object can only be unsealed while the SHA-256
+// bank values of those PCRs are equal to their values at sealing time
+func (s *Sealer) Seal(seed []byte, pcrs ...uint) (*SealedSeed, error) {
+	if len(seed) == 0 || len(seed) > maxSealedSize {
+		return nil, fmt.Errorf("seed must be 1 to %d bytes, got %d", maxSealedSize, len(seed))
+	}
+	if len(pcrs) > maxPCRs {
+		return nil, fmt.Errorf("at most %d pcrs can be selected, got %d", maxPCRs, len(pcrs))
+	}
+
+	// Step 1: Build the authorization policy
+	// Without PCRs the object is authorized with an empty password
+	// With PCRs the object can only be used through a policy session that proves
+	// the selected PCRs still hold the values they had when the seed was sealed
+	attributes := tpm2.TPMAObject{
+		FixedTPM:     true,
+		FixedParent:  true,
+		UserWithAuth: len(pcrs) == 0,
+		NoDA:         true,
+	}
+
+	var authPolicy []byte
+	if len(pcrs) > 0 {
+		pcrDigest, err := s.pcrDigest(pcrs)
+		if err != nil {
+			return nil, err
+		}
+
+		calculator, err := tpm2.NewPolicyCalculator(tpm2.TPMAlgSHA256)
+		if err != nil {
+			return nil, err
+		}
+
+		policy := tpm2.PolicyPCR{
+			PcrDigest: tpm2.TPM2BDigest{Buffer: pcrDigest},
+			Pcrs:      pcrSelection(pcrs),
+		}
+		if err = policy.Update(calculator); err != nil {
+			return nil, err
+		}
+
+		authPolicy = calculator.Hash().Digest
+	}
+
+	// Step 2: Create the storage root key that will wrap the sealed object
+	srk, flush, err := s.createSRK()
+	if err != nil {
+		return nil, err
+	}
+	defer flush()
+
+	// Step 3: Create the sealed data object holding the seed
+	create := tpm2.Create{
+		ParentHandle: tpm2.AuthHandle{
+			Handle: srk.ObjectHandle,
+			Name:   srk.Name,
+			Auth:   tpm2.PasswordAuth(nil),
+		},
+		InSensitive: tpm2.TPM2BSensitiveCreate{
+			Sensitive: &tpm2.TPMSSensitiveCreate{
+				Data: tpm2.NewTPMUSensitiveCreate(&tpm2.TPM2BSensitiveData{Buffer: seed}),
+			},
+		},
+		InPublic: tpm2.New2B(tpm2.TPMTPublic{
+			Type:             tpm2.TPMAlgKeyedHash,
+			NameAlg:          tpm2.TPMAlgSHA256,
+			ObjectAttributes: attributes,
+			AuthPolicy:       tpm2.TPM2BDigest{Buffer: authPolicy},
+		}),
+	}
+
+	created, err := create.Execute(s.tpm)
+	if err != nil {
+		return nil, fmt.Errorf("seal seed: %w", err)
+	}
+
+	return &SealedSeed{
+		Public:  tpm2.Marshal(created.OutPublic),
+		Private: tpm2.Marshal(created.OutPrivate),
+		PCRs:    append([]uint(nil), pcrs...),
+	}, nil
+}
+
+// Unseal returns the seed held by sealed
+// The caller is responsible for wiping the returned slice; prefer DeriveKeys,
+// which keeps the seed in memory only for the duration of the derivation
+func (s *Sealer) Unseal(sealed *SealedSeed) ([]byte, error) {
+	if sealed == nil {
+		return nil, errors.New("sealed seed is nil")
+	}
+
+	public, err := tpm2.Unmarshal[tpm2.TPM2BPublic](sealed.Public)
+	if err != nil {
+		return nil, fmt.Errorf("parse sealed public area: %w", err)
+	}
+
+	private, err := tpm2.Unmarshal[tpm2.TPM2BPrivate](sealed.Private)
+	if err != nil {
+		return nil, fmt.Errorf("parse sealed private area: %w", err)
+	}
+
+	// Step 1: Recreate the storage root key
+	// The SRK is derived from the owner hierarchy primary seed, so the same
+	// template always produces the same key on the same TPM
+	srk, flush, err := s.createSRK()
+	if err != nil {
+		return nil, err
+	}
+	defer flush()
+
+	// Step 2: Load the sealed object under the SRK
+	load := tpm2.Load{
+		ParentHandle: tpm2.AuthHandle{
+			Handle: srk.ObjectHandle,
+			Name:   srk.Name,
+			Auth:   tpm2.PasswordAuth(nil),
+		},
+		InPrivate: *private,
+		InPublic:  *public,
+	}
+
+	loaded, err := load.Execute(s.tpm)
+	if err != nil {
+		return nil, fmt.Errorf("load sealed seed: %w", err)
+	}
+	defer func() {
+		_, _ = tpm2.FlushContext{FlushHandle: loaded.ObjectHandle}.Execute(s.tpm)
+	}()
+
+	// Step 3: Authorize and unseal
+	// A PCR bound object is authorized by a just-in-time policy session that runs
+	// PolicyPCR against the current PCR values; the TPM rejects the unseal when they
+	// no longer match the policy digest recorded at sealing time
+	auth := tpm2.PasswordAuth(nil)
+	if len(sealed.PCRs) > 0 {
+		selection := pcrSelection(sealed.PCRs)
+		auth = tpm2.Policy(tpm2.TPMAlgSHA256, 16, func(t transport.TPM, handle tpm2.TPMISHPolicy, _ tpm2.TPM2BNonce) error {
+			_, err := tpm2.PolicyPCR{
+				PolicySession: handle,
+				Pcrs:          selection,
+			}.Execute(t)
+			return err
+		})
+	}
+
+	unsealed, err := tpm2.Unseal{
+		ItemHandle: tpm2.AuthHandle{
+			Handle: loaded.ObjectHandle,
+			Name:   loaded.Name,
+			Auth:   auth,
+		},
+	}.Execute(s.tpm)
+	if err != nil {
+		return nil, fmt.Errorf("unseal seed: %w", err)
+	}
+
+	return unsealed.OutData.Buffer, nil
+}
+
+// DeriveKeys unseals the seed, derives the BIP44 key pair m/44'/coin'/account'/chain/address
+// and wipes the seed before returning
+func (s *Sealer) DeriveKeys(sealed *SealedSeed, coin, account, chain, address uint32) (*hdwallet.PrivateKey,
+	*hdwallet.PublicKey, error) {
+	seed, err := s.Unseal(sealed)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer wipe(seed)
+
+	return hdwallet.GenerateKeysFromSeed(seed, coin, account, chain, address)
+}
+
+// createSRK creates the TCG reference ECC P-256 storage root key in the owner hierarchy
+// The returned function flushes the key from the TPM
+func (s *Sealer) createSRK() (*tpm2.CreatePrimaryResponse, func(), error) {
+	srk, err := tpm2.CreatePrimary{
+		PrimaryHandle: tpm2.TPMRHOwner,
+		InPublic:      tpm2.New2B(tpm2.ECCSRKTemplate),
+	}.Execute(s.tpm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create storage root key: %w", err)
+	}
+
+	flush := func() {
+		_, _ = tpm2.FlushContext{FlushHandle: srk.ObjectHandle}.Execute(s.tpm)
+	}
+
+	return srk, flush, nil
+}
+
+// pcrDigest returns the SHA-256 digest of the concatenated current values of pcrs
+// as expected by TPM2_PolicyPCR
+func (s *Sealer) pcrDigest(pcrs []uint) ([]byte, error) {
+	read, err := tpm2.PCRRead{PCRSelectionIn: pcrSelection(pcrs)}.Execute(s.tpm)
+	if err != nil {
+		return nil, fmt.Errorf("read pcrs: %w", err)
+	}
+
+	hash := sha256.New()
+	for _, digest := range read.PCRValues.Digests {
+		hash.Write(digest.Buffer)
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// pcrSelection selects pcrs in the SHA-256 bank
+func pcrSelection(pcrs []uint) tpm2.TPMLPCRSelection {
+	return tpm2.TPMLPCRSelection{
+		PCRSelections: []tpm2.TPMSPCRSelection{
+			{
+				Hash:      tpm2.TPMAlgSHA256,
+				PCRSelect: tpm2.PCClientCompatible.PCRs(pcrs...),
+			},
+		},
+	}
+}
+
+// wipe overwrites secret material with zeros
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
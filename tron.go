@@ -3,8 +3,7 @@ package hdwallet
 import (
 	"crypto/sha256"
 
-	"github.com/btcsuite/btcd/btcutil/base58"
-	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/not-for-prod/hdwallet/codec"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -19,7 +18,7 @@ import (
 //
 // TRON addresses always start with 'T' when encoded and are 34 characters long
 // Example: TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy
-func GenerateTronAddress(publicKey *secp256k1.PublicKey) string {
+func GenerateTronAddress(publicKey *PublicKey) string {
 	// Step 1: Extract public key coordinates (remove compression prefix)
 	// SerializeUncompressed() returns 65 bytes: [0x04][32-byte X][32-byte Y]
 	// We skip the first byte (0x04 prefix) to get the raw 64-byte coordinates
@@ -63,5 +62,5 @@ func GenerateTronAddress(publicKey *secp256k1.PublicKey) string {
 	// The 0x41 prefix ensures TRON addresses always start with 'T'
 	// Final format: 34-character string starting with 'T'
 	// Example: TLsV52sRDL79HXGGm9yzwKibb6BeruhUzy
-	return base58.Encode(addressWithChecksum)
+	return codec.Base58Encode(addressWithChecksum)
 }
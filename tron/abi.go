@@ -0,0 +1,110 @@
+package tron
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/not-for-prod/hdwallet/internal/abi"
+)
+
+// WordLength is the size of an ABI-encoded static parameter
+const WordLength = abi.WordLength
+
+// Function selectors of the TRC-20 calls, the first 4 bytes of Keccak-256 of their signature
+var (
+	TransferSelector     = Selector("transfer(address,uint256)")
+	TransferFromSelector = Selector("transferFrom(address,address,uint256)")
+	ApproveSelector      = Selector("approve(address,uint256)")
+	BalanceOfSelector    = Selector("balanceOf(address)")
+	// IncreaseAllowanceSelector is the OpenZeppelin extension raising an allowance
+	IncreaseAllowanceSelector = Selector("increaseAllowance(address,uint256)")
+)
+
+// Selector returns the 4-byte selector of a function signature such as "transfer(address,uint256)"
+func Selector(signature string) [4]byte {
+	return abi.Selector(signature)
+}
+
+// AddressWord encodes a base58 TRON address as a contract parameter: the 20-byte
+// hash without the 0x41 prefix, left-padded with zeros to 32 bytes
+func AddressWord(address string) ([WordLength]byte, error) {
+	decoded, err := DecodeAddress(address)
+	if err != nil {
+		return [WordLength]byte{}, err
+	}
+
+	var word [WordLength]byte
+	copy(word[WordLength-20:], decoded[1:])
+
+	return word, nil
+}
+
+// WordAddress decodes an address parameter back to its base58 form
+// Some encoders keep the 0x41 prefix in byte 11, which is accepted; any other
+// non-zero padding is rejected
+func WordAddress(word []byte) (string, error) {
+	if len(word) != WordLength {
+		return "", fmt.Errorf("address parameter must be %d bytes, got %d", WordLength, len(word))
+	}
+	padding := word[:WordLength-20]
+	if padding[len(padding)-1] == AddressPrefix {
+		padding = padding[:len(padding)-1]
+	}
+	if !abi.IsZero(padding) {
+		return "", errors.New("address parameter has non-zero padding")
+	}
+
+	return EncodeAddress(append([]byte{AddressPrefix}, word[WordLength-20:]...))
+}
+
+// AddressToHex returns the 64-character hex form of an address parameter, as seen
+// in the parameter field of TronGrid triggersmartcontract calls
+func AddressToHex(address string) (string, error) {
+	word, err := AddressWord(address)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(word[:]), nil
+}
+
+// HexToAddress decodes the 64-character hex form of an address parameter, with or
+// without a 0x prefix, to a base58 address
+func HexToAddress(s string) (string, error) {
+	word, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+	if err != nil {
+		return "", fmt.Errorf("invalid address parameter %q: %w", s, err)
+	}
+
+	return WordAddress(word)
+}
+
+// Uint256Word encodes a non-negative amount as a uint256 parameter
+func Uint256Word(n *big.Int) ([WordLength]byte, error) {
+	return abi.Uint256Word(n)
+}
+
+// DecodeUint256 decodes a uint256 return value, such as the result of balanceOf
+func DecodeUint256(result []byte) (*big.Int, error) {
+	if len(result) != WordLength {
+		return nil, fmt.Errorf("uint256 result must be %d bytes, got %d", WordLength, len(result))
+	}
+
+	return new(big.Int).SetBytes(result), nil
+}
+
+// DecodeBool decodes a bool return value, such as the result of transfer and approve
+// An empty result, returned by tokens that do not follow the standard, decodes as true
+func DecodeBool(result []byte) (bool, error) {
+	if len(result) == 0 {
+		return true, nil
+	}
+	if len(result) != WordLength || !abi.IsZero(result[:WordLength-1]) || result[WordLength-1] > 1 {
+		return false, fmt.Errorf("invalid bool result %x", result)
+	}
+
+	return result[WordLength-1] == 1, nil
+}
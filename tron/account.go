@@ -0,0 +1,134 @@
+package tron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// CoinType is the SLIP-0044 coin type of TRON
+const CoinType = 195
+
+// Account is the hdwallet.Account of a TRON key
+type Account struct {
+	signer     hdwallet.Signer
+	publicKey  *hdwallet.PublicKey
+	address    string
+	timestamps hdwallet.NonceManager
+}
+
+var _ hdwallet.Account = (*Account)(nil)
+
+// AccountOption configures an Account
+type AccountOption func(*Account)
+
+// WithNonceManager sets the manager SignNext reserves transaction timestamps from,
+// see ReserveTimestamp
+func WithNonceManager(timestamps hdwallet.NonceManager) AccountOption {
+	return func(a *Account) {
+		a.timestamps = timestamps
+	}
+}
+
+// NewAccount returns the TRON account of signer, typically Wallet.Signer or an HSM signer
+func NewAccount(signer hdwallet.Signer, opts ...AccountOption) (*Account, error) {
+	publicKey, err := hdwallet.SignerPublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{
+		signer:    signer,
+		publicKey: publicKey,
+		address:   hdwallet.GenerateTronAddress(publicKey),
+	}
+	for _, opt := range opts {
+		opt(account)
+	}
+
+	return account, nil
+}
+
+// Coin implements hdwallet.Account
+func (a *Account) Coin() uint32 {
+	return CoinType
+}
+
+// Address returns the base58check address of the account
+func (a *Account) Address() string {
+	return a.address
+}
+
+// PublicKey implements hdwallet.Account
+func (a *Account) PublicKey() *hdwallet.PublicKey {
+	return a.publicKey
+}
+
+// SignDigest returns the 65-byte r || s || v signature of digest, as SignDigest does
+func (a *Account) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	signature, err := hdwallet.SignWithContext(ctx, a.signer, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return hdwallet.RecoverableSignature(a.publicKey, digest, signature)
+}
+
+// SignTransaction adds the account's signature to a serialized protocol.Transaction,
+// which may already carry signatures of other permission keys, and returns it serialized
+//
+// Policies see the transfer of the transaction (see Transaction.Intent) as its signing
+// intent, replacing any declared in ctx
+func (a *Account) SignTransaction(ctx context.Context, payload []byte) ([]byte, error) {
+	tx, err := ParseTransaction(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := tx.Intent()
+	if err != nil {
+		return nil, fmt.Errorf("decode contract: %w", err)
+	}
+	ctx = hdwallet.WithSigningIntent(ctx, *intent)
+
+	id := tx.ID()
+	signature, err := a.SignDigest(ctx, id[:])
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.AddSignature(signature); err != nil {
+		return nil, err
+	}
+
+	return tx.Marshal(), nil
+}
+
+// SignNext moves the timestamp of the unsigned tx to one reserved for the account from
+// the manager set with WithNonceManager, as ReserveTimestamp does, shifting its
+// expiration by as much, and signs it like SignTransaction
+//
+// Unused timestamps leave harmless gaps, so they are never released
+func (a *Account) SignNext(ctx context.Context, tx *Transaction) ([]byte, error) {
+	if a.timestamps == nil {
+		return nil, errors.New("account has no nonce manager")
+	}
+	if len(tx.Signatures) != 0 {
+		return nil, errors.New("transaction is already signed, its timestamp cannot change")
+	}
+
+	timestamp, err := tx.timestamp()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := ReserveTimestamp(ctx, a.timestamps, a.address, BlockRef{Timestamp: timestamp})
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.setTimestamp(ref.Timestamp); err != nil {
+		return nil, err
+	}
+
+	return a.SignTransaction(ctx, tx.Marshal())
+}
@@ -0,0 +1,44 @@
+// Package tron builds and signs TRON transactions for keys derived by hdwallet
+//
+// Transactions are encoded with protowire directly, following the field numbers
+// of the java-tron protocol definitions, so no generated protobuf code is needed
+package tron
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/not-for-prod/hdwallet/codec"
+)
+
+const (
+	// AddressPrefix is the first byte of every mainnet TRON address
+	AddressPrefix = 0x41
+	// AddressLength is the length of a decoded address, prefix included
+	AddressLength = 21
+)
+
+// DecodeAddress decodes a base58check TRON address ("T...") to its 21 bytes
+func DecodeAddress(address string) ([]byte, error) {
+	payload, err := codec.Base58CheckDecode(address, codec.Strict)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRON address %q: %w", address, err)
+	}
+	if len(payload) != AddressLength {
+		return nil, fmt.Errorf("invalid TRON address %q: wrong length", address)
+	}
+	if payload[0] != AddressPrefix {
+		return nil, fmt.Errorf("invalid TRON address %q: prefix 0x%02x", address, payload[0])
+	}
+
+	return payload, nil
+}
+
+// EncodeAddress encodes 21 address bytes (0x41 || 20-byte hash) as base58check
+func EncodeAddress(address []byte) (string, error) {
+	if len(address) != AddressLength || address[0] != AddressPrefix {
+		return "", errors.New("TRON address must be 21 bytes starting with 0x41")
+	}
+
+	return codec.Base58CheckEncode(address), nil
+}
@@ -0,0 +1,148 @@
+package tron
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/not-for-prod/hdwallet"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Asset is the asset name of TRX in signing intents; TRC-10 tokens are named by their
+// ID and TRC-20 tokens by their contract address
+const Asset = "TRX"
+
+// Intent describes the transfer made by the transaction: TRX sent by a TransferContract
+// or to a contract without call data, TRC-10 tokens sent by a TransferAssetContract, and
+// TRC-20 tokens moved or approved by a transfer, transferFrom, approve or increaseAllowance
+// call. Other contracts and calls may move funds that cannot be read from the
+// transaction, so their intent has no amount and LimitPolicy denies them
+func (t *Transaction) Intent() (*hdwallet.SigningIntent, error) {
+	raw, err := messageFields(t.RawData)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := messageFields(raw[11].bytes)
+	if err != nil {
+		return nil, err
+	}
+	parameterAny, err := messageFields(contract[2].bytes)
+	if err != nil {
+		return nil, err
+	}
+	parameter, err := messageFields(parameterAny[2].bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ContractType(contract[1].varint) {
+	case TransferContract:
+		to, err := EncodeAddress(parameter[2].bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &hdwallet.SigningIntent{Asset: Asset, Amount: amount(parameter[3].varint), Destination: to}, nil
+	case TransferAssetContract:
+		to, err := EncodeAddress(parameter[3].bytes)
+		if err != nil {
+			return nil, err
+		}
+		return &hdwallet.SigningIntent{
+			Asset:       string(parameter[1].bytes),
+			Amount:      amount(parameter[4].varint),
+			Destination: to,
+		}, nil
+	case TriggerSmartContract:
+		contractAddress, err := EncodeAddress(parameter[2].bytes)
+		if err != nil {
+			return nil, err
+		}
+		callValue, data := parameter[3].varint, parameter[4].bytes
+		// call_token_value sends TRC-10 tokens along the call
+		if parameter[5].varint == 0 {
+			if len(data) == 0 {
+				return &hdwallet.SigningIntent{Asset: Asset, Amount: amount(callValue), Destination: contractAddress}, nil
+			}
+			if callValue == 0 {
+				if intent, ok := tokenCallIntent(contractAddress, data); ok {
+					return intent, nil
+				}
+			}
+		}
+		return &hdwallet.SigningIntent{Asset: contractAddress, Destination: contractAddress}, nil
+	default:
+		return &hdwallet.SigningIntent{Asset: Asset}, nil
+	}
+}
+
+// tokenCallIntent describes a TRC-20 call to contract moving tokens or granting an
+// allowance over them; ok is false for any other call data
+func tokenCallIntent(contract string, data []byte) (*hdwallet.SigningIntent, bool) {
+	var destination string
+	var value *big.Int
+	var err error
+	switch {
+	case bytes.HasPrefix(data, TransferSelector[:]):
+		destination, value, err = DecodeTRC20Transfer(data)
+	case bytes.HasPrefix(data, TransferFromSelector[:]):
+		_, destination, value, err = DecodeTRC20TransferFrom(data)
+	case bytes.HasPrefix(data, ApproveSelector[:]):
+		destination, value, err = DecodeTRC20Approve(data)
+	case bytes.HasPrefix(data, IncreaseAllowanceSelector[:]):
+		destination, value, err = decodeAddressAmountCall(data, IncreaseAllowanceSelector)
+	default:
+		return nil, false
+	}
+	if err != nil {
+		return nil, false
+	}
+
+	return &hdwallet.SigningIntent{Asset: contract, Amount: value, Destination: destination}, true
+}
+
+// amount converts an int64 protobuf amount, encoded as a varint
+func amount(varint uint64) *big.Int {
+	return big.NewInt(int64(varint))
+}
+
+// messageField is the last value of a protobuf field, bytes for length-delimited
+// fields and varint for varints
+type messageField struct {
+	bytes  []byte
+	varint uint64
+}
+
+// messageFields decodes the fields of a protobuf message by number
+func messageFields(data []byte) (map[protowire.Number]messageField, error) {
+	fields := make(map[protowire.Number]messageField)
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		switch wireType {
+		case protowire.BytesType:
+			var value []byte
+			value, n = protowire.ConsumeBytes(data)
+			fields[number] = messageField{bytes: value}
+		case protowire.VarintType:
+			var value uint64
+			value, n = protowire.ConsumeVarint(data)
+			fields[number] = messageField{varint: value}
+		default:
+			n = protowire.ConsumeFieldValue(number, wireType, data)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("empty message")
+	}
+
+	return fields, nil
+}
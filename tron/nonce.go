@@ -0,0 +1,107 @@
+package tron
+
+import (
+	"context"
+	"time"
+
+	"github.com/not-for-prod/hdwallet"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ReserveTimestamp sets the transaction timestamp of ref (see NewTransaction) to a
+// millisecond reserved for owner through nonces
+//
+// TRON accounts have no nonce: a transaction ID is the hash of its raw data, so two
+// identical transfers built in the same millisecond collide and the second one is
+// rejected as a duplicate. Reserved timestamps are strictly increasing per owner,
+// never earlier than ref.Timestamp (the current time when unset)
+func ReserveTimestamp(ctx context.Context, nonces hdwallet.NonceManager, owner string, ref BlockRef) (BlockRef, error) {
+	floor := ref.Timestamp
+	if floor.IsZero() {
+		floor = time.Now()
+	}
+
+	key := hdwallet.NonceKey{Coin: CoinType, Address: owner}
+	if err := nonces.Advance(ctx, key, uint64(floor.UnixMilli())); err != nil {
+		return BlockRef{}, err
+	}
+	millis, err := nonces.Reserve(ctx, key)
+	if err != nil {
+		return BlockRef{}, err
+	}
+	ref.Timestamp = time.UnixMilli(int64(millis))
+
+	return ref, nil
+}
+
+// Fields of raw_data rewritten when moving a transaction to another timestamp
+const (
+	rawDataExpiration protowire.Number = 8
+	rawDataTimestamp  protowire.Number = 14
+)
+
+// timestamp returns the timestamp of the transaction, the current time when unset
+func (t *Transaction) timestamp() (time.Time, error) {
+	fields, err := messageFields(t.RawData)
+	if err != nil {
+		return time.Time{}, err
+	}
+	millis, ok := fields[rawDataTimestamp]
+	if !ok {
+		return time.Now(), nil
+	}
+
+	return time.UnixMilli(int64(millis.varint)), nil
+}
+
+// setTimestamp rewrites the timestamp of raw_data, moving the expiration by the same
+// amount so the validity window is kept; other fields are copied unchanged
+func (t *Transaction) setTimestamp(timestamp time.Time) error {
+	current, err := t.timestamp()
+	if err != nil {
+		return err
+	}
+	shift := timestamp.UnixMilli() - current.UnixMilli()
+
+	var raw []byte
+	found := false
+	data := t.RawData
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		field := data[:n]
+		data = data[n:]
+
+		if wireType == protowire.VarintType && (number == rawDataTimestamp || number == rawDataExpiration) {
+			value, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+
+			if number == rawDataTimestamp {
+				value, found = uint64(timestamp.UnixMilli()), true
+			} else {
+				value = uint64(int64(value) + shift)
+			}
+			raw = protowire.AppendVarint(append(raw, field...), value)
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		raw = append(append(raw, field...), data[:n]...)
+		data = data[n:]
+	}
+	if !found {
+		raw = protowire.AppendTag(raw, rawDataTimestamp, protowire.VarintType)
+		raw = protowire.AppendVarint(raw, uint64(timestamp.UnixMilli()))
+	}
+	t.RawData = raw
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package tron
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// PermissionType is the kind of an account permission
+type PermissionType int32
+
+const (
+	PermissionOwner   PermissionType = 0
+	PermissionWitness PermissionType = 1
+	PermissionActive  PermissionType = 2
+)
+
+// Limits enforced by java-tron on account permissions (TotalSignNum and the number of actives)
+const (
+	MaxPermissionKeys = 5
+	MaxActives        = 8
+)
+
+// Key is a weighted signer of a permission
+type Key struct {
+	Address string
+	Weight  int64
+}
+
+// Permission is a TRON account permission: a set of weighted keys and the threshold their
+// combined weight must reach for a transaction signed under the permission to be valid
+type Permission struct {
+	Type      PermissionType
+	Name      string
+	Threshold int64
+	Keys      []Key
+	// Operations is the 32-byte bitmap of contract types an active permission may sign,
+	// see Operations; it must be empty for owner and witness permissions
+	Operations []byte
+}
+
+// Operations returns the bitmap allowing the given contract types, for Permission.Operations
+func Operations(types ...ContractType) []byte {
+	bitmap := make([]byte, 32)
+	for _, contractType := range types {
+		bitmap[contractType/8] |= 1 << (contractType % 8)
+	}
+
+	return bitmap
+}
+
+// Allows reports whether the permission may sign contracts of the given type
+// Permissions without an operations bitmap (owner) may sign every contract
+func (p Permission) Allows(contractType ContractType) bool {
+	if len(p.Operations) == 0 {
+		return true
+	}
+	if len(p.Operations) != 32 || contractType < 0 || contractType >= 256 {
+		return false
+	}
+
+	return p.Operations[contractType/8]&(1<<(contractType%8)) != 0
+}
+
+// TotalWeight returns the sum of the key weights
+func (p Permission) TotalWeight() int64 {
+	var total int64
+	for _, key := range p.Keys {
+		total += key.Weight
+	}
+
+	return total
+}
+
+// Weight returns the combined weight of the distinct keys among signers
+func (p Permission) Weight(signers []string) int64 {
+	signed := make(map[string]bool, len(signers))
+	for _, signer := range signers {
+		signed[signer] = true
+	}
+
+	var weight int64
+	for _, key := range p.Keys {
+		if signed[key.Address] {
+			weight += key.Weight
+		}
+	}
+
+	return weight
+}
+
+// Satisfied reports whether the signatures attached to tx reach the threshold of p,
+// and returns the weight they carry
+func (p Permission) Satisfied(tx *Transaction) (bool, int64, error) {
+	signers, err := tx.Signers()
+	if err != nil {
+		return false, 0, err
+	}
+
+	weight := p.Weight(signers)
+
+	return weight >= p.Threshold, weight, nil
+}
+
+// Validate applies the checks java-tron performs on permission updates
+func (p Permission) Validate() error {
+	if len(p.Keys) == 0 || len(p.Keys) > MaxPermissionKeys {
+		return fmt.Errorf("permission %q must have between 1 and %d keys", p.Name, MaxPermissionKeys)
+	}
+	if p.Threshold <= 0 {
+		return fmt.Errorf("permission %q threshold must be positive", p.Name)
+	}
+
+	seen := make(map[string]bool, len(p.Keys))
+	for _, key := range p.Keys {
+		if _, err := DecodeAddress(key.Address); err != nil {
+			return fmt.Errorf("permission %q: %w", p.Name, err)
+		}
+		if key.Weight <= 0 {
+			return fmt.Errorf("permission %q: key %s weight must be positive", p.Name, key.Address)
+		}
+		if seen[key.Address] {
+			return fmt.Errorf("permission %q: duplicate key %s", p.Name, key.Address)
+		}
+		seen[key.Address] = true
+	}
+	if total := p.TotalWeight(); total < p.Threshold {
+		return fmt.Errorf("permission %q threshold %d exceeds total key weight %d", p.Name, p.Threshold, total)
+	}
+
+	switch p.Type {
+	case PermissionOwner, PermissionWitness:
+		if len(p.Operations) != 0 {
+			return fmt.Errorf("permission %q: only active permissions have operations", p.Name)
+		}
+		if p.Type == PermissionWitness && len(p.Keys) != 1 {
+			return errors.New("witness permission must have exactly one key")
+		}
+	case PermissionActive:
+		if len(p.Operations) != 32 {
+			return fmt.Errorf("permission %q operations must be 32 bytes", p.Name)
+		}
+	default:
+		return fmt.Errorf("permission %q has unknown type %d", p.Name, p.Type)
+	}
+
+	return nil
+}
+
+// PermissionUpdate replaces every permission of an account
+// Active permissions receive IDs 2, 3... in order; use them as ContractOptions.PermissionID
+type PermissionUpdate struct {
+	Account string
+	Owner   Permission
+	// Witness is only allowed for super representative accounts
+	Witness *Permission
+	Actives []Permission
+}
+
+// NewPermissionUpdateTransaction builds an AccountPermissionUpdateContract transaction
+// It must be signed under the current owner permission of the account
+func NewPermissionUpdateTransaction(update PermissionUpdate, ref BlockRef, opts ContractOptions) (*Transaction, error) {
+	parameter, err := update.marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTransaction(AccountPermissionUpdateContract, parameter, ref, opts)
+}
+
+func (u PermissionUpdate) marshal() ([]byte, error) {
+	account, err := DecodeAddress(u.Account)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.Actives) == 0 || len(u.Actives) > MaxActives {
+		return nil, fmt.Errorf("between 1 and %d active permissions are required", MaxActives)
+	}
+
+	var out []byte
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendBytes(out, account)
+
+	owner := u.Owner
+	owner.Type = PermissionOwner
+	encoded, err := owner.marshal(0)
+	if err != nil {
+		return nil, err
+	}
+	out = protowire.AppendTag(out, 2, protowire.BytesType)
+	out = protowire.AppendBytes(out, encoded)
+
+	if u.Witness != nil {
+		witness := *u.Witness
+		witness.Type = PermissionWitness
+		if encoded, err = witness.marshal(1); err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, 3, protowire.BytesType)
+		out = protowire.AppendBytes(out, encoded)
+	}
+
+	for i, active := range u.Actives {
+		active.Type = PermissionActive
+		if encoded, err = active.marshal(int32(i + 2)); err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, 4, protowire.BytesType)
+		out = protowire.AppendBytes(out, encoded)
+	}
+
+	return out, nil
+}
+
+// marshal encodes the permission as a protocol.Permission message with the given ID
+func (p Permission) marshal(id int32) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if p.Type != PermissionOwner {
+		out = protowire.AppendTag(out, 1, protowire.VarintType)
+		out = protowire.AppendVarint(out, uint64(p.Type))
+	}
+	if id != 0 {
+		out = protowire.AppendTag(out, 2, protowire.VarintType)
+		out = protowire.AppendVarint(out, uint64(id))
+	}
+	if p.Name != "" {
+		out = protowire.AppendTag(out, 3, protowire.BytesType)
+		out = protowire.AppendString(out, p.Name)
+	}
+	out = protowire.AppendTag(out, 4, protowire.VarintType)
+	out = protowire.AppendVarint(out, uint64(p.Threshold))
+	if len(p.Operations) != 0 {
+		out = protowire.AppendTag(out, 6, protowire.BytesType)
+		out = protowire.AppendBytes(out, p.Operations)
+	}
+
+	for _, key := range p.Keys {
+		address, _ := DecodeAddress(key.Address)
+
+		var encoded []byte
+		encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+		encoded = protowire.AppendBytes(encoded, address)
+		encoded = protowire.AppendTag(encoded, 2, protowire.VarintType)
+		encoded = protowire.AppendVarint(encoded, uint64(key.Weight))
+
+		out = protowire.AppendTag(out, 7, protowire.BytesType)
+		out = protowire.AppendBytes(out, encoded)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,186 @@
+package tron
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/not-for-prod/hdwallet/internal/abi"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// TRC20TransferData returns the call data of transfer(to, amount)
+func TRC20TransferData(to string, amount *big.Int) ([]byte, error) {
+	return addressAmountCall(TransferSelector, to, amount)
+}
+
+// TRC20TransferFromData returns the call data of transferFrom(from, to, amount)
+func TRC20TransferFromData(from, to string, amount *big.Int) ([]byte, error) {
+	fromWord, err := AddressWord(from)
+	if err != nil {
+		return nil, err
+	}
+	toWord, err := AddressWord(to)
+	if err != nil {
+		return nil, err
+	}
+	amountWord, err := Uint256Word(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return abi.EncodeCall(TransferFromSelector, fromWord, toWord, amountWord), nil
+}
+
+// TRC20ApproveData returns the call data of approve(spender, amount)
+func TRC20ApproveData(spender string, amount *big.Int) ([]byte, error) {
+	return addressAmountCall(ApproveSelector, spender, amount)
+}
+
+// TRC20BalanceOfData returns the call data of balanceOf(owner), for triggerconstantcontract
+func TRC20BalanceOfData(owner string) ([]byte, error) {
+	word, err := AddressWord(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return abi.EncodeCall(BalanceOfSelector, word), nil
+}
+
+// DecodeTRC20Transfer decodes transfer call data to its recipient and amount
+func DecodeTRC20Transfer(data []byte) (string, *big.Int, error) {
+	return decodeAddressAmountCall(data, TransferSelector)
+}
+
+// DecodeTRC20TransferFrom decodes transferFrom call data to its owner, recipient and amount
+func DecodeTRC20TransferFrom(data []byte) (string, string, *big.Int, error) {
+	words, err := abi.DecodeCall(data, TransferFromSelector, 3)
+	if err != nil {
+		return "", "", nil, err
+	}
+	from, err := WordAddress(words[0])
+	if err != nil {
+		return "", "", nil, err
+	}
+	to, err := WordAddress(words[1])
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return from, to, new(big.Int).SetBytes(words[2]), nil
+}
+
+// DecodeTRC20Approve decodes approve call data to its spender and amount
+func DecodeTRC20Approve(data []byte) (string, *big.Int, error) {
+	return decodeAddressAmountCall(data, ApproveSelector)
+}
+
+// SmartContractCall is a TriggerSmartContract call
+type SmartContractCall struct {
+	Owner    string
+	Contract string
+	Data     []byte
+	// CallValue is the amount of sun sent along with the call
+	CallValue int64
+}
+
+// NewSmartContractTransaction builds a TriggerSmartContract transaction
+// opts.FeeLimit must be set, java-tron rejects contract calls without one
+func NewSmartContractTransaction(call SmartContractCall, ref BlockRef, opts ContractOptions) (*Transaction, error) {
+	if opts.FeeLimit <= 0 {
+		return nil, errors.New("smart contract calls require a fee limit")
+	}
+	owner, err := DecodeAddress(call.Owner)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := DecodeAddress(call.Contract)
+	if err != nil {
+		return nil, err
+	}
+
+	var parameter []byte
+	parameter = protowire.AppendTag(parameter, 1, protowire.BytesType)
+	parameter = protowire.AppendBytes(parameter, owner)
+	parameter = protowire.AppendTag(parameter, 2, protowire.BytesType)
+	parameter = protowire.AppendBytes(parameter, contract)
+	if call.CallValue != 0 {
+		parameter = protowire.AppendTag(parameter, 3, protowire.VarintType)
+		parameter = protowire.AppendVarint(parameter, uint64(call.CallValue))
+	}
+	if len(call.Data) != 0 {
+		parameter = protowire.AppendTag(parameter, 4, protowire.BytesType)
+		parameter = protowire.AppendBytes(parameter, call.Data)
+	}
+
+	return NewTransaction(TriggerSmartContract, parameter, ref, opts)
+}
+
+// NewTRC20TransferTransaction builds a transaction calling transfer(to, amount) on a
+// TRC-20 token contract; amount is in the token's smallest unit
+func NewTRC20TransferTransaction(owner, token, to string, amount *big.Int, ref BlockRef, opts ContractOptions) (*Transaction, error) {
+	data, err := TRC20TransferData(to, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSmartContractTransaction(SmartContractCall{Owner: owner, Contract: token, Data: data}, ref, opts)
+}
+
+// NewTRC10TransferTransaction builds a TransferAssetContract transaction moving amount
+// of the TRC-10 token tokenID (its decimal ID, such as "1002000")
+func NewTRC10TransferTransaction(owner, to, tokenID string, amount int64, ref BlockRef, opts ContractOptions) (*Transaction, error) {
+	if _, err := strconv.ParseUint(tokenID, 10, 63); err != nil {
+		return nil, fmt.Errorf("invalid TRC-10 token ID %q", tokenID)
+	}
+	if amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	ownerAddress, err := DecodeAddress(owner)
+	if err != nil {
+		return nil, err
+	}
+	toAddress, err := DecodeAddress(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var parameter []byte
+	parameter = protowire.AppendTag(parameter, 1, protowire.BytesType)
+	parameter = protowire.AppendString(parameter, tokenID)
+	parameter = protowire.AppendTag(parameter, 2, protowire.BytesType)
+	parameter = protowire.AppendBytes(parameter, ownerAddress)
+	parameter = protowire.AppendTag(parameter, 3, protowire.BytesType)
+	parameter = protowire.AppendBytes(parameter, toAddress)
+	parameter = protowire.AppendTag(parameter, 4, protowire.VarintType)
+	parameter = protowire.AppendVarint(parameter, uint64(amount))
+
+	return NewTransaction(TransferAssetContract, parameter, ref, opts)
+}
+
+func addressAmountCall(selector [4]byte, address string, amount *big.Int) ([]byte, error) {
+	addressWord, err := AddressWord(address)
+	if err != nil {
+		return nil, err
+	}
+	amountWord, err := Uint256Word(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return abi.EncodeCall(selector, addressWord, amountWord), nil
+}
+
+func decodeAddressAmountCall(data []byte, selector [4]byte) (string, *big.Int, error) {
+	words, err := abi.DecodeCall(data, selector, 2)
+	if err != nil {
+		return "", nil, err
+	}
+	address, err := WordAddress(words[0])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return address, new(big.Int).SetBytes(words[1]), nil
+}
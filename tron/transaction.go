@@ -0,0 +1,285 @@
+package tron
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/not-for-prod/hdwallet"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ContractType is the type of the single contract carried by a TRON transaction
+type ContractType int32
+
+const (
+	TransferContract                ContractType = 1
+	TransferAssetContract           ContractType = 2
+	TriggerSmartContract            ContractType = 31
+	AccountPermissionUpdateContract ContractType = 46
+)
+
+// contractTypeNames maps contract types to their protobuf message names, used in type URLs
+var contractTypeNames = map[ContractType]string{
+	TransferContract:                "TransferContract",
+	TransferAssetContract:           "TransferAssetContract",
+	TriggerSmartContract:            "TriggerSmartContract",
+	AccountPermissionUpdateContract: "AccountPermissionUpdateContract",
+}
+
+// DefaultExpiration is the expiration applied by the builders when none is given;
+// java-tron rejects transactions expiring more than 24 hours after their reference block
+const DefaultExpiration = 60 * time.Second
+
+// BlockRef is the recent block a transaction references (TaPoS), taken from /wallet/getnowblock
+type BlockRef struct {
+	Number    int64
+	Hash      []byte
+	Timestamp time.Time
+}
+
+// Transaction is a TRON transaction: the serialized raw_data message and its signatures
+//
+// Keeping raw_data as bytes means transactions built elsewhere (raw_data_hex returned
+// by a node) can be signed and co-signed without re-encoding, which would change the ID
+type Transaction struct {
+	RawData    []byte
+	Signatures [][]byte
+}
+
+// ContractOptions are the optional fields of a built transaction
+type ContractOptions struct {
+	// PermissionID selects the account permission the transaction is signed under:
+	// 0 for owner, 2 and above for active permissions
+	PermissionID int32
+	// Expiration defaults to the reference block time plus DefaultExpiration
+	Expiration time.Time
+	// FeeLimit is the maximum energy fee in sun, only meaningful for smart contract calls
+	FeeLimit int64
+}
+
+// NewTransaction builds a transaction carrying one contract of the given type whose
+// serialized parameter message is parameter
+func NewTransaction(contractType ContractType, parameter []byte, ref BlockRef, opts ContractOptions) (*Transaction, error) {
+	name, ok := contractTypeNames[contractType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported contract type %d", contractType)
+	}
+	if len(ref.Hash) != 32 {
+		return nil, errors.New("reference block hash must be 32 bytes")
+	}
+
+	timestamp := ref.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	expiration := opts.Expiration
+	if expiration.IsZero() {
+		expiration = timestamp.Add(DefaultExpiration)
+	}
+
+	// google.protobuf.Any wrapping the contract parameter
+	var any []byte
+	any = protowire.AppendTag(any, 1, protowire.BytesType)
+	any = protowire.AppendString(any, "type.googleapis.com/protocol."+name)
+	any = protowire.AppendTag(any, 2, protowire.BytesType)
+	any = protowire.AppendBytes(any, parameter)
+
+	var contract []byte
+	contract = protowire.AppendTag(contract, 1, protowire.VarintType)
+	contract = protowire.AppendVarint(contract, uint64(contractType))
+	contract = protowire.AppendTag(contract, 2, protowire.BytesType)
+	contract = protowire.AppendBytes(contract, any)
+	if opts.PermissionID != 0 {
+		contract = protowire.AppendTag(contract, 5, protowire.VarintType)
+		contract = protowire.AppendVarint(contract, uint64(opts.PermissionID))
+	}
+
+	number := binary.BigEndian.AppendUint64(nil, uint64(ref.Number))
+
+	var raw []byte
+	raw = protowire.AppendTag(raw, 1, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, number[6:8])
+	raw = protowire.AppendTag(raw, 4, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, ref.Hash[8:16])
+	raw = protowire.AppendTag(raw, 8, protowire.VarintType)
+	raw = protowire.AppendVarint(raw, uint64(expiration.UnixMilli()))
+	raw = protowire.AppendTag(raw, 11, protowire.BytesType)
+	raw = protowire.AppendBytes(raw, contract)
+	raw = protowire.AppendTag(raw, 14, protowire.VarintType)
+	raw = protowire.AppendVarint(raw, uint64(timestamp.UnixMilli()))
+	if opts.FeeLimit != 0 {
+		raw = protowire.AppendTag(raw, 18, protowire.VarintType)
+		raw = protowire.AppendVarint(raw, uint64(opts.FeeLimit))
+	}
+
+	return &Transaction{RawData: raw}, nil
+}
+
+// ParseTransaction decodes a serialized protocol.Transaction (as accepted by /wallet/broadcasthex)
+func ParseTransaction(data []byte) (*Transaction, error) {
+	tx := &Transaction{}
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if wireType == protowire.BytesType && (number == 1 || number == 2) {
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if number == 1 {
+				tx.RawData = append([]byte(nil), value...)
+			} else {
+				tx.Signatures = append(tx.Signatures, append([]byte(nil), value...))
+			}
+			data = data[n:]
+			continue
+		}
+
+		// Results (field 5) and unknown fields are not part of the signed data
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	if tx.RawData == nil {
+		return nil, errors.New("transaction has no raw_data")
+	}
+
+	return tx, nil
+}
+
+// Marshal serializes the transaction as a protocol.Transaction
+func (t *Transaction) Marshal() []byte {
+	var out []byte
+	out = protowire.AppendTag(out, 1, protowire.BytesType)
+	out = protowire.AppendBytes(out, t.RawData)
+	for _, signature := range t.Signatures {
+		out = protowire.AppendTag(out, 2, protowire.BytesType)
+		out = protowire.AppendBytes(out, signature)
+	}
+
+	return out
+}
+
+// ID returns the transaction ID, SHA-256 of raw_data, which is also the signed digest
+func (t *Transaction) ID() [32]byte {
+	return RawDataDigest(t.RawData)
+}
+
+// RawDataDigest returns the digest signed by TRON accounts, SHA-256 of the serialized
+// raw_data message (raw_data_hex in node responses)
+func RawDataDigest(rawData []byte) [32]byte {
+	return sha256.Sum256(rawData)
+}
+
+// TransactionDigest returns the signed digest of a serialized protocol.Transaction
+func TransactionDigest(transaction []byte) ([32]byte, error) {
+	tx, err := ParseTransaction(transaction)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return tx.ID(), nil
+}
+
+// Sign appends the signature of key to the transaction
+// Keys come from hdwallet.GenerateKeysFromMnemonic or Wallet.DeriveKey; signatures of
+// different keys can be collected on separate machines and merged with AddSignature
+func (t *Transaction) Sign(key *hdwallet.PrivateKey) error {
+	id := t.ID()
+	signature, err := SignDigest(key, id[:])
+	if err != nil {
+		return err
+	}
+
+	return t.AddSignature(signature)
+}
+
+// AddSignature attaches a 65-byte signature produced by another co-signer
+// The signature must recover to a signer that has not signed the transaction yet
+func (t *Transaction) AddSignature(signature []byte) error {
+	signer, err := t.recover(signature)
+	if err != nil {
+		return err
+	}
+
+	signers, err := t.Signers()
+	if err != nil {
+		return err
+	}
+	for _, existing := range signers {
+		if existing == signer {
+			return fmt.Errorf("transaction already signed by %s", signer)
+		}
+	}
+
+	t.Signatures = append(t.Signatures, signature)
+
+	return nil
+}
+
+// Signers returns the addresses recovered from the attached signatures, in order
+func (t *Transaction) Signers() ([]string, error) {
+	signers := make([]string, 0, len(t.Signatures))
+	for i, signature := range t.Signatures {
+		signer, err := t.recover(signature)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: %w", i, err)
+		}
+		signers = append(signers, signer)
+	}
+
+	return signers, nil
+}
+
+func (t *Transaction) recover(signature []byte) (string, error) {
+	id := t.ID()
+	publicKey, err := RecoverPublicKey(id[:], signature)
+	if err != nil {
+		return "", err
+	}
+
+	return hdwallet.GenerateTronAddress(publicKey), nil
+}
+
+// SignDigest signs a 32-byte digest and returns the 65-byte r || s || v signature
+// used by TRON, where v is the recovery ID (0 to 3, in practice 0 or 1) as produced by java-tron
+func SignDigest(key *hdwallet.PrivateKey, digest []byte) ([]byte, error) {
+	if len(digest) != 32 {
+		return nil, fmt.Errorf("digest must be 32 bytes, got %d", len(digest))
+	}
+
+	signature, err := key.Sign(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature.Recoverable()
+}
+
+// RecoverPublicKey recovers the public key of a 65-byte r || s || v signature
+// v may be the raw recovery ID (0 to 3) or offset by 27 as produced by TronWeb
+func RecoverPublicKey(digest, signature []byte) (*hdwallet.PublicKey, error) {
+	if len(signature) != 65 {
+		return nil, fmt.Errorf("signature must be 65 bytes, got %d", len(signature))
+	}
+
+	parsed, err := hdwallet.ParseCompactSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := hdwallet.RecoverPublicKey(digest, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("recover signer: %w", err)
+	}
+
+	return publicKey, nil
+}
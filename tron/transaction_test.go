@@ -0,0 +1,70 @@
+package tron
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// The raw_data below is spelled out field by field from the TRON protocol messages
+const (
+	transferParameter = "0a15411111111111111111111111111111111111111111" + // owner_address
+		"1215412222222222222222222222222222222222222222" + // to_address
+		"18c0843d" // amount, 1 TRX
+
+	transferRawData = "0a021234" + // ref_block_bytes
+		"220808090a0b0c0d0e0f" + // ref_block_hash
+		"40e0a499ffbc31" + // expiration
+		"5a67" + "0801" + "1263" + // contract: type, parameter
+		"0a2d747970652e676f6f676c65617069732e636f6d2f70726f746f636f6c2e5472616e73666572436f6e7472616374" +
+		"1232" + transferParameter +
+		"7080d095ffbc31" // timestamp
+
+	transferID = "ac8a56b73ccf363a1afe46fa902aedf98f286c4f718b21f651dccb09bbc06567"
+)
+
+func TestTransactionID(t *testing.T) {
+	parameter := mustHex(t, transferParameter)
+	hash := make([]byte, 32)
+	for i := range hash {
+		hash[i] = byte(i)
+	}
+	ref := BlockRef{Number: 0x1234, Hash: hash, Timestamp: time.UnixMilli(1700000000000)}
+
+	tx, err := NewTransaction(TransferContract, parameter, ref, ContractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(tx.RawData); got != transferRawData {
+		t.Errorf("raw_data = %s, want %s", got, transferRawData)
+	}
+
+	id := tx.ID()
+	if got := hex.EncodeToString(id[:]); got != transferID {
+		t.Errorf("ID = %s, want %s", got, transferID)
+	}
+	if digest := RawDataDigest(mustHex(t, transferRawData)); !bytes.Equal(digest[:], id[:]) {
+		t.Errorf("RawDataDigest = %x, want %s", digest, transferID)
+	}
+
+	tx.Signatures = [][]byte{bytes.Repeat([]byte{0x01}, 65)}
+	digest, err := TransactionDigest(tx.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(digest[:]); got != transferID {
+		t.Errorf("TransactionDigest = %s, want %s", got, transferID)
+	}
+}
+
+// mustHex decodes a hex test vector, failing the test if it is malformed
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
@@ -0,0 +1,528 @@
+package hdwallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// Wallet is a BIP44 wallet for a single coin type
+//
+// Besides deriving keys it keeps track of which accounts and addresses were already
+// handed out, so integrators no longer have to implement the index bookkeeping themselves:
+// NextAccount and NextAddress return the next fresh index, skip indexes that a
+// UsageOracle reports as used, and persist the high-water mark in an IndexStore
+//
+// A Wallet is safe for concurrent use
+type Wallet struct {
+	mu      sync.Mutex
+	coin    uint32
+	account uint32
+	oracle  UsageOracle
+	indexes IndexStore
+	auditor Auditor
+	policy  Policy
+	metrics Metrics
+	// ownMetrics is set by WithMetrics; otherwise DefaultMetrics is looked up on use
+	ownMetrics bool
+	seedKDF    SeedKDF
+
+	// keyMu guards the master key and its lock lifecycle, see Unlock and Lock
+	keyMu       sync.RWMutex
+	masterKey   *bip32.Key
+	mnemonic    []byte
+	fingerprint string
+	lockTimeout time.Duration
+	lockTimer   *time.Timer
+	session     uint64
+	lastUsed    atomic.Int64
+}
+
+// WalletOption configures optional Wallet behaviour
+type WalletOption func(*Wallet)
+
+// WithUsageOracle makes NextAccount and NextAddress skip indexes already used on chain
+func WithUsageOracle(oracle UsageOracle) WalletOption {
+	return func(w *Wallet) {
+		w.oracle = oracle
+	}
+}
+
+// WithIndexStore persists the account and address high-water marks in store
+// Without it the marks are kept in memory and lost when the process exits
+func WithIndexStore(store IndexStore) WalletOption {
+	return func(w *Wallet) {
+		w.indexes = store
+	}
+}
+
+// WithAuditor reports every private key derivation and signature of the wallet to auditor
+func WithAuditor(auditor Auditor) WalletOption {
+	return func(w *Wallet) {
+		w.auditor = auditor
+	}
+}
+
+// WithPolicy evaluates policy before every private key derivation and signature of the wallet
+func WithPolicy(policy Policy) WalletOption {
+	return func(w *Wallet) {
+		w.policy = policy
+	}
+}
+
+// WithMetrics reports the derivations and signatures of the wallet to metrics
+// instead of the package DefaultMetrics, nil disables them
+func WithMetrics(metrics Metrics) WalletOption {
+	return func(w *Wallet) {
+		w.metrics = metrics
+		w.ownMetrics = true
+	}
+}
+
+// WithAccount sets the account used by NextAddress until NextAccount selects another one
+func WithAccount(account uint32) WalletOption {
+	return func(w *Wallet) {
+		w.account = account
+	}
+}
+
+// NewWallet creates a Wallet for coin from a BIP39 mnemonic and optional passphrase
+// The wallet starts unlocked and keeps the mnemonic, so it can be unlocked again after
+// Lock; Unlock then only accepts the passphrase given here, unless WithMasterFingerprint
+// names another master key
+func NewWallet(mnemonic, passphrase string, coin uint32, opts ...WalletOption) (*Wallet, error) {
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, errors.New("invalid mnemonic")
+	}
+
+	w, err := newWallet(coin, opts)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := stretchSeed(context.Background(), w.seedKDF, []byte(mnemonic), []byte(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	defer wipeBytes(seed)
+
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	w.mnemonic = []byte(mnemonic)
+	if w.fingerprint == "" {
+		w.fingerprint = fingerprint(masterKey.PublicKey().Key)
+	}
+	w.setMasterKey(masterKey)
+
+	return w, nil
+}
+
+// NewWalletFromSeed creates a Wallet for coin from a BIP39 seed
+// The wallet starts unlocked; once locked, Unlock fails with ErrNoMnemonic, see NewLockedWallet
+func NewWalletFromSeed(seed []byte, coin uint32, opts ...WalletOption) (*Wallet, error) {
+	masterKey, err := bip32.NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newWallet(coin, opts)
+	if err != nil {
+		return nil, err
+	}
+	w.setMasterKey(masterKey)
+
+	return w, nil
+}
+
+func newWallet(coin uint32, opts []WalletOption) (*Wallet, error) {
+	if coin >= HardenedOffset {
+		return nil, fmt.Errorf("coin type %d out of range", coin)
+	}
+
+	w := &Wallet{
+		coin:    coin,
+		seedKDF: DefaultSeedKDF(),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if w.account >= HardenedOffset {
+		return nil, fmt.Errorf("account %d out of range", w.account)
+	}
+	if w.indexes == nil {
+		w.indexes = NewMemoryIndexStore()
+	}
+
+	return w, nil
+}
+
+// Coin returns the SLIP-0044 coin type of the wallet
+func (w *Wallet) Coin() uint32 {
+	return w.coin
+}
+
+// Account returns the account currently used by NextAddress
+func (w *Wallet) Account() uint32 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.account
+}
+
+// DeriveKey derives the private key at m/44'/coin'/account'/chain/address
+// The access is checked by the Policy and reported to the Auditor, if configured
+func (w *Wallet) DeriveKey(account, chain, address uint32) (*PrivateKey, error) {
+	return w.DeriveKeyContext(context.Background(), account, chain, address)
+}
+
+// DeriveKeyContext is DeriveKey with a context carrying audit metadata (see WithAuditContext)
+func (w *Wallet) DeriveKeyContext(ctx context.Context, account, chain, address uint32) (*PrivateKey, error) {
+	start := time.Now()
+	privateKey, err := w.deriveKeyAuthorized(ctx, account, chain, address)
+	observe(w.observer(), MetricDeriveKey, coinLabel(w.coin), start, err)
+
+	return privateKey, err
+}
+
+func (w *Wallet) deriveKeyAuthorized(ctx context.Context, account, chain, address uint32) (*PrivateKey, error) {
+	path, err := checkedBIP44Path(w.coin, account, chain, address)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.authorize(ctx, AuditDeriveKey, path, nil); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := w.deriveKeyAt(path)
+	if err != nil {
+		w.release(ctx, AuditDeriveKey, path, nil)
+		return nil, err
+	}
+
+	return privateKey, nil
+}
+
+// SignDigest signs a 32-byte digest with the key at m/44'/coin'/account'/chain/address
+// and returns the DER encoded signature
+// The signature is checked by the Policy and reported to the Auditor, if configured
+func (w *Wallet) SignDigest(account, chain, address uint32, digest []byte) ([]byte, error) {
+	return w.SignDigestContext(context.Background(), account, chain, address, digest)
+}
+
+// SignDigestContext is SignDigest with a context carrying audit metadata (see WithAuditContext)
+// and the signing intent evaluated by policies (see WithSigningIntent)
+func (w *Wallet) SignDigestContext(ctx context.Context, account, chain, address uint32, digest []byte) ([]byte, error) {
+	start := time.Now()
+	signature, err := w.signDigest(ctx, account, chain, address, digest)
+	observe(w.observer(), MetricSign, coinLabel(w.coin), start, err)
+
+	return signature, err
+}
+
+func (w *Wallet) signDigest(ctx context.Context, account, chain, address uint32, digest []byte) ([]byte, error) {
+	path, err := checkedBIP44Path(w.coin, account, chain, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.signDigestAt(ctx, path, digest)
+}
+
+// signDigestAt signs digest with the key at path, which may be outside m/44'
+func (w *Wallet) signDigestAt(ctx context.Context, path DerivationPath, digest []byte) ([]byte, error) {
+	if err := w.authorize(ctx, AuditSign, path, digest); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := w.deriveKeyAt(path)
+	if err != nil {
+		w.release(ctx, AuditSign, path, digest)
+		return nil, err
+	}
+	defer privateKey.Zero()
+
+	signature, err := NewKeySigner(privateKey).SignDigest(digest)
+	if err != nil {
+		w.release(ctx, AuditSign, path, digest)
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+// deriveKey derives a private key without auditing, for internal public key derivations
+func (w *Wallet) deriveKey(account, chain, address uint32) (*PrivateKey, error) {
+	path, err := checkedBIP44Path(w.coin, account, chain, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.deriveKeyAt(path)
+}
+
+// deriveKeyAt is deriveKey for a full path
+func (w *Wallet) deriveKeyAt(path DerivationPath) (*PrivateKey, error) {
+	var privateKey *PrivateKey
+	err := w.withMasterKey(func(masterKey *bip32.Key) error {
+		key, err := DerivePath(masterKey, path)
+		if err != nil {
+			return err
+		}
+		privateKey = PrivateKeyFromSecp256k1(secp256k1.PrivKeyFromBytes(key.Key))
+		wipeBytes(key.Key)
+
+		return nil
+	})
+
+	return privateKey, err
+}
+
+// observer returns the metrics of the wallet, the current DefaultMetrics unless
+// WithMetrics was given, so SetDefaultMetrics also reaches existing wallets
+func (w *Wallet) observer() Metrics {
+	if w.ownMetrics {
+		return w.metrics
+	}
+
+	return DefaultMetrics()
+}
+
+// authorize evaluates the configured Policy and then audits the operation
+// Denied operations are not reported to the Auditor, they never touch key material
+func (w *Wallet) authorize(ctx context.Context, operation AuditOperation, path DerivationPath, digest []byte) error {
+	if w.policy != nil {
+		if err := w.policy.Evaluate(ctx, newSigningRequest(ctx, operation, w.coin, path, digest)); err != nil {
+			return err
+		}
+	}
+
+	if err := w.audit(ctx, operation, path, digest); err != nil {
+		w.release(ctx, operation, path, digest)
+		return err
+	}
+
+	return nil
+}
+
+// release gives back what the Policy reserved for an authorized operation that failed
+func (w *Wallet) release(ctx context.Context, operation AuditOperation, path DerivationPath, digest []byte) {
+	if w.policy != nil {
+		releasePolicy(ctx, w.policy, newSigningRequest(ctx, operation, w.coin, path, digest))
+	}
+}
+
+// audit reports an operation to the configured Auditor
+// An auditor error aborts the operation, so key usage never goes unrecorded
+func (w *Wallet) audit(ctx context.Context, operation AuditOperation, path DerivationPath, digest []byte) error {
+	if w.auditor == nil {
+		return nil
+	}
+
+	if err := w.auditor.Audit(newAuditEvent(ctx, operation, w.coin, path, digest)); err != nil {
+		return fmt.Errorf("audit %s %s: %w", operation, path, err)
+	}
+
+	return nil
+}
+
+// PublicKey derives the public key at m/44'/coin'/account'/chain/address
+func (w *Wallet) PublicKey(account, chain, address uint32) (*PublicKey, error) {
+	start := time.Now()
+	privateKey, err := w.deriveKey(account, chain, address)
+	observe(w.observer(), MetricDerivePublicKey, coinLabel(w.coin), start, err)
+	if err != nil {
+		return nil, err
+	}
+	defer privateKey.Zero()
+
+	return privateKey.PublicKey(), nil
+}
+
+// AccountXPub returns the extended public key of m/44'/coin'/account'
+// It allows watch-only derivation of every address of the account and is the
+// reference used to label accounts in BIP-329 exports
+func (w *Wallet) AccountXPub(account uint32) (string, error) {
+	var xpub string
+	err := w.withMasterKey(func(masterKey *bip32.Key) error {
+		accountKey, err := DeriveAccountKey(masterKey, w.coin, account)
+		if err != nil {
+			return err
+		}
+		defer wipeKey(accountKey)
+		xpub = accountKey.PublicKey().String()
+
+		return nil
+	})
+
+	return xpub, err
+}
+
+// NextAccount returns the next fresh account index and makes it the current account
+//
+// The search starts at the stored high-water mark. When a UsageOracle is configured,
+// accounts whose first receiving address (chain 0, index 0) is used are skipped,
+// because wallets always hand out that address first. The mark is advanced past
+// the returned account, so the same account is never handed out twice
+func (w *Wallet) NextAccount() (uint32, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	parent := DerivationPath{Purpose + HardenedOffset, w.coin + HardenedOffset}.String()
+
+	account, err := w.nextIndex(parent, func(candidate uint32) DerivationPath {
+		return BIP44Path(w.coin, candidate, 0, 0)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	w.account = account
+
+	return account, nil
+}
+
+// NextAddress returns the next fresh address index on chain (0 external, 1 internal)
+// of the current account together with its public key
+//
+// Indexes reported as used by the UsageOracle are skipped and the high-water mark
+// is advanced past the returned index
+func (w *Wallet) NextAddress(chain uint32) (uint32, *PublicKey, error) {
+	if chain >= HardenedOffset {
+		return 0, nil, fmt.Errorf("chain %d out of range", chain)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	parent := DerivationPath{
+		Purpose + HardenedOffset,
+		w.coin + HardenedOffset,
+		w.account + HardenedOffset,
+		chain,
+	}.String()
+
+	address, err := w.nextIndex(parent, func(candidate uint32) DerivationPath {
+		return BIP44Path(w.coin, w.account, chain, candidate)
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	publicKey, err := w.PublicKey(w.account, chain, address)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return address, publicKey, nil
+}
+
+// nextIndex finds the first index at or above the stored high-water mark of parent
+// whose probe address is not used, then persists the mark past it
+// probe returns the address path that decides whether a candidate index is used
+func (w *Wallet) nextIndex(parent string, probe func(candidate uint32) DerivationPath) (uint32, error) {
+	candidate, err := w.indexes.LoadIndex(parent)
+	if err != nil {
+		return 0, fmt.Errorf("load index of %s: %w", parent, err)
+	}
+
+	for ; candidate < HardenedOffset; candidate++ {
+		if w.oracle == nil {
+			break
+		}
+
+		used, err := w.isUsed(probe(candidate))
+		if err != nil {
+			return 0, err
+		}
+		if !used {
+			break
+		}
+	}
+
+	if candidate >= HardenedOffset {
+		return 0, fmt.Errorf("no unused index left below %s", parent)
+	}
+
+	if err = w.indexes.StoreIndex(parent, candidate+1); err != nil {
+		return 0, fmt.Errorf("store index of %s: %w", parent, err)
+	}
+
+	return candidate, nil
+}
+
+// isUsed asks the oracle about the address at path, which must be a full BIP44 address path
+func (w *Wallet) isUsed(path DerivationPath) (bool, error) {
+	publicKey, err := w.PublicKey(path[2]-HardenedOffset, path[3], path[4])
+	if err != nil {
+		return false, err
+	}
+
+	used, err := w.oracle.IsUsed(path, publicKey)
+	if err != nil {
+		return false, fmt.Errorf("check usage of %s: %w", path, err)
+	}
+
+	return used, nil
+}
+
+// UsageOracle reports whether a derived address has already been used,
+// typically by querying a block explorer or an indexer for its transaction history
+type UsageOracle interface {
+	IsUsed(path DerivationPath, publicKey *PublicKey) (bool, error)
+}
+
+// UsageOracleFunc adapts a function to the UsageOracle interface
+type UsageOracleFunc func(path DerivationPath, publicKey *PublicKey) (bool, error)
+
+// IsUsed calls f(path, publicKey)
+func (f UsageOracleFunc) IsUsed(path DerivationPath, publicKey *PublicKey) (bool, error) {
+	return f(path, publicKey)
+}
+
+// IndexStore persists the high-water marks of NextAccount and NextAddress
+// Marks are keyed by the derivation path of the parent node, for example
+// "m/44'/195'" for accounts and "m/44'/195'/0'/0" for receiving addresses
+type IndexStore interface {
+	// LoadIndex returns the next candidate index below parent, 0 when nothing was stored yet
+	LoadIndex(parent string) (uint32, error)
+	// StoreIndex records next as the next candidate index below parent
+	StoreIndex(parent string, next uint32) error
+}
+
+// MemoryIndexStore is an IndexStore kept in process memory
+type MemoryIndexStore struct {
+	mu      sync.Mutex
+	indexes map[string]uint32
+}
+
+// NewMemoryIndexStore returns an empty MemoryIndexStore
+func NewMemoryIndexStore() *MemoryIndexStore {
+	return &MemoryIndexStore{indexes: make(map[string]uint32)}
+}
+
+// LoadIndex implements IndexStore
+func (s *MemoryIndexStore) LoadIndex(parent string) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.indexes[parent], nil
+}
+
+// StoreIndex implements IndexStore
+func (s *MemoryIndexStore) StoreIndex(parent string, next uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.indexes[parent] = next
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package hdwallet
+
+import (
+	"errors"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func newTestWallet(t *testing.T, coin uint32, opts ...WalletOption) *Wallet {
+	t.Helper()
+	wallet, err := NewWallet(testMnemonic, "", coin, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return wallet
+}
+
+func TestWalletRejectsHardenedIndexes(t *testing.T) {
+	wallet := newTestWallet(t, 60)
+	digest := make([]byte, 32)
+
+	for _, test := range []struct {
+		name                    string
+		account, chain, address uint32
+	}{
+		{"account", HardenedOffset, 0, 0},
+		{"chain", 0, HardenedOffset, 0},
+		{"address", 0, 0, HardenedOffset + 5},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := wallet.DeriveKey(test.account, test.chain, test.address); err == nil {
+				t.Error("DeriveKey accepted a hardened index")
+			}
+			if _, err := wallet.SignDigest(test.account, test.chain, test.address, digest); err == nil {
+				t.Error("SignDigest accepted a hardened index")
+			}
+			if _, err := wallet.PublicKey(test.account, test.chain, test.address); err == nil {
+				t.Error("PublicKey accepted a hardened index")
+			}
+		})
+	}
+
+	if _, err := wallet.AccountXPub(HardenedOffset); err == nil {
+		t.Error("AccountXPub accepted a hardened account")
+	}
+}
+
+func TestWalletLockUnlock(t *testing.T) {
+	wallet := newTestWallet(t, 60)
+	before, err := wallet.PublicKey(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet.Lock()
+	if _, err = wallet.PublicKey(0, 0, 0); !errors.Is(err, ErrLocked) {
+		t.Fatalf("PublicKey of a locked wallet = %v, want %v", err, ErrLocked)
+	}
+	if err = wallet.Unlock("other passphrase"); !errors.Is(err, ErrPassphraseMismatch) {
+		t.Fatalf("Unlock with another passphrase = %v, want %v", err, ErrPassphraseMismatch)
+	}
+	if err = wallet.Unlock(""); err != nil {
+		t.Fatal(err)
+	}
+	after, err := wallet.PublicKey(0, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.Equal(before) {
+		t.Error("Unlock restored another master key")
+	}
+
+	seedWallet, err := NewWalletFromSeed(make([]byte, 64), 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedWallet.Lock()
+	if err = seedWallet.Unlock(""); !errors.Is(err, ErrNoMnemonic) {
+		t.Errorf("Unlock of a seed wallet = %v, want %v", err, ErrNoMnemonic)
+	}
+}
@@ -0,0 +1,133 @@
+package watchonly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ActivityKind tells whether an activity credits or debits an address
+type ActivityKind string
+
+const (
+	ActivityReceive ActivityKind = "receive"
+	ActivitySpend   ActivityKind = "spend"
+)
+
+// Activity is a transaction touching a watched address
+type Activity struct {
+	TxID    string       `json:"txid"`
+	Address string       `json:"address"`
+	Kind    ActivityKind `json:"kind"`
+}
+
+// Block is a block of the best chain with the activity of the watched addresses
+type Block struct {
+	Height uint64 `json:"height"`
+	Hash   string `json:"hash"`
+	// Parent is the hash of the block at Height-1, used to detect reorgs
+	Parent   string     `json:"parent"`
+	Activity []Activity `json:"activity"`
+}
+
+// ChainBackend is the view of a blockchain followed by a Tracker, typically a node
+// RPC client or an indexer. Both methods answer for the backend's current best
+// chain, which may reorganize between calls
+type ChainBackend interface {
+	// TipHeight returns the height of the best block
+	TipHeight(ctx context.Context) (uint64, error)
+	// Block returns the best chain block at height, with the activity of the
+	// watched addresses; activity of other addresses is ignored
+	Block(ctx context.Context, height uint64, watched []string) (*Block, error)
+}
+
+// MemoryChain is a ChainBackend kept in process memory, for dry runs of deposit
+// flows and reorg handling
+type MemoryChain struct {
+	mu     sync.Mutex
+	blocks []*Block
+	mined  uint64
+}
+
+// NewMemoryChain returns a chain holding only its genesis block at height 0
+func NewMemoryChain() *MemoryChain {
+	c := &MemoryChain{}
+	c.Mine()
+
+	return c
+}
+
+// Mine appends a block holding activity to the chain and returns it
+func (c *MemoryChain) Mine(activity ...Activity) *Block {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	block := &Block{Activity: slices.Clone(activity)}
+	if len(c.blocks) > 0 {
+		parent := c.blocks[len(c.blocks)-1]
+		block.Height, block.Parent = parent.Height+1, parent.Hash
+	}
+
+	// Blocks mined on a rewound chain get new hashes, as they would on a real fork
+	c.mined++
+	hash := sha256.Sum256(binary.BigEndian.AppendUint64([]byte(block.Parent), c.mined))
+	block.Hash = hex.EncodeToString(hash[:])
+	c.blocks = append(c.blocks, block)
+
+	return block
+}
+
+// Rewind drops the blocks above height, simulating a reorg once new blocks are mined
+func (c *MemoryChain) Rewind(height uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height < uint64(len(c.blocks)) {
+		c.blocks = c.blocks[:height+1]
+	}
+}
+
+// TipHeight implements ChainBackend
+func (c *MemoryChain) TipHeight(ctx context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return uint64(len(c.blocks) - 1), nil
+}
+
+// Block implements ChainBackend
+func (c *MemoryChain) Block(ctx context.Context, height uint64, watched []string) (*Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if height >= uint64(len(c.blocks)) {
+		return nil, fmt.Errorf("no block at height %d", height)
+	}
+
+	set := make(map[string]bool, len(watched))
+	for _, address := range watched {
+		set[addressKey(address)] = true
+	}
+
+	block := *c.blocks[height]
+	block.Activity = slices.DeleteFunc(slices.Clone(block.Activity), func(activity Activity) bool {
+		return !set[addressKey(activity.Address)]
+	})
+
+	return &block, nil
+}
+
+// addressKey returns the form of address used for comparisons
+// Ethereum addresses are case folded, as their case is only a checksum
+func addressKey(address string) string {
+	if strings.HasPrefix(address, "0x") {
+		return strings.ToLower(address)
+	}
+
+	return address
+}
@@ -0,0 +1,282 @@
+package watchonly
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// MaxCount bounds the number of addresses enumerated per source and report entry
+const MaxCount = 1 << 20
+
+// reportVersion is the current Report format version
+const reportVersion = 1
+
+// Auditor key curves recorded in reports
+const (
+	CurveSecp256k1 = "secp256k1"
+	CurveP256      = "P-256"
+)
+
+// ExpectedAddress is an address derived by a source
+type ExpectedAddress struct {
+	Index uint32 `json:"index"`
+	// Path is relative to the extended key of the source, for example "0/5"
+	Path    string `json:"path"`
+	Address string `json:"address"`
+}
+
+// Enumerate derives count addresses of the source starting at index from
+func (s *Source) Enumerate(from, count uint32) ([]ExpectedAddress, error) {
+	if count > MaxCount {
+		return nil, fmt.Errorf("count %d exceeds %d", count, MaxCount)
+	}
+	if uint64(from)+uint64(count) > uint64(hdwallet.HardenedOffset) {
+		return nil, fmt.Errorf("range %d+%d exceeds the non-hardened indexes", from, count)
+	}
+
+	prefix := strings.TrimPrefix(s.Path.String(), "m")
+	addresses := make([]ExpectedAddress, 0, count)
+	for index := range count {
+		index += from
+		address, err := s.Address(index)
+		if err != nil {
+			return nil, fmt.Errorf("derive index %d: %w", index, err)
+		}
+		addresses = append(addresses, ExpectedAddress{
+			Index:   index,
+			Path:    strings.TrimPrefix(fmt.Sprintf("%s/%d", prefix, index), "/"),
+			Address: address,
+		})
+	}
+
+	return addresses, nil
+}
+
+// ReportEntry lists the addresses a source derives over an index range
+type ReportEntry struct {
+	Coin uint32 `json:"coin"`
+	// Source is the descriptor form of the source, see Source.String
+	Source string `json:"source"`
+	// Fingerprint is the BIP32 fingerprint of the source's extended key
+	Fingerprint string            `json:"fingerprint"`
+	From        uint32            `json:"from"`
+	Count       uint32            `json:"count"`
+	Addresses   []ExpectedAddress `json:"addresses"`
+}
+
+// Report is the list of addresses an auditor expects from the declared sources
+type Report struct {
+	Version int `json:"version"`
+	// AuditorCurve and AuditorKey identify the key the report is signed with,
+	// AuditorKey is the hex SEC1 compressed public key
+	AuditorCurve string `json:"auditor_curve"`
+	AuditorKey   string `json:"auditor_key"`
+	// Statement is free text of the auditor, such as the scope of the engagement
+	Statement string        `json:"statement,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Entries   []ReportEntry `json:"entries"`
+}
+
+// NewReport returns an empty report with the given statement
+func NewReport(statement string) *Report {
+	return &Report{
+		Version:   reportVersion,
+		Statement: statement,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Add enumerates count addresses of source from index from into the report
+func (r *Report) Add(source *Source, from, count uint32) error {
+	addresses, err := source.Enumerate(from, count)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	r.Entries = append(r.Entries, ReportEntry{
+		Coin:        source.Coin,
+		Source:      source.String(),
+		Fingerprint: source.Fingerprint(),
+		From:        from,
+		Count:       count,
+		Addresses:   addresses,
+	})
+
+	return nil
+}
+
+// Lookup returns the entry and expected address matching address
+// Ethereum addresses are compared case-insensitively, as their case is only a checksum
+func (r *Report) Lookup(address string) (ReportEntry, ExpectedAddress, bool) {
+	for _, entry := range r.Entries {
+		for _, expected := range entry.Addresses {
+			if expected.Address == address ||
+				strings.HasPrefix(address, "0x") && strings.EqualFold(expected.Address, address) {
+				return entry, expected, true
+			}
+		}
+	}
+
+	return ReportEntry{}, ExpectedAddress{}, false
+}
+
+// Check re-derives every entry from its source and fails on the first address that differs
+func (r *Report) Check() error {
+	if r.Version != reportVersion {
+		return fmt.Errorf("unsupported report version %d", r.Version)
+	}
+
+	for i, entry := range r.Entries {
+		source, err := ParseSource(entry.Source, entry.Coin)
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		if source.Fingerprint() != entry.Fingerprint {
+			return fmt.Errorf("entry %d: fingerprint %s, want %s", i, entry.Fingerprint, source.Fingerprint())
+		}
+		if len(entry.Addresses) != int(entry.Count) {
+			return fmt.Errorf("entry %d: %d addresses for a count of %d", i, len(entry.Addresses), entry.Count)
+		}
+		addresses, err := source.Enumerate(entry.From, entry.Count)
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		for j, expected := range addresses {
+			if entry.Addresses[j] != expected {
+				return fmt.Errorf("entry %d: index %d is %s, want %s", i, expected.Index,
+					entry.Addresses[j].Address, expected.Address)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Attestation is a report signed by the auditor
+// The signature is the hex DER ECDSA signature of the SHA-256 digest of the compact
+// JSON report, so attestations can be pretty-printed without invalidating them
+type Attestation struct {
+	Report    json.RawMessage `json:"report"`
+	Signature string          `json:"signature"`
+}
+
+// Attest signs report with the auditor's signer, an in-memory key or a hardware token,
+// after recording the signer's public key in the report
+func Attest(signer hdwallet.Signer, report *Report) (*Attestation, error) {
+	curve, key, err := encodeAuditorKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	report.AuditorCurve = curve
+	report.AuditorKey = key
+	if err := report.Check(); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(data)
+	signature, err := signer.SignDigest(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign report: %w", err)
+	}
+
+	return &Attestation{
+		Report:    data,
+		Signature: hex.EncodeToString(signature),
+	}, nil
+}
+
+// Verify checks that the attestation is signed by auditor, whose key must be known
+// out of band, and that every address of the report derives from its source
+func (a *Attestation) Verify(auditor crypto.PublicKey) (*Report, error) {
+	report := &Report{}
+	if err := json.Unmarshal(a.Report, report); err != nil {
+		return nil, fmt.Errorf("parse report: %w", err)
+	}
+
+	curve, key, err := encodeAuditorKey(auditor)
+	if err != nil {
+		return nil, err
+	}
+	if report.AuditorCurve != curve || !strings.EqualFold(report.AuditorKey, key) {
+		return nil, errors.New("report is not issued by the given auditor key")
+	}
+	signature, err := hex.DecodeString(a.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, a.Report); err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(compact.Bytes())
+	if !hdwallet.VerifyDigest(auditor, digest[:], signature) {
+		return nil, errors.New("invalid attestation signature")
+	}
+
+	if err := report.Check(); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ParseAuditorKey parses the hex SEC1 public key of an auditor on curve
+func ParseAuditorKey(curve, key string) (crypto.PublicKey, error) {
+	data, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("decode auditor key: %w", err)
+	}
+
+	switch curve {
+	case CurveSecp256k1:
+		publicKey, format, err := hdwallet.ParsePublicKey(data)
+		if err != nil {
+			return nil, err
+		}
+		if format != hdwallet.PublicKeyCompressed && format != hdwallet.PublicKeyUncompressed {
+			return nil, fmt.Errorf("auditor key is not a SEC1 key but %s", format)
+		}
+		return publicKey, nil
+	case CurveP256:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), data)
+		if x == nil {
+			return nil, errors.New("invalid P-256 auditor key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auditor key curve %q", curve)
+	}
+}
+
+// encodeAuditorKey returns the curve and hex compressed form of an auditor public key
+func encodeAuditorKey(publicKey crypto.PublicKey) (string, string, error) {
+	switch key := publicKey.(type) {
+	case *hdwallet.PublicKey:
+		return CurveSecp256k1, hex.EncodeToString(key.SerializeCompressed()), nil
+	case *secp256k1.PublicKey:
+		return CurveSecp256k1, hex.EncodeToString(key.SerializeCompressed()), nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return "", "", fmt.Errorf("unsupported auditor key curve %s", key.Curve.Params().Name)
+		}
+		return CurveP256, hex.EncodeToString(elliptic.MarshalCompressed(key.Curve, key.X, key.Y)), nil
+	default:
+		return "", "", fmt.Errorf("unsupported auditor key type %T", publicKey)
+	}
+}
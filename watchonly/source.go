@@ -0,0 +1,383 @@
+// Package watchonly is the auditor mode of hdwallet: it derives and attests addresses
+// from extended public keys and output descriptors only
+//
+// Sources never accept mnemonics, seeds or extended private keys, so the package and
+// the hdwallet-audit command built on it can run on an auditor's machine with no
+// access to the keys of the wallet under audit. Reports of the expected addresses are
+// signed with the auditor's own key, letting third parties check that the deposit
+// addresses an exchange publishes belong to the accounts it declared
+//
+// A Tracker follows the addresses of a source on chain through a ChainBackend, with
+// confirmation depth aware states and reorg notifications for deposit crediting
+package watchonly
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/not-for-prod/hdwallet"
+	"github.com/not-for-prod/hdwallet/bitcoin"
+	"github.com/not-for-prod/hdwallet/codec"
+	"github.com/not-for-prod/hdwallet/cosmos"
+	"github.com/not-for-prod/hdwallet/ethereum"
+	"github.com/not-for-prod/hdwallet/tron"
+	"github.com/tyler-smith/go-bip32"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// ErrPrivateMaterial is returned for inputs holding private keys or mnemonics,
+// which auditor mode refuses to handle
+var ErrPrivateMaterial = errors.New("watch-only sources accept extended public keys only")
+
+// Script is the Bitcoin output type of a descriptor
+type Script string
+
+const (
+	// ScriptNone is used by the account based chains, whose addresses derive from the key alone
+	ScriptNone       Script = ""
+	ScriptPKH        Script = "pkh"
+	ScriptWPKH       Script = "wpkh"
+	ScriptSHWPKH     Script = "sh(wpkh)"
+	ScriptTaprootKey Script = "tr"
+)
+
+// CosmosHRP is the bech32 prefix of the Cosmos addresses derived by sources of coin 118
+const CosmosHRP = "cosmos"
+
+// extendedKeyVersion describes a BIP32 or SLIP-132 extended public key version
+type extendedKeyVersion struct {
+	testnet bool
+	// script is the output type implied by the version, ScriptNone for xpub and tpub
+	script Script
+}
+
+// publicVersions are the accepted extended public key versions
+var publicVersions = map[string]extendedKeyVersion{
+	"0488b21e": {testnet: false},                       // xpub
+	"049d7cb2": {testnet: false, script: ScriptSHWPKH}, // ypub
+	"04b24746": {testnet: false, script: ScriptWPKH},   // zpub
+	"043587cf": {testnet: true},                        // tpub
+	"044a5262": {testnet: true, script: ScriptSHWPKH},  // upub
+	"045f1cf6": {testnet: true, script: ScriptWPKH},    // vpub
+}
+
+// Source is a watch-only description of an address chain: an extended public key,
+// the non-hardened levels below it and, for Bitcoin, the output type
+//
+// It is parsed from a bare extended public key (the account xpub as exported by
+// Wallet.AccountXPub, whose receiving chain is audited unless a suffix such as
+// "/1/*" selects another) or from a BIP-380 descriptor with a single key, such as
+// "wpkh([73c5da0a/84h/0h/0h]xpub.../0/*)#checksum"
+type Source struct {
+	Coin   uint32
+	Script Script
+	// OriginFingerprint and OriginPath are the key origin of the descriptor, when given
+	OriginFingerprint string
+	OriginPath        hdwallet.DerivationPath
+	// Path holds the levels between the extended key and the wildcard index
+	Path hdwallet.DerivationPath
+
+	key     *bip32.Key
+	xpub    string
+	testnet bool
+}
+
+// ParseSource parses a watch-only source for coin
+// Descriptors with a script function are Bitcoin only and require coin 0
+func ParseSource(text string, coin uint32) (*Source, error) {
+	text = strings.TrimSpace(text)
+	if len(strings.Fields(text)) > 1 {
+		// No descriptor or extended key contains spaces, a mnemonic does
+		return nil, fmt.Errorf("%w: input contains several words", ErrPrivateMaterial)
+	}
+
+	if body, checksum, ok := strings.Cut(text, "#"); ok {
+		want, err := DescriptorChecksum(body)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != want {
+			return nil, fmt.Errorf("invalid descriptor checksum %q, want %q", checksum, want)
+		}
+		text = body
+	}
+
+	script, expression, err := parseScript(text)
+	if err != nil {
+		return nil, err
+	}
+	if script != ScriptNone && coin != bitcoin.CoinType {
+		return nil, fmt.Errorf("%s descriptors are only defined for Bitcoin, not coin %d", script, coin)
+	}
+
+	source := &Source{Coin: coin, Script: script}
+	if err := source.parseKeyExpression(expression); err != nil {
+		return nil, err
+	}
+	if source.Coin == bitcoin.CoinType && source.Script == ScriptNone {
+		source.Script = ScriptWPKH
+	}
+	if _, err := source.Address(0); err != nil {
+		return nil, err
+	}
+
+	return source, nil
+}
+
+// parseScript strips the script functions of a descriptor
+func parseScript(text string) (Script, string, error) {
+	for _, script := range []Script{ScriptSHWPKH, ScriptWPKH, ScriptPKH, ScriptTaprootKey} {
+		open := strings.ReplaceAll(string(script), ")", "") + "("
+		closing := strings.Repeat(")", strings.Count(open, "("))
+		if inner, ok := strings.CutPrefix(text, open); ok {
+			inner, ok = strings.CutSuffix(inner, closing)
+			if !ok || strings.ContainsAny(inner, "()") {
+				return "", "", fmt.Errorf("malformed %s descriptor", script)
+			}
+			return script, inner, nil
+		}
+	}
+	if strings.ContainsAny(text, "()") {
+		return "", "", errors.New("unsupported descriptor, expected pkh, wpkh, sh(wpkh) or tr with a single key")
+	}
+
+	return ScriptNone, text, nil
+}
+
+// parseKeyExpression parses "[fingerprint/origin]xpub/suffix/*"
+func (s *Source) parseKeyExpression(expression string) error {
+	if origin, ok := strings.CutPrefix(expression, "["); ok {
+		origin, rest, ok := strings.Cut(origin, "]")
+		if !ok {
+			return errors.New("unterminated key origin")
+		}
+		fingerprint, path, _ := strings.Cut(origin, "/")
+		if decoded, err := hex.DecodeString(fingerprint); err != nil || len(decoded) != 4 {
+			return fmt.Errorf("invalid key origin fingerprint %q", fingerprint)
+		}
+		originPath, err := hdwallet.ParseDerivationPath("m/" + path)
+		if err != nil {
+			return fmt.Errorf("key origin: %w", err)
+		}
+		s.OriginFingerprint = strings.ToLower(fingerprint)
+		s.OriginPath = originPath
+		expression = rest
+	}
+
+	encoded, suffix, _ := strings.Cut(expression, "/")
+	key, err := bip32.B58Deserialize(encoded)
+	if err != nil {
+		return fmt.Errorf("parse extended key: %w", err)
+	}
+	if key.IsPrivate {
+		return ErrPrivateMaterial
+	}
+	version, ok := publicVersions[hex.EncodeToString(key.Version)]
+	if !ok {
+		return fmt.Errorf("unsupported extended public key version %x", key.Version)
+	}
+	if _, err := hdwallet.PublicKeyFromBytes(key.Key); err != nil {
+		return fmt.Errorf("parse extended key: %w", err)
+	}
+	if version.script != ScriptNone {
+		if s.Coin != bitcoin.CoinType {
+			return fmt.Errorf("SLIP-132 extended keys are only defined for Bitcoin, not coin %d", s.Coin)
+		}
+		if s.Script == ScriptNone {
+			s.Script = version.script
+		} else if s.Script != version.script {
+			return fmt.Errorf("%s descriptor with a %s extended key", s.Script, version.script)
+		}
+	}
+
+	// A bare key is an account key audited on its receiving chain
+	path := DefaultPath
+	if suffix != "" {
+		levels, ok := strings.CutSuffix(suffix, "*")
+		if !ok {
+			return errors.New("key expression must end with the /* wildcard")
+		}
+		if path, err = hdwallet.ParseDerivationPath("m/" + strings.TrimSuffix(levels, "/")); err != nil {
+			return err
+		}
+	}
+	for _, index := range path {
+		if index >= hdwallet.HardenedOffset {
+			return errors.New("hardened levels cannot be derived from an extended public key")
+		}
+	}
+
+	s.key = key
+	s.xpub = encoded
+	s.testnet = version.testnet
+	s.Path = path
+
+	return nil
+}
+
+// DefaultPath is the suffix of bare extended keys, the BIP44 receiving chain
+var DefaultPath = hdwallet.DerivationPath{0}
+
+// Fingerprint returns the hex BIP32 fingerprint of the source's extended key
+func (s *Source) Fingerprint() string {
+	return hex.EncodeToString(hash160(s.key.Key)[:4])
+}
+
+// String returns the source as a descriptor with checksum, the form recorded in reports
+func (s *Source) String() string {
+	var expression strings.Builder
+	if s.OriginFingerprint != "" {
+		expression.WriteString("[" + s.OriginFingerprint)
+		if len(s.OriginPath) > 0 {
+			expression.WriteString(strings.TrimPrefix(s.OriginPath.Format(hdwallet.HardenedH), "m"))
+		}
+		expression.WriteString("]")
+	}
+	expression.WriteString(s.xpub)
+	for _, index := range s.Path {
+		fmt.Fprintf(&expression, "/%d", index)
+	}
+	expression.WriteString("/*")
+
+	descriptor := expression.String()
+	switch s.Script {
+	case ScriptNone:
+		return descriptor
+	case ScriptSHWPKH:
+		descriptor = "sh(wpkh(" + descriptor + "))"
+	default:
+		descriptor = string(s.Script) + "(" + descriptor + ")"
+	}
+	checksum, _ := DescriptorChecksum(descriptor)
+
+	return descriptor + "#" + checksum
+}
+
+// PublicKey derives the public key at index of the source
+func (s *Source) PublicKey(index uint32) (*hdwallet.PublicKey, error) {
+	if index >= hdwallet.HardenedOffset {
+		return nil, fmt.Errorf("index %d is hardened", index)
+	}
+
+	child, err := hdwallet.DerivePath(s.key, s.Path.Child(index))
+	if err != nil {
+		return nil, err
+	}
+
+	return hdwallet.PublicKeyFromBytes(child.Key)
+}
+
+// Address derives the address at index of the source
+func (s *Source) Address(index uint32) (string, error) {
+	publicKey, err := s.PublicKey(index)
+	if err != nil {
+		return "", err
+	}
+
+	return s.encode(publicKey)
+}
+
+// encode returns the address of publicKey for the coin and script of the source
+func (s *Source) encode(publicKey *hdwallet.PublicKey) (string, error) {
+	switch s.Coin {
+	case bitcoin.CoinType:
+		return s.bitcoinAddress(publicKey)
+	case ethereum.CoinType:
+		return hdwallet.GenerateEthereumAddress(publicKey), nil
+	case tron.CoinType:
+		return hdwallet.GenerateTronAddress(publicKey), nil
+	case cosmos.CoinType:
+		return cosmos.Address(publicKey, CosmosHRP)
+	default:
+		return "", fmt.Errorf("no watch-only address format for coin %d", s.Coin)
+	}
+}
+
+// bitcoinAddress encodes the output of publicKey for the source's script
+func (s *Source) bitcoinAddress(publicKey *hdwallet.PublicKey) (string, error) {
+	network := bitcoin.MainNet
+	if s.testnet {
+		network = bitcoin.TestNet
+	}
+	pubKeyHash := hash160(publicKey.SerializeCompressed())
+
+	switch s.Script {
+	case ScriptPKH:
+		return codec.Base58CheckEncode(append([]byte{network.PubKeyHashAddrID}, pubKeyHash...)), nil
+	case ScriptWPKH:
+		return bitcoin.SegwitAddress(network, 0, pubKeyHash)
+	case ScriptSHWPKH:
+		redeemScript := append([]byte{0x00, 0x14}, pubKeyHash...)
+		return codec.Base58CheckEncode(append([]byte{network.ScriptHashAddrID}, hash160(redeemScript)...)), nil
+	case ScriptTaprootKey:
+		outputKey, _, err := bitcoin.TaprootOutputKey(publicKey, nil)
+		if err != nil {
+			return "", err
+		}
+		return bitcoin.SegwitAddress(network, 1, outputKey[:])
+	default:
+		return "", fmt.Errorf("unsupported script %q", s.Script)
+	}
+}
+
+// Descriptor checksum character sets of BIP-380
+const (
+	descriptorInputCharset    = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+	descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// DescriptorChecksum returns the 8 character BIP-380 checksum of a descriptor without "#"
+func DescriptorChecksum(descriptor string) (string, error) {
+	c := uint64(1)
+	class, classCount := 0, 0
+	for i := range len(descriptor) {
+		position := strings.IndexByte(descriptorInputCharset, descriptor[i])
+		if position < 0 {
+			return "", fmt.Errorf("invalid descriptor character %q at position %d", descriptor[i], i)
+		}
+		c = descriptorPolymod(c, position&31)
+		class = class*3 + position>>5
+		if classCount++; classCount == 3 {
+			c = descriptorPolymod(c, class)
+			class, classCount = 0, 0
+		}
+	}
+	if classCount > 0 {
+		c = descriptorPolymod(c, class)
+	}
+	for range 8 {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+
+	var checksum [8]byte
+	for j := range checksum {
+		checksum[j] = descriptorChecksumCharset[(c>>(5*(7-j)))&31]
+	}
+
+	return string(checksum[:]), nil
+}
+
+func descriptorPolymod(c uint64, value int) uint64 {
+	c0 := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ uint64(value)
+	for i, generator := range []uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd} {
+		if c0>>i&1 == 1 {
+			c ^= generator
+		}
+	}
+
+	return c
+}
+
+// hash160 returns RIPEMD-160(SHA-256(data))
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	hash := ripemd160.New()
+	hash.Write(sum[:])
+
+	return hash.Sum(nil)
+}
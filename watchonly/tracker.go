@@ -0,0 +1,521 @@
+package watchonly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/not-for-prod/hdwallet"
+)
+
+// Tracker defaults
+const (
+	DefaultGapLimit      = 20
+	DefaultConfirmations = 6
+	DefaultMaxReorgDepth = 100
+)
+
+// ErrDeepReorg is returned by Tracker.Sync when the chain reorganized below the
+// oldest block the tracker keeps, see WithMaxReorgDepth
+var ErrDeepReorg = errors.New("reorg deeper than the tracked history")
+
+// AddressState is the confirmation depth aware usage state of a tracked address
+type AddressState int
+
+const (
+	// StateUnused addresses have no activity on the best chain
+	StateUnused AddressState = iota
+	// StateSeen addresses have activity with fewer than the required confirmations
+	StateSeen
+	// StateConfirmed addresses received funds with the required confirmations
+	StateConfirmed
+	// StateSpent addresses were spent from with the required confirmations
+	StateSpent
+)
+
+// String returns the name of the state
+func (s AddressState) String() string {
+	switch s {
+	case StateUnused:
+		return "unused"
+	case StateSeen:
+		return "seen"
+	case StateConfirmed:
+		return "confirmed"
+	case StateSpent:
+		return "spent"
+	default:
+		return fmt.Sprintf("AddressState(%d)", int(s))
+	}
+}
+
+// TrackedActivity is an activity of a tracked address in a block of the best chain
+type TrackedActivity struct {
+	Activity
+	Index     uint32 `json:"index"`
+	Height    uint64 `json:"height"`
+	BlockHash string `json:"block_hash"`
+	// Confirmed is set once the activity reached the required confirmations
+	// and was passed to the confirmation handler
+	Confirmed bool `json:"confirmed"`
+}
+
+// Reorg describes blocks disconnected from the best chain
+type Reorg struct {
+	// ForkHeight is the height of the last block common to both chains
+	ForkHeight uint64
+	// Orphaned are the hashes of the disconnected blocks, lowest first
+	Orphaned []string
+	// Reverted is the activity of the disconnected blocks; entries with Confirmed
+	// set were already passed to the confirmation handler and must be undone
+	Reverted []TrackedActivity
+}
+
+// AddressStatus is the state of one tracked address
+type AddressStatus struct {
+	Index   uint32       `json:"index"`
+	Address string       `json:"address"`
+	State   AddressState `json:"state"`
+	// Confirmations of the latest activity of the address, 0 when unused
+	Confirmations uint64 `json:"confirmations"`
+}
+
+// TrackerOption configures a Tracker
+type TrackerOption func(*Tracker)
+
+// WithGapLimit sets the number of unused addresses watched past the highest used one
+func WithGapLimit(gap uint32) TrackerOption {
+	return func(t *Tracker) {
+		t.gapLimit = gap
+	}
+}
+
+// WithConfirmations sets the confirmations after which activity is final
+func WithConfirmations(confirmations uint32) TrackerOption {
+	return func(t *Tracker) {
+		t.confirmations = confirmations
+	}
+}
+
+// WithMaxReorgDepth sets the number of blocks the tracker keeps to resolve reorgs,
+// it must be at least the number of confirmations
+func WithMaxReorgDepth(depth uint32) TrackerOption {
+	return func(t *Tracker) {
+		t.maxReorgDepth = depth
+	}
+}
+
+// WithStartHeight sets the first block scanned, usually the height at which the
+// source's account was created
+func WithStartHeight(height uint64) TrackerOption {
+	return func(t *Tracker) {
+		t.next = height
+	}
+}
+
+// WithReorgHandler calls handler after Sync disconnected blocks from the best chain
+func WithReorgHandler(handler func(Reorg)) TrackerOption {
+	return func(t *Tracker) {
+		t.onReorg = handler
+	}
+}
+
+// WithConfirmationHandler calls handler once for every activity reaching the required
+// confirmations, typically to credit a deposit
+func WithConfirmationHandler(handler func(TrackedActivity)) TrackerOption {
+	return func(t *Tracker) {
+		t.onConfirmation = handler
+	}
+}
+
+// trackedBlock is a connected block kept to resolve reorgs
+type trackedBlock struct {
+	height uint64
+	hash   string
+}
+
+// Tracker follows the addresses of a Source on a chain through a ChainBackend
+//
+// It watches the gap limit window past the highest index with any activity, so
+// addresses seen in a block that is later orphaned stay watched: the window never
+// shrinks on reorgs. Activity only becomes final, and reaches the confirmation
+// handler, once buried under the required confirmations; reorgs are detected from
+// block parent hashes and reported to the reorg handler with the activity they revert
+//
+// Handlers are called from Sync after the tracker's state is updated, they may call
+// the tracker's methods. A Tracker is safe for concurrent use
+type Tracker struct {
+	mu             sync.Mutex
+	source         *Source
+	backend        ChainBackend
+	gapLimit       uint32
+	confirmations  uint32
+	maxReorgDepth  uint32
+	onReorg        func(Reorg)
+	onConfirmation func(TrackedActivity)
+
+	addresses []string
+	indexes   map[string]uint32
+	// blocks are the last connected blocks, lowest first
+	blocks []trackedBlock
+	// next is the height of the next block to connect
+	next     uint64
+	activity []TrackedActivity
+}
+
+// NewTracker returns a tracker of the addresses of source on the chain of backend
+func NewTracker(source *Source, backend ChainBackend, opts ...TrackerOption) (*Tracker, error) {
+	t := &Tracker{
+		source:        source,
+		backend:       backend,
+		gapLimit:      DefaultGapLimit,
+		confirmations: DefaultConfirmations,
+		maxReorgDepth: DefaultMaxReorgDepth,
+		indexes:       make(map[string]uint32),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if t.gapLimit == 0 || t.gapLimit > MaxCount {
+		return nil, fmt.Errorf("gap limit %d must be between 1 and %d", t.gapLimit, MaxCount)
+	}
+	if t.confirmations == 0 {
+		return nil, errors.New("confirmations must be at least 1")
+	}
+	if t.maxReorgDepth < t.confirmations {
+		return nil, fmt.Errorf("max reorg depth %d is below the %d confirmations", t.maxReorgDepth, t.confirmations)
+	}
+	if err := t.derive(t.gapLimit); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// derive extends the watched addresses to the first count indexes
+func (t *Tracker) derive(count uint32) error {
+	if count > MaxCount {
+		return fmt.Errorf("tracking %d addresses exceeds %d", count, MaxCount)
+	}
+	for index := uint32(len(t.addresses)); index < count; index++ {
+		address, err := t.source.Address(index)
+		if err != nil {
+			return fmt.Errorf("derive index %d: %w", index, err)
+		}
+		t.addresses = append(t.addresses, address)
+		t.indexes[addressKey(address)] = index
+	}
+
+	return nil
+}
+
+// Sync connects the blocks the backend added since the last call, resolving reorgs,
+// then calls the handlers
+func (t *Tracker) Sync(ctx context.Context) error {
+	var (
+		reorgs    []Reorg
+		confirmed []TrackedActivity
+		err       error
+	)
+	func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		reorgs, err = t.sync(ctx)
+		confirmed = t.confirm()
+	}()
+
+	if t.onReorg != nil {
+		for _, reorg := range reorgs {
+			t.onReorg(reorg)
+		}
+	}
+	if t.onConfirmation != nil {
+		for _, activity := range confirmed {
+			t.onConfirmation(activity)
+		}
+	}
+
+	return err
+}
+
+// sync connects blocks up to the backend tip, the caller holds t.mu
+func (t *Tracker) sync(ctx context.Context) ([]Reorg, error) {
+	tip, err := t.backend.TipHeight(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("chain tip: %w", err)
+	}
+
+	var reorgs []Reorg
+
+	// Our last block, or the backend tip when it is below it, must still be on the
+	// best chain: a reorg replacing blocks without extending the chain is only
+	// visible there. A tip below ours with a matching hash is a lagging backend
+	if last, ok := t.last(); ok {
+		height := min(tip, last.height)
+		block, err := t.backend.Block(ctx, height, nil)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", height, err)
+		}
+		if hash, _ := t.hashAt(height); block.Hash != hash {
+			reorg, err := t.rewind(ctx, tip)
+			if err != nil {
+				return nil, err
+			}
+			reorgs = append(reorgs, reorg)
+		} else if tip < last.height {
+			return nil, nil
+		}
+	}
+
+	for t.next <= tip {
+		block, err := t.fetch(ctx, t.next)
+		if err != nil {
+			return reorgs, err
+		}
+
+		if last, ok := t.last(); ok && block.Parent != last.hash {
+			reorg, err := t.rewind(ctx, tip)
+			if err != nil {
+				return reorgs, err
+			}
+			reorgs = append(reorgs, reorg)
+			continue
+		}
+
+		t.connect(block)
+	}
+
+	return reorgs, nil
+}
+
+// fetch returns the block at height, fetching it again while its activity extends
+// the gap limit window so that activity of newly watched addresses is included
+func (t *Tracker) fetch(ctx context.Context, height uint64) (*Block, error) {
+	for {
+		block, err := t.backend.Block(ctx, height, slices.Clone(t.addresses))
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", height, err)
+		}
+		if block.Height != height {
+			return nil, fmt.Errorf("backend returned block %d for height %d", block.Height, height)
+		}
+
+		watched := uint32(len(t.addresses))
+		for _, activity := range block.Activity {
+			if index, ok := t.indexes[addressKey(activity.Address)]; ok {
+				if err := t.derive(max(uint32(len(t.addresses)), index+1+t.gapLimit)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if uint32(len(t.addresses)) == watched {
+			return block, nil
+		}
+	}
+}
+
+// connect appends block to the tracked chain
+func (t *Tracker) connect(block *Block) {
+	for _, activity := range block.Activity {
+		index, ok := t.indexes[addressKey(activity.Address)]
+		if !ok {
+			continue
+		}
+		activity.Address = t.addresses[index]
+		t.activity = append(t.activity, TrackedActivity{
+			Activity:  activity,
+			Index:     index,
+			Height:    block.Height,
+			BlockHash: block.Hash,
+		})
+	}
+
+	t.blocks = append(t.blocks, trackedBlock{height: block.Height, hash: block.Hash})
+	if len(t.blocks) > int(t.maxReorgDepth) {
+		t.blocks = slices.Delete(t.blocks, 0, len(t.blocks)-int(t.maxReorgDepth))
+	}
+	t.next = block.Height + 1
+}
+
+// rewind disconnects the tracked blocks the backend no longer has on its best chain
+func (t *Tracker) rewind(ctx context.Context, tip uint64) (Reorg, error) {
+	fork := -1
+	for i := len(t.blocks) - 1; i >= 0; i-- {
+		if t.blocks[i].height > tip {
+			continue
+		}
+		block, err := t.backend.Block(ctx, t.blocks[i].height, nil)
+		if err != nil {
+			return Reorg{}, fmt.Errorf("block %d: %w", t.blocks[i].height, err)
+		}
+		if block.Hash == t.blocks[i].hash {
+			fork = i
+			break
+		}
+	}
+	if fork < 0 {
+		return Reorg{}, fmt.Errorf("%w: no common block in the last %d", ErrDeepReorg, len(t.blocks))
+	}
+
+	forkHeight := t.blocks[fork].height
+	reorg := Reorg{ForkHeight: forkHeight}
+	for _, block := range t.blocks[fork+1:] {
+		reorg.Orphaned = append(reorg.Orphaned, block.hash)
+	}
+	t.activity = slices.DeleteFunc(t.activity, func(activity TrackedActivity) bool {
+		if activity.Height > forkHeight {
+			reorg.Reverted = append(reorg.Reverted, activity)
+			return true
+		}
+		return false
+	})
+
+	t.blocks = t.blocks[:fork+1]
+	t.next = forkHeight + 1
+
+	return reorg, nil
+}
+
+// confirm marks the activity that reached the required confirmations and returns it
+func (t *Tracker) confirm() []TrackedActivity {
+	var confirmed []TrackedActivity
+	for i, activity := range t.activity {
+		if !activity.Confirmed && t.confirmationsOf(activity.Height) >= uint64(t.confirmations) {
+			t.activity[i].Confirmed = true
+			confirmed = append(confirmed, t.activity[i])
+		}
+	}
+
+	return confirmed
+}
+
+// last returns the last connected block
+func (t *Tracker) last() (trackedBlock, bool) {
+	if len(t.blocks) == 0 {
+		return trackedBlock{}, false
+	}
+
+	return t.blocks[len(t.blocks)-1], true
+}
+
+// hashAt returns the hash of the tracked block at height
+func (t *Tracker) hashAt(height uint64) (string, bool) {
+	last, ok := t.last()
+	if !ok || height > last.height || last.height-height >= uint64(len(t.blocks)) {
+		return "", false
+	}
+
+	return t.blocks[len(t.blocks)-1-int(last.height-height)].hash, true
+}
+
+// confirmationsOf returns the confirmations of a block at height
+func (t *Tracker) confirmationsOf(height uint64) uint64 {
+	last, ok := t.last()
+	if !ok || height > last.height {
+		return 0
+	}
+
+	return last.height - height + 1
+}
+
+// Height returns the height of the last connected block
+func (t *Tracker) Height() (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.last()
+
+	return last.height, ok
+}
+
+// Status returns the state of a tracked address
+func (t *Tracker) Status(address string) (AddressStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	index, ok := t.indexes[addressKey(address)]
+	if !ok {
+		return AddressStatus{}, false
+	}
+
+	return t.status(index), true
+}
+
+// Statuses returns the state of every watched address, in index order
+func (t *Tracker) Statuses() []AddressStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]AddressStatus, len(t.addresses))
+	for index := range statuses {
+		statuses[index] = t.status(uint32(index))
+	}
+
+	return statuses
+}
+
+// status computes the state of the address at index
+func (t *Tracker) status(index uint32) AddressStatus {
+	status := AddressStatus{Index: index, Address: t.addresses[index], State: StateUnused}
+	for _, activity := range t.activity {
+		if activity.Index != index {
+			continue
+		}
+		status.Confirmations = t.confirmationsOf(activity.Height)
+
+		state := StateSeen
+		if status.Confirmations >= uint64(t.confirmations) {
+			state = StateConfirmed
+			if activity.Kind == ActivitySpend {
+				state = StateSpent
+			}
+		}
+		status.State = max(status.State, state)
+	}
+
+	return status
+}
+
+// NextUnused returns the lowest watched index without activity on the best chain
+func (t *Tracker) NextUnused() (uint32, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	used := make([]bool, len(t.addresses))
+	for _, activity := range t.activity {
+		used[activity.Index] = true
+	}
+	index := uint32(slices.Index(used, false))
+
+	return index, t.addresses[index]
+}
+
+// IsUsed implements hdwallet.UsageOracle for wallets deriving the source's addresses:
+// an address with any activity, confirmed or not, is used
+// The last element of path is the address index, and publicKey must be the source's
+// key at that index
+func (t *Tracker) IsUsed(path hdwallet.DerivationPath, publicKey *hdwallet.PublicKey) (bool, error) {
+	if len(path) == 0 {
+		return false, errors.New("empty derivation path")
+	}
+	index := path[len(path)-1]
+
+	expected, err := t.source.PublicKey(index)
+	if err != nil {
+		return false, err
+	}
+	if !expected.Equal(publicKey) {
+		return false, fmt.Errorf("key at %s is not the source's key at index %d", path, index)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.ContainsFunc(t.activity, func(activity TrackedActivity) bool {
+		return activity.Index == index
+	}), nil
+}
@@ -0,0 +1,18 @@
+// Package yubikey provides a hardware-backed Signer using the PIV applet of a YubiKey
+// over PC/SC, for small teams that want signing keys that never leave a token
+// without operating a full HSM.
+//
+// Keys are generated on the device, can be attested with the Yubico PIV attestation
+// chain to prove they were never exported, and sign digests through the hdwallet.Signer
+// interface.
+//
+// The PIV applet only supports NIST curves (P-256 and P-384), not secp256k1, so
+// the signer cannot produce blockchain transaction signatures. It is intended for
+// operator authorization, approvals and attestation signatures next to wallets
+// derived by this package.
+//
+// The backend uses cgo and links against pcsc-lite on Linux, therefore it is only
+// compiled with the yubikey build tag:
+//
+//	go build -tags yubikey
+package yubikey
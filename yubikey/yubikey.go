@@ -0,0 +1,206 @@
+//go:build yubikey
+
+package yubikey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-piv/piv-go/piv"
+	"github.com/not-for-prod/hdwallet"
+)
+
+// YubiKey is an open connection to the PIV applet of a YubiKey
+// A YubiKey holds an exclusive PC/SC connection and must be closed when no longer needed
+type YubiKey struct {
+	yk *piv.YubiKey
+}
+
+// GenerateOptions configures on-device key generation
+type GenerateOptions struct {
+	// ManagementKey authorizes key generation, nil uses piv.DefaultManagementKey
+	ManagementKey *[24]byte
+	// Algorithm is piv.AlgorithmEC256 (default) or piv.AlgorithmEC384
+	Algorithm piv.Algorithm
+	// PINPolicy defaults to piv.PINPolicyAlways
+	PINPolicy piv.PINPolicy
+	// TouchPolicy defaults to piv.TouchPolicyAlways so every signature needs physical presence
+	TouchPolicy piv.TouchPolicy
+}
+
+// Attestation proves that the key in a slot was generated on a genuine YubiKey and cannot be exported
+type Attestation struct {
+	// SlotCertificate is the attestation certificate of the slot key, signed by the device
+	SlotCertificate *x509.Certificate
+	// DeviceCertificate is the device attestation certificate, signed by the Yubico PIV CA
+	DeviceCertificate *x509.Certificate
+	// Details is the verified content of the attestation (serial, firmware, policies)
+	Details *piv.Attestation
+}
+
+// Open connects to the YubiKey with the given serial number
+// A zero serial opens the first YubiKey found
+func Open(serial uint32) (*YubiKey, error) {
+	cards, err := piv.Cards()
+	if err != nil {
+		return nil, fmt.Errorf("list smart cards: %w", err)
+	}
+
+	for _, card := range cards {
+		if !strings.Contains(strings.ToLower(card), "yubikey") {
+			continue
+		}
+
+		yk, err := piv.Open(card)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", card, err)
+		}
+
+		if serial == 0 {
+			return &YubiKey{yk: yk}, nil
+		}
+
+		got, err := yk.Serial()
+		if err == nil && got == serial {
+			return &YubiKey{yk: yk}, nil
+		}
+
+		_ = yk.Close()
+	}
+
+	if serial == 0 {
+		return nil, errors.New("no yubikey found")
+	}
+
+	return nil, fmt.Errorf("yubikey with serial %d not found", serial)
+}
+
+// Close releases the PC/SC connection
+func (y *YubiKey) Close() error {
+	return y.yk.Close()
+}
+
+// Serial returns the serial number of the YubiKey
+func (y *YubiKey) Serial() (uint32, error) {
+	return y.yk.Serial()
+}
+
+// GenerateKey generates a new ECDSA key in slot, replacing any key stored there,
+// and returns a Signer for it that authenticates with pin
+func (y *YubiKey) GenerateKey(slot piv.Slot, pin string, opts GenerateOptions) (*Signer, error) {
+	managementKey := piv.DefaultManagementKey
+	if opts.ManagementKey != nil {
+		managementKey = *opts.ManagementKey
+	}
+
+	key := piv.Key{
+		Algorithm:   opts.Algorithm,
+		PINPolicy:   opts.PINPolicy,
+		TouchPolicy: opts.TouchPolicy,
+	}
+	if key.Algorithm == 0 {
+		key.Algorithm = piv.AlgorithmEC256
+	}
+	if key.Algorithm != piv.AlgorithmEC256 && key.Algorithm != piv.AlgorithmEC384 {
+		return nil, errors.New("only EC P-256 and P-384 keys are supported")
+	}
+	if key.PINPolicy == 0 {
+		key.PINPolicy = piv.PINPolicyAlways
+	}
+	if key.TouchPolicy == 0 {
+		key.TouchPolicy = piv.TouchPolicyAlways
+	}
+
+	public, err := y.yk.GenerateKey(managementKey, slot, key)
+	if err != nil {
+		return nil, fmt.Errorf("generate key in slot %s: %w", slot, err)
+	}
+
+	return y.signer(slot, public, piv.KeyAuth{PIN: pin, PINPolicy: key.PINPolicy})
+}
+
+// Signer returns a Signer for the key already stored in slot
+// The public key is taken from the slot attestation, so the key must have been
+// generated on the device (imported keys cannot be attested and are rejected)
+func (y *YubiKey) Signer(slot piv.Slot, pin string) (*Signer, error) {
+	cert, err := y.yk.Attest(slot)
+	if err != nil {
+		return nil, fmt.Errorf("read public key of slot %s: %w", slot, err)
+	}
+
+	return y.signer(slot, cert.PublicKey, piv.KeyAuth{PIN: pin})
+}
+
+// Attest returns the verified attestation of the key stored in slot
+func (y *YubiKey) Attest(slot piv.Slot) (*Attestation, error) {
+	device, err := y.yk.AttestationCertificate()
+	if err != nil {
+		return nil, fmt.Errorf("read device attestation certificate: %w", err)
+	}
+
+	slotCert, err := y.yk.Attest(slot)
+	if err != nil {
+		return nil, fmt.Errorf("attest slot %s: %w", slot, err)
+	}
+
+	// piv.Verify checks that the slot certificate is signed by the device
+	// certificate and that the device certificate chains to the Yubico PIV CA
+	details, err := piv.Verify(device, slotCert)
+	if err != nil {
+		return nil, fmt.Errorf("verify attestation of slot %s: %w", slot, err)
+	}
+
+	return &Attestation{
+		SlotCertificate:   slotCert,
+		DeviceCertificate: device,
+		Details:           details,
+	}, nil
+}
+
+func (y *YubiKey) signer(slot piv.Slot, public crypto.PublicKey, auth piv.KeyAuth) (*Signer, error) {
+	ecdsaPublic, ok := public.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("slot %s does not hold an ECDSA key", slot)
+	}
+
+	private, err := y.yk.PrivateKey(slot, ecdsaPublic, auth)
+	if err != nil {
+		return nil, fmt.Errorf("open key in slot %s: %w", slot, err)
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key in slot %s cannot sign", slot)
+	}
+
+	return &Signer{public: ecdsaPublic, key: signer}, nil
+}
+
+// Signer is a hdwallet.Signer whose private key stays on the YubiKey
+// Signing is serialized by the device; a Signer must not outlive the YubiKey it came from
+type Signer struct {
+	public *ecdsa.PublicKey
+	key    crypto.Signer
+}
+
+var _ hdwallet.Signer = (*Signer)(nil)
+
+// Public returns the *ecdsa.PublicKey of the slot key
+func (s *Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// SignDigest signs digest on the device and returns the DER encoded ECDSA signature
+// Depending on the key policies the call may block until the user touches the YubiKey
+func (s *Signer) SignDigest(digest []byte) ([]byte, error) {
+	if len(digest) == 0 {
+		return nil, errors.New("digest is empty")
+	}
+
+	return s.key.Sign(rand.Reader, digest, crypto.SHA256)
+}